@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How many members are fetched from Discord at a time.
+const memberPageSize = 1000
+
+// What a member can do in a channel, following Discord's permission hierarchy: the guild owner can do anything, then
+// the permissions of @everyone and the member's roles, where Administrator grants everything, then the channel's
+// @everyone overwrite, its overwrites for the member's roles, and its overwrite for the member.
+func effectivePermissions(g *discordgo.Guild, c *discordgo.Channel, m *discordgo.Member) int64 {
+	if m.User.ID == g.OwnerID {
+		return discordgo.PermissionAll
+	}
+	var perms int64
+	for _, r := range g.Roles {
+		// The @everyone role has the guild's ID.
+		if r.ID == g.ID || slices.Contains(m.Roles, r.ID) {
+			perms |= r.Permissions
+		}
+	}
+	if perms&discordgo.PermissionAdministrator != 0 {
+		return discordgo.PermissionAll
+	}
+
+	var everyone, member *discordgo.PermissionOverwrite
+	var allow, deny int64
+	for _, o := range c.PermissionOverwrites {
+		switch {
+		case o.ID == g.ID:
+			everyone = o
+		case o.Type == discordgo.PermissionOverwriteTypeMember && o.ID == m.User.ID:
+			member = o
+		case o.Type == discordgo.PermissionOverwriteTypeRole && slices.Contains(m.Roles, o.ID):
+			allow |= o.Allow
+			deny |= o.Deny
+		}
+	}
+	if everyone != nil {
+		perms = perms&^everyone.Deny | everyone.Allow
+	}
+	perms = perms&^deny | allow
+	if member != nil {
+		perms = perms&^member.Deny | member.Allow
+	}
+	return perms
+}
+
+// Every member of the guild, fetched a page at a time.
+func guildMembers(s *discordgo.Session) ([]*discordgo.Member, error) {
+	var members []*discordgo.Member
+	after := ""
+	for {
+		page, err := s.GuildMembers(JuiceworksGuildId, after, memberPageSize)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, page...)
+		if len(page) < memberPageSize {
+			return members, nil
+		}
+		after = page[len(page)-1].User.ID
+	}
+}
+
+// List who can see the channel the command was called from, so staff can check it's private before sharing
+// something sensitive.
+func whoCanSeeCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Fetching every member can take a while.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}))
+
+	content, err := whoCanSeeContent(s, i.ChannelID)
+	if err != nil {
+		log.Printf("Error working out who can see channel: %v", err)
+		content = "Error working out who can see the channel: " + describeError(err)
+	}
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+	}
+}
+
+// Describe who can see a channel: clients and guests first, since they're who a privacy check is about, then
+// Juiceworks members and bots.
+func whoCanSeeContent(s *discordgo.Session, channelID string) (string, error) {
+	c, err := s.Channel(channelID)
+	if err != nil {
+		return "", err
+	}
+	g, err := s.Guild(JuiceworksGuildId)
+	if err != nil {
+		return "", err
+	}
+	members, err := guildMembers(s)
+	if err != nil {
+		return "", err
+	}
+
+	var guests, staff, bots []string
+	for _, m := range members {
+		if effectivePermissions(g, c, m)&discordgo.PermissionViewChannel == 0 {
+			continue
+		}
+		switch {
+		case m.User.Bot:
+			bots = append(bots, m.User.Mention())
+		case slices.Contains(m.Roles, JuiceworksRoleId):
+			staff = append(staff, m.User.Mention())
+		default:
+			guests = append(guests, m.User.Mention())
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d members can see <#%s>.\n", len(guests)+len(staff)+len(bots), c.ID)
+	for _, group := range []struct {
+		name    string
+		members []string
+	}{{"Clients and guests", guests}, {"Juiceworks", staff}, {"Bots", bots}} {
+		if len(group.members) > 0 {
+			fmt.Fprintf(&b, "**%s (%d):** %s\n", group.name, len(group.members), strings.Join(group.members, " "))
+		}
+	}
+	content := b.String()
+	if len(content) > 2000 {
+		content = truncate(content, 1990) + "\n…"
+	}
+	return content, nil
+}
+
+// Explain whether a member has a permission in a channel by the last layer of the hierarchy that decided it, like
+// "the overwrite for @Juiceworks allows it".
+func explainPermission(g *discordgo.Guild, c *discordgo.Channel, m *discordgo.Member, permission int64) (bool, string) {
+	if m.User.ID == g.OwnerID {
+		return true, "they own the server"
+	}
+	var granting []string
+	for _, r := range g.Roles {
+		if r.ID != g.ID && !slices.Contains(m.Roles, r.ID) {
+			continue
+		}
+		if r.Permissions&discordgo.PermissionAdministrator != 0 {
+			return true, "they have Administrator through " + roleMention(g, r.ID)
+		}
+		if r.Permissions&permission != 0 {
+			granting = append(granting, roleMention(g, r.ID))
+		}
+	}
+	allowed := len(granting) > 0
+	reason := "none of their roles grant it"
+	if allowed {
+		reason = "their roles grant it (" + strings.Join(granting, ", ") + ")"
+	}
+
+	var allowing, denying []string
+	var member *discordgo.PermissionOverwrite
+	for _, o := range c.PermissionOverwrites {
+		switch {
+		case o.ID == g.ID:
+			if o.Deny&permission != 0 {
+				allowed, reason = false, "this channel's @everyone overwrite denies it"
+			}
+			if o.Allow&permission != 0 {
+				allowed, reason = true, "this channel's @everyone overwrite allows it"
+			}
+		case o.Type == discordgo.PermissionOverwriteTypeMember && o.ID == m.User.ID:
+			member = o
+		case o.Type == discordgo.PermissionOverwriteTypeRole && slices.Contains(m.Roles, o.ID):
+			if o.Allow&permission != 0 {
+				allowing = append(allowing, roleMention(g, o.ID))
+			}
+			if o.Deny&permission != 0 {
+				denying = append(denying, roleMention(g, o.ID))
+			}
+		}
+	}
+	// An allow from any of their roles beats a deny from another.
+	if len(allowing) > 0 {
+		allowed, reason = true, "the overwrite for "+strings.Join(allowing, ", ")+" allows it"
+	} else if len(denying) > 0 {
+		allowed, reason = false, "the overwrite for "+strings.Join(denying, ", ")+" denies it"
+	}
+	if member != nil && member.Deny&permission != 0 {
+		allowed, reason = false, "their own overwrite in this channel denies it"
+	}
+	if member != nil && member.Allow&permission != 0 {
+		allowed, reason = true, "their own overwrite in this channel allows it"
+	}
+	return allowed, reason
+}
+
+// Mention a role, or @everyone without pinging it.
+func roleMention(g *discordgo.Guild, roleID string) string {
+	if roleID == g.ID {
+		return "@everyone"
+	}
+	return "<@&" + roleID + ">"
+}
+
+// Explain whether a member can see and post in a channel, so staff don't have to guess when someone says they can't
+// see it.
+func checkAccessCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range i.ApplicationCommandData().Options {
+		options[o.Name] = o
+	}
+	user := options["user"].UserValue(s)
+	channelID := i.ChannelID
+	if o, ok := options["channel"]; ok {
+		channelID = o.ChannelValue(s).ID
+	}
+
+	content, err := checkAccessContent(s, user, channelID)
+	if err != nil {
+		log.Printf("Error checking access: %v", err)
+		content = "Error checking access: " + describeError(err)
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Describe whether a user can view and send messages in a channel and why, with what to do about it in a project.
+func checkAccessContent(s *discordgo.Session, user *discordgo.User, channelID string) (string, error) {
+	m, err := s.GuildMember(JuiceworksGuildId, user.ID)
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMember {
+		return fmt.Sprintf("%s isn't in the server, so they can't see any channels. Invite them first.", user.Mention()), nil
+	} else if err != nil {
+		return "", err
+	}
+	c, err := s.Channel(channelID)
+	if err != nil {
+		return "", err
+	}
+	g, err := s.Guild(JuiceworksGuildId)
+	if err != nil {
+		return "", err
+	}
+
+	canView, viewReason := explainPermission(g, c, m, discordgo.PermissionViewChannel)
+	canSend, sendReason := explainPermission(g, c, m, discordgo.PermissionSendMessages)
+	if !canView {
+		canSend, sendReason = false, "they can't see the channel"
+	}
+	yesNo := map[bool]string{true: "yes", false: "no"}
+	var b strings.Builder
+	switch {
+	case canView && canSend:
+		fmt.Fprintf(&b, "%s can see <#%s> and send messages in it.\n", user.Mention(), c.ID)
+	case canView:
+		fmt.Fprintf(&b, "%s can see <#%s>, but can't send messages in it.\n", user.Mention(), c.ID)
+	default:
+		fmt.Fprintf(&b, "%s can't see <#%s>.\n", user.Mention(), c.ID)
+	}
+	fmt.Fprintf(&b, "- View Channel: %s, because %s.\n", yesNo[canView], viewReason)
+	fmt.Fprintf(&b, "- Send Messages: %s, because %s.\n", yesNo[canSend], sendReason)
+
+	if p, ok := getProject(workspaceProjectID(c.ID)); ok && !canView {
+		if _, listed := p.Members[user.ID]; listed {
+			b.WriteString("They're a member of this project, so their access was probably removed by hand. Running /add-member again gives it back.")
+		} else {
+			fmt.Fprintf(&b, "They aren't a member of this project. Add them with /add-member in <#%s>.", p.ChannelID)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How many matching projects an access request offers to approve for. With the Deny button, that fills a row.
+const accessRequestMatches = 4
+
+// Post a Request access button in the channel the command was called from and pin it, for a lobby where people
+// without access to their project can ask for it.
+func accessButtonCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	content := "Posted and pinned the Request access button."
+	m, err := s.ChannelMessageSendComplex(i.ChannelID, &discordgo.MessageSend{
+		Content: "Can't see your project's channel? Ask for access and the Juiceworks team will add you.",
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Request access", Style: discordgo.PrimaryButton, CustomID: "access-request"},
+			}},
+		},
+	})
+	if err == nil {
+		err = s.ChannelMessagePin(i.ChannelID, m.ID)
+	}
+	if err != nil {
+		log.Printf("Error posting access button: %v", err)
+		content = "Error posting access button: " + describeError(err)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Ask which project someone wants access to, and why, in a modal.
+func startAccessRequest(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "access-request-submit",
+			Title:    "Request access",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "project",
+						Label:     "Which project?",
+						Style:     discordgo.TextInputShort,
+						Required:  true,
+						MaxLength: 100,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "reason",
+						Label:     "Why do you need access?",
+						Style:     discordgo.TextInputParagraph,
+						Required:  true,
+						MaxLength: 1000,
+					},
+				}},
+			},
+		},
+	}))
+}
+
+// Post an access request in the internal channel, with a button to approve it for each project that matches and one
+// to deny it. The requester isn't told which projects matched, so the lobby can't be used to find out what exists.
+func submitAccessRequest(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := i.Member.User
+	var query, reason string
+	for _, row := range i.ModalSubmitData().Components {
+		for _, c := range row.(*discordgo.ActionsRow).Components {
+			switch input := c.(*discordgo.TextInput); input.CustomID {
+			case "project":
+				query = strings.TrimSpace(input.Value)
+			case "reason":
+				reason = input.Value
+			}
+		}
+	}
+
+	var buttons []discordgo.MessageComponent
+	for _, m := range findProjects(query) {
+		if len(buttons) == accessRequestMatches {
+			break
+		}
+		if m.project.Trash != nil {
+			continue
+		}
+		buttons = append(buttons, discordgo.Button{
+			Label:    truncate("Add to #"+m.project.Name, 80),
+			Style:    discordgo.SuccessButton,
+			CustomID: "access-approve:" + m.project.ChannelID + ":" + user.ID,
+		})
+	}
+	content := user.Mention() + " asked for access to a project."
+	if len(buttons) == 0 {
+		content += " No project matches what they asked for, so add them by hand if it's right."
+	}
+	buttons = append(buttons, discordgo.Button{Label: "Deny", Style: discordgo.DangerButton, CustomID: "access-deny:" + user.ID})
+
+	_, err := s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{
+		Content: content,
+		Embed: themed(i.GuildID, &discordgo.MessageEmbed{
+			Title:     "Access request from " + user.Username,
+			Thumbnail: &discordgo.MessageEmbedThumbnail{URL: user.AvatarURL("")},
+			Fields: []*discordgo.MessageEmbedField{
+				{Name: "Project", Value: truncate(query, 1024)},
+				{Name: "Reason", Value: truncate(reason, 1024)},
+			},
+		}),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: buttons},
+		},
+	})
+	content = "Thanks! The Juiceworks team will let you know once someone has had a look."
+	if err != nil {
+		log.Printf("Error posting access request: %v", err)
+		content = "Error sending your request: " + describeError(err)
+	}
+
+	log.Printf("Received an access request from %s for %q.", user, query)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Handle the approve and Deny buttons on an access request. Approving adds the requester to the project the way
+// /add-member does, including sending them the terms first if they haven't accepted them.
+func reviewAccessRequest(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	action, rest, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	approved := action == "access-approve"
+	projectID, userID := "", rest
+	if approved {
+		projectID, userID, _ = strings.Cut(rest, ":")
+	}
+
+	message := "Sorry, your request for access to a Juiceworks project wasn't approved. Ask your Juiceworks contact if you think that's wrong."
+	outcome := "Denied"
+	if approved {
+		member, err := s.GuildMember(JuiceworksGuildId, userID)
+		if err != nil {
+			log.Printf("Error reading member roles: %v", err)
+			logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Error reading member roles: " + describeError(err),
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			}))
+			return
+		}
+
+		// The terms flow responds to the interaction itself, and adds them once they agree.
+		if requiresTerms(member) {
+			sendTerms(s, i, projectID, member.User)
+			content := fmt.Sprintf("%s\n**Approved for <#%s>** by %s. They've been sent the terms.", i.Message.Content, projectID, i.Member.User.Mention())
+			if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+				ID:              i.Message.ID,
+				Channel:         i.ChannelID,
+				Content:         &content,
+				Components:      &[]discordgo.MessageComponent{},
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			}); err != nil {
+				log.Printf("Error updating access request: %v", err)
+			}
+			return
+		}
+
+		undoAddMember := undoAddingMember(s, projectID, member.User, member)
+		if err := grantChannelAccess(s, i, projectID, member.User, member); err != nil {
+			return
+		}
+		pushUndo(i.Member.User.ID, fmt.Sprintf("Adding %s to <#%s>", member.User.Mention(), projectID), undoAddMember)
+		message = fmt.Sprintf("Your request was approved. You can now see <#%s>.", projectID)
+		outcome = fmt.Sprintf("Approved for <#%s>", projectID)
+	}
+
+	// Let the requester know the outcome.
+	dm, err := s.UserChannelCreate(userID)
+	if err == nil {
+		_, err = s.ChannelMessageSend(dm.ID, message)
+	}
+	if err != nil {
+		log.Printf("Error sending access request outcome: %v", err)
+	}
+
+	// Replace the buttons with the outcome so the request can't be reviewed twice.
+	log.Printf("%s access request of user %s by %s.", outcome, userID, i.Member.User)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:         fmt.Sprintf("%s\n**%s** by %s.", i.Message.Content, outcome, i.Member.User.Mention()),
+			Components:      []discordgo.MessageComponent{},
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}))
+}
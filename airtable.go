@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Upsert a project into the Airtable table used by the ops team, matching rows on the "Channel ID" field, with its
+// status and its next deadline, which is the earliest unpaid milestone with a due date. Does nothing unless
+// AIRTABLE_TOKEN, AIRTABLE_BASE_ID and AIRTABLE_TABLE are set.
+func syncProjectToAirtable(p *project) (err error) {
+	token, base, table := os.Getenv("AIRTABLE_TOKEN"), os.Getenv("AIRTABLE_BASE_ID"), os.Getenv("AIRTABLE_TABLE")
+	if token == "" || base == "" || table == "" {
+		return nil
+	}
+	defer func() { noteIntegrationCall("Airtable", err) }()
+
+	members := make([]string, 0, len(p.Members))
+	for _, name := range p.Members {
+		members = append(members, name)
+	}
+	slices.Sort(members)
+
+	// The earliest unpaid milestone with a due date, if any. Empty clears the field.
+	var deadline, deadlineName any
+	for _, m := range p.Milestones {
+		if m.Due.IsZero() || !m.PaidAt.IsZero() {
+			continue
+		}
+		if due, ok := deadline.(string); !ok || m.Due.Format(time.DateOnly) < due {
+			deadline, deadlineName = m.Due.Format(time.DateOnly), m.Name
+		}
+	}
+	status := projectStatus(*p)
+	if p.Trash != nil {
+		status = "trashed"
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"performUpsert": map[string]any{"fieldsToMergeOn": []string{"Channel ID"}},
+		"typecast":      true,
+		"records": []map[string]any{{
+			"fields": map[string]any{
+				"Channel ID":     p.ChannelID,
+				"Name":           p.Name,
+				"Created":        p.CreatedAt.Format(time.RFC3339),
+				"Created By":     p.CreatedBy,
+				"Members":        strings.Join(members, ", "),
+				"Status":         status,
+				"Next Deadline":  deadline,
+				"Next Milestone": deadlineName,
+			},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := "https://api.airtable.com/v0/" + url.PathEscape(base) + "/" + url.PathEscape(table)
+	req, err := http.NewRequest(http.MethodPatch, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("airtable returned %s: %s", resp.Status, msg)
+	}
+	return nil
+}
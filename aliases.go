@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The names Discord accepts for slash commands.
+var commandNamePattern = regexp.MustCompile(`^[-_\p{Ll}\p{N}]{1,32}$`)
+
+// The most commands Discord lets an application register in a guild. Aliases count towards it, and going over it stops
+// the bot from starting.
+const maxGuildCommands = 100
+
+// Short names for commands, read from COMMAND_ALIASES as a comma-separated list like mc=make-channel,am=add-member.
+// Aliases that aren't valid command names, clash with a command, point at a command that doesn't exist or isn't a slash
+// command, or would take the bot past Discord's command limit are logged and skipped.
+func commandAliases() map[string]string {
+	aliases := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv("COMMAND_ALIASES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		alias, command, _ := strings.Cut(entry, "=")
+		alias, command = strings.TrimSpace(alias), strings.TrimSpace(command)
+		exists := func(name string) bool {
+			return slices.ContainsFunc(commands, func(c *discordgo.ApplicationCommand) bool {
+				return c.Name == name
+			})
+		}
+		// Message commands can't have descriptions, so Discord would refuse a copy of one described as an alias.
+		isSlashCommand := slices.ContainsFunc(commands, func(c *discordgo.ApplicationCommand) bool {
+			return c.Name == command && (c.Type == 0 || c.Type == discordgo.ChatApplicationCommand)
+		})
+		if !commandNamePattern.MatchString(alias) || exists(alias) || !isSlashCommand {
+			log.Printf("Ignoring invalid COMMAND_ALIASES entry %q", entry)
+			continue
+		}
+		if _, ok := aliases[alias]; !ok && len(commands)+len(aliases) >= maxGuildCommands {
+			log.Printf("Ignoring COMMAND_ALIASES entry %q, since Discord allows at most %d commands", entry, maxGuildCommands)
+			continue
+		}
+		aliases[alias] = command
+	}
+	return aliases
+}
+
+// Resolve a command name that may be an alias to the command it stands for.
+func resolveCommand(name string) string {
+	if command, ok := commandAliases()[name]; ok {
+		return command
+	}
+	return name
+}
+
+// Build a copy of each aliased command under its alias, to register alongside the real commands.
+func aliasCommands() []*discordgo.ApplicationCommand {
+	var aliased []*discordgo.ApplicationCommand
+	for alias, command := range commandAliases() {
+		for _, c := range commands {
+			if c.Name == command {
+				a := *c
+				a.Name = alias
+				a.Description = truncate("/"+command+": "+c.Description, 100)
+				aliased = append(aliased, &a)
+			}
+		}
+	}
+	return aliased
+}
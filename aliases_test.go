@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Message commands can't be aliased, since Discord would refuse the copy and the bot wouldn't start.
+func TestAliasOfMessageCommand(t *testing.T) {
+	t.Setenv("COMMAND_ALIASES", "bm=Bookmark,mc=make-channel")
+	aliases := commandAliases()
+	if _, ok := aliases["bm"]; ok {
+		t.Error("the message command was aliased")
+	}
+	if aliases["mc"] != "make-channel" {
+		t.Errorf("aliases = %v, want mc for make-channel", aliases)
+	}
+}
+
+// Aliases that would take the bot past Discord's command limit are skipped.
+func TestAliasesPastCommandLimit(t *testing.T) {
+	var entries []string
+	for n := range maxGuildCommands {
+		entries = append(entries, fmt.Sprintf("mc%d=make-channel", n))
+	}
+	t.Setenv("COMMAND_ALIASES", strings.Join(entries, ","))
+	if got := len(commands) + len(aliasCommands()); got != maxGuildCommands {
+		t.Errorf("%d commands would be registered, want %d", got, maxGuildCommands)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/juiceworks/juiceworks-discord/projectapi"
+)
+
+// List the registered projects, ordered by name. Only what projectapi.Project has is listed, so secrets and rates
+// stay in the bot.
+func apiListProjects(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	var projects []projectapi.Project
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			projects = append(projects, apiProject(p))
+		}
+	})
+	slices.SortFunc(projects, func(a, b projectapi.Project) int { return strings.Compare(a.Name, b.Name) })
+	writeJSON(w, http.StatusOK, projects)
+}
+
+// A project as the API shows it.
+func apiProject(p *project) projectapi.Project {
+	listed := projectapi.Project{
+		ChannelID:  p.ChannelID,
+		Name:       p.Name,
+		CreatedAt:  p.CreatedAt,
+		CreatedBy:  p.CreatedBy,
+		Members:    maps.Clone(p.Members),
+		CreatorID:  p.CreatorID,
+		ClientName: p.ClientName,
+		Status:     p.Status,
+	}
+	if p.Trash != nil {
+		listed.Trash = &projectapi.Trash{DeletedAt: p.Trash.DeletedAt, DeletedBy: p.Trash.DeletedBy, PurgeAt: p.Trash.PurgeAt}
+	}
+	return listed
+}
+
+// Add a member to a project and the rest of its workspace, like /add-member. Members who still have to accept the
+// terms are refused, since there's nobody to send the terms from. A body with userIds instead of userId adds several
+// members at once, answering with what happened to each.
+func apiAddMember(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	projectID := workspaceProjectID(r.PathValue("channel"))
+	if _, ok := getProject(projectID); !ok {
+		http.Error(w, "unknown project", http.StatusNotFound)
+		return
+	}
+	var req struct {
+		UserID  string   `json:"userId"`
+		UserIDs []string `json:"userIds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.UserID == "") == (len(req.UserIDs) == 0) {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if len(req.UserIDs) > 0 {
+		writeJSON(w, http.StatusOK, addMembersOverAPI(s, projectID, req.UserIDs))
+		return
+	}
+	member, err := s.GuildMember(JuiceworksGuildId, req.UserID)
+	if err != nil {
+		log.Printf("Error reading member roles: %v", err)
+		http.Error(w, "unknown member", http.StatusNotFound)
+		return
+	}
+	if requiresTerms(member) {
+		http.Error(w, "member has to accept the terms first; add them with /add-member", http.StatusConflict)
+		return
+	}
+
+	err = addProjectMember(s, projectID, member.User, member, "")
+	var failed *memberChangeError
+	if errors.As(err, &failed) {
+		log.Printf("Error %s: %v", failed.step, failed.err)
+		http.Error(w, "error "+failed.step, http.StatusBadGateway)
+		return
+	}
+
+	log.Printf("Added %s to channel %s over the API.", member.User, projectID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Add several members to a project at once for apiAddMember, reporting what happened to each.
+func addMembersOverAPI(s *discordgo.Session, projectID string, userIDs []string) []projectapi.MemberResult {
+	results := make([]projectapi.MemberResult, len(userIDs))
+	var members []*discordgo.Member
+	var indexes []int
+	for n, userID := range userIDs {
+		results[n].UserID = userID
+		member, err := s.GuildMember(JuiceworksGuildId, userID)
+		switch {
+		case err != nil:
+			log.Printf("Error reading member roles: %v", err)
+			results[n].Error = "unknown member"
+		case requiresTerms(member):
+			results[n].Error = "member has to accept the terms first; add them with /add-member"
+		default:
+			members = append(members, member)
+			indexes = append(indexes, n)
+		}
+	}
+
+	for k, err := range addProjectMembers(s, projectID, members, "") {
+		var failed *memberChangeError
+		if errors.As(err, &failed) {
+			log.Printf("Error %s: %v", failed.step, failed.err)
+			results[indexes[k]].Error = "error " + failed.step
+			continue
+		}
+		log.Printf("Added %s to channel %s over the API.", members[k].User, projectID)
+	}
+	return results
+}
+
+// Remove a member from a project and the rest of its workspace, like /remove-member.
+func apiRemoveMember(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	projectID := workspaceProjectID(r.PathValue("channel"))
+	if _, ok := getProject(projectID); !ok {
+		http.Error(w, "unknown project", http.StatusNotFound)
+		return
+	}
+	userID := r.PathValue("user")
+	err := removeProjectMember(s, projectID, userID, "")
+	var failed *memberChangeError
+	if errors.As(err, &failed) {
+		log.Printf("Error %s: %v", failed.step, failed.err)
+		http.Error(w, "error "+failed.step, http.StatusBadGateway)
+		return
+	}
+
+	log.Printf("Removed user %s from channel %s over the API.", userID, projectID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Push every project to the systems mirroring the registry again, to bring them back in line after an outage or a
+// manual edit. Returns how many projects failed to sync.
+func apiReconcile(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	var channelIDs []string
+	readStore(func(d *storeData) {
+		for id := range d.Projects {
+			channelIDs = append(channelIDs, id)
+		}
+	})
+	failed := 0
+	for _, channelID := range channelIDs {
+		if !projectChanged(channelID) {
+			failed++
+		}
+	}
+	log.Printf("Reconciled %d projects, %d failed.", len(channelIDs), failed)
+	writeJSON(w, http.StatusOK, map[string]int{"projects": len(channelIDs), "failed": failed})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The largest attachment that's archived. Larger ones are skipped and recorded as failures.
+const maxArchivedFileSize = 100 << 20
+
+// How long links handed out by /files work, and how many files it lists.
+const (
+	fileLinkLifetime = 7 * 24 * time.Hour
+	filesListLimit   = 20
+)
+
+// An attachment copied to object storage.
+type archivedFile struct {
+	Key        string    `json:"key"`
+	Name       string    `json:"name"`
+	Size       int       `json:"size"`
+	MessageID  string    `json:"messageId"`
+	UploadedBy string    `json:"uploadedBy"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
+
+// The key prefix for a project's files, from S3_PREFIX. {channel} is replaced with the channel ID and {project} with
+// the project's name. Defaults to projects/{channel}/.
+func archivePrefix(p project) string {
+	prefix := os.Getenv("S3_PREFIX")
+	if prefix == "" {
+		prefix = "projects/{channel}/"
+	}
+	return strings.NewReplacer("{channel}", p.ChannelID, "{project}", p.Name).Replace(prefix)
+}
+
+// Copy attachments posted in project channels to object storage, since Discord's links to them expire.
+func archiveAttachments(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if len(m.Attachments) == 0 || !s3Configured() {
+		return
+	}
+	p, ok := getProject(m.ChannelID)
+	if !ok {
+		return
+	}
+
+	for _, a := range m.Attachments {
+		if a.Size > maxArchivedFileSize {
+			recordFailure("archiving attachment", fmt.Errorf("%s in channel %s is too large (%d bytes)", a.Filename, m.ChannelID, a.Size))
+			continue
+		}
+		body, err := downloadAttachment(a.URL)
+		if err != nil {
+			recordFailure("archiving attachment", err)
+			continue
+		}
+		key := archivePrefix(p) + m.ID + "/" + a.Filename
+		if err := s3Put(key, a.ContentType, body); err != nil {
+			recordFailure("archiving attachment", err)
+			continue
+		}
+
+		f := archivedFile{Key: key, Name: a.Filename, Size: a.Size, MessageID: m.ID, UploadedBy: m.Author.ID, UploadedAt: time.Now()}
+		err = updateProject(m.ChannelID, func(p *project) {
+			p.Files = append(p.Files, f)
+		})
+		if err != nil {
+			log.Printf("Error recording archived file: %v", err)
+		}
+		log.Printf("Archived %s from channel %s to %s.", a.Filename, m.ChannelID, key)
+	}
+}
+
+// Download an attachment from Discord's CDN.
+func downloadAttachment(link string) ([]byte, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(link)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discord returned %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxArchivedFileSize))
+}
+
+// A link to download an archived file through the bot, which works until it expires. The bot redirects to a
+// short-lived storage link, so the links in /files stay short.
+func fileLink(key string, expires time.Time) string {
+	value := key + "|" + strconv.FormatInt(expires.Unix(), 10)
+	params := url.Values{"key": {key}, "expires": {strconv.FormatInt(expires.Unix(), 10)}, "sig": {signLink(value)}}
+	return strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/") + "/files?" + params.Encode()
+}
+
+// Redirect a file link from /files to the file in storage.
+func downloadFile(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil || !validLinkSignature(q.Get("key")+"|"+q.Get("expires"), q.Get("sig")) {
+		http.Error(w, "This file link is invalid.", http.StatusForbidden)
+		return
+	}
+	if time.Now().Unix() > expires {
+		http.Error(w, "This file link has expired. Use /files in Discord to get a new one.", http.StatusGone)
+		return
+	}
+	http.Redirect(w, r, s3PresignedURL(q.Get("key"), 5*time.Minute), http.StatusFound)
+}
+
+// List the files archived from the project channel the command was called from, newest first, with links to them.
+func filesCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	p, ok := getProject(i.ChannelID)
+	var description string
+	switch {
+	case !ok:
+		description = "This channel is not a registered project."
+	case !s3Configured():
+		description = "File archiving isn't set up."
+	case len(p.Files) == 0:
+		description = "No files have been archived from this channel yet."
+	default:
+		files := slices.Clone(p.Files)
+		slices.Reverse(files)
+		expires := time.Now().Add(fileLinkLifetime)
+		var b strings.Builder
+		for _, f := range files[:min(len(files), filesListLimit)] {
+			name := strings.NewReplacer("[", "(", "]", ")").Replace(f.Name)
+			line := fmt.Sprintf("- %s (%d KB, <@%s>, <t:%d:d>)\n", name, (f.Size+1023)/1024, f.UploadedBy, f.UploadedAt.Unix())
+			if os.Getenv("PUBLIC_URL") != "" && os.Getenv("LINK_SIGNING_KEY") != "" {
+				line = fmt.Sprintf("- [%s](%s) (%d KB, <@%s>, <t:%d:d>)\n", name, fileLink(f.Key, expires), (f.Size+1023)/1024, f.UploadedBy, f.UploadedAt.Unix())
+			}
+			if b.Len()+len(line) > 4096 {
+				break
+			}
+			b.WriteString(line)
+		}
+		description = b.String()
+		if len(files) > filesListLimit {
+			description += fmt.Sprintf("…and %d older files.", len(files)-filesListLimit)
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{themed(i.GuildID, &discordgo.MessageEmbed{
+				Title:       "Archived files",
+				Description: truncate(description, 4096),
+			})},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
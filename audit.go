@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The kinds of action recorded in the audit log.
+const (
+	auditDenied              = "denied"
+	auditEmojiUploaded       = "emoji-uploaded"
+	auditEmojiRemoved        = "emoji-removed"
+	auditMerged              = "merged"
+	auditQuarantined         = "quarantined"
+	auditUnquarantined       = "unquarantined"
+	auditRenamed             = "renamed"
+	auditUserDataDeleted     = "user-data-deleted"
+	auditTokenRotated        = "token-rotated"
+	auditPermissionsMigrated = "permissions-migrated"
+	auditCreatorTransferred  = "creator-transferred"
+	auditChannelAdopted      = "channel-adopted"
+	auditProjectDeleted      = "project-deleted"
+	auditProjectRestored     = "project-restored"
+	auditSudo                = "sudo"
+	auditMemberAdded         = "member-added"
+	auditMemberRemoved       = "member-removed"
+	auditProjectCreated      = "project-created"
+	auditTokenCreated        = "token-created"
+	auditTokenRevoked        = "token-revoked"
+	auditTermsAccepted       = "terms-accepted"
+)
+
+// How many entries each page of /audit shows.
+const auditPageSize = 10
+
+// How long the page buttons on an /audit search keep working.
+const auditQueryLifetime = time.Hour
+
+// Something done with the bot worth keeping a record of. The summary is what's posted to the audit channel.
+type auditEntry struct {
+	At        time.Time `json:"at"`
+	Action    string    `json:"action"`
+	ActorID   string    `json:"actorId"`
+	TargetID  string    `json:"targetId,omitempty"`
+	ChannelID string    `json:"channelId,omitempty"`
+	Summary   string    `json:"summary"`
+}
+
+// What an /audit search is filtered by. Zero values match everything.
+type auditFilter struct {
+	actorID   string
+	targetID  string
+	channelID string
+	action    string
+	since     time.Time
+	until     time.Time
+	createdAt time.Time
+}
+
+// Searches made with /audit, keyed by interaction ID, so their page buttons know what to show. These only live in
+// memory: the buttons stop working after a while anyway.
+var (
+	auditQueriesMu sync.Mutex
+	auditQueries   = make(map[string]auditFilter)
+)
+
+// Record someone being added to or taken out of a project. Changes made over the API have no actor.
+func auditMemberChange(s *discordgo.Session, action, actorID, userID, channelID string) {
+	verb, preposition := "added", "to"
+	if action == auditMemberRemoved {
+		verb, preposition = "removed", "from"
+	}
+	summary := fmt.Sprintf("<@%s> %s <@%s> %s <#%s>.", actorID, verb, userID, preposition, channelID)
+	if actorID == "" {
+		summary = fmt.Sprintf("<@%s> was %s %s <#%s> over the API.", userID, verb, preposition, channelID)
+	}
+	postAudit(s, auditEntry{Action: action, ActorID: actorID, TargetID: userID, ChannelID: channelID, Summary: summary})
+}
+
+// Record a project being created. Projects created over the API on nobody's behalf have no actor.
+func auditProjectCreation(s *discordgo.Session, actorID, channelID string) {
+	summary := fmt.Sprintf("<@%s> created <#%s>.", actorID, channelID)
+	if actorID == "" {
+		summary = fmt.Sprintf("<#%s> was created over the API.", channelID)
+	}
+	postAudit(s, auditEntry{Action: auditProjectCreated, ActorID: actorID, ChannelID: channelID, Summary: summary})
+}
+
+// Record an action in the audit log, and post it to the audit channel in AUDIT_CHANNEL_ID if one is set. Mentions in
+// the post don't ping anyone.
+func postAudit(s *discordgo.Session, entry auditEntry) {
+	entry.At = time.Now()
+	err := updateStore(func(d *storeData) {
+		d.AuditLog = append(d.AuditLog, entry)
+	})
+	if err != nil {
+		log.Printf("Error saving audit entry: %v", err)
+	}
+
+	auditChannelID := os.Getenv("AUDIT_CHANNEL_ID")
+	if auditChannelID == "" {
+		return
+	}
+	_, err = s.ChannelMessageSendComplex(auditChannelID, &discordgo.MessageSend{
+		Content:         truncate(entry.Summary, 2000),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		log.Printf("Error posting to audit channel: %v", err)
+	}
+}
+
+// Whether an entry matches a search.
+func (f auditFilter) matches(e auditEntry) bool {
+	return (f.actorID == "" || e.ActorID == f.actorID) &&
+		(f.targetID == "" || e.TargetID == f.targetID) &&
+		(f.channelID == "" || e.ChannelID == f.channelID) &&
+		(f.action == "" || e.Action == f.action) &&
+		(f.since.IsZero() || !e.At.Before(f.since)) &&
+		(f.until.IsZero() || e.At.Before(f.until))
+}
+
+// Find the audit entries a search matches, newest first.
+func searchAudit(f auditFilter) []auditEntry {
+	var entries []auditEntry
+	readStore(func(d *storeData) {
+		for n := len(d.AuditLog) - 1; n >= 0; n-- {
+			if f.matches(d.AuditLog[n]) {
+				entries = append(entries, d.AuditLog[n])
+			}
+		}
+	})
+	return entries
+}
+
+// Show one page of a search's results, with buttons to move between pages.
+func auditPage(queryID string, entries []auditEntry, page int) *discordgo.InteractionResponseData {
+	pages := max(1, (len(entries)+auditPageSize-1)/auditPageSize)
+	page = min(max(page, 1), pages)
+
+	embed := themed(JuiceworksGuildId, &discordgo.MessageEmbed{
+		Title:  "Audit log",
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d of %d · %d entries", page, pages, len(entries))},
+	})
+	var lines []string
+	for _, e := range entries[(page-1)*auditPageSize : min(page*auditPageSize, len(entries))] {
+		lines = append(lines, fmt.Sprintf("<t:%d:f> `%s` %s", e.At.Unix(), e.Action, truncate(e.Summary, 300)))
+	}
+	embed.Description = "No entries match."
+	if len(lines) > 0 {
+		embed.Description = truncate(strings.Join(lines, "\n"), 4096)
+	}
+
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Previous", Style: discordgo.SecondaryButton, Disabled: page == 1,
+					CustomID: fmt.Sprintf("audit-page:%s:%d", queryID, page-1)},
+				discordgo.Button{Label: "Next", Style: discordgo.SecondaryButton, Disabled: page == pages,
+					CustomID: fmt.Sprintf("audit-page:%s:%d", queryID, page+1)},
+			}},
+		},
+		Flags: discordgo.MessageFlagsEphemeral,
+	}
+}
+
+// Export audit entries as CSV, for compliance reviews.
+func auditCSV(entries []auditEntry) ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write([]string{"time", "action", "actor", "target", "channel", "summary"})
+	for _, e := range entries {
+		w.Write([]string{e.At.UTC().Format(time.RFC3339), e.Action, e.ActorID, e.TargetID, e.ChannelID, e.Summary})
+	}
+	w.Flush()
+	return b.Bytes(), w.Error()
+}
+
+// Search the audit log by who acted, who it was done to, where, what kind of action and when. Results are shown a
+// page at a time, or exported as CSV.
+func auditCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range i.ApplicationCommandData().Options {
+		options[o.Name] = o
+	}
+
+	f := auditFilter{createdAt: time.Now()}
+	if o, ok := options["actor"]; ok {
+		f.actorID = o.UserValue(nil).ID
+	}
+	if o, ok := options["target"]; ok {
+		f.targetID = o.UserValue(nil).ID
+	}
+	if o, ok := options["channel"]; ok {
+		f.channelID = o.ChannelValue(nil).ID
+	}
+	if o, ok := options["action"]; ok {
+		f.action = o.StringValue()
+	}
+	var content string
+	for _, name := range []string{"since", "until"} {
+		o, ok := options[name]
+		if !ok {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", o.StringValue())
+		if err != nil {
+			content = fmt.Sprintf("`%s` isn't a date like 2024-01-31.", o.StringValue())
+			break
+		}
+		if name == "since" {
+			f.since = day
+		} else {
+			// Include the whole of the last day.
+			f.until = day.AddDate(0, 0, 1)
+		}
+	}
+	if content != "" {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: content, Flags: discordgo.MessageFlagsEphemeral},
+		}))
+		return
+	}
+
+	entries := searchAudit(f)
+	if o, ok := options["csv"]; ok && o.BoolValue() {
+		body, err := auditCSV(entries)
+		data := &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Exported %d audit entries.", len(entries)),
+			Files:   []*discordgo.File{{Name: "audit.csv", ContentType: "text/csv", Reader: bytes.NewReader(body)}},
+			Flags:   discordgo.MessageFlagsEphemeral,
+		}
+		if err != nil {
+			log.Printf("Error exporting audit log: %v", err)
+			data = &discordgo.InteractionResponseData{Content: "Error exporting audit log: " + describeError(err), Flags: discordgo.MessageFlagsEphemeral}
+		}
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: data,
+		}))
+		return
+	}
+
+	auditQueriesMu.Lock()
+	for id, q := range auditQueries {
+		if time.Since(q.createdAt) > auditQueryLifetime {
+			delete(auditQueries, id)
+		}
+	}
+	auditQueries[i.ID] = f
+	auditQueriesMu.Unlock()
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: auditPage(i.ID, entries, 1),
+	}))
+}
+
+// Move between the pages of an /audit search. The search is run again, so new entries show up as you go.
+func auditPageButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	parts := strings.Split(i.MessageComponentData().CustomID, ":")
+	queryID := parts[1]
+	page, _ := strconv.Atoi(parts[2])
+
+	auditQueriesMu.Lock()
+	f, ok := auditQueries[queryID]
+	auditQueriesMu.Unlock()
+	if !ok || time.Since(f.createdAt) > auditQueryLifetime {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "This search has expired. Run /audit again.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: auditPage(queryID, searchAudit(f), page),
+	}))
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Who can use a command or component, and where.
+type policy struct {
+	// Whether the caller needs the Juiceworks role. Otherwise any member of the Juiceworks guild can use it.
+	staff bool
+	// Whether the caller also needs the Administrator permission.
+	admin bool
+	// Whether the command is blocked in the internal channel.
+	notInternal bool
+	// Whether the command is blocked in project channels.
+	notInProjects bool
+	// Whether the interaction can come from a DM with the bot as well as the Juiceworks guild.
+	allowDMs bool
+}
+
+// Policies used by most commands.
+var (
+	staffPolicy   = policy{staff: true}
+	adminPolicy   = policy{staff: true, admin: true}
+	memberPolicy  = policy{}
+	anyonePolicy  = policy{allowDMs: true}
+	projectPolicy = policy{staff: true, notInternal: true}
+)
+
+// Check an interaction against its policy, and tell the caller why if they're refused. Interactions without a policy
+// are always refused. Returns true if the interaction may go ahead.
+func authorize(s *discordgo.Session, i *discordgo.InteractionCreate, name string, policies map[string]policy) bool {
+	reason := denial(s, i, name, policies)
+	if reason == "" {
+		if i.Member != nil && roleDenial(i, policies[name]) != "" {
+			auditSudoUse(s, i, name)
+		}
+		return true
+	}
+
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	log.Printf("Denied %s to %s in channel %s: %s", name, user, i.ChannelID, reason)
+	auditDenial(s, name, user, i.ChannelID, reason)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: reason,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+	return false
+}
+
+// Work out why the caller of an interaction can't use a command or component, or return an empty string if they can.
+func denial(s *discordgo.Session, i *discordgo.InteractionCreate, name string, policies map[string]policy) string {
+	p, ok := policies[name]
+	switch {
+	case !ok:
+		return "This command has no access policy."
+	case i.GuildID == "" && p.allowDMs:
+		return ""
+	case i.GuildID != JuiceworksGuildId || i.Member == nil:
+		return "This command can only be used in the Juiceworks Discord server."
+	}
+	// Someone who used /sudo for the command gets past its roles, but not where it can be used.
+	if reason := roleDenial(i, p); reason != "" && !elevated(i.Member.User.ID, elevatedCommand(i)) {
+		return reason
+	}
+	return channelDenial(s, i, name, p)
+}
+
+// Check the caller of an interaction has the roles and permissions a policy needs. Returns why they're refused, or an
+// empty string if they aren't.
+func roleDenial(i *discordgo.InteractionCreate, p policy) string {
+	switch {
+	case p.staff && !slices.Contains(i.Member.Roles, JuiceworksRoleId):
+		return "This command can only be used by Juiceworks members."
+	case p.admin && i.Member.Permissions&discordgo.PermissionAdministrator == 0:
+		return "This command can only be used by administrators."
+	}
+	return ""
+}
+
+// Channels and categories a command is allowed or denied in, set with /command-channels on top of its policy.
+type channelRules struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// Check where an interaction was used against the channels its policy denies and, for commands, the rules set with
+// /command-channels. Rules match a channel or the category it's in. Returns why the interaction is refused, or an
+// empty string if it isn't.
+func channelDenial(s *discordgo.Session, i *discordgo.InteractionCreate, name string, p policy) string {
+	ids := []string{i.ChannelID}
+	channel, err := s.State.Channel(i.ChannelID)
+	if err != nil {
+		channel, err = s.Channel(i.ChannelID)
+	}
+	if err == nil && channel.ParentID != "" {
+		ids = append(ids, channel.ParentID)
+	}
+	matches := func(list []string) bool {
+		return slices.ContainsFunc(ids, func(id string) bool {
+			return slices.Contains(list, id)
+		})
+	}
+
+	var denied []string
+	if p.notInternal {
+		denied = append(denied, InternalChannelId)
+	}
+	var allowed []string
+	if i.Type == discordgo.InteractionApplicationCommand {
+		readStore(func(d *storeData) {
+			if r, ok := d.CommandChannels[name]; ok {
+				denied = append(denied, r.Deny...)
+				allowed = slices.Clone(r.Allow)
+			}
+		})
+	}
+
+	switch {
+	case matches(denied):
+		if i.ChannelID == InternalChannelId {
+			return "This command cannot be used in the internal channel."
+		}
+		return "This command cannot be used in this channel."
+	case len(allowed) > 0 && !matches(allowed):
+		return "This command cannot be used in this channel."
+	case p.notInProjects:
+		if _, ok := getProject(i.ChannelID); ok {
+			return "This command cannot be used in project channels."
+		}
+	}
+	return ""
+}
+
+// Manage the channels and categories each command can be used in. Only administrators can change them.
+func commandChannelsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	var content string
+	switch sub.Name {
+	case "allow", "deny", "reset":
+		command := strings.TrimPrefix(strings.TrimSpace(options["command"].StringValue()), "/")
+		if _, ok := commandPolicies[command]; !ok {
+			content = fmt.Sprintf("There's no /%s command.", command)
+			break
+		}
+		var channelID string
+		if o, ok := options["channel"]; ok {
+			channelID = o.ChannelValue(nil).ID
+		}
+		err := updateStore(func(d *storeData) {
+			if d.CommandChannels == nil {
+				d.CommandChannels = make(map[string]*channelRules)
+			}
+			r, ok := d.CommandChannels[command]
+			if !ok {
+				r = &channelRules{}
+				d.CommandChannels[command] = r
+			}
+			switch sub.Name {
+			case "allow":
+				r.Deny = slices.DeleteFunc(r.Deny, func(id string) bool { return id == channelID })
+				if !slices.Contains(r.Allow, channelID) {
+					r.Allow = append(r.Allow, channelID)
+				}
+			case "deny":
+				r.Allow = slices.DeleteFunc(r.Allow, func(id string) bool { return id == channelID })
+				if !slices.Contains(r.Deny, channelID) {
+					r.Deny = append(r.Deny, channelID)
+				}
+			case "reset":
+				delete(d.CommandChannels, command)
+			}
+		})
+		if err != nil {
+			log.Printf("Error saving command channels: %v", err)
+			content = "Error saving command channels: " + describeError(err)
+			break
+		}
+		log.Printf("Updated channel rules for %s: %s %s.", command, sub.Name, channelID)
+		switch sub.Name {
+		case "allow":
+			content = fmt.Sprintf("/%s can now be used in <#%s>. Once a command has allowed channels, it can only be used in those.", command, channelID)
+		case "deny":
+			content = fmt.Sprintf("/%s can no longer be used in <#%s>.", command, channelID)
+		case "reset":
+			content = fmt.Sprintf("/%s is back to its default channels.", command)
+		}
+
+	case "list":
+		var lines []string
+		readStore(func(d *storeData) {
+			for command, r := range d.CommandChannels {
+				line := "- /" + command + ":"
+				for _, id := range r.Allow {
+					line += " +<#" + id + ">"
+				}
+				for _, id := range r.Deny {
+					line += " -<#" + id + ">"
+				}
+				lines = append(lines, line)
+			}
+		})
+		slices.Sort(lines)
+		content = "No channel rules have been set."
+		if len(lines) > 0 {
+			content = truncate(strings.Join(lines, "\n"), 2000)
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Record a refused interaction in the audit channel.
+func auditDenial(s *discordgo.Session, name string, user *discordgo.User, channelID, reason string) {
+	where := "a DM"
+	if channelID != "" {
+		where = "<#" + channelID + ">"
+	}
+	postAudit(s, auditEntry{
+		Action:    auditDenied,
+		ActorID:   user.ID,
+		ChannelID: channelID,
+		Summary:   fmt.Sprintf("Denied `%s` to %s in %s: %s", name, user.Mention(), where, reason),
+	})
+}
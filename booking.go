@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How old a signed booking webhook can be before it's taken for a replay.
+const bookingWebhookMaxAge = 5 * time.Minute
+
+// How long a booked call's Scheduled Event lasts when the provider doesn't say when it ends.
+const defaultBookingDuration = 30 * time.Minute
+
+// The Cal.com bookings announced within bookingWebhookMaxAge, keyed by booking UID, so a delivery replayed before it
+// goes stale isn't announced twice.
+var (
+	recentBookingsMu sync.Mutex
+	recentBookings   = make(map[string]time.Time)
+)
+
+// Record that a booking is being announced, returning false if it already was.
+func firstBookingDelivery(uid string) bool {
+	recentBookingsMu.Lock()
+	defer recentBookingsMu.Unlock()
+	for id, at := range recentBookings {
+		if time.Since(at) > bookingWebhookMaxAge {
+			delete(recentBookings, id)
+		}
+	}
+	if _, seen := recentBookings[uid]; seen {
+		return false
+	}
+	recentBookings[uid] = time.Now()
+	return true
+}
+
+// The staff member leading a project: whoever's on point in its rotation, or else the member assigned to it with a
+// lead role. Empty if it has neither.
+func projectLead(channelID string) string {
+	if userID, ok := onPoint(channelID); ok {
+		return userID
+	}
+	p, _ := getProject(channelID)
+	for _, a := range p.Assignments {
+		if strings.Contains(strings.ToLower(a.Role), "lead") {
+			return a.UserID
+		}
+	}
+	return ""
+}
+
+// Post the project lead's scheduling link for the project the command was called from, or the team's BOOKING_URL
+// when the lead hasn't set one with /booking-link. The channel ID is embedded in the link so the provider's webhook
+// can announce the booking back in the same channel.
+func book(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	lead := projectLead(i.ChannelID)
+	base := os.Getenv("BOOKING_URL")
+	with := "the team"
+	readStore(func(d *storeData) {
+		if link, ok := d.BookingLinks[lead]; ok && lead != "" {
+			base, with = link, "<@"+lead+">"
+		}
+	})
+	link, err := bookingLink(base, i.ChannelID)
+	if err != nil {
+		log.Printf("Error building booking link: %v", err)
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Error building booking link: " + describeError(err),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         fmt.Sprintf("Book a call with %s here: %s", with, link),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}))
+}
+
+// Set or remove the caller's own scheduling link, which /book posts in projects they lead.
+func bookingLinkCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	link := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+	userID := i.Member.User.ID
+
+	var content string
+	if u, err := url.Parse(link); !strings.EqualFold(link, "none") && (err != nil || u.Scheme != "https" || u.Host == "") {
+		content = "The link has to be an https:// address, like your Cal.com or Calendly page."
+	} else if err := updateStore(func(d *storeData) {
+		if strings.EqualFold(link, "none") {
+			delete(d.BookingLinks, userID)
+			return
+		}
+		if d.BookingLinks == nil {
+			d.BookingLinks = make(map[string]string)
+		}
+		d.BookingLinks[userID] = link
+	}); err != nil {
+		log.Printf("Error saving booking link: %v", err)
+		content = "Error saving booking link: " + describeError(err)
+	} else if strings.EqualFold(link, "none") {
+		content = "Removed your booking link. /book posts the team's link in your projects now."
+	} else {
+		content = "Saved your booking link. /book posts it in projects you lead."
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Tag a Cal.com or Calendly scheduling link with the channel it was shared in. Cal.com passes booking metadata
+// through to its webhooks, while Calendly only passes UTM parameters.
+func bookingLink(base, channelID string) (string, error) {
+	if base == "" {
+		return "", notConfigured("BOOKING_URL is not set")
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	if strings.HasSuffix(u.Hostname(), "calendly.com") {
+		q.Set("utm_content", channelID)
+	} else {
+		q.Set("metadata[channel]", channelID)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Handle a Cal.com booking webhook.
+func calcomWebhook(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	body, ok := readSignedBody(r, os.Getenv("CALCOM_WEBHOOK_SECRET"), "", r.Header.Get("X-Cal-Signature-256"))
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var event struct {
+		TriggerEvent string    `json:"triggerEvent"`
+		CreatedAt    time.Time `json:"createdAt"`
+		Payload      struct {
+			UID       string            `json:"uid"`
+			Title     string            `json:"title"`
+			StartTime time.Time         `json:"startTime"`
+			EndTime   time.Time         `json:"endTime"`
+			Location  string            `json:"location"`
+			Metadata  map[string]string `json:"metadata"`
+			Attendees []struct {
+				Name string `json:"name"`
+			} `json:"attendees"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		log.Printf("Error decoding Cal.com webhook: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	// Cal.com doesn't sign a timestamp separately, but the body's creation time is signed along with it, so checking
+	// it and the booking's UID stops old deliveries being replayed.
+	if time.Since(event.CreatedAt).Abs() > bookingWebhookMaxAge {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	if event.TriggerEvent != "BOOKING_CREATED" || !firstBookingDelivery(event.Payload.UID) {
+		return
+	}
+
+	attendees := make([]string, len(event.Payload.Attendees))
+	for n, a := range event.Payload.Attendees {
+		attendees[n] = a.Name
+	}
+	announceBooking(s, &booking{
+		channelID: event.Payload.Metadata["channel"],
+		title:     event.Payload.Title,
+		attendees: strings.Join(attendees, ", "),
+		location:  event.Payload.Location,
+		start:     event.Payload.StartTime,
+		end:       event.Payload.EndTime,
+	})
+}
+
+// Handle a Calendly booking webhook.
+func calendlyWebhook(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	// The signature header looks like "t=<timestamp>,v1=<signature>", signed over "<timestamp>.<body>".
+	var timestamp, signature string
+	for _, part := range strings.Split(r.Header.Get("Calendly-Webhook-Signature"), ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			switch k {
+			case "t":
+				timestamp = v
+			case "v1":
+				signature = v
+			}
+		}
+	}
+	// The timestamp is signed too, so checking it stops old deliveries being replayed.
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(sec, 0)).Abs() > bookingWebhookMaxAge {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, ok := readSignedBody(r, os.Getenv("CALENDLY_WEBHOOK_SECRET"), timestamp+".", signature)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var event struct {
+		Event   string `json:"event"`
+		Payload struct {
+			Name           string `json:"name"`
+			ScheduledEvent struct {
+				Name      string    `json:"name"`
+				StartTime time.Time `json:"start_time"`
+				EndTime   time.Time `json:"end_time"`
+				Location  struct {
+					JoinURL  string `json:"join_url"`
+					Location string `json:"location"`
+				} `json:"location"`
+			} `json:"scheduled_event"`
+			Tracking struct {
+				UTMContent string `json:"utm_content"`
+			} `json:"tracking"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		log.Printf("Error decoding Calendly webhook: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	if event.Event != "invitee.created" {
+		return
+	}
+
+	location := event.Payload.ScheduledEvent.Location.JoinURL
+	if location == "" {
+		location = event.Payload.ScheduledEvent.Location.Location
+	}
+	announceBooking(s, &booking{
+		channelID: event.Payload.Tracking.UTMContent,
+		title:     event.Payload.ScheduledEvent.Name,
+		attendees: event.Payload.Name,
+		location:  location,
+		start:     event.Payload.ScheduledEvent.StartTime,
+		end:       event.Payload.ScheduledEvent.EndTime,
+	})
+}
+
+// A confirmed booking reported by a scheduling provider.
+type booking struct {
+	channelID string
+	title     string
+	attendees string
+	location  string
+	start     time.Time
+	end       time.Time
+}
+
+// Announce a confirmed booking in its project channel and create a matching Discord Scheduled Event.
+func announceBooking(s *discordgo.Session, b *booking) {
+	// The channel ID comes from a link anyone can edit, so only post to live project channels clients can see.
+	p, ok := getProject(b.channelID)
+	if !ok || p.Trash != nil || isStaffOnlyChannel(b.channelID) {
+		log.Printf("Ignoring booking for channel %q, which isn't an active project.", b.channelID)
+		return
+	}
+	channel, err := s.Channel(b.channelID)
+	if err != nil || channel.GuildID != JuiceworksGuildId {
+		log.Printf("Ignoring booking for unknown channel %q: %v", b.channelID, err)
+		return
+	}
+	if !b.end.After(b.start) {
+		b.end = b.start.Add(defaultBookingDuration)
+	}
+	if b.location == "" {
+		b.location = "Online"
+	}
+	if b.attendees == "" {
+		b.attendees = "Not listed"
+	}
+
+	pings, allowed := projectPings(channel.ID)
+	_, err = s.ChannelMessageSendComplex(channel.ID, &discordgo.MessageSend{
+		Content: pings,
+		Embeds: []*discordgo.MessageEmbed{themed(JuiceworksGuildId, &discordgo.MessageEmbed{
+			Title:       "Call booked: " + b.title,
+			Description: fmt.Sprintf("<t:%d:F> (<t:%d:R>)", b.start.Unix(), b.start.Unix()),
+			Fields: []*discordgo.MessageEmbedField{
+				{Name: "Attendees", Value: b.attendees, Inline: true},
+				{Name: "Location", Value: b.location, Inline: true},
+			},
+		})},
+		AllowedMentions: allowed,
+	})
+	if err != nil {
+		recordFailure("announcing booking", err)
+	}
+
+	_, err = s.GuildScheduledEventCreate(JuiceworksGuildId, &discordgo.GuildScheduledEventParams{
+		Name:               b.title,
+		Description:        fmt.Sprintf("Booked in #%s with %s.", channel.Name, b.attendees),
+		ScheduledStartTime: &b.start,
+		ScheduledEndTime:   &b.end,
+		PrivacyLevel:       discordgo.GuildScheduledEventPrivacyLevelGuildOnly,
+		EntityType:         discordgo.GuildScheduledEventEntityTypeExternal,
+		EntityMetadata:     &discordgo.GuildScheduledEventEntityMetadata{Location: b.location},
+	})
+	if err != nil {
+		recordFailure("creating scheduled event for booking", err)
+		return
+	}
+	log.Printf("Announced booking %q in channel %s.", b.title, channel.ID)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Deliver a signed Cal.com webhook, returning the response status.
+func deliverCalcom(t *testing.T, mux *http.ServeMux, body string) int {
+	t.Helper()
+	mac := hmac.New(sha256.New, []byte("calcom-secret"))
+	mac.Write([]byte(body))
+	r := httptest.NewRequest(http.MethodPost, "/webhooks/calcom", strings.NewReader(body))
+	r.Header.Set("X-Cal-Signature-256", hex.EncodeToString(mac.Sum(nil)))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	return w.Code
+}
+
+// A Cal.com booking for a channel, created at a given time.
+func calcomBooking(channelID string, createdAt time.Time) string {
+	return `{"triggerEvent": "BOOKING_CREATED", "createdAt": "` + createdAt.UTC().Format(time.RFC3339) + `", "payload": {
+		"uid": "booking-1", "title": "Kickoff", "startTime": "2030-01-01T10:00:00Z", "metadata": {"channel": "` + channelID + `"}}}`
+}
+
+// A signed delivery is announced once, however often it's replayed, and stale ones are refused.
+func TestCalcomWebhookReplay(t *testing.T) {
+	f, s := newTestBot(t)
+	t.Setenv("CALCOM_WEBHOOK_SECRET", "calcom-secret")
+	channelID := addProject(t, f, "acme")
+	mux := httpMux(s)
+
+	if code := deliverCalcom(t, mux, calcomBooking(channelID, time.Now().Add(-time.Hour))); code != http.StatusUnauthorized {
+		t.Errorf("a stale delivery = %d, want %d", code, http.StatusUnauthorized)
+	}
+	body := calcomBooking(channelID, time.Now())
+	for range 2 {
+		if code := deliverCalcom(t, mux, body); code != http.StatusNoContent {
+			t.Errorf("delivery = %d, want %d", code, http.StatusNoContent)
+		}
+	}
+	if posted := f.posted(channelID); len(posted) != 1 {
+		t.Errorf("%d announcements were posted, want 1", len(posted))
+	}
+
+	// Cal.com didn't say when the call ends, so the Scheduled Event gets the default length rather than ending before
+	// it starts.
+	requests, _ := f.history()
+	created := 0
+	for _, r := range requests {
+		if !strings.HasSuffix(r.Path, "/scheduled-events") {
+			continue
+		}
+		created++
+		var event struct {
+			Start time.Time `json:"scheduled_start_time"`
+			End   time.Time `json:"scheduled_end_time"`
+		}
+		if err := json.Unmarshal(r.Body, &event); err != nil {
+			t.Fatal(err)
+		}
+		if got := event.End.Sub(event.Start); got != defaultBookingDuration {
+			t.Errorf("the Scheduled Event lasts %v, want %v", got, defaultBookingDuration)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d Scheduled Events were created, want 1", created)
+	}
+}
+
+// Bookings only go to live project channels clients can see, whatever channel the link was edited to name.
+func TestBookingForNonProjectChannel(t *testing.T) {
+	f, s := newTestBot(t)
+	lobby := f.addChannel("lobby", "")
+	trashed := addProject(t, f, "globex")
+	if err := updateProject(trashed, func(p *project) { p.Trash = &trashedProject{} }); err != nil {
+		t.Fatal(err)
+	}
+	internal := f.addChannel("acme-internal", "")
+	acme := addProject(t, f, "acme")
+	if err := updateProject(acme, func(p *project) { p.InternalChannelID = internal }); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, channelID := range []string{lobby, trashed, internal, InternalChannelId} {
+		announceBooking(s, &booking{channelID: channelID, title: "Kickoff", start: time.Now()})
+		if posted := f.posted(channelID); len(posted) != 0 {
+			t.Errorf("the booking was announced in %s", channelID)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How many bookmarks each person can keep.
+const maxBookmarks = 100
+
+// A message someone saved for later.
+type bookmark struct {
+	ChannelID string `json:"channelId"`
+	MessageID string `json:"messageId"`
+	AuthorID  string `json:"authorId"`
+	// The start of the message, so the list makes sense without opening every link.
+	Preview string    `json:"preview"`
+	SavedAt time.Time `json:"savedAt"`
+}
+
+// Save the message the context menu was opened on to the caller's bookmarks.
+func bookmarkMessage(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	m := data.Resolved.Messages[data.TargetID]
+	userID := i.Member.User.ID
+	preview := m.Content
+	if preview == "" && len(m.Attachments) > 0 {
+		preview = m.Attachments[0].Filename
+	}
+	b := bookmark{
+		ChannelID: m.ChannelID,
+		MessageID: m.ID,
+		AuthorID:  m.Author.ID,
+		Preview:   truncate(strings.ReplaceAll(preview, "\n", " "), 80),
+		SavedAt:   time.Now(),
+	}
+
+	content := "Bookmarked the message. See your bookmarks with /bookmarks."
+	err := updateStore(func(d *storeData) {
+		bookmarks := d.Bookmarks[userID]
+		switch {
+		case slices.ContainsFunc(bookmarks, func(saved bookmark) bool { return saved.MessageID == b.MessageID }):
+			content = "You've already bookmarked that message."
+		case len(bookmarks) >= maxBookmarks:
+			content = fmt.Sprintf("You already have %d bookmarks. Remove some with `/bookmarks remove` first.", maxBookmarks)
+		default:
+			if d.Bookmarks == nil {
+				d.Bookmarks = make(map[string][]bookmark)
+			}
+			d.Bookmarks[userID] = append(bookmarks, b)
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving bookmark: %v", err)
+		content = "Error saving bookmark: " + describeError(err)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// List or remove the caller's bookmarks.
+func bookmarksCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	userID := i.Member.User.ID
+	var content string
+	switch sub := i.ApplicationCommandData().Options[0]; sub.Name {
+	case "list":
+		var bookmarks []bookmark
+		readStore(func(d *storeData) {
+			bookmarks = slices.Clone(d.Bookmarks[userID])
+		})
+		content = "You haven't bookmarked anything. Right-click a message and pick Apps → Bookmark to save it for later."
+		if len(bookmarks) > 0 {
+			lines := make([]string, len(bookmarks))
+			for n, b := range bookmarks {
+				lines[n] = fmt.Sprintf("%d. https://discord.com/channels/%s/%s/%s <@%s>: %s",
+					n+1, JuiceworksGuildId, b.ChannelID, b.MessageID, b.AuthorID, b.Preview)
+			}
+			content = strings.Join(lines, "\n")
+		}
+
+	case "remove":
+		n := int(sub.Options[0].IntValue())
+		var removed bool
+		err := updateStore(func(d *storeData) {
+			if bookmarks := d.Bookmarks[userID]; n >= 1 && n <= len(bookmarks) {
+				d.Bookmarks[userID] = slices.Delete(bookmarks, n-1, n)
+				if len(d.Bookmarks[userID]) == 0 {
+					delete(d.Bookmarks, userID)
+				}
+				removed = true
+			}
+		})
+		switch {
+		case err != nil:
+			log.Printf("Error removing bookmark: %v", err)
+			content = "Error removing bookmark: " + describeError(err)
+		case !removed:
+			content = fmt.Sprintf("You don't have a bookmark %d.", n)
+		default:
+			content = fmt.Sprintf("Removed bookmark %d.", n)
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         truncate(content, 2000),
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}))
+}
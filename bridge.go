@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A chat platform that project channels can be bridged to.
+type bridge interface {
+	// Start receiving messages from the platform. Platforms that push messages through webhooks don't need to do
+	// anything here.
+	start(s *discordgo.Session) error
+	// Post a Discord message to a room on the platform.
+	send(room string, m *bridgeMessage) error
+}
+
+// A message relayed across a bridge. The channel is the Discord side's, for looking up the project's credentials.
+type bridgeMessage struct {
+	channelID string
+	author    string
+	avatarURL string
+	text      string
+	files     []*discordgo.File
+}
+
+// The platform and room a Discord channel is bridged to.
+type bridgeConfig struct {
+	Platform string `json:"platform"`
+	Room     string `json:"room"`
+}
+
+// The supported bridge platforms, keyed by the name used in the /bridge command.
+var bridges = map[string]bridge{
+	"slack":  slackBridge{},
+	"matrix": &matrixBridge{},
+}
+
+// Start every configured bridge platform.
+func startBridges(s *discordgo.Session) {
+	for name, b := range bridges {
+		if err := b.start(s); err != nil {
+			log.Printf("Could not start %s bridge: %v", name, err)
+		}
+	}
+}
+
+// Bridge the channel the command was called from to a room on another platform, or remove the bridge if no room is
+// given.
+func bridgeChannel(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var platform, room string
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "platform":
+			platform = o.StringValue()
+		case "room":
+			room = strings.TrimSpace(o.StringValue())
+		}
+	}
+	if _, ok := bridges[platform]; !ok {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Unknown bridge platform: " + platform,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	err := updateStore(func(d *storeData) {
+		if room == "" {
+			delete(d.Bridges, i.ChannelID)
+			return
+		}
+		if d.Bridges == nil {
+			d.Bridges = make(map[string]bridgeConfig)
+		}
+		d.Bridges[i.ChannelID] = bridgeConfig{Platform: platform, Room: room}
+	})
+	if err != nil {
+		log.Printf("Error saving bridge: %v", err)
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Error saving bridge: " + describeError(err),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	content := "Removed the bridge from this channel."
+	if room != "" {
+		content = fmt.Sprintf("Bridged this channel to %s room %s.", platform, room)
+	}
+	log.Printf("Set bridge for channel %s to %s %q.", i.ChannelID, platform, room)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Relay a message from a bridged Discord channel to its room on the other platform.
+func relayToBridge(s *discordgo.Session, m *discordgo.MessageCreate) {
+	// Skip bots and webhooks, which includes the bot's own relayed messages.
+	if m.Author == nil || m.Author.Bot || m.WebhookID != "" {
+		return
+	}
+	var cfg bridgeConfig
+	readStore(func(d *storeData) {
+		cfg = d.Bridges[m.ChannelID]
+	})
+	b, ok := bridges[cfg.Platform]
+	if !ok {
+		return
+	}
+
+	msg := &bridgeMessage{channelID: m.ChannelID, author: authorName(m.Message), avatarURL: m.Author.AvatarURL(""), text: textWithAttachments(m.Message)}
+	if err := b.send(cfg.Room, msg); err != nil {
+		recordFailure("relaying message to "+cfg.Platform, err)
+		recordFailedJob(jobBridgeRelay, fmt.Sprintf("Relay a message from <#%s> to %s", m.ChannelID, cfg.Platform), bridgeRelay{
+			Platform:  cfg.Platform,
+			Room:      cfg.Room,
+			ChannelID: m.ChannelID,
+			MessageID: m.ID,
+			AuthorID:  m.Author.ID,
+			Author:    msg.author,
+			AvatarURL: msg.avatarURL,
+			Text:      msg.text,
+		}, err)
+	}
+}
+
+// A message that couldn't be relayed to another platform, kept as a failed job to replay. Attachments are relayed as
+// links, so the text is everything there is to send.
+type bridgeRelay struct {
+	Platform  string `json:"platform"`
+	Room      string `json:"room"`
+	ChannelID string `json:"channelId"`
+	MessageID string `json:"messageId"`
+	AuthorID  string `json:"authorId"`
+	Author    string `json:"author"`
+	AvatarURL string `json:"avatarUrl,omitempty"`
+	Text      string `json:"text"`
+}
+
+// Post a message from another platform into the Discord channel bridged to its room.
+func relayFromBridge(s *discordgo.Session, platform, room string, m *bridgeMessage) {
+	channelID := bridgedChannel(platform, room)
+	if channelID == "" {
+		return
+	}
+
+	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{themed(JuiceworksGuildId, &discordgo.MessageEmbed{
+			Author:      &discordgo.MessageEmbedAuthor{Name: fmt.Sprintf("%s (%s)", m.author, platform), IconURL: m.avatarURL},
+			Description: m.text,
+		})},
+		Files: m.files,
+	})
+	if err != nil {
+		recordFailure("relaying message from "+platform, err)
+	}
+}
+
+// The name a message's author goes by in the guild: their nickname, global display name, or username.
+func authorName(m *discordgo.Message) string {
+	if m.Member != nil && m.Member.Nick != "" {
+		return m.Member.Nick
+	}
+	if m.Author.GlobalName != "" {
+		return m.Author.GlobalName
+	}
+	return m.Author.Username
+}
+
+// A message's content followed by links to its attachments, for platforms that can't show Discord attachments.
+func textWithAttachments(m *discordgo.Message) string {
+	text := m.Content
+	for _, a := range m.Attachments {
+		text += "\n" + a.URL
+	}
+	return text
+}
+
+// The Discord channel bridged to a room on a platform, or "" if there isn't one.
+func bridgedChannel(platform, room string) string {
+	var channelID string
+	readStore(func(d *storeData) {
+		for discordChannel, cfg := range d.Bridges {
+			if cfg.Platform == platform && cfg.Room == room {
+				channelID = discordChannel
+				break
+			}
+		}
+	})
+	return channelID
+}
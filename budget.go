@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How often projects' budget burn is checked against the alert thresholds.
+const budgetCheckInterval = time.Hour
+
+// The percentages of a project's budget staff are alerted at in the internal channel, in order.
+var budgetThresholds = []int{75, 90, 100}
+
+// The hourly rate a member's call time is billed at on a project: their assignment's rate, or the project's own rate
+// for other staff. Clients' time isn't billed.
+func callRate(p project, userID string) (int64, bool) {
+	if n := slices.IndexFunc(p.Assignments, func(a assignment) bool { return a.UserID == userID }); n >= 0 {
+		return p.Assignments[n].HourlyRate, true
+	}
+	if _, client := p.Members[userID]; client || p.HourlyRate == 0 {
+		return 0, false
+	}
+	return p.HourlyRate, true
+}
+
+// How much of a project's budget its logged call time has used, in cents.
+func projectBurn(p project, now time.Time) int64 {
+	var burned float64
+	for _, c := range p.Calls {
+		if rate, ok := callRate(p, c.UserID); ok && c.duration(now) > 0 {
+			burned += c.duration(now).Hours() * float64(rate)
+		}
+	}
+	return int64(math.Round(burned))
+}
+
+// The percentage of a project's budget that's been used.
+func burnPercent(p project, now time.Time) int {
+	if p.Budget <= 0 {
+		return 0
+	}
+	return int(projectBurn(p, now) * 100 / p.Budget)
+}
+
+// Describe a project's budget burn for /project-info.
+func describeBurn(p project, now time.Time) string {
+	return fmt.Sprintf("%s of %s used (%d%%)", formatAmount(projectBurn(p, now)), formatAmount(p.Budget), burnPercent(p, now))
+}
+
+// Set or clear the budget of the project the command was called from, and the hourly rate for staff who aren't
+// assigned to it.
+func budgetCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	var content string
+	_, ok := getProject(i.ChannelID)
+	switch {
+	case !ok:
+		content = "This channel is not a registered project."
+	case sub.Name == "set":
+		content = setBudget(i.ChannelID, options)
+	case sub.Name == "clear":
+		err := updateProject(i.ChannelID, func(p *project) {
+			p.Budget, p.HourlyRate, p.BudgetAlerted = 0, 0, 0
+		})
+		if err != nil {
+			log.Printf("Error clearing budget: %v", err)
+			content = "Error clearing budget: " + describeError(err)
+			break
+		}
+		log.Printf("Cleared the budget of channel %s.", i.ChannelID)
+		content = "Cleared this project's budget."
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Set a project's budget, and optionally its hourly rate, returning the response to show the caller.
+func setBudget(channelID string, options map[string]*discordgo.ApplicationCommandInteractionDataOption) string {
+	budget := int64(math.Round(options["amount"].FloatValue() * 100))
+	rate, setRate := int64(0), false
+	if o, ok := options["hourly-rate"]; ok {
+		rate, setRate = int64(math.Round(o.FloatValue()*100)), true
+	}
+	if budget <= 0 {
+		return "The budget must be more than zero."
+	}
+	if rate < 0 {
+		return "The hourly rate can't be negative."
+	}
+
+	var p project
+	err := updateProject(channelID, func(stored *project) {
+		stored.Budget = budget
+		if setRate {
+			stored.HourlyRate = rate
+		}
+		// Alert again at thresholds the new budget puts the project back under.
+		stored.BudgetAlerted = crossedThreshold(burnPercent(*stored, time.Now()))
+		p = copyProject(stored)
+	})
+	if err != nil {
+		log.Printf("Error saving budget: %v", err)
+		return "Error saving budget: " + describeError(err)
+	}
+
+	log.Printf("Set the budget of channel %s to %d at %d per hour.", channelID, p.Budget, p.HourlyRate)
+	content := fmt.Sprintf("Set this project's budget to %s. %s.", formatAmount(p.Budget), describeBurn(p, time.Now()))
+	if p.HourlyRate > 0 {
+		content += fmt.Sprintf(" Staff who aren't assigned are billed at %s per hour.", formatAmount(p.HourlyRate))
+	}
+	return content
+}
+
+// The highest alert threshold a burn percentage has reached, or zero.
+func crossedThreshold(percent int) int {
+	crossed := 0
+	for _, t := range budgetThresholds {
+		if percent >= t {
+			crossed = t
+		}
+	}
+	return crossed
+}
+
+// Check budget burn in the background.
+func startBudgetChecks(s *discordgo.Session) {
+	go func() {
+		for range time.Tick(budgetCheckInterval) {
+			if !paused("checking budgets") {
+				alertBudgetBurn(s)
+			}
+		}
+	}()
+}
+
+// Alert staff in the internal channel when a project's call time reaches another threshold of its budget. Each
+// threshold is only alerted once, until the budget changes.
+func alertBudgetBurn(s *discordgo.Session) {
+	type crossing struct {
+		channelID string
+		threshold int
+		burn      string
+	}
+	var crossings []crossing
+	now := time.Now()
+	err := updateStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if p.Budget <= 0 || p.Trash != nil {
+				continue
+			}
+			if t := crossedThreshold(burnPercent(*p, now)); t > p.BudgetAlerted {
+				p.BudgetAlerted = t
+				crossings = append(crossings, crossing{p.ChannelID, t, describeBurn(*p, now)})
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving budget alerts: %v", err)
+		return
+	}
+
+	for _, c := range crossings {
+		content := fmt.Sprintf("<#%s> has used %d%% of its budget: %s.", c.channelID, c.threshold, c.burn)
+		if userID, ok := onPoint(c.channelID); ok {
+			content += fmt.Sprintf(" <@%s> is on point.", userID)
+		}
+		if _, err := s.ChannelMessageSend(InternalChannelId, content); err != nil {
+			recordFailure("sending budget alert", err)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// When the team is working. Outside these hours the bot can auto-reply to clients, and non-urgent reminders wait
+// until the team is back.
+type businessHours struct {
+	// Opening and closing times, like 09:00 and 17:00.
+	Start    string         `json:"start"`
+	End      string         `json:"end"`
+	Weekdays []time.Weekday `json:"weekdays"`
+	// The IANA time zone the hours are in, like America/New_York.
+	Timezone  string `json:"timezone"`
+	AutoReply bool   `json:"autoReply"`
+}
+
+// The weekday names accepted by /business-hours, in the order time.Weekday numbers them.
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// The next opening time each channel was auto-replied to about, so clients only get one auto-reply per closure.
+var (
+	autoRepliedMu sync.Mutex
+	autoReplied   = make(map[string]time.Time)
+)
+
+// Work out whether the team is working at a time and, if not, when they'll be back. With no business hours set, the
+// team is always working.
+func openAt(t time.Time) (bool, time.Time) {
+	var b *businessHours
+	readStore(func(d *storeData) {
+		if d.BusinessHours != nil {
+			c := *d.BusinessHours
+			b = &c
+		}
+	})
+	if b == nil {
+		return true, t
+	}
+
+	loc, err := time.LoadLocation(b.Timezone)
+	if err != nil {
+		log.Printf("Error loading business hours time zone: %v", err)
+		return true, t
+	}
+	start, _ := time.Parse("15:04", b.Start)
+	end, _ := time.Parse("15:04", b.End)
+
+	t = t.In(loc)
+	for day := 0; day <= 7; day++ {
+		date := t.AddDate(0, 0, day)
+		if !slices.Contains(b.Weekdays, date.Weekday()) {
+			continue
+		}
+		opens := time.Date(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		closes := time.Date(date.Year(), date.Month(), date.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+		if !t.Before(opens) && t.Before(closes) {
+			return true, t
+		}
+		if t.Before(opens) {
+			return false, opens
+		}
+	}
+	return false, t
+}
+
+// Parse a comma-separated list of weekday names, like mon,tue,wed.
+func parseWeekdays(list string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		n := slices.IndexFunc(weekdayNames, func(w string) bool {
+			return strings.HasPrefix(name, w)
+		})
+		if n < 0 {
+			return nil, fmt.Errorf("unknown weekday %q", name)
+		}
+		if !slices.Contains(days, time.Weekday(n)) {
+			days = append(days, time.Weekday(n))
+		}
+	}
+	slices.Sort(days)
+	return days, nil
+}
+
+// Set or clear the team's business hours. Only administrators can change them.
+func businessHoursCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	var content string
+	switch sub.Name {
+	case "set":
+		content = setBusinessHours(sub.Options)
+	case "clear":
+		err := updateStore(func(d *storeData) {
+			d.BusinessHours = nil
+		})
+		if err != nil {
+			log.Printf("Error saving business hours: %v", err)
+			content = "Error saving business hours: " + describeError(err)
+			break
+		}
+		log.Printf("Cleared business hours.")
+		content = "Cleared the business hours. The bot will treat the team as always available."
+	case "show":
+		readStore(func(d *storeData) {
+			content = "No business hours are set."
+			if b := d.BusinessHours; b != nil {
+				days := make([]string, len(b.Weekdays))
+				for n, day := range b.Weekdays {
+					days[n] = weekdayNames[day]
+				}
+				autoReply := "off"
+				if b.AutoReply {
+					autoReply = "on"
+				}
+				content = fmt.Sprintf("%s to %s %s on %s. Auto-reply is %s.", b.Start, b.End, b.Timezone, strings.Join(days, ", "), autoReply)
+			}
+		})
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Save new business hours, returning the response to show the caller.
+func setBusinessHours(options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	b := businessHours{
+		Weekdays:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		AutoReply: true,
+	}
+	for _, o := range options {
+		switch o.Name {
+		case "start":
+			b.Start = strings.TrimSpace(o.StringValue())
+		case "end":
+			b.End = strings.TrimSpace(o.StringValue())
+		case "timezone":
+			b.Timezone = strings.TrimSpace(o.StringValue())
+		case "weekdays":
+			days, err := parseWeekdays(o.StringValue())
+			if err != nil || len(days) == 0 {
+				return "Weekdays must look like mon,tue,wed,thu,fri."
+			}
+			b.Weekdays = days
+		case "auto-reply":
+			b.AutoReply = o.BoolValue()
+		}
+	}
+
+	start, err := time.Parse("15:04", b.Start)
+	if err != nil {
+		return "The start time must look like 09:00."
+	}
+	end, err := time.Parse("15:04", b.End)
+	if err != nil {
+		return "The end time must look like 17:00."
+	}
+	if !end.After(start) {
+		return "The end time must be after the start time."
+	}
+	if _, err := time.LoadLocation(b.Timezone); err != nil {
+		return "The time zone must be an IANA name like America/New_York."
+	}
+
+	err = updateStore(func(d *storeData) {
+		d.BusinessHours = &b
+	})
+	if err != nil {
+		log.Printf("Error saving business hours: %v", err)
+		return "Error saving business hours: " + describeError(err)
+	}
+	log.Printf("Set business hours to %s-%s %s.", b.Start, b.End, b.Timezone)
+	return fmt.Sprintf("Set the business hours to %s to %s %s.", b.Start, b.End, b.Timezone)
+}
+
+// Let clients who post in a project channel outside business hours know when the team will be back. Each channel gets
+// at most one auto-reply while the team is away.
+func autoReply(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.GuildID != JuiceworksGuildId || m.Author == nil || m.Author.Bot || m.Member == nil ||
+		slices.Contains(m.Member.Roles, JuiceworksRoleId) {
+		return
+	}
+	if _, ok := getProject(m.ChannelID); !ok {
+		return
+	}
+	enabled := false
+	readStore(func(d *storeData) {
+		enabled = d.BusinessHours != nil && d.BusinessHours.AutoReply
+	})
+	if !enabled {
+		return
+	}
+	open, back := openAt(time.Now())
+	if open {
+		return
+	}
+
+	autoRepliedMu.Lock()
+	replied := autoReplied[m.ChannelID].Equal(back)
+	autoReplied[m.ChannelID] = back
+	autoRepliedMu.Unlock()
+	if replied {
+		return
+	}
+
+	content := fmt.Sprintf("Thanks for your message! The team is away right now and will be back <t:%d:F> (<t:%d:R>).", back.Unix(), back.Unix())
+	if _, err := s.ChannelMessageSendReply(m.ChannelID, content, m.Reference()); err != nil {
+		log.Printf("Error sending auto-reply: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The maximum number of events listed by /upcoming.
+const upcomingEventsLimit = 5
+
+// A single event returned by the Google Calendar API.
+type calendarEvent struct {
+	Summary  string `json:"summary"`
+	HTMLLink string `json:"htmlLink"`
+	Start    struct {
+		DateTime time.Time `json:"dateTime"`
+		Date     string    `json:"date"`
+	} `json:"start"`
+}
+
+// List the next events in the shared project calendar. Events are matched to the project by searching for the
+// channel name, so calendar entries should mention it in their title or description.
+func upcomingEvents(query string, limit int) (events []calendarEvent, err error) {
+	calendarID := os.Getenv("GOOGLE_CALENDAR_ID")
+	apiKey := os.Getenv("GOOGLE_API_KEY")
+	if calendarID == "" || apiKey == "" {
+		return nil, notConfigured("GOOGLE_CALENDAR_ID and GOOGLE_API_KEY must be set")
+	}
+	defer func() { noteIntegrationCall("Google Calendar", err) }()
+
+	params := url.Values{}
+	params.Set("key", apiKey)
+	params.Set("q", query)
+	params.Set("timeMin", time.Now().Format(time.RFC3339))
+	params.Set("singleEvents", "true")
+	params.Set("orderBy", "startTime")
+	params.Set("maxResults", fmt.Sprint(limit))
+	endpoint := "https://www.googleapis.com/calendar/v3/calendars/" + url.PathEscape(calendarID) + "/events?" + params.Encode()
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("google calendar returned %s", resp.Status)
+	}
+
+	var body struct {
+		Items []calendarEvent `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.Items, nil
+}
+
+// List the next calendar events for the project the command was called from.
+func upcoming(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	channel, err := s.State.Channel(i.ChannelID)
+	if err != nil {
+		if channel, err = s.Channel(i.ChannelID); err != nil {
+			log.Printf("Error reading channel: %v", err)
+			logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Error reading channel: " + describeError(err),
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			}))
+			return
+		}
+	}
+
+	// The calendar API can be slow, so defer the response.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+
+	events, err := upcomingEvents(channel.Name, upcomingEventsLimit)
+	if err != nil {
+		log.Printf("Error reading calendar events: %v", err)
+		content := "Error reading calendar events: " + describeError(err)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+		logResponseErr(err)
+		return
+	}
+	if len(events) == 0 {
+		content := "No upcoming events for this project."
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+		logResponseErr(err)
+		return
+	}
+
+	// List each event with a Discord timestamp so it renders in the reader's timezone.
+	embed := themed(i.GuildID, &discordgo.MessageEmbed{Title: "Upcoming events for #" + channel.Name})
+	for _, e := range events {
+		when := e.Start.Date
+		if !e.Start.DateTime.IsZero() {
+			when = fmt.Sprintf("<t:%d:F> (<t:%d:R>)", e.Start.DateTime.Unix(), e.Start.DateTime.Unix())
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  e.Summary,
+			Value: fmt.Sprintf("%s\n[Open in calendar](%s)", when, e.HTMLLink),
+		})
+	}
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{embed}})
+	logResponseErr(err)
+}
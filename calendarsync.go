@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a kickoff call is put in the calendar for.
+const kickoffCallLength = time.Hour
+
+// A Google service account key, as downloaded from the Cloud console.
+type googleServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// The access token for writing to the calendar, reused until shortly before it expires.
+var (
+	googleTokenMu      sync.Mutex
+	googleToken        string
+	googleTokenExpires time.Time
+)
+
+// Whether the bot can write to the shared calendar: GOOGLE_CALENDAR_ID names it, and GOOGLE_SERVICE_ACCOUNT_FILE is
+// the key of a service account it's shared with. API keys can only read calendars.
+func calendarWriteConfigured() bool {
+	return os.Getenv("GOOGLE_CALENDAR_ID") != "" && os.Getenv("GOOGLE_SERVICE_ACCOUNT_FILE") != ""
+}
+
+// Get an access token for the calendar by signing a JWT with the service account's key.
+func googleAccessToken() (string, error) {
+	googleTokenMu.Lock()
+	defer googleTokenMu.Unlock()
+	if googleToken != "" && time.Until(googleTokenExpires) > time.Minute {
+		return googleToken, nil
+	}
+
+	raw, err := os.ReadFile(os.Getenv("GOOGLE_SERVICE_ACCOUNT_FILE"))
+	if err != nil {
+		return "", err
+	}
+	var account googleServiceAccount
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return "", fmt.Errorf("reading service account key: %w", err)
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return "", errors.New("the service account key has no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("the service account key isn't an RSA key")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iss":   account.ClientEmail,
+		"scope": "https://www.googleapis.com/auth/calendar.events",
+		"aud":   account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.PostForm(account.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("google returned %s getting a token: %s", resp.Status, msg)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	googleToken, googleTokenExpires = token.AccessToken, now.Add(time.Duration(token.ExpiresIn)*time.Second)
+	return googleToken, nil
+}
+
+// An event the bot keeps in the shared calendar for a project.
+type projectCalendarEvent struct {
+	// The event's ID in the calendar, derived from what it's for so syncing again updates it instead of adding another.
+	id      string
+	summary string
+	// Set for all-day events, like deadlines.
+	date time.Time
+	// Set for calls.
+	start, end time.Time
+}
+
+// The calendar ID for something in a project. Google lets clients choose event IDs, as long as they only use the
+// characters 0-9 and a-v, which hex digits do.
+func calendarEventID(channelID, what string) string {
+	sum := sha256.Sum256([]byte(channelID + "/" + what))
+	return hex.EncodeToString(sum[:16])
+}
+
+// The events a project should have in the calendar: its unpaid milestones' due dates and its kickoff call. Titles
+// start with the channel name, so /upcoming finds them. Projects in the trash have none.
+func projectCalendarEvents(p *project) []projectCalendarEvent {
+	if p.Trash != nil {
+		return nil
+	}
+	var events []projectCalendarEvent
+	for _, m := range p.Milestones {
+		if m.Due.IsZero() || !m.PaidAt.IsZero() {
+			continue
+		}
+		events = append(events, projectCalendarEvent{
+			id:      calendarEventID(p.ChannelID, "milestone/"+m.Name),
+			summary: fmt.Sprintf("#%s: %s due", p.Name, m.Name),
+			date:    m.Due,
+		})
+	}
+	if p.Kickoff != nil && !p.Kickoff.StartsAt.IsZero() {
+		events = append(events, projectCalendarEvent{
+			id:      calendarEventID(p.ChannelID, "kickoff"),
+			summary: fmt.Sprintf("#%s: kickoff call", p.Name),
+			start:   p.Kickoff.StartsAt,
+			end:     p.Kickoff.StartsAt.Add(kickoffCallLength),
+		})
+	}
+	return events
+}
+
+// Make the shared calendar match a project's deadlines and calls, adding and updating its events and removing the ones
+// it no longer has. Does nothing unless the calendar can be written to.
+func syncProjectToCalendar(p *project) (err error) {
+	if !calendarWriteConfigured() {
+		return nil
+	}
+	defer func() { noteIntegrationCall("Google Calendar", err) }()
+
+	var synced []string
+	readStore(func(d *storeData) {
+		synced = slices.Clone(d.CalendarEvents[p.ChannelID])
+	})
+	events := projectCalendarEvents(p)
+	var ids []string
+	for _, e := range events {
+		if err := putCalendarEvent(e); err != nil {
+			return err
+		}
+		ids = append(ids, e.id)
+	}
+	for _, id := range synced {
+		if slices.Contains(ids, id) {
+			continue
+		}
+		if err := calendarRequest(http.MethodDelete, "/"+id, nil); err != nil {
+			return err
+		}
+	}
+
+	return updateStore(func(d *storeData) {
+		if len(ids) == 0 {
+			delete(d.CalendarEvents, p.ChannelID)
+			return
+		}
+		if d.CalendarEvents == nil {
+			d.CalendarEvents = make(map[string][]string)
+		}
+		d.CalendarEvents[p.ChannelID] = ids
+	})
+}
+
+// Add an event to the calendar, or update it if it's already there.
+func putCalendarEvent(e projectCalendarEvent) error {
+	// Events the bot deleted before are only cancelled, so updating one brings it back.
+	body := map[string]any{"id": e.id, "summary": e.summary, "status": "confirmed"}
+	if !e.date.IsZero() {
+		// The end of an all-day event is the day after it.
+		body["start"] = map[string]string{"date": e.date.Format(time.DateOnly)}
+		body["end"] = map[string]string{"date": e.date.AddDate(0, 0, 1).Format(time.DateOnly)}
+	} else {
+		body["start"] = map[string]string{"dateTime": e.start.Format(time.RFC3339)}
+		body["end"] = map[string]string{"dateTime": e.end.Format(time.RFC3339)}
+	}
+	err := calendarRequest(http.MethodPut, "/"+e.id, body)
+	var notFound calendarNotFound
+	if errors.As(err, &notFound) {
+		return calendarRequest(http.MethodPost, "", body)
+	}
+	return err
+}
+
+// The calendar has no event with the ID asked for.
+type calendarNotFound struct{ status string }
+
+func (e calendarNotFound) Error() string { return "google calendar returned " + e.status }
+
+// Call the events endpoint of the shared calendar. Deleting an event that's already gone succeeds.
+func calendarRequest(method, path string, body any) error {
+	token, err := googleAccessToken()
+	if err != nil {
+		return err
+	}
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	endpoint := "https://www.googleapis.com/calendar/v3/calendars/" + url.PathEscape(os.Getenv("GOOGLE_CALENDAR_ID")) + "/events" + path
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		if method == http.MethodDelete {
+			return nil
+		}
+		return calendarNotFound{resp.Status}
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("google calendar returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long call records are kept.
+const callRetention = 180 * 24 * time.Hour
+
+// Someone's time in one of a project's voice channels. LeftAt is zero while they're still there.
+type callSession struct {
+	UserID    string    `json:"userId"`
+	ChannelID string    `json:"channelId"`
+	JoinedAt  time.Time `json:"joinedAt"`
+	LeftAt    time.Time `json:"leftAt,omitempty"`
+}
+
+// How long a session lasted, counting open sessions up to now.
+func (c callSession) duration(now time.Time) time.Duration {
+	if c.LeftAt.IsZero() {
+		return now.Sub(c.JoinedAt)
+	}
+	return c.LeftAt.Sub(c.JoinedAt)
+}
+
+// Format a duration in hours and minutes, like 2h 05m.
+func formatCallDuration(d time.Duration) string {
+	m := int(d.Round(time.Minute).Minutes())
+	return fmt.Sprintf("%dh %02dm", m/60, m%60)
+}
+
+// Find the project a voice channel belongs to.
+func voiceChannelProject(voiceChannelID string) (string, bool) {
+	var channelID string
+	readStore(func(d *storeData) {
+		for id, p := range d.Projects {
+			if slices.Contains(p.VoiceChannels, voiceChannelID) {
+				channelID = id
+			}
+		}
+	})
+	return channelID, channelID != ""
+}
+
+// Record people joining and leaving project voice channels. Any open session the user has is closed first, since
+// they can only be in one voice channel at a time.
+func logVoiceState(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+	if v.GuildID != JuiceworksGuildId || v.Member == nil || v.Member.User.Bot {
+		return
+	}
+	now := time.Now()
+	projectID, joined := voiceChannelProject(v.ChannelID)
+
+	// Moving between channels or muting also sends an update, so leave a session alone if the user is still in it.
+	err := updateStore(func(d *storeData) {
+		stillThere := false
+		for _, p := range d.Projects {
+			for n := range p.Calls {
+				c := &p.Calls[n]
+				if c.UserID != v.UserID || !c.LeftAt.IsZero() {
+					continue
+				}
+				if c.ChannelID == v.ChannelID {
+					stillThere = true
+					continue
+				}
+				c.LeftAt = now
+				log.Printf("%s left voice channel %s after %s.", v.Member.User, c.ChannelID, formatCallDuration(c.duration(now)))
+			}
+			p.Calls = slices.DeleteFunc(p.Calls, func(c callSession) bool {
+				return !c.LeftAt.IsZero() && now.Sub(c.LeftAt) > callRetention
+			})
+		}
+		if joined && !stillThere {
+			if p, ok := d.Projects[projectID]; ok {
+				p.Calls = append(p.Calls, callSession{UserID: v.UserID, ChannelID: v.ChannelID, JoinedAt: now})
+				log.Printf("%s joined voice channel %s.", v.Member.User, v.ChannelID)
+			}
+		}
+	})
+	if err != nil {
+		recordFailure("recording voice activity", err)
+	}
+}
+
+// Manage which voice channels count towards the project the command was called from, and summarize time spent in
+// them.
+func callLogCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	var content string
+	if _, ok := getProject(i.ChannelID); !ok {
+		content = "This channel is not a registered project."
+	} else {
+		switch sub.Name {
+		case "track", "untrack":
+			content = trackVoiceChannel(s, i.ChannelID, sub.Name == "track", options["channel"].ChannelValue(s))
+		case "summary":
+			days := int64(30)
+			if o, ok := options["days"]; ok {
+				days = max(o.IntValue(), 1)
+			}
+			content = callSummary(i.ChannelID, time.Now().AddDate(0, 0, -int(days)), canSeeRates(i))
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Start or stop counting time in a voice channel towards a project, returning the response to show the caller.
+func trackVoiceChannel(s *discordgo.Session, channelID string, track bool, voice *discordgo.Channel) string {
+	if owner, ok := voiceChannelProject(voice.ID); track && ok && owner != channelID {
+		return fmt.Sprintf("<#%s> already counts towards <#%s>.", voice.ID, owner)
+	}
+	err := updateProject(channelID, func(p *project) {
+		p.VoiceChannels = slices.DeleteFunc(p.VoiceChannels, func(id string) bool { return id == voice.ID })
+		if track {
+			p.VoiceChannels = append(p.VoiceChannels, voice.ID)
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving voice channels: %v", err)
+		return "Error saving voice channels: " + describeError(err)
+	}
+	log.Printf("Set tracking of voice channel %s in project %s to %t.", voice.ID, channelID, track)
+	if track {
+		return fmt.Sprintf("Time in <#%s> now counts towards this project.", voice.ID)
+	}
+	return fmt.Sprintf("Time in <#%s> no longer counts towards this project.", voice.ID)
+}
+
+// Summarize time each person spent in a project's voice channels since a given time, with what it comes to at the
+// hourly rates of assigned contractors when the caller can see rates.
+func callSummary(channelID string, since time.Time, showCosts bool) string {
+	p, _ := getProject(channelID)
+	now := time.Now()
+	durations := make(map[string]time.Duration)
+	for _, c := range p.Calls {
+		if c.duration(now) <= 0 || (!c.LeftAt.IsZero() && c.LeftAt.Before(since)) {
+			continue
+		}
+		start := c.JoinedAt
+		if start.Before(since) {
+			start = since
+		}
+		durations[c.UserID] += callSession{JoinedAt: start, LeftAt: c.LeftAt}.duration(now)
+	}
+	if len(durations) == 0 {
+		return fmt.Sprintf("No calls since %s.", since.Format("Jan 2"))
+	}
+
+	userIDs := make([]string, 0, len(durations))
+	var total time.Duration
+	for id, d := range durations {
+		userIDs = append(userIDs, id)
+		total += d
+	}
+	slices.SortFunc(userIDs, func(a, b string) int {
+		return cmp.Compare(durations[b], durations[a])
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Call time since %s: %s\n", since.Format("Jan 2"), formatCallDuration(total))
+	var billable int64
+	for _, id := range userIDs {
+		line := fmt.Sprintf("- <@%s>: %s", id, formatCallDuration(durations[id]))
+		if n := slices.IndexFunc(p.Assignments, func(a assignment) bool { return a.UserID == id }); n >= 0 && showCosts {
+			cost := int64(math.Round(durations[id].Hours() * float64(p.Assignments[n].HourlyRate)))
+			billable += cost
+			line += " (" + formatAmount(cost) + ")"
+		}
+		b.WriteString(line + "\n")
+	}
+	if billable > 0 {
+		fmt.Fprintf(&b, "Billable at assigned rates: %s\n", formatAmount(billable))
+	}
+	return truncate(b.String(), 2000)
+}
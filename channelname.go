@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The longest channel name Discord accepts, in characters.
+const maxChannelNameLength = 100
+
+// Turn what someone typed into a name Discord accepts for a text channel, the way Discord itself would: lowercase,
+// with spaces and punctuation turned into single dashes. Letters, numbers and emoji in any script are kept, while
+// invisible characters like zero-width spaces and right-to-left marks are dropped, so names can't hide anything. The
+// result may be empty, for callers to reject.
+func sanitizeChannelName(name string) string {
+	var b strings.Builder
+	var previous rune
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r == '‍' && unicode.Is(unicode.So, previous):
+			// Zero-width joiners hold emoji sequences together, like 👩‍💻.
+			b.WriteRune(r)
+		case unicode.Is(unicode.Cf, r) || unicode.IsControl(r):
+			continue
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r) || r == '_' ||
+			(r > unicode.MaxASCII && unicode.Is(unicode.So, r)):
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+		previous = r
+	}
+
+	runes := []rune(b.String())
+	if len(runes) > maxChannelNameLength {
+		runes = runes[:maxChannelNameLength]
+	}
+	return strings.TrimRight(string(runes), "-‍")
+}
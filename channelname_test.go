@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeChannelName(t *testing.T) {
+	for _, c := range []struct{ name, want string }{
+		{"Acme Site!", "acme-site"},
+		{"  --Acme__Site--  ", "acme__site"},
+		{"שלום עולם", "שלום-עולם"},
+		{"acme​site‏", "acmesite"},
+		{"👩‍💻 team", "👩‍💻-team"},
+		{"a‍b", "ab"},
+		{"café", "café"},
+		{"!!!", ""},
+	} {
+		if got := sanitizeChannelName(c.name); got != c.want {
+			t.Errorf("sanitizeChannelName(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+// Whatever someone types, the name must be one Discord accepts and that stays the same when sanitized again, so a
+// stored name always matches the channel's.
+func FuzzSanitizeChannelName(f *testing.F) {
+	for _, seed := range []string{
+		"Acme Site!",
+		"שלום עולם",
+		"مرحبا بالعالم",
+		"‮evil‬ name",
+		"‏acme‎",
+		"acme​site",
+		"a‍‍b",
+		"‍👩",
+		"👩‍💻 team",
+		"👨‍👩‍👧‍👦",
+		"🏳️‍🌈 pride",
+		"é́té",
+		"́leading mark",
+		"İstanbul",
+		"-_-",
+		strings.Repeat("ab ", 60),
+		strings.Repeat("👩‍💻", 40),
+		"\xff\xfe bad bytes",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		got := sanitizeChannelName(name)
+		if !utf8.ValidString(got) {
+			t.Fatalf("sanitizeChannelName(%q) = %q, which isn't valid UTF-8", name, got)
+		}
+		if n := utf8.RuneCountInString(got); n > maxChannelNameLength {
+			t.Fatalf("sanitizeChannelName(%q) is %d characters long, want at most %d", name, n, maxChannelNameLength)
+		}
+		if strings.HasPrefix(got, "-") || strings.HasSuffix(got, "-") || strings.Contains(got, "--") {
+			t.Fatalf("sanitizeChannelName(%q) = %q, which has stray dashes", name, got)
+		}
+		if again := sanitizeChannelName(got); again != got {
+			t.Fatalf("sanitizeChannelName(%q) = %q, but sanitizing that again gives %q", name, got, again)
+		}
+	})
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A client company, with who to talk to and how to bill them.
+type client struct {
+	Name      string          `json:"name"`
+	Email     string          `json:"email,omitempty"`
+	Billing   string          `json:"billing,omitempty"`
+	Contacts  []clientContact `json:"contacts,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	// Whether the client's project channels are kept in categories of their own.
+	Grouped bool `json:"grouped,omitempty"`
+	// The categories made for the client's channels, in the order they were made.
+	Categories []string `json:"categories,omitempty"`
+}
+
+// A person at a client.
+type clientContact struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Role  string `json:"role,omitempty"`
+}
+
+// The key a client is stored under.
+func clientKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Look up a client by name. The returned copy is safe to use without holding the store lock.
+func getClient(name string) (client, bool) {
+	var c client
+	var ok bool
+	readStore(func(d *storeData) {
+		var stored *client
+		if stored, ok = d.Clients[clientKey(name)]; ok {
+			c = *stored
+			c.Contacts = slices.Clone(stored.Contacts)
+			c.Categories = slices.Clone(stored.Categories)
+		}
+	})
+	return c, ok
+}
+
+// Manage the client directory.
+func clientCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	var content string
+	var embeds []*discordgo.MessageEmbed
+	switch sub.Name {
+	case "add":
+		content = addClient(options)
+	case "set":
+		content = setClient(options)
+	case "contact":
+		content = addClientContact(options)
+	case "link":
+		content = linkClient(s, i.ChannelID, options["name"].StringValue())
+	case "group":
+		content = setClientGrouping(s, options["name"].StringValue(), options["enabled"].BoolValue())
+	case "info":
+		if embed, ok := clientInfo(options["name"].StringValue()); ok {
+			embeds = []*discordgo.MessageEmbed{embed}
+		} else {
+			content = fmt.Sprintf("There's no client named %s.", options["name"].StringValue())
+		}
+	case "list":
+		content = listClients()
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Embeds:  embeds,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Add a client to the directory, returning the response to show the caller.
+func addClient(options map[string]*discordgo.ApplicationCommandInteractionDataOption) string {
+	c := &client{Name: strings.TrimSpace(options["name"].StringValue()), CreatedAt: time.Now()}
+	if o, ok := options["email"]; ok {
+		c.Email = strings.TrimSpace(o.StringValue())
+	}
+	if o, ok := options["billing"]; ok {
+		c.Billing = strings.TrimSpace(o.StringValue())
+	}
+
+	var exists bool
+	err := updateStore(func(d *storeData) {
+		if _, exists = d.Clients[clientKey(c.Name)]; exists {
+			return
+		}
+		if d.Clients == nil {
+			d.Clients = make(map[string]*client)
+		}
+		d.Clients[clientKey(c.Name)] = c
+	})
+	switch {
+	case err != nil:
+		log.Printf("Error saving client: %v", err)
+		return "Error saving client: " + describeError(err)
+	case exists:
+		return fmt.Sprintf("There's already a client named %s. Use `/client set` to change it.", c.Name)
+	}
+	log.Printf("Added client %q.", c.Name)
+	return fmt.Sprintf("Added %s to the client directory.", c.Name)
+}
+
+// Change a client's email address or billing details, returning the response to show the caller.
+func setClient(options map[string]*discordgo.ApplicationCommandInteractionDataOption) string {
+	name := options["name"].StringValue()
+	var found bool
+	err := updateStore(func(d *storeData) {
+		var c *client
+		if c, found = d.Clients[clientKey(name)]; !found {
+			return
+		}
+		if o, ok := options["email"]; ok {
+			c.Email = strings.TrimSpace(o.StringValue())
+		}
+		if o, ok := options["billing"]; ok {
+			c.Billing = strings.TrimSpace(o.StringValue())
+		}
+	})
+	switch {
+	case err != nil:
+		log.Printf("Error saving client: %v", err)
+		return "Error saving client: " + describeError(err)
+	case !found:
+		return fmt.Sprintf("There's no client named %s.", name)
+	}
+	log.Printf("Updated client %q.", name)
+	return fmt.Sprintf("Updated %s.", name)
+}
+
+// Add a contact to a client, replacing any contact with the same name, and return the response to show the caller.
+func addClientContact(options map[string]*discordgo.ApplicationCommandInteractionDataOption) string {
+	name := options["name"].StringValue()
+	contact := clientContact{Name: strings.TrimSpace(options["contact-name"].StringValue())}
+	if o, ok := options["contact-email"]; ok {
+		contact.Email = strings.TrimSpace(o.StringValue())
+	}
+	if o, ok := options["role"]; ok {
+		contact.Role = strings.TrimSpace(o.StringValue())
+	}
+
+	var found bool
+	err := updateStore(func(d *storeData) {
+		var c *client
+		if c, found = d.Clients[clientKey(name)]; !found {
+			return
+		}
+		c.Contacts = slices.DeleteFunc(c.Contacts, func(existing clientContact) bool {
+			return strings.EqualFold(existing.Name, contact.Name)
+		})
+		c.Contacts = append(c.Contacts, contact)
+	})
+	switch {
+	case err != nil:
+		log.Printf("Error saving client contact: %v", err)
+		return "Error saving client contact: " + describeError(err)
+	case !found:
+		return fmt.Sprintf("There's no client named %s.", name)
+	}
+	return fmt.Sprintf("Saved %s as a contact at %s.", contact.Name, name)
+}
+
+// Link a project to a client, returning the response to show the caller. The project takes the client's name for
+// client nicknames unless it already has one.
+func linkClient(s *discordgo.Session, channelID, name string) string {
+	c, ok := getClient(name)
+	if !ok {
+		return fmt.Sprintf("There's no client named %s.", name)
+	}
+	if _, ok := getProject(channelID); !ok {
+		return "This channel is not a registered project."
+	}
+	err := updateProject(channelID, func(p *project) {
+		p.Client = clientKey(c.Name)
+		if p.ClientName == "" {
+			p.ClientName = c.Name
+		}
+	})
+	if err != nil {
+		log.Printf("Error linking client: %v", err)
+		return "Error linking client: " + describeError(err)
+	}
+	log.Printf("Linked channel %s to client %q.", channelID, c.Name)
+	if p, _ := getProject(channelID); c.Grouped && p.CategoryID == "" {
+		if err := groupClientChannel(s, clientKey(c.Name), channelID); err != nil {
+			log.Printf("Error grouping client channel: %v", err)
+			return fmt.Sprintf("Linked this project to %s, but couldn't move it to their category: %s", c.Name, describeError(err))
+		}
+	}
+	return fmt.Sprintf("Linked this project to %s.", c.Name)
+}
+
+// Show a client's details with all of their projects and what they still owe.
+func clientInfo(name string) (*discordgo.MessageEmbed, bool) {
+	c, ok := getClient(name)
+	if !ok {
+		return nil, false
+	}
+	var projects []string
+	var outstanding int64
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if p.Client != clientKey(c.Name) {
+				continue
+			}
+			_, _, owed := milestoneTotals(p.Milestones)
+			outstanding += owed
+			line := "<#" + p.ChannelID + ">"
+			if owed > 0 {
+				line += " (" + formatAmount(owed) + " outstanding)"
+			}
+			projects = append(projects, line)
+		}
+	})
+	slices.Sort(projects)
+
+	embed := themed(JuiceworksGuildId, &discordgo.MessageEmbed{Title: c.Name})
+	if c.Email != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Email", Value: c.Email, Inline: true})
+	}
+	if c.Billing != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Billing", Value: truncate(c.Billing, 1024), Inline: true})
+	}
+	if len(c.Contacts) > 0 {
+		contacts := make([]string, len(c.Contacts))
+		for n, contact := range c.Contacts {
+			contacts[n] = contact.Name
+			if contact.Role != "" {
+				contacts[n] += ", " + contact.Role
+			}
+			if contact.Email != "" {
+				contacts[n] += " (" + contact.Email + ")"
+			}
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Contacts", Value: truncate(strings.Join(contacts, "\n"), 1024)})
+	}
+	if len(projects) == 0 {
+		projects = append(projects, "None")
+	}
+	embed.Fields = append(embed.Fields,
+		&discordgo.MessageEmbedField{Name: "Projects", Value: truncate(strings.Join(projects, "\n"), 1024)},
+		&discordgo.MessageEmbedField{Name: "Outstanding", Value: formatAmount(outstanding)},
+	)
+	return embed, true
+}
+
+// List the clients in the directory.
+func listClients() string {
+	var names []string
+	readStore(func(d *storeData) {
+		for _, c := range d.Clients {
+			names = append(names, "- "+c.Name)
+		}
+	})
+	if len(names) == 0 {
+		return "The client directory is empty."
+	}
+	slices.Sort(names)
+	return truncate(strings.Join(names, "\n"), 2000)
+}
+
+// Discord allows 50 channels in a category.
+const maxCategoryChannels = 50
+
+// How many channels are in a category, going by the state cache.
+func categoryChannelCount(s *discordgo.Session, categoryID string) int {
+	guild, err := s.State.Guild(JuiceworksGuildId)
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, channel := range guild.Channels {
+		if channel.ParentID == categoryID {
+			count++
+		}
+	}
+	return count
+}
+
+// Move a project channel into its client's category. When the client's categories are full, another one is made.
+func groupClientChannel(s *discordgo.Session, key, channelID string) error {
+	c, ok := getClient(key)
+	if !ok {
+		return userErrorf(errCodeUnknownClient, "there's no client named %s", key)
+	}
+	categoryID := ""
+	for _, id := range c.Categories {
+		if categoryChannelCount(s, id) < maxCategoryChannels {
+			categoryID = id
+			break
+		}
+	}
+	if categoryID == "" {
+		name := c.Name
+		if len(c.Categories) > 0 {
+			name = fmt.Sprintf("%s (%d)", c.Name, len(c.Categories)+1)
+		}
+		category, err := s.GuildChannelCreateComplex(JuiceworksGuildId, discordgo.GuildChannelCreateData{
+			Name: truncate(name, 100),
+			Type: discordgo.ChannelTypeGuildCategory,
+			PermissionOverwrites: []*discordgo.PermissionOverwrite{
+				{ID: JuiceworksRoleId, Type: discordgo.PermissionOverwriteTypeRole, Allow: discordgo.PermissionViewChannel},
+				{ID: JuiceworksGuildId, Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionViewChannel},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		categoryID = category.ID
+		err = updateStore(func(d *storeData) {
+			if c, ok := d.Clients[key]; ok {
+				c.Categories = append(c.Categories, categoryID)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The channel keeps its own permissions rather than syncing with the category.
+	_, err := s.ChannelEdit(channelID, &discordgo.ChannelEdit{ParentID: categoryID})
+	return err
+}
+
+// Delete client categories that have no channels left, such as when a client's last project is archived. Categories
+// made in the last minute are left alone, since a channel is about to be moved into them.
+func cleanUpClientCategories(s *discordgo.Session) {
+	var empty []string
+	readStore(func(d *storeData) {
+		for _, c := range d.Clients {
+			for _, id := range c.Categories {
+				created, err := discordgo.SnowflakeTimestamp(id)
+				if err == nil && time.Since(created) > time.Minute && categoryChannelCount(s, id) == 0 {
+					empty = append(empty, id)
+				}
+			}
+		}
+	})
+	if len(empty) == 0 {
+		return
+	}
+
+	for _, id := range empty {
+		if _, err := s.ChannelDelete(id); err != nil {
+			recordFailure("deleting empty client category", err)
+		}
+	}
+	err := updateStore(func(d *storeData) {
+		for _, c := range d.Clients {
+			c.Categories = slices.DeleteFunc(c.Categories, func(id string) bool {
+				return slices.Contains(empty, id)
+			})
+		}
+	})
+	if err != nil {
+		log.Printf("Error removing client categories: %v", err)
+	}
+}
+
+// Clean up client categories when a channel is moved, such as into the archive.
+func cleanUpClientCategoriesOnUpdate(s *discordgo.Session, _ *discordgo.ChannelUpdate) {
+	cleanUpClientCategories(s)
+}
+
+// Clean up client categories when a channel is deleted.
+func cleanUpClientCategoriesOnDelete(s *discordgo.Session, _ *discordgo.ChannelDelete) {
+	cleanUpClientCategories(s)
+}
+
+// Turn grouping a client's project channels into their own categories on or off. Turning it on moves their existing
+// projects in; turning it off leaves channels where they are.
+func setClientGrouping(s *discordgo.Session, name string, enabled bool) string {
+	c, ok := getClient(name)
+	if !ok {
+		return fmt.Sprintf("There's no client named %s.", name)
+	}
+	err := updateStore(func(d *storeData) {
+		d.Clients[clientKey(c.Name)].Grouped = enabled
+	})
+	if err != nil {
+		log.Printf("Error saving client: %v", err)
+		return "Error saving client: " + describeError(err)
+	}
+	if !enabled {
+		return fmt.Sprintf("New projects for %s will no longer be grouped.", c.Name)
+	}
+
+	var channelIDs []string
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if p.Client == clientKey(c.Name) && p.CategoryID == "" {
+				channelIDs = append(channelIDs, p.ChannelID)
+			}
+		}
+	})
+	for _, channelID := range channelIDs {
+		if err := groupClientChannel(s, clientKey(c.Name), channelID); err != nil {
+			log.Printf("Error grouping client channel: %v", err)
+			return "Error grouping client channel: " + describeError(err)
+		}
+	}
+	return fmt.Sprintf("Grouped %d projects for %s into their own category.", len(channelIDs), c.Name)
+}
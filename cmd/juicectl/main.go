@@ -0,0 +1,272 @@
+// Command juicectl runs ops tasks against the Juiceworks bot from the terminal. It talks to the bot's API at
+// JUICEWORKS_API_URL with API_KEY. When the bot can't be reached, commands that only read fall back to the bot's
+// data file at DATA_FILE.
+//
+// Usage:
+//
+//	juicectl projects
+//	juicectl export
+//	juicectl add-member <channel ID> <user ID>...
+//	juicectl remove-member <channel ID> <user ID>
+//	juicectl reconcile
+//	juicectl import [--dry-run]
+//	juicectl tokens
+//	juicectl create-token <name> <read|project-admin|admin>
+//	juicectl revoke-token <token ID>
+//
+// Reconciling, importing and managing tokens need an admin token.
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/juiceworks/juiceworks-discord/projectapi"
+)
+
+// Returned when there's no API to call.
+var errNoAPIURL = errors.New("JUICEWORKS_API_URL is not set")
+
+// Call the bot's API, decoding a JSON response into out if it isn't nil.
+func call(method, path string, body, out any) error {
+	base := os.Getenv("JUICEWORKS_API_URL")
+	if base == "" {
+		return errNoAPIURL
+	}
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(base, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", os.Getenv("API_KEY"))
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("bot returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// The registered projects, from the bot if it's up and from its data file if it can't be reached. When the bot answers
+// with an error, like for a bad token, that's returned instead of data that may be stale.
+func projects() ([]projectapi.Project, error) {
+	var list []projectapi.Project
+	err := call(http.MethodGet, "/api/projects", nil, &list)
+	var unreachable *url.Error
+	switch {
+	case err == nil:
+		return list, nil
+	case errors.Is(err, errNoAPIURL) || errors.As(err, &unreachable):
+		log.Printf("Couldn't reach the bot (%v), reading the data file instead.", err)
+	default:
+		return nil, err
+	}
+
+	path := os.Getenv("DATA_FILE")
+	if path == "" {
+		path = "data.json"
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Projects map[string]projectapi.Project `json:"projects"`
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	for _, p := range data.Projects {
+		list = append(list, p)
+	}
+	slices.SortFunc(list, func(a, b projectapi.Project) int { return strings.Compare(a.Name, b.Name) })
+	return list, nil
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: juicectl projects | export | add-member <channel> <user>... | remove-member <channel> <user> | reconcile | import [--dry-run] | tokens | create-token <name> <scope> | revoke-token <id>")
+	}
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "projects":
+		list, err := projects()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CHANNEL\tNAME\tSTATUS\tCLIENT\tMEMBERS")
+		for _, p := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", p.ChannelID, p.Name, p.State(), p.ClientName, len(p.Members))
+		}
+		return w.Flush()
+
+	case "export":
+		list, err := projects()
+		if err != nil {
+			return err
+		}
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"Channel ID", "Name", "Status", "Client", "Created", "Members"})
+		for _, p := range list {
+			members := make([]string, 0, len(p.Members))
+			for _, name := range p.Members {
+				members = append(members, name)
+			}
+			slices.Sort(members)
+			w.Write([]string{p.ChannelID, p.Name, p.State(), p.ClientName, p.CreatedAt.Format(time.RFC3339), strings.Join(members, ", ")})
+		}
+		w.Flush()
+		return w.Error()
+
+	case "add-member":
+		if len(args) < 2 {
+			return errors.New("usage: juicectl add-member <channel ID> <user ID>...")
+		}
+		if len(args) == 2 {
+			return call(http.MethodPost, "/api/projects/"+args[0]+"/members", map[string]string{"userId": args[1]}, nil)
+		}
+		var results []projectapi.MemberResult
+		if err := call(http.MethodPost, "/api/projects/"+args[0]+"/members", map[string][]string{"userIds": args[1:]}, &results); err != nil {
+			return err
+		}
+		failed := 0
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Printf("%s: %s\n", r.UserID, r.Error)
+				failed++
+			}
+		}
+		fmt.Printf("Added %d members, %d failed.\n", len(results)-failed, failed)
+		if failed > 0 {
+			return fmt.Errorf("%d members weren't added", failed)
+		}
+		return nil
+
+	case "remove-member":
+		if len(args) != 2 {
+			return errors.New("usage: juicectl remove-member <channel ID> <user ID>")
+		}
+		return call(http.MethodDelete, "/api/projects/"+args[0]+"/members/"+args[1], nil, nil)
+
+	case "reconcile":
+		var result map[string]int
+		if err := call(http.MethodPost, "/api/reconcile", nil, &result); err != nil {
+			return err
+		}
+		fmt.Printf("Reconciled %d projects, %d failed.\n", result["projects"], result["failed"])
+		return nil
+
+	case "import":
+		path := "/api/import"
+		verb := "Imported"
+		if slices.Equal(args, []string{"--dry-run"}) {
+			path += "?dry-run=true"
+			verb = "Would import"
+		} else if len(args) > 0 {
+			return errors.New("usage: juicectl import [--dry-run]")
+		}
+		var imported []struct {
+			ChannelID  string `json:"channelId"`
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			ClientName string `json:"clientName"`
+			Template   string `json:"template"`
+			Members    int    `json:"members"`
+			Internal   bool   `json:"internal"`
+		}
+		if err := call(http.MethodPost, path, nil, &imported); err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CHANNEL\tNAME\tSTATUS\tCLIENT\tTEMPLATE\tMEMBERS\tINTERNAL")
+		for _, p := range imported {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%t\n", p.ChannelID, p.Name, p.Status, p.ClientName, p.Template, p.Members, p.Internal)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("%s %d channels.\n", verb, len(imported))
+		return nil
+
+	case "tokens":
+		var tokens []struct {
+			ID        string    `json:"id"`
+			Name      string    `json:"name"`
+			Scope     string    `json:"scope"`
+			CreatedBy string    `json:"createdBy"`
+			CreatedAt time.Time `json:"createdAt"`
+		}
+		if err := call(http.MethodGet, "/api/tokens", nil, &tokens); err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSCOPE\tCREATED BY\tCREATED")
+		for _, t := range tokens {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Scope, t.CreatedBy, t.CreatedAt.Format(time.DateOnly))
+		}
+		return w.Flush()
+
+	case "create-token":
+		if len(args) != 2 {
+			return errors.New("usage: juicectl create-token <name> <read|project-admin|admin>")
+		}
+		var created struct {
+			Token struct {
+				ID string `json:"id"`
+			} `json:"token"`
+			Secret string `json:"secret"`
+		}
+		if err := call(http.MethodPost, "/api/tokens", map[string]string{"name": args[0], "scope": args[1]}, &created); err != nil {
+			return err
+		}
+		fmt.Printf("Created token %s. Copy it now, since it can't be shown again:\n%s\n", created.Token.ID, created.Secret)
+		return nil
+
+	case "revoke-token":
+		if len(args) != 1 {
+			return errors.New("usage: juicectl revoke-token <token ID>")
+		}
+		return call(http.MethodDelete, "/api/tokens/"+args[0], nil, nil)
+
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	godotenv.Load(".env")
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatalf("juicectl: %v", err)
+	}
+}
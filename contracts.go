@@ -0,0 +1,218 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The progress of a contract sent for signing, as reported by Dropbox Sign's events.
+var contractStatuses = map[string]string{
+	"signature_request_sent":       "sent",
+	"signature_request_viewed":     "viewed",
+	"signature_request_signed":     "signed by some signers",
+	"signature_request_all_signed": "completed",
+	"signature_request_declined":   "declined",
+	"signature_request_canceled":   "cancelled",
+	"signature_request_expired":    "expired",
+}
+
+// A contract sent to the client through Dropbox Sign.
+type contract struct {
+	RequestID string    `json:"requestId"`
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	SentAt    time.Time `json:"sentAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Whether the contract is fully signed.
+func (c *contract) completed() bool {
+	return c != nil && c.Status == contractStatuses["signature_request_all_signed"]
+}
+
+// Whether the contract can't be signed anymore because it was declined, cancelled or expired.
+func (c *contract) ended() bool {
+	if c == nil {
+		return false
+	}
+	for _, event := range []string{"signature_request_declined", "signature_request_canceled", "signature_request_expired"} {
+		if c.Status == contractStatuses[event] {
+			return true
+		}
+	}
+	return false
+}
+
+// Send the contract template in DROPBOX_SIGN_TEMPLATE_ID to a client for signing, returning the signature request ID.
+// The client signs as the template role in DROPBOX_SIGN_SIGNER_ROLE, which defaults to Client. The project channel is
+// attached as metadata so events can be matched to it.
+func sendContract(channelID, name, email string) (id string, err error) {
+	defer func() { noteIntegrationCall("Dropbox Sign", err) }()
+	role := os.Getenv("DROPBOX_SIGN_SIGNER_ROLE")
+	if role == "" {
+		role = "Client"
+	}
+	params := url.Values{
+		"template_ids[]":                       {os.Getenv("DROPBOX_SIGN_TEMPLATE_ID")},
+		"signers[" + role + "][name]":          {name},
+		"signers[" + role + "][email_address]": {email},
+		"metadata[channel_id]":                 {channelID},
+	}
+	if os.Getenv("DROPBOX_SIGN_TEST_MODE") != "" {
+		params.Set("test_mode", "1")
+	}
+	req, err := http.NewRequest(http.MethodPost, "https://api.hellosign.com/v3/signature_request/send_with_template",
+		strings.NewReader(params.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(os.Getenv("DROPBOX_SIGN_API_KEY"), "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("dropbox sign returned %s: %s", resp.Status, msg)
+	}
+
+	var result struct {
+		SignatureRequest struct {
+			SignatureRequestID string `json:"signature_request_id"`
+		} `json:"signature_request"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.SignatureRequest.SignatureRequestID, nil
+}
+
+// Send the contract to the client of the project the command was called from.
+func sendContractCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range i.ApplicationCommandData().Options {
+		options[o.Name] = o
+	}
+	name := strings.TrimSpace(options["name"].StringValue())
+	email := strings.TrimSpace(options["email"].StringValue())
+
+	var content string
+	p, ok := getProject(i.ChannelID)
+	_, addrErr := mail.ParseAddress(email)
+	switch {
+	case addrErr != nil:
+		content = "That doesn't look like an email address."
+	case !ok:
+		content = "This channel is not a registered project."
+	case os.Getenv("DROPBOX_SIGN_API_KEY") == "" || os.Getenv("DROPBOX_SIGN_TEMPLATE_ID") == "":
+		content = "Contract signing isn't set up."
+	case p.Contract != nil && !p.Contract.completed() && !p.Contract.ended():
+		content = fmt.Sprintf("A contract was already sent to %s and is %s.", p.Contract.Email, p.Contract.Status)
+	default:
+		requestID, err := sendContract(i.ChannelID, name, email)
+		if err != nil {
+			log.Printf("Error sending contract: %v", err)
+			content = "Error sending contract: " + describeError(err)
+			break
+		}
+		err = updateProject(i.ChannelID, func(p *project) {
+			p.Contract = &contract{RequestID: requestID, Email: email, Status: "sent", SentAt: time.Now(), UpdatedAt: time.Now()}
+		})
+		if err != nil {
+			log.Printf("Error saving contract: %v", err)
+		}
+		log.Printf("Sent contract %s for channel %s to %s.", requestID, i.ChannelID, email)
+		content = fmt.Sprintf("Sent the contract to %s. Progress will be posted here.", email)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// A Dropbox Sign event callback.
+type dropboxSignEvent struct {
+	Event struct {
+		EventTime string `json:"event_time"`
+		EventType string `json:"event_type"`
+		EventHash string `json:"event_hash"`
+	} `json:"event"`
+	SignatureRequest struct {
+		SignatureRequestID string            `json:"signature_request_id"`
+		Metadata           map[string]string `json:"metadata"`
+	} `json:"signature_request"`
+}
+
+// Record a contract's progress from Dropbox Sign and post it in the project channel. Events are signed with the API
+// key, and Dropbox Sign expects a fixed reply to count the callback as delivered.
+func dropboxSignWebhook(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxWebhookBodySize); err != nil {
+		log.Printf("Error decoding Dropbox Sign webhook: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var e dropboxSignEvent
+	if err := json.Unmarshal([]byte(r.FormValue("json")), &e); err != nil {
+		log.Printf("Error decoding Dropbox Sign webhook: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Dropbox Sign signs the event time and type with the API key.
+	mac := hmac.New(sha256.New, []byte(os.Getenv("DROPBOX_SIGN_API_KEY")))
+	mac.Write([]byte(e.Event.EventTime + e.Event.EventType))
+	if os.Getenv("DROPBOX_SIGN_API_KEY") == "" || !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(e.Event.EventHash)) {
+		log.Printf("Rejected webhook on %s: invalid signature", r.URL.Path)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	io.WriteString(w, "Hello API Event Received")
+
+	status, ok := contractStatuses[e.Event.EventType]
+	channelID := e.SignatureRequest.Metadata["channel_id"]
+	if !ok || channelID == "" {
+		return
+	}
+	p, ok := getProject(channelID)
+	if !ok || p.Contract == nil || p.Contract.RequestID != e.SignatureRequest.SignatureRequestID || p.Contract.Status == status {
+		return
+	}
+	err := updateProject(channelID, func(p *project) {
+		p.Contract.Status = status
+		p.Contract.UpdatedAt = time.Now()
+	})
+	if err != nil {
+		recordFailure("recording contract status", err)
+	}
+
+	log.Printf("Contract %s for channel %s is %s.", e.SignatureRequest.SignatureRequestID, channelID, status)
+	pings, allowed := projectPings(channelID)
+	_, err = s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         strings.TrimSpace(fmt.Sprintf("The contract sent to %s is %s. %s", p.Contract.Email, status, pings)),
+		AllowedMentions: allowed,
+	})
+	if err != nil {
+		recordFailure("posting contract status", err)
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+// Only contracts that can still be signed hold up sending a new one.
+func TestContractEnded(t *testing.T) {
+	for event, status := range contractStatuses {
+		c := &contract{Status: status}
+		want := event == "signature_request_declined" || event == "signature_request_canceled" || event == "signature_request_expired"
+		if c.ended() != want {
+			t.Errorf("a %s contract ended() = %v, want %v", status, c.ended(), want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How many times a user can run a command in a window.
+type commandLimit struct {
+	uses   int
+	window time.Duration
+}
+
+// The default limits on expensive commands. COMMAND_LIMITS can change these or add more, as a comma-separated list
+// like make-channel=3/1h,add-member=30/1h.
+var defaultCommandLimits = map[string]commandLimit{
+	"make-channel":  {3, time.Hour},
+	"health-report": {5, time.Hour},
+	"escalate":      {3, time.Hour},
+}
+
+// When each user recently ran each limited command, keyed by command name and then user ID.
+var (
+	commandUsesMu sync.Mutex
+	commandUses   = make(map[string]map[string][]time.Time)
+)
+
+// The limits in effect, with COMMAND_LIMITS applied over the defaults. Malformed entries are logged and skipped.
+func commandLimits() map[string]commandLimit {
+	limits := make(map[string]commandLimit, len(defaultCommandLimits))
+	for name, l := range defaultCommandLimits {
+		limits[name] = l
+	}
+	for _, entry := range strings.Split(os.Getenv("COMMAND_LIMITS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, rate, _ := strings.Cut(entry, "=")
+		uses, window, _ := strings.Cut(rate, "/")
+		n, err := strconv.Atoi(uses)
+		d, err2 := time.ParseDuration(window)
+		if err != nil || err2 != nil || n < 0 || d <= 0 {
+			log.Printf("Ignoring malformed COMMAND_LIMITS entry %q", entry)
+			continue
+		}
+		limits[name] = commandLimit{n, d}
+	}
+	return limits
+}
+
+// Count a use of a command and return how long the user has to wait if they've run it too often. Uses that are
+// refused don't count against the limit.
+func cooldown(command, userID string, now time.Time) time.Duration {
+	limit, ok := commandLimits()[command]
+	if !ok {
+		return 0
+	}
+
+	commandUsesMu.Lock()
+	defer commandUsesMu.Unlock()
+	if commandUses[command] == nil {
+		commandUses[command] = make(map[string][]time.Time)
+	}
+
+	// Forget uses that have left the window.
+	uses := commandUses[command][userID]
+	for len(uses) > 0 && now.Sub(uses[0]) >= limit.window {
+		uses = uses[1:]
+	}
+	if len(uses) >= limit.uses {
+		commandUses[command][userID] = uses
+		if len(uses) == 0 {
+			return limit.window
+		}
+		return uses[0].Add(limit.window).Sub(now)
+	}
+	commandUses[command][userID] = append(uses, now)
+	return 0
+}
+
+// Refuse a command the caller has run too often. Returns true if the command may go ahead.
+func checkCooldown(s *discordgo.Session, i *discordgo.InteractionCreate, command string) bool {
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	wait := cooldown(command, user.ID, time.Now())
+	if wait <= 0 {
+		return true
+	}
+
+	log.Printf("Rate limited %s on %s for %s.", user, command, wait)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("You're using /%s too often. Try again in %ds.", command, int(math.Ceil(wait.Seconds()))),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+	return false
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Make another member the client lead of the project the command was called from.
+func transferCreatorCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := i.ApplicationCommandData().Options[0].UserValue(s)
+	content := transferCreator(s, i.Member.User, workspaceProjectID(i.ChannelID), user)
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Move a project's client lead designation to a member, returning the response to show the caller. With
+// PROJECT_LEAD_ROLE_ID set, the role moves too, though the previous lead keeps it while they lead another project.
+// Both leads are told in the project channel.
+func transferCreator(s *discordgo.Session, caller *discordgo.User, projectID string, user *discordgo.User) string {
+	p, ok := getProject(projectID)
+	switch {
+	case !ok:
+		return "This channel is not a registered project."
+	case p.Members[user.ID] == "":
+		return fmt.Sprintf("%s isn't a member of this project. Add them with /add-member first.", user.Mention())
+	case p.CreatorID == user.ID:
+		return fmt.Sprintf("%s is already the client lead.", user.Mention())
+	}
+	previous := p.CreatorID
+
+	if roleID := os.Getenv("PROJECT_LEAD_ROLE_ID"); roleID != "" {
+		if err := s.GuildMemberRoleAdd(JuiceworksGuildId, user.ID, roleID); err != nil {
+			log.Printf("Error granting project lead role: %v", err)
+			return "Error granting project lead role: " + describeError(err)
+		}
+		if previous != "" && len(ledProjects(previous)) == 1 {
+			if err := s.GuildMemberRoleRemove(JuiceworksGuildId, previous, roleID); err != nil {
+				log.Printf("Error removing project lead role: %v", err)
+			}
+		}
+	}
+
+	err := updateProject(projectID, func(p *project) {
+		p.CreatorID = user.ID
+	})
+	if err != nil {
+		log.Printf("Error recording client lead: %v", err)
+		return "Error recording client lead: " + describeError(err)
+	}
+
+	announcement := fmt.Sprintf("%s is now the client lead for this project.", user.Mention())
+	mentions := []string{user.ID}
+	if previous != "" {
+		announcement = fmt.Sprintf("<@%s> handed the client lead for this project over to %s.", previous, user.Mention())
+		mentions = append(mentions, previous)
+	}
+	_, err = s.ChannelMessageSendComplex(projectID, &discordgo.MessageSend{
+		Content:         announcement,
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: mentions},
+	})
+	if err != nil {
+		log.Printf("Error announcing client lead: %v", err)
+	}
+
+	log.Printf("%s made %s the client lead of %s.", caller, user, projectID)
+	postAudit(s, auditEntry{
+		Action:    auditCreatorTransferred,
+		ActorID:   caller.ID,
+		TargetID:  user.ID,
+		ChannelID: projectID,
+		Summary:   fmt.Sprintf("<@%s> made %s the client lead of <#%s>, taking over from %s.", caller.ID, user.Mention(), projectID, mentionOrNobody(previous)),
+	})
+	return fmt.Sprintf("%s is now the client lead.", user.Mention())
+}
+
+// The channel IDs of the projects a user is the client lead of.
+func ledProjects(userID string) []string {
+	var ids []string
+	readStore(func(d *storeData) {
+		for id, p := range d.Projects {
+			if p.CreatorID == userID {
+				ids = append(ids, id)
+			}
+		}
+	})
+	return ids
+}
+
+// Mention a user, or say there's nobody.
+func mentionOrNobody(userID string) string {
+	if userID == "" {
+		return "nobody"
+	}
+	return "<@" + userID + ">"
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How many cross-posts each project remembers. The oldest are forgotten first.
+const maxCrossPosts = 500
+
+// A message shared between a project's channel and its internal channel.
+type crossPost struct {
+	SourceChannelID string    `json:"sourceChannelId"`
+	SourceMessageID string    `json:"sourceMessageId"`
+	ChannelID       string    `json:"channelId"`
+	MessageID       string    `json:"messageId"`
+	SharedBy        string    `json:"sharedBy"`
+	SharedAt        time.Time `json:"sharedAt"`
+}
+
+// Share the message the context menu was opened on from a project's channel to its internal channel.
+func shareToInternalMessage(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	shareMessage(s, i, true)
+}
+
+// Share the message the context menu was opened on from a project's internal channel to its channel.
+func shareToClientMessage(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	shareMessage(s, i, false)
+}
+
+// Repost a message in the other channel of its project's pair, with who wrote it and its attachments, and record it.
+func shareMessage(s *discordgo.Session, i *discordgo.InteractionCreate, toInternal bool) {
+	respond := func(content string) {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+	}
+
+	data := i.ApplicationCommandData()
+	m := data.Resolved.Messages[data.TargetID]
+	p, ok := pairedProject(m.ChannelID)
+	inInternal := ok && m.ChannelID == p.InternalChannelID
+	switch {
+	case !ok:
+		respond("This channel is not a registered project.")
+		return
+	case p.InternalChannelID == "":
+		respond("This project has no internal channel. Create one with /internal-channel.")
+		return
+	case toInternal && inInternal:
+		respond("This message is already in the internal channel. Use Share to client to share it with the client.")
+		return
+	case !toInternal && !inInternal:
+		respond("This message is already visible to the client. Use Share to internal to share it with the team.")
+		return
+	}
+	target := p.InternalChannelID
+	if !toInternal {
+		target = p.ChannelID
+	}
+	var shared *crossPost
+	readStore(func(d *storeData) {
+		if stored, ok := d.Projects[p.ChannelID]; ok {
+			n := slices.IndexFunc(stored.CrossPosts, func(c crossPost) bool {
+				return c.SourceMessageID == m.ID && c.ChannelID == target
+			})
+			if n >= 0 {
+				c := stored.CrossPosts[n]
+				shared = &c
+			}
+		}
+	})
+	if shared != nil {
+		respond(fmt.Sprintf("<@%s> already shared this message: https://discord.com/channels/%s/%s/%s",
+			shared.SharedBy, JuiceworksGuildId, shared.ChannelID, shared.MessageID))
+		return
+	}
+
+	// Copying attachments can take a while.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}))
+	edit := func(content string) {
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			log.Printf("Error responding to interaction: %v", err)
+		}
+	}
+
+	// Attachment links can expire, so copy the files themselves, falling back to links for ones that are too large.
+	content := fmt.Sprintf("🔁 Shared by <@%s> from <#%s>, originally from <@%s> <t:%d:f>:\n%s",
+		i.Member.User.ID, m.ChannelID, m.Author.ID, m.Timestamp.Unix(), m.Content)
+	var files []*discordgo.File
+	for _, a := range m.Attachments {
+		if a.Size > maxBridgedFileSize {
+			content += "\n" + a.URL
+			continue
+		}
+		data, err := downloadAttachment(a.URL)
+		if err != nil {
+			log.Printf("Error downloading attachment %q: %v", a.Filename, err)
+			content += "\n" + a.URL
+			continue
+		}
+		files = append(files, &discordgo.File{Name: a.Filename, ContentType: a.ContentType, Reader: bytes.NewReader(data)})
+	}
+	copied, err := s.ChannelMessageSendComplex(target, &discordgo.MessageSend{
+		Content:         truncate(content, 2000),
+		Embeds:          m.Embeds,
+		Files:           files,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		log.Printf("Error sharing message: %v", err)
+		edit("Error sharing message: " + describeError(err))
+		return
+	}
+
+	err = updateProject(p.ChannelID, func(p *project) {
+		p.CrossPosts = append(p.CrossPosts, crossPost{
+			SourceChannelID: m.ChannelID,
+			SourceMessageID: m.ID,
+			ChannelID:       target,
+			MessageID:       copied.ID,
+			SharedBy:        i.Member.User.ID,
+			SharedAt:        time.Now(),
+		})
+		if len(p.CrossPosts) > maxCrossPosts {
+			p.CrossPosts = slices.Delete(p.CrossPosts, 0, len(p.CrossPosts)-maxCrossPosts)
+		}
+	})
+	if err != nil {
+		log.Printf("Error recording cross-post: %v", err)
+	}
+	log.Printf("%s shared message %s from %s to %s.", i.Member.User, m.ID, m.ChannelID, target)
+	edit(fmt.Sprintf("Shared in <#%s>.", target))
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The review states of a deliverable.
+const (
+	deliverablePending          = "pending"
+	deliverableApproved         = "approved"
+	deliverableChangesRequested = "changes-requested"
+)
+
+// Work handed to the client for a milestone. IDs count up from 1 within each project.
+type deliverable struct {
+	ID          int       `json:"id"`
+	Milestone   string    `json:"milestone"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link,omitempty"`
+	FileName    string    `json:"fileName,omitempty"`
+	MessageID   string    `json:"messageId"`
+	DeliveredBy string    `json:"deliveredBy"`
+	DeliveredAt time.Time `json:"deliveredAt"`
+	Status      string    `json:"status"`
+	ReviewedBy  string    `json:"reviewedBy,omitempty"`
+	ReviewedAt  time.Time `json:"reviewedAt,omitempty"`
+	Feedback    string    `json:"feedback,omitempty"`
+}
+
+// The embed showing a deliverable and its review state.
+func deliverableEmbed(d deliverable) *discordgo.MessageEmbed {
+	embed := themed(JuiceworksGuildId, &discordgo.MessageEmbed{
+		Title: d.Title,
+		URL:   d.Link,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Milestone", Value: d.Milestone, Inline: true},
+			{Name: "Delivered by", Value: "<@" + d.DeliveredBy + ">", Inline: true},
+		},
+		Timestamp: d.DeliveredAt.Format(time.RFC3339),
+	})
+	switch d.Status {
+	case deliverablePending:
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Status", Value: "Waiting for review"})
+	case deliverableApproved:
+		embed.Color = 0x2ecc71
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: "Status", Value: fmt.Sprintf("Approved by <@%s> <t:%d:R>", d.ReviewedBy, d.ReviewedAt.Unix()),
+		})
+	case deliverableChangesRequested:
+		embed.Color = 0xe67e22
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: "Status", Value: fmt.Sprintf("Changes requested by <@%s> <t:%d:R>", d.ReviewedBy, d.ReviewedAt.Unix()),
+		}, &discordgo.MessageEmbedField{Name: "Feedback", Value: truncate(d.Feedback, 1024)})
+	}
+	return embed
+}
+
+// The review buttons on a deliverable, or none once it's been reviewed.
+func deliverableButtons(d deliverable) []discordgo.MessageComponent {
+	if d.Status != deliverablePending {
+		return []discordgo.MessageComponent{}
+	}
+	id := strconv.Itoa(d.ID)
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{Label: "Approve", Style: discordgo.SuccessButton, CustomID: "deliverable-approve:" + id},
+			discordgo.Button{Label: "Request changes", Style: discordgo.SecondaryButton, CustomID: "deliverable-changes:" + id},
+		}},
+	}
+}
+
+// Record a deliverable against a milestone of the project the command was called from, and post it for the client to
+// review. Files are re-uploaded by the bot so the post doesn't depend on the caller's attachment link.
+func deliver(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+	content := deliverContent(s, i)
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+	logResponseErr(err)
+}
+
+// Post and record a deliverable, returning the response to show the caller.
+func deliverContent(s *discordgo.Session, i *discordgo.InteractionCreate) string {
+	data := i.ApplicationCommandData()
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range data.Options {
+		options[o.Name] = o
+	}
+
+	p, ok := getProject(i.ChannelID)
+	if !ok {
+		return "This channel is not a registered project."
+	}
+	name := strings.TrimSpace(options["milestone"].StringValue())
+	n := slices.IndexFunc(p.Milestones, func(m milestone) bool { return strings.EqualFold(m.Name, name) })
+	if n < 0 {
+		return fmt.Sprintf("This project has no milestone called \"%s\".", name)
+	}
+	d := deliverable{
+		Milestone:   p.Milestones[n].Name,
+		Title:       strings.TrimSpace(options["title"].StringValue()),
+		DeliveredBy: i.Member.User.ID,
+		DeliveredAt: time.Now(),
+		Status:      deliverablePending,
+	}
+	if o, ok := options["link"]; ok {
+		d.Link = strings.TrimSpace(o.StringValue())
+		if !strings.HasPrefix(d.Link, "https://") && !strings.HasPrefix(d.Link, "http://") {
+			return "The link has to start with https://."
+		}
+	}
+	var files []*discordgo.File
+	if o, ok := options["file"]; ok {
+		a := data.Resolved.Attachments[o.Value.(string)]
+		body, err := downloadAttachment(a.URL)
+		if err != nil {
+			log.Printf("Error reading deliverable file: %v", err)
+			return "Error reading deliverable file: " + describeError(err)
+		}
+		d.FileName = a.Filename
+		files = append(files, &discordgo.File{Name: a.Filename, ContentType: a.ContentType, Reader: bytes.NewReader(body)})
+	}
+	if d.Link == "" && d.FileName == "" {
+		return "Attach a file or give a link to deliver."
+	}
+
+	// Save the deliverable first so its ID is taken before the buttons refer to it.
+	err := updateProject(i.ChannelID, func(p *project) {
+		d.ID = 1
+		if len(p.Deliverables) > 0 {
+			d.ID = p.Deliverables[len(p.Deliverables)-1].ID + 1
+		}
+		p.Deliverables = append(p.Deliverables, d)
+	})
+	if err != nil {
+		log.Printf("Error saving deliverable: %v", err)
+		return "Error saving deliverable: " + describeError(err)
+	}
+
+	pings, allowed := projectPings(i.ChannelID)
+	m, err := s.ChannelMessageSendComplex(i.ChannelID, &discordgo.MessageSend{
+		Content:         strings.TrimSpace("New deliverable for review. " + pings),
+		Embeds:          []*discordgo.MessageEmbed{deliverableEmbed(d)},
+		Components:      deliverableButtons(d),
+		Files:           files,
+		AllowedMentions: allowed,
+	})
+	if err != nil {
+		log.Printf("Error posting deliverable: %v", err)
+		if err := updateProject(i.ChannelID, func(p *project) {
+			p.Deliverables = slices.DeleteFunc(p.Deliverables, func(x deliverable) bool { return x.ID == d.ID })
+		}); err != nil {
+			log.Printf("Error removing unposted deliverable: %v", err)
+		}
+		return "Error posting deliverable: " + describeError(err)
+	}
+	err = updateProject(i.ChannelID, func(p *project) {
+		for n := range p.Deliverables {
+			if p.Deliverables[n].ID == d.ID {
+				p.Deliverables[n].MessageID = m.ID
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving deliverable: %v", err)
+	}
+	log.Printf("%s delivered %q for milestone %q in channel %s.", i.Member.User, d.Title, d.Milestone, i.ChannelID)
+	return "Posted the deliverable for review."
+}
+
+// Find the deliverable a review button or modal refers to, and check the caller may review it. Juiceworks members
+// can't review deliverables on the client's behalf. Returns why the review is refused, if it is.
+func reviewableDeliverable(i *discordgo.InteractionCreate, customID string) (deliverable, string) {
+	_, arg, _ := strings.Cut(customID, ":")
+	id, _ := strconv.Atoi(arg)
+	p, _ := getProject(i.ChannelID)
+	n := slices.IndexFunc(p.Deliverables, func(d deliverable) bool { return d.ID == id })
+	switch {
+	case n < 0:
+		return deliverable{}, "That deliverable no longer exists."
+	case slices.Contains(i.Member.Roles, JuiceworksRoleId):
+		return deliverable{}, "Only the client can review deliverables."
+	case p.Deliverables[n].Status != deliverablePending:
+		return deliverable{}, "That deliverable has already been reviewed."
+	}
+	return p.Deliverables[n], ""
+}
+
+// Returned by saveReview when someone else reviewed the deliverable first.
+var errAlreadyReviewed = errors.New("that deliverable has already been reviewed")
+
+// Save a review of a deliverable and update its post. The deliverable is checked to still be pending as the review
+// is saved, since two people can review it at once, and errAlreadyReviewed is returned for the second.
+func saveReview(s *discordgo.Session, i *discordgo.InteractionCreate, d deliverable) error {
+	saved := false
+	err := updateProject(i.ChannelID, func(p *project) {
+		for n := range p.Deliverables {
+			if p.Deliverables[n].ID == d.ID && p.Deliverables[n].Status == deliverablePending {
+				p.Deliverables[n] = d
+				saved = true
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return errAlreadyReviewed
+	}
+	embeds, components := []*discordgo.MessageEmbed{deliverableEmbed(d)}, deliverableButtons(d)
+	_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    i.ChannelID,
+		ID:         d.MessageID,
+		Embeds:     &embeds,
+		Components: &components,
+	})
+	if err != nil {
+		log.Printf("Error updating deliverable post: %v", err)
+	}
+	return nil
+}
+
+// Approve a deliverable and let the team know in the internal channel.
+func approveDeliverable(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	d, refused := reviewableDeliverable(i, i.MessageComponentData().CustomID)
+	content := refused
+	if refused == "" {
+		d.Status, d.ReviewedBy, d.ReviewedAt = deliverableApproved, i.Member.User.ID, time.Now()
+		if err := saveReview(s, i, d); errors.Is(err, errAlreadyReviewed) {
+			content = "That deliverable has already been reviewed."
+		} else if err != nil {
+			log.Printf("Error saving approval: %v", err)
+			content = "Error saving approval: " + describeError(err)
+		} else {
+			log.Printf("%s approved deliverable %d in channel %s.", i.Member.User, d.ID, i.ChannelID)
+			content = "Thanks! The team has been told you approved it."
+			_, err := s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{
+				Content: fmt.Sprintf("%s approved \"%s\" for milestone \"%s\" in <#%s>.",
+					i.Member.User.Mention(), d.Title, d.Milestone, i.ChannelID),
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			})
+			if err != nil {
+				log.Printf("Error announcing approval: %v", err)
+			}
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Ask the client what needs to change.
+func requestChanges(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	d, refused := reviewableDeliverable(i, i.MessageComponentData().CustomID)
+	if refused != "" {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: refused,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "deliverable-feedback:" + strconv.Itoa(d.ID),
+			Title:    truncate("Changes to "+d.Title, 45),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "feedback",
+						Label:     "What should change?",
+						Style:     discordgo.TextInputParagraph,
+						Required:  true,
+						MaxLength: 1000,
+					},
+				}},
+			},
+		},
+	}))
+}
+
+// Record the changes a client asked for, and let the person who delivered it know.
+func submitChanges(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	d, refused := reviewableDeliverable(i, data.CustomID)
+	content := refused
+	if refused == "" {
+		d.Status, d.ReviewedBy, d.ReviewedAt = deliverableChangesRequested, i.Member.User.ID, time.Now()
+		d.Feedback = data.Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
+		if err := saveReview(s, i, d); errors.Is(err, errAlreadyReviewed) {
+			content = "That deliverable has already been reviewed."
+		} else if err != nil {
+			log.Printf("Error saving feedback: %v", err)
+			content = "Error saving feedback: " + describeError(err)
+		} else {
+			log.Printf("%s requested changes to deliverable %d in channel %s.", i.Member.User, d.ID, i.ChannelID)
+			content = "Thanks! Your feedback has been passed on."
+			_, allowed := projectPings(i.ChannelID, d.DeliveredBy)
+			_, err := s.ChannelMessageSendComplex(i.ChannelID, &discordgo.MessageSend{
+				Content:         fmt.Sprintf("<@%s>, %s asked for changes to \"%s\":\n> %s", d.DeliveredBy, i.Member.User.Mention(), d.Title, strings.ReplaceAll(d.Feedback, "\n", "\n> ")),
+				AllowedMentions: allowed,
+			})
+			if err != nil {
+				log.Printf("Error posting feedback: %v", err)
+			}
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// Two clients reviewing the same deliverable at once both pass the first check, but only the first review is saved.
+func TestConcurrentDeliverableReviews(t *testing.T) {
+	f, s := newTestBot(t)
+	channelID := addProject(t, f, "acme")
+	err := updateProject(channelID, func(p *project) {
+		p.Deliverables = []deliverable{{ID: 1, Title: "Logo", Status: deliverablePending}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	approver := f.addMember("3000000000000000001", "approver")
+	critic := f.addMember("3000000000000000002", "critic")
+
+	first := componentInteraction(channelID, approver, "deliverable-approve:1")
+	second := componentInteraction(channelID, critic, "deliverable-changes:1")
+	approval, refused := reviewableDeliverable(first, "deliverable-approve:1")
+	changes, refusedToo := reviewableDeliverable(second, "deliverable-changes:1")
+	if refused != "" || refusedToo != "" {
+		t.Fatalf("the reviews were refused: %q, %q", refused, refusedToo)
+	}
+	approval.Status, approval.ReviewedBy = deliverableApproved, approver.User.ID
+	changes.Status, changes.ReviewedBy = deliverableChangesRequested, critic.User.ID
+
+	if err := saveReview(s, first, approval); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveReview(s, second, changes); !errors.Is(err, errAlreadyReviewed) {
+		t.Errorf("the second review = %v, want %v", err, errAlreadyReviewed)
+	}
+	if p, _ := getProject(channelID); p.Deliverables[0].Status != deliverableApproved || p.Deliverables[0].ReviewedBy != approver.User.ID {
+		t.Errorf("the deliverable is %s by %s, want the first review kept", p.Deliverables[0].Status, p.Deliverables[0].ReviewedBy)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The most messages read from a channel when counting a week's activity.
+const maxDigestMessages = 1000
+
+// The client who receives a project's weekly digest.
+type digestRecipient struct {
+	Email    string `json:"email"`
+	OptedOut bool   `json:"optedOut,omitempty"`
+}
+
+// Set or clear the client email that receives the weekly digest for the channel the command was called from.
+func digestEmail(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	email := ""
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		email = strings.TrimSpace(options[0].StringValue())
+	}
+
+	// Don't quietly re-subscribe a client who opted out.
+	optedOut := false
+	readStore(func(d *storeData) {
+		if r, ok := d.DigestRecipients[i.ChannelID]; ok && r.OptedOut && strings.EqualFold(r.Email, email) {
+			optedOut = true
+		}
+	})
+	if optedOut {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: email + " has opted out of the weekly digest.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	err := updateStore(func(d *storeData) {
+		if email == "" {
+			delete(d.DigestRecipients, i.ChannelID)
+			return
+		}
+		if d.DigestRecipients == nil {
+			d.DigestRecipients = make(map[string]*digestRecipient)
+		}
+		d.DigestRecipients[i.ChannelID] = &digestRecipient{Email: email}
+	})
+	if err != nil {
+		log.Printf("Error saving digest email: %v", err)
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Error saving digest email: " + describeError(err),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	content := "This channel will no longer send a weekly digest."
+	if email != "" {
+		content = "The weekly digest for this channel will be sent to " + email + "."
+	}
+	log.Printf("Set digest email for channel %s to %q.", i.ChannelID, email)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Send the weekly digests every Monday at 9:00 in the bot's local time.
+func startDigests(s *discordgo.Session) {
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), 9, 0, 0, 0, now.Location())
+			next = next.AddDate(0, 0, (int(time.Monday)-int(now.Weekday())+7)%7)
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 7)
+			}
+			time.Sleep(time.Until(next))
+			if !paused("sending digests") {
+				sendDigests(s)
+			}
+		}
+	}()
+}
+
+// Send the weekly digest for every project with a subscribed client. Projects in the trash are skipped.
+func sendDigests(s *discordgo.Session) {
+	recipients := make(map[string]string)
+	readStore(func(d *storeData) {
+		for channelID, r := range d.DigestRecipients {
+			if p, ok := d.Projects[channelID]; ok && p.Trash != nil {
+				continue
+			}
+			if !r.OptedOut {
+				recipients[channelID] = r.Email
+			}
+		}
+	})
+
+	for channelID, email := range recipients {
+		text, err := buildDigest(s, channelID)
+		if err != nil {
+			recordFailure(fmt.Sprintf("building digest for channel %s", channelID), err)
+			continue
+		}
+		_, err = sendEmail(&outgoingEmail{
+			from:    os.Getenv("EMAIL_FROM"),
+			to:      []string{email},
+			subject: "Your weekly project update",
+			text:    text,
+			headers: map[string]string{"List-Unsubscribe": "<" + digestUnsubscribeURL(channelID) + ">"},
+		})
+		if err != nil {
+			recordFailure(fmt.Sprintf("sending digest for channel %s", channelID), err)
+			continue
+		}
+		log.Printf("Sent weekly digest for channel %s to %s.", channelID, email)
+	}
+}
+
+// How far ahead a digest lists milestone deadlines.
+const digestDeadlineWindow = 14 * 24 * time.Hour
+
+// Summarize the past week in a project channel: how busy it was, the decisions recorded with /note and the
+// deliverables the client approved. Then list its deadlines and calendar events coming up.
+func buildDigest(s *discordgo.Session, channelID string) (string, error) {
+	channel, err := s.Channel(channelID)
+	if err != nil {
+		return "", err
+	}
+
+	// Count messages from people, and find the notes the bot posted, paging back until the start of the week.
+	weekAgo := time.Now().AddDate(0, 0, -7)
+	count, before := 0, ""
+	var decisions []string
+	for read := 0; read < maxDigestMessages; {
+		messages, err := s.ChannelMessages(channelID, 100, before, "", "")
+		if err != nil {
+			return "", err
+		}
+		done := len(messages) < 100
+		for _, m := range messages {
+			if m.Timestamp.Before(weekAgo) {
+				done = true
+				break
+			}
+			if !m.Author.Bot {
+				count++
+			} else if len(m.Embeds) > 0 && m.Embeds[0].Title == "📝 Note" {
+				decisions = append(decisions, m.Embeds[0].Description)
+			}
+		}
+		read += len(messages)
+		if done || len(messages) == 0 {
+			break
+		}
+		before = messages[len(messages)-1].ID
+	}
+
+	digest := digestMessage{Channel: channel.Name, Messages: count, UnsubscribeURL: digestUnsubscribeURL(channelID)}
+	// Messages come newest first.
+	slices.Reverse(decisions)
+	digest.Decisions = decisions
+	if p, ok := getProject(channelID); ok {
+		for _, d := range p.Deliverables {
+			if d.Status == deliverableApproved && d.ReviewedAt.After(weekAgo) {
+				digest.Completed = append(digest.Completed, d.Title)
+			}
+		}
+		for _, m := range p.Milestones {
+			if !m.Due.IsZero() && m.PaidAt.IsZero() && m.Due.After(weekAgo) && time.Until(m.Due) < digestDeadlineWindow {
+				digest.Deadlines = append(digest.Deadlines, digestEvent{When: m.Due.Format("Mon Jan 2"), Summary: m.Name})
+			}
+		}
+	}
+	if os.Getenv("GOOGLE_CALENDAR_ID") != "" {
+		events, err := upcomingEvents(channel.Name, upcomingEventsLimit)
+		if err != nil {
+			log.Printf("Error reading calendar events for digest: %v", err)
+		}
+		for _, e := range events {
+			// Deadlines synced to the calendar are already listed.
+			if slices.ContainsFunc(digest.Deadlines, func(d digestEvent) bool {
+				return e.Summary == fmt.Sprintf("#%s: %s due", channel.Name, d.Summary)
+			}) {
+				continue
+			}
+			when := e.Start.Date
+			if !e.Start.DateTime.IsZero() {
+				when = e.Start.DateTime.Format("Mon Jan 2, 15:04 MST")
+			}
+			digest.Events = append(digest.Events, digestEvent{When: when, Summary: e.Summary})
+		}
+	}
+	return renderMessage("digest", digest), nil
+}
+
+// The link a client follows to stop receiving a project's digest.
+func digestUnsubscribeURL(channelID string) string {
+	params := url.Values{"channel": {channelID}, "sig": {signLink(channelID)}}
+	return strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/") + "/digest/unsubscribe?" + params.Encode()
+}
+
+// Opt a client out of a project's weekly digest.
+func digestUnsubscribe(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channel")
+	if !validLinkSignature(channelID, r.URL.Query().Get("sig")) {
+		http.Error(w, "This unsubscribe link is invalid.", http.StatusForbidden)
+		return
+	}
+
+	err := updateStore(func(d *storeData) {
+		if r, ok := d.DigestRecipients[channelID]; ok {
+			r.OptedOut = true
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving digest opt-out: %v", err)
+		http.Error(w, "Something went wrong. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Client opted out of the digest for channel %s.", channelID)
+	if _, err := s.ChannelMessageSend(InternalChannelId, fmt.Sprintf("The client for <#%s> opted out of the weekly digest.", channelID)); err != nil {
+		log.Printf("Error reporting digest opt-out: %v", err)
+	}
+	io.WriteString(w, "You will no longer receive the weekly digest for this project.")
+}
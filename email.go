@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// An email conversation posted to a project channel. Each conversation gets its own thread.
+type emailThread struct {
+	ChannelID    string   `json:"channelId"`
+	Subject      string   `json:"subject"`
+	Participants []string `json:"participants"`
+	// Message-Id headers of every email in the conversation, used to match replies to the thread.
+	MessageIDs []string `json:"messageIds"`
+}
+
+// How long after it was signed an inbound email webhook is accepted, so a captured one can't be replayed later.
+const emailWebhookMaxAge = 5 * time.Minute
+
+// A project's inbound address. Mailgun or SES routes everything sent to EMAIL_DOMAIN to the bot, so the local part is
+// a random token rather than anything guessable like the channel ID. Empty until the project has been given one.
+func projectEmailAddress(p project) string {
+	if p.EmailToken == "" {
+		return ""
+	}
+	return p.EmailToken + "@" + os.Getenv("EMAIL_DOMAIN")
+}
+
+// Give a project an inbound address, unless it already has one, and return it.
+func assignProjectEmailAddress(channelID string) (string, error) {
+	token := make([]byte, 12)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	var p project
+	err := updateProject(channelID, func(stored *project) {
+		if stored.EmailToken == "" {
+			stored.EmailToken = hex.EncodeToString(token)
+		}
+		p = copyProject(stored)
+	})
+	if err != nil {
+		return "", err
+	}
+	return projectEmailAddress(p), nil
+}
+
+// The project an inbound address belongs to.
+func emailRecipientProject(address string) (project, bool) {
+	if addr, err := mail.ParseAddress(address); err == nil {
+		address = addr.Address
+	}
+	token, domain, _ := strings.Cut(address, "@")
+	if token == "" || !strings.EqualFold(domain, os.Getenv("EMAIL_DOMAIN")) {
+		return project{}, false
+	}
+	var p project
+	var ok bool
+	readStore(func(d *storeData) {
+		for _, stored := range d.Projects {
+			if stored.EmailToken != "" && hmac.Equal([]byte(stored.EmailToken), []byte(strings.ToLower(token))) {
+				p, ok = copyProject(stored), true
+				return
+			}
+		}
+	})
+	return p, ok
+}
+
+// Show the inbound email address for the project the command was called from, giving it one the first time.
+func emailAddress(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var content string
+	if os.Getenv("EMAIL_DOMAIN") == "" {
+		content = "The email gateway is not configured."
+	} else if _, ok := getProject(i.ChannelID); !ok {
+		content = "This channel is not a registered project."
+	} else if address, err := assignProjectEmailAddress(i.ChannelID); err != nil {
+		log.Printf("Error assigning email address: %v", err)
+		content = "Error assigning email address: " + describeError(err)
+	} else {
+		content = "Emails sent to " + address + " are posted in this channel."
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Handle an email forwarded by a Mailgun inbound route, posting it to the project channel it was addressed to.
+func mailgunWebhook(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxWebhookBodySize); err != nil {
+		log.Printf("Error decoding Mailgun webhook: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Mailgun signs the timestamp, in seconds, and a random token with the webhook signing key.
+	mac := hmac.New(sha256.New, []byte(os.Getenv("MAILGUN_WEBHOOK_SIGNING_KEY")))
+	mac.Write([]byte(r.FormValue("timestamp") + r.FormValue("token")))
+	seconds, err := strconv.ParseInt(r.FormValue("timestamp"), 10, 64)
+	if err != nil || os.Getenv("MAILGUN_WEBHOOK_SIGNING_KEY") == "" ||
+		time.Since(time.Unix(seconds, 0)).Abs() > emailWebhookMaxAge ||
+		!hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(r.FormValue("signature"))) {
+		log.Printf("Rejected webhook on %s: invalid signature", r.URL.Path)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+
+	var files []*discordgo.File
+	if r.MultipartForm != nil {
+		for _, headers := range r.MultipartForm.File {
+			for _, fh := range headers {
+				if f, err := readEmailAttachment(fh); err != nil {
+					log.Printf("Error reading email attachment %q: %v", fh.Filename, err)
+				} else {
+					files = append(files, f)
+				}
+			}
+		}
+	}
+
+	postEmail(s, &inboundEmail{
+		recipient:  r.FormValue("recipient"),
+		from:       r.FormValue("from"),
+		sender:     r.FormValue("sender"),
+		subject:    r.FormValue("subject"),
+		text:       r.FormValue("stripped-text"),
+		messageID:  r.FormValue("Message-Id"),
+		references: strings.Fields(r.FormValue("In-Reply-To") + " " + r.FormValue("References")),
+		files:      files,
+	})
+}
+
+// Read an uploaded attachment into a Discord file.
+func readEmailAttachment(fh *multipart.FileHeader) (*discordgo.File, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return &discordgo.File{Name: fh.Filename, ContentType: fh.Header.Get("Content-Type"), Reader: bytes.NewReader(data)}, nil
+}
+
+// An email received by the gateway.
+type inboundEmail struct {
+	recipient  string
+	from       string
+	sender     string
+	subject    string
+	text       string
+	messageID  string
+	references []string
+	files      []*discordgo.File
+}
+
+// Post an email to its project channel. Replies go into the thread of the conversation they belong to, and new
+// conversations start a new thread.
+func postEmail(s *discordgo.Session, e *inboundEmail) {
+	// Only registered projects get email, and not once they're in the trash. Internal and incident channels aren't
+	// projects, but are checked anyway, since a client's email must never end up in one.
+	p, ok := emailRecipientProject(e.recipient)
+	if !ok || p.Trash != nil || isStaffOnlyChannel(p.ChannelID) {
+		log.Printf("Ignoring email for %q, which isn't a project's address.", e.recipient)
+		return
+	}
+	channel, err := s.Channel(p.ChannelID)
+	if err != nil || channel.GuildID != JuiceworksGuildId {
+		log.Printf("Ignoring email for unknown channel %q: %v", p.ChannelID, err)
+		return
+	}
+
+	// Find the thread of the conversation this email replies to, if any.
+	var threadID string
+	readStore(func(d *storeData) {
+		for id, t := range d.EmailThreads {
+			if t.ChannelID == channel.ID && slices.ContainsFunc(e.references, func(ref string) bool {
+				return slices.Contains(t.MessageIDs, ref)
+			}) {
+				threadID = id
+				break
+			}
+		}
+	})
+
+	msg := &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{themed(JuiceworksGuildId, &discordgo.MessageEmbed{
+			Author:      &discordgo.MessageEmbedAuthor{Name: e.from},
+			Title:       e.subject,
+			Description: truncate(e.text, 4096),
+			Footer:      &discordgo.MessageEmbedFooter{Text: "Received by email"},
+		})},
+		Files: e.files,
+	}
+	msg.Content, msg.AllowedMentions = projectPings(channel.ID)
+
+	if threadID != "" {
+		if _, err := s.ChannelMessageSendComplex(threadID, msg); err != nil {
+			recordFailure("posting email reply", err)
+			return
+		}
+	} else {
+		m, err := s.ChannelMessageSendComplex(channel.ID, msg)
+		if err != nil {
+			recordFailure("posting email", err)
+			return
+		}
+		name := e.subject
+		if name == "" {
+			name = "Email from " + e.sender
+		}
+		thread, err := s.MessageThreadStart(channel.ID, m.ID, truncate(name, 100), 10080)
+		if err != nil {
+			log.Printf("Error starting email thread: %v", err)
+			return
+		}
+		threadID = thread.ID
+	}
+
+	err = updateStore(func(d *storeData) {
+		if d.EmailThreads == nil {
+			d.EmailThreads = make(map[string]*emailThread)
+		}
+		t, ok := d.EmailThreads[threadID]
+		if !ok {
+			t = &emailThread{ChannelID: channel.ID, Subject: e.subject}
+			d.EmailThreads[threadID] = t
+		}
+		if !slices.Contains(t.Participants, e.sender) {
+			t.Participants = append(t.Participants, e.sender)
+		}
+		t.MessageIDs = append(t.MessageIDs, e.messageID)
+	})
+	if err != nil {
+		log.Printf("Error saving email thread: %v", err)
+	}
+	log.Printf("Posted email from %s to channel %s.", e.sender, channel.ID)
+}
+
+// Email replies posted in an email thread back to the conversation's participants.
+func relayToEmail(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Author == nil || m.Author.Bot || m.WebhookID != "" {
+		return
+	}
+	var thread emailThread
+	var address string
+	readStore(func(d *storeData) {
+		if t, ok := d.EmailThreads[m.ChannelID]; ok {
+			thread = *t
+			thread.Participants = slices.Clone(t.Participants)
+			thread.MessageIDs = slices.Clone(t.MessageIDs)
+			if p, ok := d.Projects[t.ChannelID]; ok && p.Trash == nil {
+				address = projectEmailAddress(*p)
+			}
+		}
+	})
+	if thread.ChannelID == "" || address == "" {
+		return
+	}
+
+	subject := thread.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	e := &outgoingEmail{
+		from:    (&mail.Address{Name: authorName(m.Message), Address: address}).String(),
+		to:      thread.Participants,
+		subject: subject,
+		text:    textWithAttachments(m.Message),
+	}
+	if n := len(thread.MessageIDs); n > 0 {
+		e.headers = map[string]string{
+			"In-Reply-To": thread.MessageIDs[n-1],
+			"References":  strings.Join(thread.MessageIDs, " "),
+		}
+	}
+
+	messageID, err := sendEmail(e)
+	if err != nil {
+		recordFailure("sending email reply", err)
+		return
+	}
+	err = updateStore(func(d *storeData) {
+		if t, ok := d.EmailThreads[m.ChannelID]; ok {
+			t.MessageIDs = append(t.MessageIDs, messageID)
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving email thread: %v", err)
+	}
+}
+
+// Whether a channel only staff can see: the internal channel, a project's internal channel, or an incident channel.
+func isStaffOnlyChannel(channelID string) bool {
+	staffOnly := channelID == InternalChannelId
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			staffOnly = staffOnly || p.InternalChannelID == channelID
+		}
+		for _, inc := range d.Incidents {
+			staffOnly = staffOnly || inc.ChannelID == channelID
+		}
+	})
+	return staffOnly
+}
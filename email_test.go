@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/mail"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A mailer keeping what it's asked to send.
+type fakeMailer struct {
+	sent []*outgoingEmail
+}
+
+func (m *fakeMailer) send(e *outgoingEmail) (string, error) {
+	m.sent = append(m.sent, e)
+	return "<sent@example.com>", nil
+}
+
+// Replace the configured mailer with a fake one for the rest of the test.
+func useFakeMailer(t *testing.T) *fakeMailer {
+	m := &fakeMailer{}
+	mailers["fake"] = m
+	t.Cleanup(func() { delete(mailers, "fake") })
+	t.Setenv("EMAIL_PROVIDER", "fake")
+	return m
+}
+
+// Start an email thread in a new project, with the given Message-Ids already in it.
+func addEmailThread(t *testing.T, f *fakeDiscord, messageIDs ...string) string {
+	t.Helper()
+	t.Setenv("EMAIL_DOMAIN", "mail.example.com")
+	channelID := addProject(t, f, "acme")
+	err := updateStore(func(d *storeData) {
+		d.Projects[channelID].EmailToken = "acme"
+		d.EmailThreads = map[string]*emailThread{channelID: {
+			ChannelID:    channelID,
+			Subject:      "Launch",
+			Participants: []string{"client@example.com"},
+			MessageIDs:   messageIDs,
+		}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return channelID
+}
+
+// Names can hold characters that mean something in an address, so they have to be quoted rather than pasted in.
+func TestEmailReplyQuotesAuthorName(t *testing.T) {
+	f, s := newTestBot(t)
+	m := useFakeMailer(t)
+	channelID := addEmailThread(t, f, "<first@example.com>")
+
+	author := f.addMember("2000000000000000001", "jane")
+	author.Nick = `Smith, Jane <"JJ">`
+	relayToEmail(s, &discordgo.MessageCreate{Message: &discordgo.Message{
+		ChannelID: channelID,
+		Content:   "On it.",
+		Author:    author.User,
+		Member:    author,
+	}})
+
+	if len(m.sent) != 1 {
+		t.Fatalf("%d emails were sent, want 1", len(m.sent))
+	}
+	from, err := mail.ParseAddress(m.sent[0].from)
+	if err != nil {
+		t.Fatalf("the From address %q doesn't parse: %v", m.sent[0].from, err)
+	}
+	if from.Name != author.Nick || from.Address != "acme@mail.example.com" {
+		t.Errorf("the email is from %q <%s>, want %q <acme@mail.example.com>", from.Name, from.Address, author.Nick)
+	}
+}
+
+// Message-Ids come from inbound mail, so one carrying a line break mustn't turn into headers of its own.
+func TestSendEmailRejectsHeaderLineBreaks(t *testing.T) {
+	m := useFakeMailer(t)
+	_, err := sendEmail(&outgoingEmail{
+		from:    "acme@mail.example.com",
+		to:      []string{"client@example.com"},
+		subject: "Re: Launch",
+		headers: map[string]string{"In-Reply-To": "<first@example.com>\r\nBcc: someone@example.com"},
+	})
+	if err == nil || len(m.sent) != 0 {
+		t.Errorf("sendEmail = %v after sending %d emails, want an error and none sent", err, len(m.sent))
+	}
+}
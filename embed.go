@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The color of the default theme, and so of embeds that don't pick another: Juiceworks orange.
+const brandColor = 0xf7931e
+
+// Open the form for an embed to post in the channel the command was called from.
+func embedCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	input := func(id, label string, style discordgo.TextInputStyle, required bool, placeholder string, maxLength int) discordgo.MessageComponent {
+		return discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.TextInput{
+				CustomID:    id,
+				Label:       label,
+				Style:       style,
+				Required:    required,
+				Placeholder: placeholder,
+				MaxLength:   maxLength,
+			},
+		}}
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "embed-post",
+			Title:    "Post an embed",
+			Components: []discordgo.MessageComponent{
+				input("title", "Title", discordgo.TextInputShort, true, "", 256),
+				input("description", "Description", discordgo.TextInputParagraph, false, "", 4000),
+				input("color", "Color", discordgo.TextInputShort, false, "#f7931e", 7),
+				input("image", "Image URL", discordgo.TextInputShort, false, "https://", 512),
+				input("fields", "Fields, one per line", discordgo.TextInputParagraph, false, "Name: value", 4000),
+			},
+		},
+	}))
+}
+
+// Build an embed from the /embed form's values in a guild's theme, or explain what's wrong with them.
+func buildEmbed(guildID string, values map[string]string) (*discordgo.MessageEmbed, string) {
+	embed := &discordgo.MessageEmbed{
+		Title:       values["title"],
+		Description: values["description"],
+	}
+	if c := strings.TrimPrefix(strings.TrimSpace(values["color"]), "#"); c != "" {
+		color, err := strconv.ParseUint(c, 16, 32)
+		if err != nil || len(c) != 6 {
+			return nil, "The color has to be a hex code like #f7931e."
+		}
+		embed.Color = int(color)
+	}
+	if link := strings.TrimSpace(values["image"]); link != "" {
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, "The image has to be a web address."
+		}
+		embed.Image = &discordgo.MessageEmbedImage{URL: link}
+	}
+	for _, line := range strings.Split(values["fields"], "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Sprintf("Fields have to look like \"Name: value\", but one is %q.", truncate(line, 100))
+		}
+		if len(embed.Fields) == 25 {
+			return nil, "An embed can have at most 25 fields."
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: truncate(name, 256), Value: truncate(value, 1024)})
+	}
+	return themed(guildID, embed), ""
+}
+
+// Post the embed from the /embed form.
+func postEmbed(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	values := make(map[string]string)
+	for _, row := range i.ModalSubmitData().Components {
+		input := row.(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput)
+		values[input.CustomID] = input.Value
+	}
+
+	embed, content := buildEmbed(i.GuildID, values)
+	if embed != nil {
+		content = "Posted the embed."
+		if _, err := s.ChannelMessageSendEmbed(i.ChannelID, embed); err != nil {
+			log.Printf("Error posting embed: %v", err)
+			content = "Error posting embed: " + describeError(err)
+		} else {
+			log.Printf("%s posted an embed in channel %s.", i.Member.User, i.ChannelID)
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
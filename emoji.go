@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord rejects emoji images of 256 KiB or more.
+const maxEmojiSize = 256 * 1024
+
+// The image types Discord accepts for emoji.
+var emojiContentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// The names Discord accepts for emoji.
+var emojiNamePattern = regexp.MustCompile(`^\w{2,32}$`)
+
+// Manage the guild's custom emoji for staff, so they don't need the Manage Expressions permission themselves.
+func emojiCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	sub := data.Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	var content string
+	switch sub.Name {
+	case "upload":
+		attachment := data.Resolved.Attachments[options["image"].Value.(string)]
+		content = uploadEmoji(s, i.Member.User, options["name"].StringValue(), attachment)
+	case "remove":
+		content = removeEmoji(s, i.Member.User, options["name"].StringValue())
+	case "list":
+		content = listEmoji(s)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Download an attachment so it can be uploaded as an emoji.
+func downloadEmojiImage(a *discordgo.MessageAttachment) ([]byte, error) {
+	if a.Size >= maxEmojiSize {
+		return nil, userErrorf(errCodeEmojiTooLarge, "the image is too large (%d bytes, the limit is %d)", a.Size, maxEmojiSize)
+	}
+	if !slices.Contains(emojiContentTypes, a.ContentType) {
+		return nil, userErrorf(errCodeEmojiFormat, "emoji have to be PNG, JPEG, GIF or WebP images, not %q", a.ContentType)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(a.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discord returned %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxEmojiSize))
+}
+
+// Upload an attachment as a custom emoji, returning the response to show the caller.
+func uploadEmoji(s *discordgo.Session, caller *discordgo.User, name string, a *discordgo.MessageAttachment) string {
+	name = strings.Trim(strings.TrimSpace(name), ":")
+	if !emojiNamePattern.MatchString(name) {
+		return "Emoji names have to be 2 to 32 letters, numbers or underscores."
+	}
+	image, err := downloadEmojiImage(a)
+	if err != nil {
+		log.Printf("Error reading emoji image: %v", err)
+		return "Error reading emoji image: " + describeError(err)
+	}
+
+	emoji, err := s.GuildEmojiCreate(JuiceworksGuildId, &discordgo.EmojiParams{
+		Name:  name,
+		Image: "data:" + a.ContentType + ";base64," + base64.StdEncoding.EncodeToString(image),
+	})
+	if err != nil {
+		log.Printf("Error uploading emoji: %v", err)
+		return "Error uploading emoji: " + describeError(err)
+	}
+
+	log.Printf("%s uploaded emoji %s (%s).", caller, emoji.Name, emoji.ID)
+	postAudit(s, auditEntry{
+		Action:  auditEmojiUploaded,
+		ActorID: caller.ID,
+		Summary: fmt.Sprintf("%s uploaded the emoji %s `:%s:`.", caller.Mention(), emoji.MessageFormat(), emoji.Name),
+	})
+	return fmt.Sprintf("Uploaded %s as `:%s:`.", emoji.MessageFormat(), emoji.Name)
+}
+
+// Find a custom emoji by name. Names are matched without case, the way Discord's emoji picker does.
+func findEmoji(s *discordgo.Session, name string) (*discordgo.Emoji, error) {
+	emojis, err := s.GuildEmojis(JuiceworksGuildId)
+	if err != nil {
+		return nil, err
+	}
+	name = strings.Trim(strings.TrimSpace(name), ":")
+	if m := customEmojiPattern.FindStringSubmatch(name); m != nil {
+		name = m[1]
+	}
+	for _, e := range emojis {
+		if strings.EqualFold(e.Name, name) {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+// Delete a custom emoji, returning the response to show the caller.
+func removeEmoji(s *discordgo.Session, caller *discordgo.User, name string) string {
+	emoji, err := findEmoji(s, name)
+	if err != nil {
+		log.Printf("Error reading emoji: %v", err)
+		return "Error reading emoji: " + describeError(err)
+	}
+	if emoji == nil {
+		return fmt.Sprintf("There's no `:%s:` emoji.", strings.Trim(name, ":"))
+	}
+	if emoji.Managed {
+		return fmt.Sprintf("`:%s:` is managed by an integration and can't be removed here.", emoji.Name)
+	}
+
+	if err := s.GuildEmojiDelete(JuiceworksGuildId, emoji.ID); err != nil {
+		log.Printf("Error removing emoji: %v", err)
+		return "Error removing emoji: " + describeError(err)
+	}
+	log.Printf("%s removed emoji %s (%s).", caller, emoji.Name, emoji.ID)
+	postAudit(s, auditEntry{
+		Action:  auditEmojiRemoved,
+		ActorID: caller.ID,
+		Summary: fmt.Sprintf("%s removed the emoji `:%s:`.", caller.Mention(), emoji.Name),
+	})
+	return fmt.Sprintf("Removed `:%s:`.", emoji.Name)
+}
+
+// List the guild's custom emoji.
+func listEmoji(s *discordgo.Session) string {
+	emojis, err := s.GuildEmojis(JuiceworksGuildId)
+	if err != nil {
+		log.Printf("Error reading emoji: %v", err)
+		return "Error reading emoji: " + describeError(err)
+	}
+	if len(emojis) == 0 {
+		return "There are no custom emoji."
+	}
+	lines := make([]string, len(emojis))
+	for n, e := range emojis {
+		lines[n] = fmt.Sprintf("%s `:%s:`", e.MessageFormat(), e.Name)
+	}
+	slices.Sort(lines)
+	return truncate(strings.Join(lines, "\n"), 2000)
+}
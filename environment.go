@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The environments the bot can run in, set with ENVIRONMENT.
+const (
+	envDev     = "dev"
+	envStaging = "staging"
+	envProd    = "prod"
+)
+
+// The guild, channel and roles the bot acts on. They're the production guild's unless the environment sets its own
+// with JUICEWORKS_GUILD_ID, INTERNAL_CHANNEL_ID, JUICEWORKS_ROLE_ID, PROJECT_CREATOR_ROLE_ID and SERVICES_ROLE_ID,
+// which it has to outside production, so dev and staging never act on the production guild.
+var (
+	JuiceworksGuildId    = productionGuildId
+	InternalChannelId    = "1256628365771669556"
+	JuiceworksRoleId     = "1257752490372370503"
+	ProjectCreatorRoleId = "1259262543034060830"
+	ServicesRoleId       = "1260738526425780264"
+)
+
+// The production Juiceworks guild.
+const productionGuildId = "1256628364987600977"
+
+// The variable each guild, channel and role ID is set with.
+var guildConfig = []struct {
+	env string
+	id  *string
+}{
+	{"JUICEWORKS_GUILD_ID", &JuiceworksGuildId},
+	{"INTERNAL_CHANNEL_ID", &InternalChannelId},
+	{"JUICEWORKS_ROLE_ID", &JuiceworksRoleId},
+	{"PROJECT_CREATOR_ROLE_ID", &ProjectCreatorRoleId},
+	{"SERVICES_ROLE_ID", &ServicesRoleId},
+}
+
+// The environment the bot is running in.
+func environment() string {
+	return os.Getenv("ENVIRONMENT")
+}
+
+// The guilds commands are registered in, from COMMAND_GUILD_IDS, a comma-separated list. Production defaults to the
+// Juiceworks guild.
+func commandGuilds() []string {
+	var guilds []string
+	for _, id := range strings.Split(os.Getenv("COMMAND_GUILD_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			guilds = append(guilds, id)
+		}
+	}
+	if len(guilds) == 0 && environment() == envProd {
+		guilds = []string{JuiceworksGuildId}
+	}
+	return guilds
+}
+
+// Make sure the environment is set, load its guild, channel and role IDs, and check its commands go where they're meant
+// to. Commands can only be registered in the guild the bot acts on. Outside production, the IDs and the guilds commands
+// are registered in have to be set, and acting on the production guild has to be allowed with
+// ALLOW_PRODUCTION_GUILD=true.
+func checkEnvironment() error {
+	env := environment()
+	switch {
+	case env == "":
+		return errors.New("ENVIRONMENT must be set to dev, staging or prod")
+	case env != envDev && env != envStaging && env != envProd:
+		return fmt.Errorf("unknown ENVIRONMENT %q, expected dev, staging or prod", env)
+	}
+
+	var missing []string
+	for _, c := range guildConfig {
+		if v := os.Getenv(c.env); v != "" {
+			*c.id = v
+		} else if env != envProd {
+			missing = append(missing, c.env)
+		}
+	}
+	productionAllowed := os.Getenv("ALLOW_PRODUCTION_GUILD") == "true"
+	elsewhere := slices.ContainsFunc(commandGuilds(), func(id string) bool { return id != JuiceworksGuildId })
+	switch {
+	case elsewhere:
+		// Every command acts on the guild the bot manages, so commands anywhere else would change that guild instead.
+		return fmt.Errorf("COMMAND_GUILD_IDS can only list the guild the bot acts on, %s", JuiceworksGuildId)
+	case env == envProd:
+		return nil
+	case len(missing) > 0:
+		return fmt.Errorf("%s must be set in %s", strings.Join(missing, ", "), env)
+	case JuiceworksGuildId == productionGuildId && !productionAllowed:
+		return fmt.Errorf("refusing to run %s in the production guild; set ALLOW_PRODUCTION_GUILD=true if that's intended", env)
+	case len(commandGuilds()) == 0:
+		return fmt.Errorf("COMMAND_GUILD_IDS must be set in %s", env)
+	case slices.Contains(commandGuilds(), productionGuildId) && !productionAllowed:
+		return fmt.Errorf("refusing to register %s commands in the production guild; set ALLOW_PRODUCTION_GUILD=true if that's intended", env)
+	}
+	return nil
+}
+
+// The prefix on command names outside production, like dev- for /dev-make-channel, so test commands can't be
+// mistaken for the real ones.
+func commandPrefix() string {
+	if environment() == envProd {
+		return ""
+	}
+	return environment() + "-"
+}
+
+// A copy of a command named for this environment.
+func environmentCommand(c *discordgo.ApplicationCommand) *discordgo.ApplicationCommand {
+	e := *c
+	e.Name = commandPrefix() + c.Name
+	// The command goes to the guild it's registered in, not the production guild it was declared with.
+	e.GuildID = ""
+	return &e
+}
+
+// The name a command is known by in the handler maps, given the name it was registered under.
+func baseCommandName(name string) string {
+	return strings.TrimPrefix(name, commandPrefix())
+}
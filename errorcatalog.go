@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Error codes shown to people when something fails, with what each means in words they'll understand. The codes are
+// stable, so someone reporting one can be matched to the full error in the logs.
+const (
+	errCodeMissingPermissions = "DISCORD_MISSING_PERMISSIONS"
+	errCodeMissingAccess      = "DISCORD_MISSING_ACCESS"
+	errCodeUnknownChannel     = "DISCORD_UNKNOWN_CHANNEL"
+	errCodeUnknownMember      = "DISCORD_UNKNOWN_MEMBER"
+	errCodeUnknownMessage     = "DISCORD_UNKNOWN_MESSAGE"
+	errCodeUnknownRole        = "DISCORD_UNKNOWN_ROLE"
+	errCodeUnknownUser        = "DISCORD_UNKNOWN_USER"
+	errCodeDMsClosed          = "DISCORD_DMS_CLOSED"
+	errCodeLimitReached       = "DISCORD_LIMIT_REACHED"
+	errCodeInvalidRequest     = "DISCORD_INVALID_REQUEST"
+	errCodeRateLimited        = "DISCORD_RATE_LIMITED"
+	errCodeDiscordUnavailable = "DISCORD_UNAVAILABLE"
+	errCodeDiscord            = "DISCORD_ERROR"
+	errCodeTimeout            = "SERVICE_TIMEOUT"
+	errCodeUnreachable        = "SERVICE_UNREACHABLE"
+	errCodeStorage            = "STORAGE_ERROR"
+	errCodeNotConfigured      = "NOT_CONFIGURED"
+	errCodeEmojiTooLarge      = "EMOJI_TOO_LARGE"
+	errCodeEmojiFormat        = "EMOJI_FORMAT"
+	errCodeUnknownClient      = "UNKNOWN_CLIENT"
+	errCodeJobTargetGone      = "JOB_TARGET_GONE"
+	errCodeInternal           = "INTERNAL_ERROR"
+)
+
+// What each error code means, for people using the bot.
+var errorCatalog = map[string]string{
+	errCodeMissingPermissions: "the bot isn't allowed to do that, so its role may need more permissions or to be moved higher",
+	errCodeMissingAccess:      "the bot can't see that channel",
+	errCodeUnknownChannel:     "that channel doesn't exist any more",
+	errCodeUnknownMember:      "they aren't in the server",
+	errCodeUnknownMessage:     "that message doesn't exist any more",
+	errCodeUnknownRole:        "that role doesn't exist any more",
+	errCodeUnknownUser:        "there's no such Discord user",
+	errCodeDMsClosed:          "they don't accept direct messages from the bot",
+	errCodeLimitReached:       "the server has reached one of Discord's limits, like the most channels, roles or pins it can have",
+	errCodeInvalidRequest:     "Discord didn't accept it, usually because something is too long or in the wrong format",
+	errCodeRateLimited:        "Discord is asking the bot to slow down, so try again in a minute",
+	errCodeDiscordUnavailable: "Discord is having trouble right now, so try again in a few minutes",
+	errCodeDiscord:            "Discord refused the request",
+	errCodeTimeout:            "a service the bot relies on took too long to answer, so try again in a minute",
+	errCodeUnreachable:        "the bot couldn't reach a service it relies on, so try again in a minute",
+	errCodeStorage:            "the bot couldn't save or read its data",
+	errCodeNotConfigured:      "this isn't set up yet, so ask an admin",
+	errCodeInternal:           "something went wrong on our side",
+}
+
+// An error with a code and a message that's safe to show people, for failures the bot detects itself. The detail,
+// if any, is what's logged.
+type codedError struct {
+	code    string
+	message string
+	detail  string
+}
+
+func (e *codedError) Error() string {
+	if e.detail != "" {
+		return e.detail
+	}
+	return e.message
+}
+
+// An error for something people did wrong, like uploading a file that's too big, whose message they should see.
+func userErrorf(code, format string, args ...any) error {
+	return &codedError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// An error for a feature that needs configuration the bot doesn't have. People are told it isn't set up, and the
+// detail of what's missing only goes to the logs.
+func notConfigured(detail string) error {
+	return &codedError{code: errCodeNotConfigured, message: errorCatalog[errCodeNotConfigured], detail: detail}
+}
+
+// The code for an error.
+func errorCode(err error) string {
+	var coded *codedError
+	var restErr *discordgo.RESTError
+	var netErr net.Error
+	var pathErr *fs.PathError
+	switch {
+	case errors.As(err, &coded):
+		return coded.code
+	case errors.As(err, &restErr):
+		return discordErrorCode(restErr)
+	// Checked before network errors, since the errno it wraps looks like one.
+	case errors.As(err, &pathErr):
+		return errCodeStorage
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return errCodeTimeout
+	case errors.As(err, &netErr):
+		return errCodeUnreachable
+	}
+	return errCodeInternal
+}
+
+// The code for an error response from the Discord API.
+func discordErrorCode(err *discordgo.RESTError) string {
+	if err.Message != nil {
+		switch err.Message.Code {
+		case discordgo.ErrCodeMissingPermissions:
+			return errCodeMissingPermissions
+		case discordgo.ErrCodeMissingAccess:
+			return errCodeMissingAccess
+		case discordgo.ErrCodeUnknownChannel:
+			return errCodeUnknownChannel
+		case discordgo.ErrCodeUnknownMember:
+			return errCodeUnknownMember
+		case discordgo.ErrCodeUnknownMessage:
+			return errCodeUnknownMessage
+		case discordgo.ErrCodeUnknownRole:
+			return errCodeUnknownRole
+		case discordgo.ErrCodeUnknownUser:
+			return errCodeUnknownUser
+		case discordgo.ErrCodeCannotSendMessagesToThisUser:
+			return errCodeDMsClosed
+		case discordgo.ErrCodeMaximumPinsReached, discordgo.ErrCodeMaximumGuildRolesReached,
+			discordgo.ErrCodeMaximumNumberOfEmojisReached, discordgo.ErrCodeMaximumNumberOfGuildChannelsReached:
+			return errCodeLimitReached
+		case discordgo.ErrCodeInvalidFormBody:
+			return errCodeInvalidRequest
+		}
+	}
+	switch {
+	case err.Response == nil:
+		return errCodeDiscord
+	case err.Response.StatusCode == http.StatusTooManyRequests:
+		return errCodeRateLimited
+	case err.Response.StatusCode >= 500:
+		return errCodeDiscordUnavailable
+	}
+	return errCodeDiscord
+}
+
+// Describe an error for the people using the bot: what went wrong in words they'll understand, and its code. Raw
+// errors can include IDs and JSON from Discord or other services, so they only go to the logs.
+func describeError(err error) string {
+	code := errorCode(err)
+	message := errorCatalog[code]
+	var coded *codedError
+	if errors.As(err, &coded) {
+		message = coded.message
+	}
+	return fmt.Sprintf("%s (error code `%s`)", message, code)
+}
@@ -0,0 +1,795 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+// The REST API path prefix discordgo uses.
+var fakeAPIPrefix = strings.TrimPrefix(discordgo.EndpointAPI, strings.TrimSuffix(discordgo.EndpointDiscord, "/"))
+
+// The fake Discord's bot user, which is also the application interactions are for.
+const fakeBotID = "1000000000000000001"
+
+// A request the fake Discord received.
+type fakeRequest struct {
+	Method string
+	Path   string
+	Body   json.RawMessage
+}
+
+// What the bot showed for an interaction: its response, or a later edit of the response or a follow-up message, which
+// have no type.
+type fakeResponse struct {
+	Kind string                             `json:"kind"`
+	Type discordgo.InteractionResponseType  `json:"type,omitempty"`
+	Data *discordgo.InteractionResponseData `json:"data,omitempty"`
+}
+
+// An error the fake Discord returns for the next request to a path.
+type fakeFailure struct {
+	method, path string
+	status, code int
+}
+
+// A stand-in for Discord, covering the REST endpoints the bot uses to manage channels and members and answer
+// interactions, and a gateway that can deliver events. It keeps a single guild's channels, permission overwrites and
+// members in memory, records every request, and can be told to fail requests, so commands can be run end to end
+// without touching Discord.
+type fakeDiscord struct {
+	server  *httptest.Server
+	guildID string
+	botUser *discordgo.User
+
+	mu        sync.Mutex
+	nextID    int64
+	channels  map[string]*discordgo.Channel
+	members   map[string]*discordgo.Member
+	roles     []*discordgo.Role
+	messages  map[string][]*discordgo.Message
+	requests  []fakeRequest
+	responses []fakeResponse
+	failures  []fakeFailure
+
+	gatewayMu sync.Mutex
+	gateway   *websocket.Conn
+	sequence  int
+}
+
+// Start a fake Discord with an empty guild. Close it when done.
+func newFakeDiscord(guildID string) *fakeDiscord {
+	f := &fakeDiscord{
+		guildID:  guildID,
+		botUser:  &discordgo.User{ID: fakeBotID, Username: "juiceworks-bot", Bot: true},
+		nextID:   1000000000000001000,
+		channels: make(map[string]*discordgo.Channel),
+		members:  make(map[string]*discordgo.Member),
+		messages: make(map[string][]*discordgo.Message),
+		// The @everyone role has the guild's ID, and lets people read and write where they're not kept out.
+		roles: []*discordgo.Role{{ID: guildID, Name: "@everyone", Permissions: discordgo.PermissionViewChannel |
+			discordgo.PermissionSendMessages | discordgo.PermissionReadMessageHistory}},
+	}
+
+	mux := http.NewServeMux()
+	api := func(pattern string, h http.HandlerFunc) {
+		method, path, _ := strings.Cut(pattern, " ")
+		mux.HandleFunc(method+" "+fakeAPIPrefix+path, h)
+	}
+	api("GET gateway", f.getGateway)
+	api("GET gateway/bot", f.getGateway)
+	api("GET users/@me", func(w http.ResponseWriter, r *http.Request) { f.reply(w, http.StatusOK, f.botUser) })
+	api("GET guilds/{guild}", f.getGuild)
+	api("GET guilds/{guild}/roles", func(w http.ResponseWriter, r *http.Request) { f.reply(w, http.StatusOK, f.guildRoles()) })
+	api("GET guilds/{guild}/members", f.listMembers)
+	api("GET guilds/{guild}/members/{user}", f.getMember)
+	api("PATCH guilds/{guild}/members/{user}", f.patchMember)
+	api("PUT guilds/{guild}/members/{user}/roles/{role}", f.putMemberRole)
+	api("DELETE guilds/{guild}/members/{user}/roles/{role}", f.deleteMemberRole)
+	api("GET guilds/{guild}/channels", f.listChannels)
+	api("POST guilds/{guild}/channels", f.createChannel)
+	api("GET channels/{channel}", f.getChannel)
+	api("PATCH channels/{channel}", f.patchChannel)
+	api("DELETE channels/{channel}", f.deleteChannel)
+	api("PUT channels/{channel}/permissions/{target}", f.putPermission)
+	api("DELETE channels/{channel}/permissions/{target}", f.deletePermission)
+	api("GET channels/{channel}/messages", f.listMessages)
+	api("POST channels/{channel}/messages", f.createMessage)
+	api("PUT channels/{channel}/pins/{message}", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	api("POST interactions/{id}/{token}/callback", f.interactionCallback)
+	api("PATCH webhooks/{app}/{token}/messages/{message}", f.webhookMessage("edit"))
+	api("POST webhooks/{app}/{token}", f.webhookMessage("followup"))
+	mux.HandleFunc("GET /ws/", f.serveGateway)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		f.error(w, http.StatusNotFound, 0, r.Method+" "+r.URL.Path+" isn't emulated")
+	})
+
+	f.server = httptest.NewServer(f.record(mux))
+	return f
+}
+
+// Start a fake Discord with a session talking to it, and an empty store saved to a temporary data file, for running
+// commands end to end. Everything is closed when the test ends.
+func newTestBot(t testing.TB) (*fakeDiscord, *discordgo.Session) {
+	t.Setenv("DATA_FILE", filepath.Join(t.TempDir(), "data.json"))
+	// Nothing reaches Discord, so run as production, where commands have the names people see.
+	t.Setenv("ENVIRONMENT", envProd)
+	t.Setenv("COMMAND_GUILD_IDS", JuiceworksGuildId)
+	storeMu.Lock()
+	store = storeData{}
+	storeMu.Unlock()
+	commandUsesMu.Lock()
+	clear(commandUses)
+	commandUsesMu.Unlock()
+	recentBookingsMu.Lock()
+	clear(recentBookings)
+	recentBookingsMu.Unlock()
+
+	f := newFakeDiscord(JuiceworksGuildId)
+	t.Cleanup(f.Close)
+	s, err := f.session()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.State.User = f.botUser
+	return f, s
+}
+
+// A slash command interaction from a member in a channel.
+func commandInteraction(channelID string, member *discordgo.Member, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:        "1000000000000000002",
+		AppID:     fakeBotID,
+		Type:      discordgo.InteractionApplicationCommand,
+		Token:     "token",
+		GuildID:   JuiceworksGuildId,
+		ChannelID: channelID,
+		Member:    member,
+		Data:      discordgo.ApplicationCommandInteractionData{Name: name, Options: options},
+	}}
+}
+
+// A button press from a member on a message in a channel.
+func componentInteraction(channelID string, member *discordgo.Member, customID string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:        "1000000000000000002",
+		AppID:     fakeBotID,
+		Type:      discordgo.InteractionMessageComponent,
+		Token:     "token",
+		GuildID:   JuiceworksGuildId,
+		ChannelID: channelID,
+		Member:    member,
+		Message:   &discordgo.Message{ID: "1000000000000000003", ChannelID: channelID},
+		Data:      discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent},
+	}}
+}
+
+// Send an interaction from the member's DMs with the bot instead, where there's a user but no guild or member.
+func inDM(i *discordgo.InteractionCreate) *discordgo.InteractionCreate {
+	i.GuildID, i.User, i.Member = "", i.Member.User, nil
+	return i
+}
+
+// The content of the last interaction response the fake Discord received.
+func (f *fakeDiscord) lastResponse(t testing.TB) string {
+	t.Helper()
+	_, responses := f.history()
+	if len(responses) == 0 {
+		t.Fatal("the interaction wasn't answered")
+	}
+	last := responses[len(responses)-1]
+	if last.Data == nil {
+		return ""
+	}
+	return last.Data.Content
+}
+
+// Stop the fake Discord.
+func (f *fakeDiscord) Close() {
+	f.gatewayMu.Lock()
+	if f.gateway != nil {
+		f.gateway.Close()
+	}
+	f.gatewayMu.Unlock()
+	f.server.Close()
+}
+
+// Create a session that talks to the fake Discord instead of the real one.
+func (f *fakeDiscord) session() (*discordgo.Session, error) {
+	s, err := discordgo.New("Bot fake-token")
+	if err != nil {
+		return nil, err
+	}
+	s.Client = &http.Client{Transport: fakeTransport{target: f.server.URL}}
+	return s, nil
+}
+
+// Sends requests meant for Discord to the fake instead.
+type fakeTransport struct {
+	target string
+}
+
+func (t fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = strings.TrimPrefix(t.target, "http://")
+	req.Host = req.URL.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// Add a channel to the guild, returning its ID.
+func (f *fakeDiscord) addChannel(name, parentID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := &discordgo.Channel{ID: f.newID(), GuildID: f.guildID, Name: name, Type: discordgo.ChannelTypeGuildText, ParentID: parentID}
+	f.channels[c.ID] = c
+	return c.ID
+}
+
+// Add a member to the guild.
+func (f *fakeDiscord) addMember(userID, username string, roles ...string) *discordgo.Member {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := &discordgo.Member{GuildID: f.guildID, User: &discordgo.User{ID: userID, Username: username}, Roles: roles}
+	f.members[userID] = m
+	return m
+}
+
+// Add a role to the guild with guild-wide permissions, returning its ID.
+func (f *fakeDiscord) addRole(name string, permissions int64) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := &discordgo.Role{ID: f.newID(), Name: name, Permissions: permissions}
+	f.roles = append(f.roles, r)
+	return r.ID
+}
+
+// A copy of the guild's roles.
+func (f *fakeDiscord) guildRoles() []discordgo.Role {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	roles := make([]discordgo.Role, len(f.roles))
+	for n, r := range f.roles {
+		roles[n] = *r
+	}
+	return roles
+}
+
+// Make the next request with a method to a path under the API, like channels/123/permissions/456, fail with an
+// HTTP status and Discord error code.
+func (f *fakeDiscord) fail(method, path string, status, code int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, fakeFailure{method: method, path: fakeAPIPrefix + path, status: status, code: code})
+}
+
+// A copy of a channel, with its permission overwrites.
+func (f *fakeDiscord) channel(id string) (discordgo.Channel, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.channels[id]
+	if !ok {
+		return discordgo.Channel{}, false
+	}
+	copied := *c
+	copied.PermissionOverwrites = append([]*discordgo.PermissionOverwrite(nil), c.PermissionOverwrites...)
+	return copied, true
+}
+
+// A copy of a member.
+func (f *fakeDiscord) member(userID string) (discordgo.Member, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, ok := f.members[userID]
+	if !ok {
+		return discordgo.Member{}, false
+	}
+	copied := *m
+	copied.Roles = append([]string(nil), m.Roles...)
+	return copied, true
+}
+
+// The contents of the messages posted in a channel so far, oldest first.
+func (f *fakeDiscord) posted(channelID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var contents []string
+	for _, m := range f.messages[channelID] {
+		contents = append(contents, m.Content)
+	}
+	return contents
+}
+
+// The requests received so far, and what was shown for interactions.
+func (f *fakeDiscord) history() ([]fakeRequest, []fakeResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeRequest(nil), f.requests...), append([]fakeResponse(nil), f.responses...)
+}
+
+// What was shown for interactions so far as indented JSON, in the order it was sent, including edits of deferred
+// responses and follow-ups, for comparing what commands show people against a saved copy.
+func (f *fakeDiscord) capturedResponses() ([]byte, error) {
+	_, responses := f.history()
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(responses)
+	return b.Bytes(), err
+}
+
+// Deliver a gateway event to the connected session, like INTERACTION_CREATE.
+func (f *fakeDiscord) dispatch(event string, data any) error {
+	f.gatewayMu.Lock()
+	defer f.gatewayMu.Unlock()
+	if f.gateway == nil {
+		return fmt.Errorf("no session is connected to the fake gateway")
+	}
+	f.sequence++
+	return f.gateway.WriteJSON(map[string]any{"op": 0, "t": event, "s": f.sequence, "d": data})
+}
+
+// Deliver an interaction from a member, as if they used a command or component.
+func (f *fakeDiscord) interact(i *discordgo.Interaction) error {
+	if i.ID == "" {
+		f.mu.Lock()
+		i.ID = f.newID()
+		f.mu.Unlock()
+	}
+	if i.Token == "" {
+		i.Token = "token-" + i.ID
+	}
+	i.AppID, i.GuildID = f.botUser.ID, f.guildID
+	return f.dispatch("INTERACTION_CREATE", i)
+}
+
+// Record requests and apply injected failures before they're handled.
+func (f *fakeDiscord) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		if !json.Valid(body) {
+			body = nil
+		}
+
+		f.mu.Lock()
+		f.requests = append(f.requests, fakeRequest{Method: r.Method, Path: r.URL.Path, Body: body})
+		var failure *fakeFailure
+		for n, fl := range f.failures {
+			if fl.method == r.Method && strings.HasPrefix(r.URL.Path, fl.path) {
+				failure = &fl
+				f.failures = append(f.failures[:n], f.failures[n+1:]...)
+				break
+			}
+		}
+		f.mu.Unlock()
+
+		if failure != nil {
+			f.error(w, failure.status, failure.code, "injected failure")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Make a snowflake-like ID. Callers hold mu.
+func (f *fakeDiscord) newID() string {
+	f.nextID++
+	return strconv.FormatInt(f.nextID, 10)
+}
+
+func (f *fakeDiscord) reply(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Reply with an error shaped like Discord's.
+func (f *fakeDiscord) error(w http.ResponseWriter, status, code int, message string) {
+	f.reply(w, status, map[string]any{"message": message, "code": code})
+}
+
+// Decode a request's JSON body, or the payload_json part of a multipart body with files.
+func decodeFakeBody(r *http.Request, v any) error {
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return json.NewDecoder(r.Body).Decode(v)
+	}
+	reader := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return err
+		}
+		if part.FormName() == "payload_json" {
+			return json.NewDecoder(part).Decode(v)
+		}
+	}
+}
+
+func (f *fakeDiscord) getGateway(w http.ResponseWriter, r *http.Request) {
+	f.reply(w, http.StatusOK, map[string]any{"url": "ws" + strings.TrimPrefix(f.server.URL, "http") + "/ws/", "shards": 1})
+}
+
+func (f *fakeDiscord) getGuild(w http.ResponseWriter, r *http.Request) {
+	roles := f.guildRoles()
+	guild := &discordgo.Guild{ID: f.guildID, Name: "Juiceworks"}
+	for n := range roles {
+		guild.Roles = append(guild.Roles, &roles[n])
+	}
+	f.reply(w, http.StatusOK, guild)
+}
+
+// List the guild's members like Discord does: by user ID, a page at a time, after a user if asked.
+func (f *fakeDiscord) listMembers(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 1
+	}
+	after := r.URL.Query().Get("after")
+	f.mu.Lock()
+	ids := make([]string, 0, len(f.members))
+	for id := range f.members {
+		if after == "" || id > after {
+			ids = append(ids, id)
+		}
+	}
+	f.mu.Unlock()
+	slices.Sort(ids)
+	members := []discordgo.Member{}
+	for _, id := range ids[:min(limit, len(ids))] {
+		if m, ok := f.member(id); ok {
+			members = append(members, m)
+		}
+	}
+	f.reply(w, http.StatusOK, members)
+}
+
+func (f *fakeDiscord) getMember(w http.ResponseWriter, r *http.Request) {
+	m, ok := f.member(r.PathValue("user"))
+	if !ok {
+		f.error(w, http.StatusNotFound, discordgo.ErrCodeUnknownMember, "Unknown Member")
+		return
+	}
+	f.reply(w, http.StatusOK, m)
+}
+
+func (f *fakeDiscord) patchMember(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Nick *string `json:"nick"`
+	}
+	decodeFakeBody(r, &data)
+	f.mu.Lock()
+	m, ok := f.members[r.PathValue("user")]
+	if ok && data.Nick != nil {
+		m.Nick = *data.Nick
+	}
+	f.mu.Unlock()
+	if !ok {
+		f.error(w, http.StatusNotFound, discordgo.ErrCodeUnknownMember, "Unknown Member")
+		return
+	}
+	m2, _ := f.member(r.PathValue("user"))
+	f.reply(w, http.StatusOK, m2)
+}
+
+func (f *fakeDiscord) putMemberRole(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	m, ok := f.members[r.PathValue("user")]
+	if ok && !strings.Contains(","+strings.Join(m.Roles, ",")+",", ","+r.PathValue("role")+",") {
+		m.Roles = append(m.Roles, r.PathValue("role"))
+	}
+	f.mu.Unlock()
+	if !ok {
+		f.error(w, http.StatusNotFound, discordgo.ErrCodeUnknownMember, "Unknown Member")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeDiscord) deleteMemberRole(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	if m, ok := f.members[r.PathValue("user")]; ok {
+		roles := m.Roles[:0]
+		for _, role := range m.Roles {
+			if role != r.PathValue("role") {
+				roles = append(roles, role)
+			}
+		}
+		m.Roles = roles
+	}
+	f.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeDiscord) createChannel(w http.ResponseWriter, r *http.Request) {
+	var data discordgo.GuildChannelCreateData
+	if err := decodeFakeBody(r, &data); err != nil {
+		f.error(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+		return
+	}
+	f.mu.Lock()
+	c := &discordgo.Channel{
+		ID:                   f.newID(),
+		GuildID:              r.PathValue("guild"),
+		Name:                 data.Name,
+		Type:                 data.Type,
+		Topic:                data.Topic,
+		ParentID:             data.ParentID,
+		PermissionOverwrites: data.PermissionOverwrites,
+	}
+	f.channels[c.ID] = c
+	f.mu.Unlock()
+	created, _ := f.channel(c.ID)
+	f.reply(w, http.StatusCreated, created)
+}
+
+func (f *fakeDiscord) listChannels(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	ids := make([]string, 0, len(f.channels))
+	for id := range f.channels {
+		ids = append(ids, id)
+	}
+	f.mu.Unlock()
+	slices.Sort(ids)
+	channels := make([]discordgo.Channel, 0, len(ids))
+	for _, id := range ids {
+		if c, ok := f.channel(id); ok {
+			channels = append(channels, c)
+		}
+	}
+	f.reply(w, http.StatusOK, channels)
+}
+
+func (f *fakeDiscord) getChannel(w http.ResponseWriter, r *http.Request) {
+	c, ok := f.channel(r.PathValue("channel"))
+	if !ok {
+		f.error(w, http.StatusNotFound, discordgo.ErrCodeUnknownChannel, "Unknown Channel")
+		return
+	}
+	f.reply(w, http.StatusOK, c)
+}
+
+func (f *fakeDiscord) patchChannel(w http.ResponseWriter, r *http.Request) {
+	var data discordgo.ChannelEdit
+	decodeFakeBody(r, &data)
+	f.mu.Lock()
+	c, ok := f.channels[r.PathValue("channel")]
+	if ok {
+		if data.Name != "" {
+			c.Name = data.Name
+		}
+		if data.Topic != "" {
+			c.Topic = data.Topic
+		}
+		if data.ParentID != "" {
+			c.ParentID = data.ParentID
+		}
+	}
+	f.mu.Unlock()
+	f.getChannel(w, r)
+}
+
+func (f *fakeDiscord) deleteChannel(w http.ResponseWriter, r *http.Request) {
+	c, ok := f.channel(r.PathValue("channel"))
+	f.mu.Lock()
+	delete(f.channels, r.PathValue("channel"))
+	f.mu.Unlock()
+	if !ok {
+		f.error(w, http.StatusNotFound, discordgo.ErrCodeUnknownChannel, "Unknown Channel")
+		return
+	}
+	f.reply(w, http.StatusOK, c)
+}
+
+func (f *fakeDiscord) putPermission(w http.ResponseWriter, r *http.Request) {
+	var data discordgo.PermissionOverwrite
+	decodeFakeBody(r, &data)
+	data.ID = r.PathValue("target")
+	f.mu.Lock()
+	c, ok := f.channels[r.PathValue("channel")]
+	if ok {
+		replaced := false
+		for n, o := range c.PermissionOverwrites {
+			if o.ID == data.ID {
+				c.PermissionOverwrites[n] = &data
+				replaced = true
+			}
+		}
+		if !replaced {
+			c.PermissionOverwrites = append(c.PermissionOverwrites, &data)
+		}
+	}
+	f.mu.Unlock()
+	if !ok {
+		f.error(w, http.StatusNotFound, discordgo.ErrCodeUnknownChannel, "Unknown Channel")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeDiscord) deletePermission(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	if c, ok := f.channels[r.PathValue("channel")]; ok {
+		var kept []*discordgo.PermissionOverwrite
+		for _, o := range c.PermissionOverwrites {
+			if o.ID != r.PathValue("target") {
+				kept = append(kept, o)
+			}
+		}
+		c.PermissionOverwrites = kept
+	}
+	f.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List a channel's messages like Discord does: newest first, a page at a time, before a message if asked.
+func (f *fakeDiscord) listMessages(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	before := r.URL.Query().Get("before")
+	f.mu.Lock()
+	var page []*discordgo.Message
+	stored := f.messages[r.PathValue("channel")]
+	for n := len(stored) - 1; n >= 0 && len(page) < limit; n-- {
+		if before == "" || stored[n].ID < before {
+			page = append(page, stored[n])
+		}
+	}
+	f.mu.Unlock()
+	if page == nil {
+		page = []*discordgo.Message{}
+	}
+	f.reply(w, http.StatusOK, page)
+}
+
+func (f *fakeDiscord) createMessage(w http.ResponseWriter, r *http.Request) {
+	var data discordgo.MessageSend
+	decodeFakeBody(r, &data)
+	f.mu.Lock()
+	m := &discordgo.Message{ID: f.newID(), ChannelID: r.PathValue("channel"), Content: data.Content, Embeds: data.Embeds,
+		Author: f.botUser, Timestamp: time.Now()}
+	if m.ChannelID != "" {
+		f.messages[m.ChannelID] = append(f.messages[m.ChannelID], m)
+	}
+	f.mu.Unlock()
+	f.reply(w, http.StatusOK, m)
+}
+
+func (f *fakeDiscord) interactionCallback(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Type discordgo.InteractionResponseType `json:"type"`
+		Data json.RawMessage                   `json:"data"`
+	}
+	if err := decodeFakeBody(r, &body); err != nil {
+		f.error(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+		return
+	}
+	resp := fakeResponse{Kind: "response", Type: body.Type}
+	if body.Data != nil {
+		resp.Data = &discordgo.InteractionResponseData{}
+		if err := decodeWithComponents(body.Data, resp.Data); err != nil {
+			f.error(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+			return
+		}
+	}
+	f.mu.Lock()
+	f.responses = append(f.responses, resp)
+	f.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Handle an edit of an interaction's response, or a follow-up message. Messages sent with the bot's own webhooks are
+// recorded with the interaction's responses; other webhooks just post a message.
+func (f *fakeDiscord) webhookMessage(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("app") != f.botUser.ID {
+			f.createMessage(w, r)
+			return
+		}
+		var raw json.RawMessage
+		data := &discordgo.InteractionResponseData{}
+		if err := decodeFakeBody(r, &raw); err != nil || decodeWithComponents(raw, data) != nil {
+			f.error(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+			return
+		}
+		f.mu.Lock()
+		f.responses = append(f.responses, fakeResponse{Kind: kind, Data: data})
+		m := &discordgo.Message{ID: f.newID(), Content: data.Content, Embeds: data.Embeds, Author: f.botUser, Timestamp: time.Now()}
+		f.mu.Unlock()
+		f.reply(w, http.StatusOK, m)
+	}
+}
+
+// Decode a message or response body into v, along with its components, which discordgo can't decode by itself since
+// they're interfaces.
+func decodeWithComponents(raw json.RawMessage, v *discordgo.InteractionResponseData) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+	var components []json.RawMessage
+	if c, ok := fields["components"]; ok {
+		if err := json.Unmarshal(c, &components); err != nil {
+			return err
+		}
+		delete(fields, "components")
+	}
+	rest, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(rest, v); err != nil {
+		return err
+	}
+	for _, c := range components {
+		component, err := discordgo.MessageComponentFromJSON(c)
+		if err != nil {
+			return err
+		}
+		v.Components = append(v.Components, component)
+	}
+	return nil
+}
+
+// Serve the gateway: say hello, answer an identify with READY and the guild, and acknowledge heartbeats. Events are
+// sent with dispatch.
+func (f *fakeDiscord) serveGateway(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	f.gatewayMu.Lock()
+	f.gateway = conn
+	f.sequence = 0
+	err = conn.WriteJSON(map[string]any{"op": 10, "d": map[string]any{"heartbeat_interval": 45000}})
+	f.gatewayMu.Unlock()
+	if err != nil {
+		return
+	}
+
+	for {
+		var payload struct {
+			Op int `json:"op"`
+		}
+		if err := conn.ReadJSON(&payload); err != nil {
+			return
+		}
+		switch payload.Op {
+		case 1:
+			f.gatewayMu.Lock()
+			conn.WriteJSON(map[string]any{"op": 11})
+			f.gatewayMu.Unlock()
+		case 2:
+			f.mu.Lock()
+			guild := &discordgo.Guild{ID: f.guildID, Name: "Juiceworks"}
+			for _, c := range f.channels {
+				copied := *c
+				guild.Channels = append(guild.Channels, &copied)
+			}
+			f.mu.Unlock()
+			f.dispatch("READY", map[string]any{"v": 9, "user": f.botUser, "session_id": "fake-session", "guilds": []any{guild}})
+			f.dispatch("GUILD_CREATE", guild)
+		case 6:
+			f.dispatch("RESUMED", map[string]any{})
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A Figma file's name and thumbnail.
+type figmaFileInfo struct {
+	Name         string `json:"name"`
+	ThumbnailURL string `json:"thumbnailUrl"`
+}
+
+// Read a Figma file's metadata for a project, with the project's figma secret if it has one and the personal access
+// token in FIGMA_TOKEN if it doesn't.
+func figmaFile(channelID, key string) (_ *figmaFileInfo, err error) {
+	defer func() { noteIntegrationCall("Figma", err) }()
+	req, err := http.NewRequest(http.MethodGet, "https://api.figma.com/v1/files/"+url.PathEscape(key)+"?depth=1", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Figma-Token", integrationToken(channelID, "figma"))
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("figma returned %s", resp.Status)
+	}
+
+	var info figmaFileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Extract the file key from a Figma link such as https://www.figma.com/design/<key>/<name>. Anything that isn't a
+// link is treated as a bare key.
+func figmaFileKey(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || !strings.HasSuffix(u.Hostname(), "figma.com") {
+		return link
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) >= 2 && slices.Contains([]string{"file", "design", "proto", "board"}, parts[0]) {
+		return parts[1]
+	}
+	return link
+}
+
+// Link a Figma file to the project the command was called from.
+func linkFigma(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Verify the command options.
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 || options[0].Type != discordgo.ApplicationCommandOptionString {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "This command requires a Figma file link.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+	key := figmaFileKey(strings.TrimSpace(options[0].StringValue()))
+	if _, ok := getProject(i.ChannelID); !ok {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "This channel is not a registered project.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	// Figma can be slow to answer, so defer the response.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+	content := linkFigmaContent(i.ChannelID, key)
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+	logResponseErr(err)
+}
+
+// Link a Figma file to a project once the bot has checked it can read it, and describe how it went.
+func linkFigmaContent(channelID, key string) string {
+	// Make sure the file exists and the bot can read it before linking it.
+	file, err := figmaFile(channelID, key)
+	if err != nil {
+		log.Printf("Error reading Figma file: %v", err)
+		return "Error reading Figma file: " + describeError(err)
+	}
+
+	// The project may have been deleted while the file was being read.
+	if _, ok := getProject(channelID); !ok {
+		return "This channel is not a registered project."
+	}
+	err = updateProject(channelID, func(p *project) {
+		if !slices.Contains(p.FigmaFiles, key) {
+			p.FigmaFiles = append(p.FigmaFiles, key)
+		}
+	})
+	if err != nil {
+		log.Printf("Error linking Figma file: %v", err)
+		return "Error linking Figma file: " + describeError(err)
+	}
+
+	log.Printf("Linked Figma file %s to channel %s.", key, channelID)
+	return fmt.Sprintf("Linked the Figma file \"%s\" to this channel.", file.Name)
+}
+
+// Handle a Figma webhook, announcing new versions of linked files in their project channels. Figma doesn't sign
+// webhooks; instead it echoes back the passcode the webhook was registered with.
+func figmaWebhook(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	var event struct {
+		EventType   string `json:"event_type"`
+		Passcode    string `json:"passcode"`
+		FileKey     string `json:"file_key"`
+		FileName    string `json:"file_name"`
+		Label       string `json:"label"`
+		Description string `json:"description"`
+		TriggeredBy struct {
+			Handle string `json:"handle"`
+		} `json:"triggered_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		log.Printf("Error decoding Figma webhook: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	passcode := os.Getenv("FIGMA_WEBHOOK_PASSCODE")
+	if passcode == "" || subtle.ConstantTimeCompare([]byte(passcode), []byte(event.Passcode)) != 1 {
+		log.Printf("Rejected webhook on %s: invalid passcode", r.URL.Path)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if event.EventType != "FILE_VERSION_UPDATE" {
+		return
+	}
+
+	var channelIDs []string
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if slices.Contains(p.FigmaFiles, event.FileKey) {
+				channelIDs = append(channelIDs, p.ChannelID)
+			}
+		}
+	})
+
+	title := "New version of " + event.FileName
+	if event.Label != "" {
+		title += ": " + event.Label
+	}
+	embed := themed(JuiceworksGuildId, &discordgo.MessageEmbed{
+		Title:       truncate(title, 256),
+		URL:         "https://www.figma.com/design/" + event.FileKey,
+		Description: event.Description,
+		Footer:      &discordgo.MessageEmbedFooter{Text: "Saved by " + event.TriggeredBy.Handle},
+	})
+	for _, channelID := range channelIDs {
+		pings, allowed := projectPings(channelID)
+		msg := &discordgo.MessageSend{Content: pings, Embeds: []*discordgo.MessageEmbed{embed}, AllowedMentions: allowed}
+		if _, err := s.ChannelMessageSendComplex(channelID, msg); err != nil {
+			log.Printf("Error announcing Figma version: %v", err)
+		}
+	}
+}
+
+// Build an embed for each Figma file linked to a project, showing the file's thumbnail.
+func figmaEmbeds(channelID string, keys []string) []*discordgo.MessageEmbed {
+	var embeds []*discordgo.MessageEmbed
+	for _, key := range keys {
+		file, err := figmaFile(channelID, key)
+		if err != nil {
+			log.Printf("Error reading Figma file: %v", err)
+			continue
+		}
+		embeds = append(embeds, themed(JuiceworksGuildId, &discordgo.MessageEmbed{
+			Title: file.Name,
+			URL:   "https://www.figma.com/design/" + key,
+			Image: &discordgo.MessageEmbedImage{URL: file.ThumbnailURL},
+		}))
+	}
+	return embeds
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The most projects /find-project lists.
+const findProjectLimit = 10
+
+// How closely a query matches some text: 0 when the text contains it, 1 when its letters appear in order, and 2 when
+// it's within a couple of typos of one of the text's words. Returns -1 for no match.
+func fuzzyScore(query, text string) int {
+	query, text = strings.ToLower(query), strings.ToLower(text)
+	if query == "" || text == "" {
+		return -1
+	}
+	if strings.Contains(text, query) {
+		return 0
+	}
+	rest := text
+	subsequence := true
+	for _, r := range query {
+		n := strings.IndexRune(rest, r)
+		if n < 0 {
+			subsequence = false
+			break
+		}
+		rest = rest[n+len(string(r)):]
+	}
+	if subsequence {
+		return 1
+	}
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == '-' || r == '_' }) {
+		if editDistance(query, word) <= min(2, len([]rune(query))/3) {
+			return 2
+		}
+	}
+	return -1
+}
+
+// The Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// A project matching a search, and how closely.
+type projectMatch struct {
+	project project
+	score   int
+}
+
+// Search the registry by project name, client name and linked deal.
+func findProjects(query string) []projectMatch {
+	var matches []projectMatch
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			best := -1
+			for _, text := range []string{p.Name, p.ClientName, p.DealID} {
+				if score := fuzzyScore(query, text); score >= 0 && (best < 0 || score < best) {
+					best = score
+				}
+			}
+			if best >= 0 {
+				matches = append(matches, projectMatch{copyProject(p), best})
+			}
+		}
+	})
+	slices.SortFunc(matches, func(a, b projectMatch) int {
+		return cmp.Or(cmp.Compare(a.score, b.score), cmp.Compare(a.project.Name, b.project.Name))
+	})
+	return matches
+}
+
+// List the projects matching a search, with links to jump to them and buttons to join them.
+func findProject(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	query := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+	matches := findProjects(query)
+
+	content := fmt.Sprintf("No projects match %q.", query)
+	var buttons []discordgo.MessageComponent
+	if len(matches) > 0 {
+		var b strings.Builder
+		fmt.Fprintf(&b, "Projects matching %q:\n", query)
+		for _, m := range matches[:min(len(matches), findProjectLimit)] {
+			fmt.Fprintf(&b, "- <#%s>", m.project.ChannelID)
+			if m.project.ClientName != "" {
+				fmt.Fprintf(&b, " (%s)", m.project.ClientName)
+			}
+			fmt.Fprintf(&b, ", %d members\n", len(m.project.Members))
+			buttons = append(buttons, discordgo.Button{
+				Label:    truncate("Add me to #"+m.project.Name, 80),
+				Style:    discordgo.SecondaryButton,
+				CustomID: "find-join:" + m.project.ChannelID,
+			})
+		}
+		if len(matches) > findProjectLimit {
+			fmt.Fprintf(&b, "…and %d more. Try a longer search.", len(matches)-findProjectLimit)
+		}
+		content = b.String()
+	}
+
+	// Discord allows five buttons per row.
+	var rows []discordgo.MessageComponent
+	for len(buttons) > 0 {
+		n := min(len(buttons), 5)
+		rows = append(rows, discordgo.ActionsRow{Components: buttons[:n]})
+		buttons = buttons[n:]
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    truncate(content, 2000),
+			Components: rows,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Add the staff member who clicked a /find-project button to that project, and the rest of its workspace.
+func joinFoundProject(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, channelID, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	user := i.Member.User
+
+	var content string
+	if _, ok := getProject(channelID); !ok {
+		content = "That project no longer exists."
+	} else if err := joinWorkspace(s, channelID, user.ID); err != nil {
+		log.Printf("Error adding member to channel: %v", err)
+		content = "Error adding member to channel: " + describeError(err)
+	} else {
+		err := updateProject(channelID, func(p *project) {
+			if p.Members == nil {
+				p.Members = make(map[string]string)
+			}
+			p.Members[user.ID] = user.Username
+		})
+		if err != nil {
+			log.Printf("Error recording project member: %v", err)
+		}
+		log.Printf("Added %s to channel %s from a search.", user, channelID)
+		content = fmt.Sprintf("Added you to <#%s>.", channelID)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Give a user access to every channel in a project's workspace.
+func joinWorkspace(s *discordgo.Session, channelID, userID string) error {
+	return firstOverwriteErr(runOverwrites(joinChanges(s, workspaceChannels(channelID), userID)))
+}
+
+// The overwrite changes giving a user access to each of a workspace's channels, for runOverwrites.
+func joinChanges(s *discordgo.Session, channels []string, userID string) []func() error {
+	var changes []func() error
+	for _, id := range channels {
+		changes = append(changes, func() error {
+			return s.ChannelPermissionSet(id, userID, discordgo.PermissionOverwriteTypeMember,
+				discordgo.PermissionViewChannel|discordgo.PermissionSendMessages, 0)
+		})
+	}
+	return changes
+}
+
+// Take a user's access to every channel in a project's workspace away.
+func leaveWorkspace(s *discordgo.Session, channelID, userID string) error {
+	var changes []func() error
+	for _, id := range workspaceChannels(channelID) {
+		changes = append(changes, func() error {
+			return s.ChannelPermissionDelete(id, userID)
+		})
+	}
+	return firstOverwriteErr(runOverwrites(changes))
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long the bot can be offline before the internal channel is told, unless GATEWAY_OFFLINE_NOTICE_AFTER says
+// otherwise.
+const defaultGatewayOfflineNotice = time.Minute
+
+// What the gateway connection has been through since the bot started.
+type gatewayStats struct {
+	connected bool
+	// When the current outage started, while disconnected.
+	disconnectedAt time.Time
+	connects       int
+	disconnects    int
+	// New sessions, which follow an identify, and sessions picked back up after a reconnect.
+	identifies int
+	resumes    int
+	offline    time.Duration
+}
+
+var (
+	gatewayMu sync.Mutex
+	gateway   gatewayStats
+)
+
+// Whether the gateway is connected.
+func gatewayConnected() bool {
+	gatewayMu.Lock()
+	defer gatewayMu.Unlock()
+	return gateway.connected
+}
+
+// How long the bot can be offline before the internal channel is told.
+func gatewayOfflineNotice() time.Duration {
+	if v := os.Getenv("GATEWAY_OFFLINE_NOTICE_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Ignoring malformed GATEWAY_OFFLINE_NOTICE_AFTER %q", v)
+	}
+	return defaultGatewayOfflineNotice
+}
+
+// Note when the gateway disconnects, and start the clock on paging someone.
+func trackGatewayDisconnect(s *discordgo.Session, d *discordgo.Disconnect) {
+	gatewayMu.Lock()
+	defer gatewayMu.Unlock()
+	if !gateway.connected {
+		return
+	}
+	gateway.connected = false
+	gateway.disconnectedAt = time.Now()
+	gateway.disconnects++
+	log.Printf("Gateway disconnected.")
+	startGatewayDownClock(gateway.disconnectedAt)
+}
+
+// Note when the gateway connects. After a long enough outage, tell the internal channel which interactions might
+// have been missed.
+func trackGatewayConnect(s *discordgo.Session, c *discordgo.Connect) {
+	gatewayMu.Lock()
+	since := gateway.disconnectedAt
+	gateway.connected = true
+	gateway.disconnectedAt = time.Time{}
+	gateway.connects++
+	var down time.Duration
+	if !since.IsZero() {
+		down = time.Since(since)
+		gateway.offline += down
+	}
+	gatewayMu.Unlock()
+
+	stopGatewayDownClock()
+	if since.IsZero() {
+		return
+	}
+	log.Printf("Gateway reconnected after %s.", down.Round(time.Second))
+	if down < gatewayOfflineNotice() {
+		return
+	}
+	// The gateway may still be settling, so send the notice without holding up the event.
+	go func() {
+		_, err := s.ChannelMessageSend(InternalChannelId, fmt.Sprintf(
+			"The bot was offline for %s, from <t:%d:T> to <t:%d:T>. Commands, buttons and forms used in that window "+
+				"never reached the bot, so anyone who used one then will need to try again.",
+			down.Round(time.Second), since.Unix(), time.Now().Unix()))
+		if err != nil {
+			recordFailure("posting offline notice", err)
+		}
+	}()
+}
+
+// Count new gateway sessions.
+func trackGatewayReady(s *discordgo.Session, r *discordgo.Ready) {
+	gatewayMu.Lock()
+	defer gatewayMu.Unlock()
+	gateway.identifies++
+}
+
+// Count gateway sessions resumed after a reconnect.
+func trackGatewayResumed(s *discordgo.Session, r *discordgo.Resumed) {
+	gatewayMu.Lock()
+	defer gatewayMu.Unlock()
+	gateway.resumes++
+}
+
+// Serve the gateway stats in the Prometheus text format.
+func gatewayMetrics(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	gatewayMu.Lock()
+	g := gateway
+	gatewayMu.Unlock()
+	if !g.connected && !g.disconnectedAt.IsZero() {
+		g.offline += time.Since(g.disconnectedAt)
+	}
+	connected := 0
+	if g.connected {
+		connected = 1
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metric := func(name, kind, help string, value any) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	metric("juiceworks_gateway_connected", "gauge", "Whether the Discord gateway is connected.", connected)
+	metric("juiceworks_gateway_connects_total", "counter", "Gateway connections made.", g.connects)
+	metric("juiceworks_gateway_disconnects_total", "counter", "Gateway connections lost.", g.disconnects)
+	metric("juiceworks_gateway_identifies_total", "counter", "New gateway sessions started.", g.identifies)
+	metric("juiceworks_gateway_resumes_total", "counter", "Gateway sessions resumed after a reconnect.", g.resumes)
+	metric("juiceworks_gateway_offline_seconds_total", "counter", "Time spent disconnected from the gateway.", g.offline.Seconds())
+	metric("juiceworks_gateway_heartbeat_latency_seconds", "gauge", "Latency of the last gateway heartbeat.", s.HeartbeatLatency().Seconds())
+	writeLatencyMetrics(w)
+}
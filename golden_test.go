@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Rewrite the golden files with what commands produce now, after checking the change to what people see is meant:
+//
+//	go test -run TestGolden -update
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// A command run against the fake Discord for a golden test, after setting up what it needs.
+type goldenCase struct {
+	name  string
+	setup func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate
+}
+
+// Options for a command interaction.
+func stringOption(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionString, Name: name, Value: value}
+}
+
+func numberOption(name string, value float64) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionNumber, Name: name, Value: value}
+}
+
+func userOption(name, userID string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionUser, Name: name, Value: userID}
+}
+
+func subcommand(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionSubCommand, Name: name, Options: options}
+}
+
+var goldenCases = []goldenCase{
+	{"make-channel", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		return commandInteraction(f.addChannel("lobby", ""), addStaff(f), "make-channel", stringOption("channel-name", "Acme Site!"))
+	}},
+	{"make-channel-short-name", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		return commandInteraction(f.addChannel("lobby", ""), addStaff(f), "make-channel", stringOption("channel-name", "!"))
+	}},
+	{"make-channel-missing-permissions", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		f.fail(http.MethodPost, "guilds/"+JuiceworksGuildId+"/channels", http.StatusForbidden, discordgo.ErrCodeMissingPermissions)
+		return commandInteraction(f.addChannel("lobby", ""), addStaff(f), "make-channel", stringOption("channel-name", "acme"))
+	}},
+	{"add-member", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		client := f.addMember("3000000000000000001", "client")
+		return commandInteraction(addProject(t, f, "acme"), addStaff(f), "add-member", userOption("user", client.User.ID))
+	}},
+	{"project-info", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		channelID := addProject(t, f, "acme")
+		err := updateProject(channelID, func(p *project) {
+			p.CreatedAt = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+			p.CreatedBy = "2000000000000000001"
+			p.ClientName = "Acme"
+			p.Members = map[string]string{"3000000000000000001": "client"}
+			p.CreatorID = "3000000000000000001"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return commandInteraction(channelID, addStaff(f), "project-info")
+	}},
+	{"milestone-add", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		return commandInteraction(addProject(t, f, "acme"), addStaff(f), "milestone",
+			subcommand("add", stringOption("name", "Design"), numberOption("amount", 1500)))
+	}},
+	{"budget-set", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		admin := addStaff(f)
+		admin.Permissions = discordgo.PermissionAdministrator
+		return commandInteraction(addProject(t, f, "acme"), admin, "budget",
+			subcommand("set", numberOption("amount", 10000), numberOption("hourly-rate", 150)))
+	}},
+	{"help", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		return commandInteraction(f.addChannel("lobby", ""), addStaff(f), "help")
+	}},
+	{"terms-set", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		return commandInteraction(f.addChannel("lobby", ""), addAdmin(f), "terms",
+			subcommand("set", stringOption("text", "Be kind.")))
+	}},
+	{"terms-show-none", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		return commandInteraction(f.addChannel("lobby", ""), addAdmin(f), "terms", subcommand("show"))
+	}},
+	{"terms-not-admin", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		return commandInteraction(f.addChannel("lobby", ""), addStaff(f), "terms", subcommand("clear"))
+	}},
+	{"screening-start", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		newcomer := f.addMember("3000000000000000001", "newcomer")
+		return inDM(componentInteraction("4000000000000000001", newcomer, "screening-start"))
+	}},
+	{"screening-submit", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		newcomer := f.addMember("3000000000000000001", "newcomer")
+		i := inDM(componentInteraction("4000000000000000001", newcomer, "screening-submit"))
+		i.Type = discordgo.InteractionModalSubmit
+		i.Data = discordgo.ModalSubmitInteractionData{CustomID: "screening-submit", Components: []discordgo.MessageComponent{
+			&discordgo.ActionsRow{Components: []discordgo.MessageComponent{&discordgo.TextInput{CustomID: "q0", Value: "Jane"}}},
+			&discordgo.ActionsRow{Components: []discordgo.MessageComponent{&discordgo.TextInput{CustomID: "q1", Value: "Acme"}}},
+		}}
+		return i
+	}},
+	{"screening-approve", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		t.Setenv("SCREENING_ROLE_ID", f.addRole("screened", 0))
+		f.addMember("3000000000000000001", "newcomer")
+		i := componentInteraction(InternalChannelId, addStaff(f), "screening-approve:3000000000000000001")
+		i.Message.Content = "<@3000000000000000001> joined and answered the screening questions."
+		return i
+	}},
+	{"screening-approve-not-staff", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		t.Setenv("SCREENING_ROLE_ID", f.addRole("screened", 0))
+		newcomer := f.addMember("3000000000000000001", "newcomer")
+		return componentInteraction(InternalChannelId, newcomer, "screening-approve:3000000000000000001")
+	}},
+	{"sudo", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		roleID := f.addRole("sudoers", 0)
+		t.Setenv("SUDO_ROLE_ID", roleID)
+		staff := f.addMember("2000000000000000001", "staff", JuiceworksRoleId, roleID)
+		return commandInteraction(addProject(t, f, "acme"), staff, "sudo",
+			stringOption("command", "/delete-project"), stringOption("reason", "The client asked us to remove it"))
+	}},
+	{"sudo-not-set-up", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		return commandInteraction(addProject(t, f, "acme"), addStaff(f), "sudo",
+			stringOption("command", "delete-project"), stringOption("reason", "Cleanup"))
+	}},
+	{"sudo-unknown-command", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		roleID := f.addRole("sudoers", 0)
+		t.Setenv("SUDO_ROLE_ID", roleID)
+		staff := f.addMember("2000000000000000001", "staff", JuiceworksRoleId, roleID)
+		return commandInteraction(addProject(t, f, "acme"), staff, "sudo",
+			stringOption("command", "launch-rockets"), stringOption("reason", "Cleanup"))
+	}},
+	{"user-data-export", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		client := f.addMember("3000000000000000001", "client")
+		return commandInteraction(f.addChannel("lobby", ""), addAdmin(f), "user-data",
+			subcommand("export", userOption("user", client.User.ID)))
+	}},
+	{"user-data-delete-member", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		client := f.addMember("3000000000000000001", "client")
+		channelID := addProject(t, f, "acme")
+		if err := updateProject(channelID, func(p *project) { p.Members = map[string]string{client.User.ID: "client"} }); err != nil {
+			t.Fatal(err)
+		}
+		return commandInteraction(f.addChannel("lobby", ""), addAdmin(f), "user-data",
+			subcommand("delete", userOption("user", client.User.ID)))
+	}},
+	{"user-data-delete", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		client := f.addMember("3000000000000000001", "client")
+		return commandInteraction(f.addChannel("lobby", ""), addAdmin(f), "user-data",
+			subcommand("delete", userOption("user", client.User.ID)))
+	}},
+	{"delete-project-not-a-project", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		return commandInteraction(f.addChannel("lobby", ""), addAdmin(f), "delete-project")
+	}},
+	{"delete-project-not-admin", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		return commandInteraction(addProject(t, f, "acme"), addStaff(f), "delete-project")
+	}},
+	{"delete-project-already-trashed", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		channelID := addProject(t, f, "acme")
+		err := updateProject(channelID, func(p *project) {
+			p.Trash = &trashedProject{PurgeAt: time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return commandInteraction(channelID, addAdmin(f), "delete-project")
+	}},
+	{"restore-project", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		client := f.addMember("3000000000000000001", "client")
+		channelID := addProject(t, f, "acme")
+		err := updateProject(channelID, func(p *project) {
+			p.Members = map[string]string{client.User.ID: "client"}
+			p.Status = statusArchived
+			p.Trash = &trashedProject{PurgeAt: time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC), Status: statusActive}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return commandInteraction(channelID, addAdmin(f), "restore-project")
+	}},
+	{"restore-project-not-trashed", func(t *testing.T, f *fakeDiscord) *discordgo.InteractionCreate {
+		return commandInteraction(addProject(t, f, "acme"), addAdmin(f), "restore-project")
+	}},
+}
+
+// Run each command and compare every response it sent, with its content, embeds and components, against the copy
+// saved in testdata/golden, so changes to what people see don't slip through unnoticed.
+func TestGolden(t *testing.T) {
+	for _, c := range goldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			f, s := newTestBot(t)
+			handleInteraction(s, c.setup(t, f))
+			got, err := f.capturedResponses()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join("testdata", "golden", c.name+".json")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v; run go test -run TestGolden -update to create it", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("responses differ from %s; run go test -run TestGolden -update if the change is meant\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+//go:generate buf generate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	juiceworksv1 "github.com/juiceworks/juiceworks-discord/gen/juiceworks/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The scope each gRPC method needs, like the REST endpoints they mirror.
+var grpcScopes = map[string]string{
+	juiceworksv1.ProjectsService_ListProjects_FullMethodName:  scopeRead,
+	juiceworksv1.ProjectsService_CreateProject_FullMethodName: scopeProjectAdmin,
+	juiceworksv1.ProjectsService_AddMember_FullMethodName:     scopeProjectAdmin,
+	juiceworksv1.ProjectsService_RemoveMember_FullMethodName:  scopeProjectAdmin,
+}
+
+// Serve the project API over gRPC on GRPC_ADDR. The server is only started when GRPC_ADDR is set.
+func startGRPCServer(s *discordgo.Session) *grpc.Server {
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		return nil
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Printf("Error starting gRPC server: %v", err)
+		return nil
+	}
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(authenticateGRPC))
+	juiceworksv1.RegisterProjectsServiceServer(server, &grpcProjects{s: s})
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			log.Printf("gRPC server stopped: %v", err)
+		}
+	}()
+	log.Printf("gRPC server listening on %s", addr)
+	return server
+}
+
+// Only allow calls with a token that has the method's scope, within the token's rate limit, like requireAPIKey. The
+// token is sent in the x-api-key metadata or as a bearer authorization. Calls that change anything are turned away in
+// maintenance mode. Every call is logged with the token that made it.
+func authenticateGRPC(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var presented string
+	if v := md.Get("x-api-key"); len(v) > 0 {
+		presented = v[0]
+	}
+	if v := md.Get("authorization"); len(v) > 0 {
+		if bearer, found := strings.CutPrefix(v[0], "Bearer "); found {
+			presented = bearer
+		}
+	}
+	name, id, tokenScope, ok := authenticateToken(presented)
+	defer func() {
+		log.Printf("gRPC %s by %s: %s", info.FullMethod, cmp.Or(name, "unknown token"), status.Code(err))
+	}()
+
+	scope, known := grpcScopes[info.FullMethod]
+	switch {
+	case !ok:
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	case !known:
+		return nil, status.Error(codes.Unimplemented, "unknown method")
+	case !hasScope(tokenScope, scope):
+		return nil, status.Error(codes.PermissionDenied, "this token needs the "+scope+" scope")
+	}
+	// Methods that only read keep working in maintenance mode, like GET requests to the REST API.
+	if on, _ := inMaintenance(); on && scope != scopeRead {
+		return nil, status.Error(codes.Unavailable, "down for maintenance; try again later")
+	}
+	if wait := apiCooldown(id, time.Now()); wait > 0 {
+		return nil, status.Errorf(codes.ResourceExhausted, "rate limited; retry in %s", wait.Round(time.Second))
+	}
+	return handler(ctx, req)
+}
+
+// The project API for internal services, backed by the same registry and Discord calls as the commands.
+type grpcProjects struct {
+	juiceworksv1.UnimplementedProjectsServiceServer
+	s *discordgo.Session
+}
+
+// List the registered projects, ordered by name.
+func (g *grpcProjects) ListProjects(ctx context.Context, req *juiceworksv1.ListProjectsRequest) (*juiceworksv1.ListProjectsResponse, error) {
+	var projects []*juiceworksv1.Project
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			projects = append(projects, grpcProject(p))
+		}
+	})
+	slices.SortFunc(projects, func(a, b *juiceworksv1.Project) int { return strings.Compare(a.Name, b.Name) })
+	return &juiceworksv1.ListProjectsResponse{Projects: projects}, nil
+}
+
+// A project as the gRPC API shows it. Like apiProject, secrets and rates stay in the bot.
+func grpcProject(p *project) *juiceworksv1.Project {
+	listed := apiProject(p)
+	return &juiceworksv1.Project{
+		ChannelId:         listed.ChannelID,
+		Name:              listed.Name,
+		CreatedAt:         timestamppb.New(listed.CreatedAt),
+		CreatedBy:         listed.CreatedBy,
+		Members:           listed.Members,
+		CreatorId:         listed.CreatorID,
+		ClientName:        listed.ClientName,
+		Status:            listed.State(),
+		InternalChannelId: p.InternalChannelID,
+	}
+}
+
+// Create a project channel, like /make-channel.
+func (g *grpcProjects) CreateProject(ctx context.Context, req *juiceworksv1.CreateProjectRequest) (*juiceworksv1.CreateProjectResponse, error) {
+	name := sanitizeChannelName(req.Name)
+	if utf8.RuneCountInString(name) < 2 {
+		return nil, status.Error(codes.InvalidArgument, "channel name must have at least 2 letters, numbers or emoji")
+	}
+	if req.CreatedBy == "" {
+		return nil, status.Error(codes.InvalidArgument, "created_by is required")
+	}
+	tmpl := projectTemplate{HuddleButton: true}
+	var tmplName string
+	if req.Template != "" {
+		tmplName = strings.ToLower(req.Template)
+		var ok bool
+		if tmpl, ok = getTemplate(req.Template); !ok {
+			return nil, status.Error(codes.NotFound, "there's no project template named "+req.Template)
+		}
+	}
+
+	channel, err := createProjectChannel(g.s, name, req.CreatedBy, tmplName, tmpl)
+	var failed *channelSetupError
+	if errors.As(err, &failed) {
+		log.Printf("Error %s: %v", failed.action, failed.err)
+		return nil, status.Error(codes.Unavailable, "error "+failed.action+": "+describeError(failed.err))
+	}
+	if req.Internal {
+		p, _ := getProject(channel.ID)
+		if _, err := createInternalChannel(g.s, p); err != nil {
+			recordFailure("creating internal channel", err)
+		}
+	}
+
+	log.Printf("Created channel over gRPC: %v", channel)
+	p, _ := getProject(channel.ID)
+	return &juiceworksv1.CreateProjectResponse{Project: grpcProject(&p)}, nil
+}
+
+// Add a member to a project and the rest of its workspace, like /add-member. Members who still have to accept the
+// terms are refused, since there's nobody to send the terms from.
+func (g *grpcProjects) AddMember(ctx context.Context, req *juiceworksv1.AddMemberRequest) (*juiceworksv1.AddMemberResponse, error) {
+	projectID := workspaceProjectID(req.ChannelId)
+	if _, ok := getProject(projectID); !ok {
+		return nil, status.Error(codes.NotFound, "unknown project")
+	}
+	member, err := g.s.GuildMember(JuiceworksGuildId, req.UserId)
+	if err != nil {
+		log.Printf("Error reading member roles: %v", err)
+		return nil, status.Error(codes.NotFound, "unknown member")
+	}
+	if requiresTerms(member) {
+		return nil, status.Error(codes.FailedPrecondition, "member has to accept the terms first; add them with /add-member")
+	}
+
+	err = addProjectMember(g.s, projectID, member.User, member, "")
+	var failed *memberChangeError
+	if errors.As(err, &failed) {
+		log.Printf("Error %s: %v", failed.step, failed.err)
+		return nil, status.Error(codes.Unavailable, "error "+failed.step)
+	}
+
+	log.Printf("Added %s to channel %s over gRPC.", member.User, projectID)
+	return &juiceworksv1.AddMemberResponse{}, nil
+}
+
+// Remove a member from a project and the rest of its workspace, like /remove-member.
+func (g *grpcProjects) RemoveMember(ctx context.Context, req *juiceworksv1.RemoveMemberRequest) (*juiceworksv1.RemoveMemberResponse, error) {
+	projectID := workspaceProjectID(req.ChannelId)
+	if _, ok := getProject(projectID); !ok {
+		return nil, status.Error(codes.NotFound, "unknown project")
+	}
+	err := removeProjectMember(g.s, projectID, req.UserId, "")
+	var failed *memberChangeError
+	if errors.As(err, &failed) {
+		log.Printf("Error %s: %v", failed.step, failed.err)
+		return nil, status.Error(codes.Unavailable, "error "+failed.step)
+	}
+
+	log.Printf("Removed user %s from channel %s over gRPC.", req.UserId, projectID)
+	return &juiceworksv1.RemoveMemberResponse{}, nil
+}
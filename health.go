@@ -0,0 +1,147 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The most projects listed by /health-report.
+const healthReportLimit = 10
+
+// A project's health out of 100, with the reasons points were taken off.
+type projectHealth struct {
+	channelID string
+	score     int
+	reasons   []string
+}
+
+// Score a project's health from its recent activity, whether a client is waiting on a reply, and its milestone
+// deadlines. Every project starts at 100 and loses points for each problem.
+func scoreProject(s *discordgo.Session, p project, now time.Time) projectHealth {
+	h := projectHealth{channelID: p.ChannelID, score: 100}
+	penalize := func(points int, reason string) {
+		h.score -= points
+		h.reasons = append(h.reasons, reason)
+	}
+
+	// Find the most recent message from a person, ignoring the bot's own posts.
+	messages, err := s.ChannelMessages(p.ChannelID, 50, "", "", "")
+	if err != nil {
+		log.Printf("Error reading channel %s: %v", p.ChannelID, err)
+	}
+	var last *discordgo.Message
+	for _, m := range messages {
+		if m.Author != nil && m.Author.ID != s.State.User.ID {
+			last = m
+			break
+		}
+	}
+
+	// Quiet projects lose 5 points a day after two days, up to 40. A channel that couldn't be read says nothing about
+	// activity, so it's reported without losing points.
+	if err != nil {
+		h.reasons = append(h.reasons, "Couldn't read messages: "+describeError(err))
+	} else if last == nil {
+		penalize(40, "No messages yet")
+	} else if idle := int(now.Sub(last.Timestamp).Hours() / 24); idle > 2 {
+		penalize(min(5*(idle-2), 40), fmt.Sprintf("No messages for %d days", idle))
+	}
+
+	// A client waiting on a reply costs more the longer they wait.
+	if last != nil && !isStaff(s, last.Author.ID) {
+		switch waiting := now.Sub(last.Timestamp); {
+		case waiting > 24*time.Hour:
+			penalize(25, fmt.Sprintf("Client message unanswered since <t:%d:R>", last.Timestamp.Unix()))
+		case waiting > 4*time.Hour:
+			penalize(10, fmt.Sprintf("Client message unanswered since <t:%d:R>", last.Timestamp.Unix()))
+		}
+	}
+
+	// Overdue milestones cost 15 points each, up to 30, and a milestone due within three days costs 10.
+	overdue, dueSoon := 0, false
+	for _, m := range p.Milestones {
+		if m.Due.IsZero() || !m.PaidAt.IsZero() {
+			continue
+		}
+		if m.Due.Before(now) {
+			overdue++
+		} else if m.Due.Sub(now) < 3*24*time.Hour {
+			dueSoon = true
+		}
+	}
+	if overdue > 0 {
+		penalize(min(15*overdue, 30), fmt.Sprintf("%d overdue milestone(s)", overdue))
+	}
+	if dueSoon {
+		penalize(10, "Milestone due within three days")
+	}
+
+	h.score = max(h.score, 0)
+	return h
+}
+
+// Check whether a user has the Juiceworks role.
+func isStaff(s *discordgo.Session, userID string) bool {
+	member, err := s.State.Member(JuiceworksGuildId, userID)
+	if err != nil {
+		if member, err = s.GuildMember(JuiceworksGuildId, userID); err != nil {
+			log.Printf("Error reading member roles: %v", err)
+			return false
+		}
+	}
+	return slices.Contains(member.Roles, JuiceworksRoleId)
+}
+
+// Describe a health score for an embed field.
+func (h projectHealth) String() string {
+	if len(h.reasons) == 0 {
+		return fmt.Sprintf("%d/100", h.score)
+	}
+	return fmt.Sprintf("%d/100: %s", h.score, strings.Join(h.reasons, "; "))
+}
+
+// Rank the registered projects by health, listing the ones that need the most attention first.
+func healthReport(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Scoring reads every project's messages, so defer the response.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+
+	var projects []project
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if p.Trash == nil {
+				projects = append(projects, copyProject(p))
+			}
+		}
+	})
+
+	now := time.Now()
+	scores := make([]projectHealth, len(projects))
+	for n, p := range projects {
+		scores[n] = scoreProject(s, p, now)
+	}
+	slices.SortFunc(scores, func(a, b projectHealth) int {
+		return cmp.Compare(a.score, b.score)
+	})
+
+	var b strings.Builder
+	for _, h := range scores[:min(len(scores), healthReportLimit)] {
+		fmt.Fprintf(&b, "- <#%s> %s\n", h.channelID, h)
+	}
+	content := "There are no registered projects."
+	if b.Len() > 0 {
+		content = truncate(b.String(), 2000)
+	}
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+	logResponseErr(err)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A channel the bot can't read isn't scored as quiet, since nothing is known about its activity.
+func TestHealthOfUnreadableChannel(t *testing.T) {
+	f, s := newTestBot(t)
+	channelID := addProject(t, f, "acme")
+	f.fail(http.MethodGet, "channels/"+channelID+"/messages", http.StatusForbidden, discordgo.ErrCodeMissingAccess)
+
+	p, _ := getProject(channelID)
+	h := scoreProject(s, p, time.Now())
+	if h.score != 100 {
+		t.Errorf("the project scored %d, want 100", h.score)
+	}
+	if len(h.reasons) != 1 || !strings.HasPrefix(h.reasons[0], "Couldn't read messages") {
+		t.Errorf("the reasons are %q, want only the read error", h.reasons)
+	}
+}
+
+// Deleted projects are waiting to be purged, so they're left out of the report as they are from budgets.
+func TestHealthReportSkipsTrashedProjects(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	kept := addProject(t, f, "acme")
+	trashed := addProject(t, f, "globex")
+	if err := updateProject(trashed, func(p *project) { p.Trash = &trashedProject{DeletedAt: time.Now()} }); err != nil {
+		t.Fatal(err)
+	}
+
+	handleInteraction(s, commandInteraction(f.addChannel("general", ""), staff, "health-report"))
+	got := f.lastResponse(t)
+	if !strings.Contains(got, "<#"+kept+">") || strings.Contains(got, "<#"+trashed+">") {
+		t.Errorf("the report is %q, want only <#%s> in it", got, kept)
+	}
+}
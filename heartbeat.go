@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// How often the bot checks in with the uptime monitor.
+const heartbeatInterval = time.Minute
+
+// Check in with the uptime monitor at HEARTBEAT_URL every minute while the gateway is connected, so the monitor
+// alerts when the bot dies or hangs without saying so. Only started once the commands are registered. Without the
+// gateway, the bot checks in as long as it's running.
+func startHeartbeat() {
+	endpoint := os.Getenv("HEARTBEAT_URL")
+	if endpoint == "" {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	needsGateway := interactionsMode() == interactionsGateway
+	go func() {
+		for ; ; time.Sleep(heartbeatInterval) {
+			if needsGateway && !gatewayConnected() {
+				continue
+			}
+			resp, err := client.Get(endpoint)
+			if err != nil {
+				log.Printf("Error sending heartbeat: %v", err)
+				continue
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				log.Printf("Error sending heartbeat: monitor returned %s", resp.Status)
+			}
+		}
+	}()
+}
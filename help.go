@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The most options a select menu can hold, and the most menus a message can hold.
+const (
+	helpMenuSize  = 25
+	helpMenuLimit = 5
+)
+
+// Placeholders shown for each option type in usage lines.
+var optionPlaceholders = map[discordgo.ApplicationCommandOptionType]string{
+	discordgo.ApplicationCommandOptionString:      "text",
+	discordgo.ApplicationCommandOptionInteger:     "number",
+	discordgo.ApplicationCommandOptionNumber:      "number",
+	discordgo.ApplicationCommandOptionBoolean:     "true/false",
+	discordgo.ApplicationCommandOptionUser:        "@user",
+	discordgo.ApplicationCommandOptionRole:        "@role",
+	discordgo.ApplicationCommandOptionChannel:     "#channel",
+	discordgo.ApplicationCommandOptionMentionable: "@user or @role",
+	discordgo.ApplicationCommandOptionAttachment:  "file",
+}
+
+// Values used for each option type in example calls.
+var optionExamples = map[discordgo.ApplicationCommandOptionType]string{
+	discordgo.ApplicationCommandOptionString:      "example",
+	discordgo.ApplicationCommandOptionInteger:     "3",
+	discordgo.ApplicationCommandOptionNumber:      "1.5",
+	discordgo.ApplicationCommandOptionBoolean:     "true",
+	discordgo.ApplicationCommandOptionUser:        "@someone",
+	discordgo.ApplicationCommandOptionRole:        "@role",
+	discordgo.ApplicationCommandOptionChannel:     "#channel",
+	discordgo.ApplicationCommandOptionMentionable: "@someone",
+	discordgo.ApplicationCommandOptionAttachment:  "(file)",
+}
+
+// The registered slash commands the caller can use where they called /help. Context menu commands are left out,
+// since they aren't typed.
+func allowedCommands(s *discordgo.Session, i *discordgo.InteractionCreate) []*discordgo.ApplicationCommand {
+	var allowed []*discordgo.ApplicationCommand
+	for _, c := range commands {
+		if c.Type == discordgo.ChatApplicationCommand && denial(s, i, c.Name, commandPolicies) == "" {
+			allowed = append(allowed, c)
+		}
+	}
+	return allowed
+}
+
+// Build select menus listing commands, splitting them across menus when there are more than one menu holds.
+func helpMenus(allowed []*discordgo.ApplicationCommand) []discordgo.MessageComponent {
+	var rows []discordgo.MessageComponent
+	for start := 0; start < len(allowed) && len(rows) < helpMenuLimit; start += helpMenuSize {
+		var options []discordgo.SelectMenuOption
+		for _, c := range allowed[start:min(start+helpMenuSize, len(allowed))] {
+			options = append(options, discordgo.SelectMenuOption{Label: "/" + commandPrefix() + c.Name, Value: c.Name, Description: c.Description})
+		}
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    fmt.Sprintf("help:%d", len(rows)),
+				Placeholder: "Choose a command for details",
+				Options:     options,
+			},
+		}})
+	}
+	return rows
+}
+
+// Describe how to call a command: one usage line for each subcommand, followed by what each option means and an
+// example call using only the required options.
+func commandUsage(c *discordgo.ApplicationCommand) string {
+	var b strings.Builder
+	usage := func(prefix string, options []*discordgo.ApplicationCommandOption) {
+		line, example := prefix, prefix
+		for _, o := range options {
+			if o.Required {
+				value := optionExamples[o.Type]
+				if len(o.Choices) > 0 {
+					value = o.Choices[0].Name
+				}
+				example += " " + o.Name + ":" + value
+			}
+			arg := o.Name + ":" + optionPlaceholders[o.Type]
+			if len(o.Choices) > 0 {
+				names := make([]string, len(o.Choices))
+				for n, choice := range o.Choices {
+					names[n] = choice.Name
+				}
+				arg = o.Name + ":" + strings.Join(names, "|")
+			}
+			if !o.Required {
+				arg = "[" + arg + "]"
+			}
+			line += " " + arg
+		}
+		fmt.Fprintf(&b, "`%s`\n", line)
+		for _, o := range options {
+			fmt.Fprintf(&b, "- `%s`: %s\n", o.Name, o.Description)
+		}
+		fmt.Fprintf(&b, "Example: `%s`\n", example)
+	}
+
+	subcommands := false
+	for _, o := range c.Options {
+		if o.Type == discordgo.ApplicationCommandOptionSubCommand {
+			subcommands = true
+			fmt.Fprintf(&b, "%s\n", o.Description)
+			usage("/"+commandPrefix()+c.Name+" "+o.Name, o.Options)
+			b.WriteString("\n")
+		}
+	}
+	if !subcommands {
+		usage("/"+commandPrefix()+c.Name, c.Options)
+	}
+	return b.String()
+}
+
+// List the commands the caller can use, with menus to see how to use each one.
+func help(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	allowed := allowedCommands(s, i)
+	var b strings.Builder
+	for _, c := range allowed {
+		fmt.Fprintf(&b, "**/%s%s**: %s\n", commandPrefix(), c.Name, c.Description)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{themed(i.GuildID, &discordgo.MessageEmbed{
+				Title:       "Commands you can use here",
+				Description: truncate(b.String(), 4096),
+			})},
+			Components: helpMenus(allowed),
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Show how to use the command picked from a /help menu.
+func helpDetails(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	values := i.MessageComponentData().Values
+	allowed := allowedCommands(s, i)
+	var command *discordgo.ApplicationCommand
+	for _, c := range allowed {
+		if len(values) > 0 && c.Name == values[0] {
+			command = c
+		}
+	}
+	if command == nil {
+		log.Printf("Ignoring help for unknown command %v", values)
+		return
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{themed(i.GuildID, &discordgo.MessageEmbed{
+				Title:       "/" + commandPrefix() + command.Name,
+				Description: truncate(command.Description+"\n\n"+commandUsage(command), 4096),
+			})},
+			Components: helpMenus(allowed),
+		},
+	}))
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The events external services can subscribe to, with a sample payload for each. Zapier shows the sample while a
+// Zap is being set up.
+var hookEvents = map[string]any{
+	"project.created":        projectCreatedEvent{ChannelID: "100000000000000001", Name: "acme-website", CreatedBy: "100000000000000002"},
+	"member.added":           memberAddedEvent{ChannelID: "100000000000000001", UserID: "100000000000000003", Username: "jane"},
+	"member.removed":         memberRemovedEvent{ChannelID: "100000000000000001", UserID: "100000000000000003", Username: "jane"},
+	"project.status_changed": projectStatusChangedEvent{ChannelID: "100000000000000001", From: "active", To: "review", ChangedBy: "100000000000000002"},
+	"project.renamed":        projectRenamedEvent{ChannelID: "100000000000000001", OldName: "acme-website", Name: "acme-web-app", RenamedBy: "100000000000000002"},
+}
+
+// Sent when make-channel creates a project.
+type projectCreatedEvent struct {
+	ChannelID string `json:"channelId"`
+	Name      string `json:"name"`
+	CreatedBy string `json:"createdBy"`
+}
+
+// Sent when add-member adds someone to a project.
+type memberAddedEvent struct {
+	ChannelID string `json:"channelId"`
+	UserID    string `json:"userId"`
+	Username  string `json:"username"`
+}
+
+// Sent when remove-member takes someone out of a project.
+type memberRemovedEvent struct {
+	ChannelID string `json:"channelId"`
+	UserID    string `json:"userId"`
+	Username  string `json:"username"`
+}
+
+// Sent when rename-project renames a project, so linked tools can follow it.
+type projectRenamedEvent struct {
+	ChannelID string `json:"channelId"`
+	OldName   string `json:"oldName"`
+	Name      string `json:"name"`
+	RenamedBy string `json:"renamedBy"`
+}
+
+// Sent when /status moves a project through its workflow.
+type projectStatusChangedEvent struct {
+	ChannelID string `json:"channelId"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	ChangedBy string `json:"changedBy"`
+}
+
+// A target URL subscribed to an event type.
+type hookSubscription struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	TargetURL string    `json:"targetUrl"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Write a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
+
+// Subscribe a target URL to an event type.
+func hooksSubscribe(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Event     string `json:"event"`
+		TargetURL string `json:"target_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if _, ok := hookEvents[req.Event]; !ok {
+		http.Error(w, "unknown event", http.StatusBadRequest)
+		return
+	}
+	if u, err := url.Parse(req.TargetURL); err != nil || u.Scheme != "https" {
+		http.Error(w, "target_url must be an https URL", http.StatusBadRequest)
+		return
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		http.Error(w, "could not create subscription", http.StatusInternalServerError)
+		return
+	}
+	sub := &hookSubscription{ID: hex.EncodeToString(id), Event: req.Event, TargetURL: req.TargetURL, CreatedAt: time.Now()}
+	err := updateStore(func(d *storeData) {
+		if d.HookSubscriptions == nil {
+			d.HookSubscriptions = make(map[string]*hookSubscription)
+		}
+		d.HookSubscriptions[sub.ID] = sub
+	})
+	if err != nil {
+		log.Printf("Error saving hook subscription: %v", err)
+		http.Error(w, "could not create subscription", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Subscribed %s to %s.", sub.TargetURL, sub.Event)
+	writeJSON(w, http.StatusCreated, map[string]string{"id": sub.ID})
+}
+
+// Remove a subscription.
+func hooksUnsubscribe(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	found := false
+	err := updateStore(func(d *storeData) {
+		if _, found = d.HookSubscriptions[id]; found {
+			delete(d.HookSubscriptions, id)
+		}
+	})
+	if err != nil {
+		log.Printf("Error removing hook subscription: %v", err)
+		http.Error(w, "could not remove subscription", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "subscription not found", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("Removed hook subscription %s.", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Return a sample payload for an event type, as a list so Zapier can use it as a polling fallback.
+func hooksSample(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	sample, ok := hookEvents[r.PathValue("event")]
+	if !ok {
+		http.Error(w, "unknown event", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, []any{sample})
+}
+
+// Log an event for the daily report, send it to stream clients, and deliver it to every URL subscribed to it.
+// Delivery happens in the background so commands aren't slowed down by slow subscribers.
+func emitEvent(event string, data any) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Error encoding %s event: %v", event, err)
+		return
+	}
+	logEvent(event, body)
+	streamEmit(event, body)
+
+	var subs []hookSubscription
+	readStore(func(d *storeData) {
+		for _, sub := range d.HookSubscriptions {
+			if sub.Event == event {
+				subs = append(subs, *sub)
+			}
+		}
+	})
+	for _, sub := range subs {
+		go deliverEvent(sub, body)
+	}
+}
+
+// An event that couldn't be delivered to a subscriber, kept as a failed job to replay.
+type hookDelivery struct {
+	SubscriptionID string          `json:"subscriptionId"`
+	Event          string          `json:"event"`
+	Body           json.RawMessage `json:"body"`
+}
+
+// POST an event to a subscriber. Events that can't be delivered are kept as failed jobs, so they can be replayed
+// with /jobs once the subscriber is back.
+func deliverEvent(sub hookSubscription, body []byte) {
+	if err := postEvent(sub, body); err != nil {
+		recordFailure(fmt.Sprintf("delivering %s event to %s", sub.Event, sub.TargetURL), err)
+		recordFailedJob(jobHookDelivery, fmt.Sprintf("Deliver %s event to %s", sub.Event, sub.TargetURL),
+			hookDelivery{SubscriptionID: sub.ID, Event: sub.Event, Body: body}, err)
+	}
+}
+
+// POST an event to a subscriber. A 410 Gone response means the subscriber wants to be removed.
+func postEvent(sub hookSubscription, body []byte) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(sub.TargetURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		err := updateStore(func(d *storeData) {
+			delete(d.HookSubscriptions, sub.ID)
+		})
+		if err != nil {
+			log.Printf("Error removing hook subscription: %v", err)
+		}
+		log.Printf("Removed hook subscription %s after the target returned 410.", sub.ID)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("target returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A HubSpot deal, with its stage resolved to the label shown in HubSpot.
+type hubspotDealInfo struct {
+	name   string
+	amount string
+	stage  string
+}
+
+// Call the HubSpot API for a project and decode the response into out. The project's hubspot secret is used if it
+// has one, and the private app token in HUBSPOT_TOKEN if it doesn't.
+func hubspotAPI(channelID, path string, out any) (err error) {
+	defer func() { noteIntegrationCall("HubSpot", err) }()
+	req, err := http.NewRequest(http.MethodGet, "https://api.hubapi.com"+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+integrationToken(channelID, "hubspot"))
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("hubspot returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Read a deal from HubSpot, with the credentials of the project it's for.
+func hubspotDeal(channelID, dealID string) (*hubspotDealInfo, error) {
+	var deal struct {
+		Properties struct {
+			DealName string `json:"dealname"`
+			Amount   string `json:"amount"`
+			Currency string `json:"deal_currency_code"`
+			Stage    string `json:"dealstage"`
+			Pipeline string `json:"pipeline"`
+		} `json:"properties"`
+	}
+	path := "/crm/v3/objects/deals/" + url.PathEscape(dealID) + "?properties=dealname,amount,deal_currency_code,dealstage,pipeline"
+	if err := hubspotAPI(channelID, path, &deal); err != nil {
+		return nil, err
+	}
+	props := deal.Properties
+
+	// Stages are stored as internal IDs, so look up the label from the deal's pipeline.
+	stage := props.Stage
+	var stageInfo struct {
+		Label string `json:"label"`
+	}
+	path = "/crm/v3/pipelines/deals/" + url.PathEscape(props.Pipeline) + "/stages/" + url.PathEscape(props.Stage)
+	if err := hubspotAPI(channelID, path, &stageInfo); err != nil {
+		log.Printf("Error reading HubSpot deal stage: %v", err)
+	} else {
+		stage = stageInfo.Label
+	}
+
+	amount := "Not set"
+	if props.Amount != "" {
+		amount = strings.TrimSpace(props.Amount + " " + props.Currency)
+	}
+	return &hubspotDealInfo{name: props.DealName, amount: amount, stage: stage}, nil
+}
+
+// Link the project the command was called from to a HubSpot deal.
+func linkDeal(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Verify the command options.
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 || options[0].Type != discordgo.ApplicationCommandOptionString {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "This command requires a deal ID.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+	dealID := strings.TrimSpace(options[0].StringValue())
+	if _, ok := getProject(i.ChannelID); !ok {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "This channel is not a registered project.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	// HubSpot can be slow to answer, so defer the response.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+	content := linkDealContent(i.ChannelID, dealID)
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+	logResponseErr(err)
+}
+
+// Link a project to a HubSpot deal once the deal is found, and describe how it went.
+func linkDealContent(channelID, dealID string) string {
+	// Make sure the deal exists before linking it.
+	deal, err := hubspotDeal(channelID, dealID)
+	if err != nil {
+		log.Printf("Error reading HubSpot deal: %v", err)
+		return "Error reading HubSpot deal: " + describeError(err)
+	}
+
+	// The project may have been deleted while the deal was being read.
+	if _, ok := getProject(channelID); !ok {
+		return "This channel is not a registered project."
+	}
+	err = updateProject(channelID, func(p *project) {
+		p.DealID = dealID
+	})
+	if err != nil {
+		log.Printf("Error linking deal: %v", err)
+		return "Error linking deal: " + describeError(err)
+	}
+
+	log.Printf("Linked deal %s to channel %s.", dealID, channelID)
+	return fmt.Sprintf("Linked this channel to the deal \"%s\" (%s).", deal.name, deal.stage)
+}
+
+// Handle a HubSpot webhook, announcing deal stage changes in the linked project channels.
+func hubspotWebhook(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading webhook body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// HubSpot's v3 signature covers the method, full URL, body and a millisecond timestamp, base64 encoded.
+	timestamp := r.Header.Get("X-HubSpot-Request-Timestamp")
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	secret := os.Getenv("HUBSPOT_CLIENT_SECRET")
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(r.Method + strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/") + r.URL.RequestURI() + string(body) + timestamp))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if err != nil || secret == "" || time.Since(time.UnixMilli(ms)).Abs() > 5*time.Minute ||
+		!hmac.Equal([]byte(expected), []byte(r.Header.Get("X-HubSpot-Signature-v3"))) {
+		log.Printf("Rejected webhook on %s: invalid signature", r.URL.Path)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var events []struct {
+		SubscriptionType string `json:"subscriptionType"`
+		ObjectID         int64  `json:"objectId"`
+		PropertyName     string `json:"propertyName"`
+	}
+	if err := json.Unmarshal(body, &events); err != nil {
+		log.Printf("Error decoding HubSpot webhook: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	for _, e := range events {
+		if e.SubscriptionType == "deal.propertyChange" && e.PropertyName == "dealstage" {
+			go announceDealStage(s, strconv.FormatInt(e.ObjectID, 10))
+		}
+	}
+}
+
+// Post a deal's current stage in every project linked to it.
+func announceDealStage(s *discordgo.Session, dealID string) {
+	var channelIDs []string
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if p.DealID == dealID {
+				channelIDs = append(channelIDs, p.ChannelID)
+			}
+		}
+	})
+	if len(channelIDs) == 0 {
+		return
+	}
+
+	// Projects linked to the same deal are in the same HubSpot account, so any of their credentials will do.
+	deal, err := hubspotDeal(channelIDs[0], dealID)
+	if err != nil {
+		log.Printf("Error reading HubSpot deal: %v", err)
+		return
+	}
+	for _, channelID := range channelIDs {
+		pings, allowed := projectPings(channelID)
+		msg := &discordgo.MessageSend{
+			Content:         strings.TrimSpace(renderMessage("deal-stage", dealStageMessage{Deal: deal.name, Stage: deal.stage}) + " " + pings),
+			AllowedMentions: allowed,
+		}
+		if _, err := s.ChannelMessageSendComplex(channelID, msg); err != nil {
+			log.Printf("Error announcing deal stage: %v", err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long a huddle can sit empty before it's deleted.
+const huddleIdleTimeout = 10 * time.Minute
+
+// A temporary voice channel started from a project channel.
+type huddle struct {
+	ProjectChannelID string    `json:"projectChannelId"`
+	CreatedAt        time.Time `json:"createdAt"`
+	// When the huddle was last seen empty, or zero while people are in it.
+	EmptySince time.Time `json:"emptySince,omitempty"`
+}
+
+// Post a "Start huddle" button in a project channel and pin it.
+func postHuddleButton(s *discordgo.Session, channelID string) error {
+	m, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: "Need to talk it through? Start a huddle: a voice channel only this project's members can join. " +
+			"It's deleted once it's been empty for 10 minutes.",
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Start huddle",
+					Style:    discordgo.PrimaryButton,
+					CustomID: "huddle-start",
+					Emoji:    &discordgo.ComponentEmoji{Name: "🎧"},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return s.ChannelMessagePin(channelID, m.ID)
+}
+
+// Post the huddle button in the project channel the command was called from.
+func huddleButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	content := "Posted and pinned the huddle button."
+	if _, ok := getProject(i.ChannelID); !ok {
+		content = "This channel is not a registered project."
+	} else if err := postHuddleButton(s, i.ChannelID); err != nil {
+		log.Printf("Error posting huddle button: %v", err)
+		content = "Error posting huddle button: " + describeError(err)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Create a huddle for the project the button was pressed in, or point to the one that's already open. The voice
+// channel copies the project channel's permissions so only the project's members can join it.
+func startHuddle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	content := startHuddleContent(s, i.ChannelID)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Create a huddle for a project, returning the response to show the caller.
+func startHuddleContent(s *discordgo.Session, channelID string) string {
+	p, ok := getProject(channelID)
+	if !ok {
+		return "This channel is not a registered project."
+	}
+	var existing string
+	readStore(func(d *storeData) {
+		for id, h := range d.Huddles {
+			if h.ProjectChannelID == channelID {
+				existing = id
+			}
+		}
+	})
+	if existing != "" {
+		return fmt.Sprintf("There's already a huddle going: <#%s>", existing)
+	}
+
+	channel, err := s.State.Channel(channelID)
+	if err != nil {
+		channel, err = s.Channel(channelID)
+	}
+	if err != nil {
+		log.Printf("Error reading project channel: %v", err)
+		return "Error reading project channel: " + describeError(err)
+	}
+	voicePermissions := int64(discordgo.PermissionVoiceConnect | discordgo.PermissionVoiceSpeak | discordgo.PermissionVoiceStreamVideo)
+	overwrites := make([]*discordgo.PermissionOverwrite, len(channel.PermissionOverwrites))
+	for n, o := range channel.PermissionOverwrites {
+		c := *o
+		if c.Allow&discordgo.PermissionViewChannel != 0 {
+			c.Allow |= voicePermissions
+		}
+		if c.Deny&discordgo.PermissionViewChannel != 0 {
+			c.Deny |= voicePermissions
+		}
+		overwrites[n] = &c
+	}
+
+	voice, err := s.GuildChannelCreateComplex(JuiceworksGuildId, discordgo.GuildChannelCreateData{
+		Name:                 truncate("huddle-"+p.Name, 100),
+		Type:                 discordgo.ChannelTypeGuildVoice,
+		ParentID:             channel.ParentID,
+		PermissionOverwrites: overwrites,
+	})
+	if err != nil {
+		return channelErrorContent(s, "creating huddle", err, "Manage Channels and Manage Roles", channelLocation(channel.ParentID))
+	}
+
+	// Count the huddle as a project voice channel so calls in it are logged.
+	err = updateStore(func(d *storeData) {
+		if d.Huddles == nil {
+			d.Huddles = make(map[string]*huddle)
+		}
+		d.Huddles[voice.ID] = &huddle{ProjectChannelID: channelID, CreatedAt: time.Now(), EmptySince: time.Now()}
+		if p, ok := d.Projects[channelID]; ok {
+			p.VoiceChannels = append(p.VoiceChannels, voice.ID)
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving huddle: %v", err)
+	}
+	log.Printf("Started huddle %s for project %s.", voice.ID, channelID)
+	return fmt.Sprintf("Started a huddle: <#%s>", voice.ID)
+}
+
+// Check on huddles every minute, deleting ones that have been empty for too long.
+func startHuddleCleanup(s *discordgo.Session) {
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			if !paused("cleaning up huddles") {
+				cleanUpHuddles(s)
+			}
+		}
+	}()
+}
+
+// Delete huddles that have been empty for longer than huddleIdleTimeout, and note when others become empty.
+func cleanUpHuddles(s *discordgo.Session) {
+	open := 0
+	readStore(func(d *storeData) {
+		open = len(d.Huddles)
+	})
+	if open == 0 {
+		return
+	}
+
+	guild, err := s.State.Guild(JuiceworksGuildId)
+	if err != nil {
+		log.Printf("Error reading voice states: %v", err)
+		return
+	}
+	occupied := make(map[string]bool)
+	s.State.RLock()
+	for _, v := range guild.VoiceStates {
+		occupied[v.ChannelID] = true
+	}
+	s.State.RUnlock()
+
+	now := time.Now()
+	var expired []string
+	err = updateStore(func(d *storeData) {
+		for id, h := range d.Huddles {
+			switch {
+			case occupied[id]:
+				h.EmptySince = time.Time{}
+			case h.EmptySince.IsZero():
+				h.EmptySince = now
+			case now.Sub(h.EmptySince) >= huddleIdleTimeout:
+				expired = append(expired, id)
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving huddles: %v", err)
+	}
+
+	for _, id := range expired {
+		// If someone already deleted the channel by hand, there's nothing left to do but forget it.
+		if _, err := s.ChannelDelete(id); err != nil && !isUnknownChannel(err) {
+			recordFailure("deleting huddle", err)
+			continue
+		}
+		err := updateStore(func(d *storeData) {
+			if p, ok := d.Projects[d.Huddles[id].ProjectChannelID]; ok {
+				// Keep the calls so they still count, but the channel is gone.
+				p.VoiceChannels = slices.DeleteFunc(p.VoiceChannels, func(v string) bool { return v == id })
+			}
+			delete(d.Huddles, id)
+		})
+		if err != nil {
+			log.Printf("Error removing huddle: %v", err)
+		}
+		log.Printf("Deleted empty huddle %s.", id)
+	}
+}
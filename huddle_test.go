@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A huddle whose channel was already deleted by hand is forgotten, rather than failing to delete it every minute.
+func TestCleanUpDeletedHuddle(t *testing.T) {
+	f, s := newTestBot(t)
+	if err := s.State.GuildAdd(&discordgo.Guild{ID: JuiceworksGuildId}); err != nil {
+		t.Fatal(err)
+	}
+	channelID := addProject(t, f, "acme")
+	err := updateStore(func(d *storeData) {
+		d.Projects[channelID].VoiceChannels = []string{"4000000000000000001"}
+		d.Huddles = map[string]*huddle{"4000000000000000001": {
+			ProjectChannelID: channelID,
+			EmptySince:       time.Now().Add(-huddleIdleTimeout),
+		}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanUpHuddles(s)
+	readStore(func(d *storeData) {
+		if len(d.Huddles) != 0 || len(d.Projects[channelID].VoiceChannels) != 0 {
+			t.Errorf("the huddle is still recorded: %v, %v", d.Huddles, d.Projects[channelID].VoiceChannels)
+		}
+		if len(d.Failures) != 0 {
+			t.Errorf("failures = %v, want none", d.Failures)
+		}
+	})
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A project found by importExistingChannels.
+type importedProject struct {
+	ChannelID  string `json:"channelId"`
+	Name       string `json:"name"`
+	Status     string `json:"status,omitempty"`
+	ClientName string `json:"clientName,omitempty"`
+	Template   string `json:"template,omitempty"`
+	Members    int    `json:"members"`
+	Internal   bool   `json:"internal,omitempty"`
+}
+
+// Work out what a project would have recorded about a channel, from the channel itself: its name and category, when
+// it was created, the members it's shared with, the client whose category it's in, and the template whose category
+// and roles it matches. A channel named archived-… is an archived project.
+func inferProject(s *discordgo.Session, c *discordgo.Channel) project {
+	p := project{
+		ChannelID:  c.ID,
+		Name:       strings.TrimPrefix(c.Name, "archived-"),
+		CategoryID: c.ParentID,
+		CreatedAt:  channelCreatedAt(c.ID),
+		Members:    make(map[string]string),
+	}
+	if p.Name != c.Name {
+		p.Status = statusArchived
+	}
+
+	shared := make(map[string]bool)
+	for _, o := range c.PermissionOverwrites {
+		if o.Allow&discordgo.PermissionViewChannel == 0 {
+			continue
+		}
+		if o.Type == discordgo.PermissionOverwriteTypeRole {
+			shared[o.ID] = true
+			continue
+		}
+		member, err := s.GuildMember(JuiceworksGuildId, o.ID)
+		if err != nil {
+			// They've left the server, so there's nobody to add.
+			log.Printf("Error reading member %s of channel %s: %v", o.ID, c.ID, err)
+			continue
+		}
+		p.Members[o.ID] = member.User.Username
+	}
+
+	readStore(func(d *storeData) {
+		for _, cl := range d.Clients {
+			if c.ParentID != "" && slices.Contains(cl.Categories, c.ParentID) {
+				p.ClientName = cl.Name
+			}
+		}
+		// The template sharing the most roles wins, and a template without a category or roles matches anything, so
+		// it's left out.
+		best := -1
+		for name, t := range d.ProjectTemplates {
+			if (t.CategoryID == "" && len(t.RoleIDs) == 0) || (t.CategoryID != "" && t.CategoryID != c.ParentID) {
+				continue
+			}
+			if !slices.ContainsFunc(t.RoleIDs, func(id string) bool { return !shared[id] }) && len(t.RoleIDs) > best {
+				best = len(t.RoleIDs)
+				p.Template = name
+				p.TemplateRoleIDs = slices.Clone(t.RoleIDs)
+				p.TopicTemplate = t.Topic
+			}
+		}
+	})
+	return p
+}
+
+// Seed the registry with the guild's existing project channels: staff-only text channels with names the bot would
+// have picked that it doesn't already know about. Channels named like a project's internal channel are linked to
+// it. With dryRun, nothing is recorded. Running it again only picks up channels it missed.
+func importExistingChannels(s *discordgo.Session, dryRun bool) ([]importedProject, error) {
+	channels, err := s.GuildChannels(JuiceworksGuildId)
+	if err != nil {
+		return nil, err
+	}
+	known := managedChannels()
+
+	var candidates []*discordgo.Channel
+	internal := make(map[string]string)
+	for _, c := range channels {
+		name := strings.TrimPrefix(c.Name, "archived-")
+		if c.Type != discordgo.ChannelTypeGuildText || c.ID == InternalChannelId || slices.Contains(known, c.ID) ||
+			sanitizeChannelName(name) != name || !staffOnly(c) {
+			continue
+		}
+		if base, ok := strings.CutSuffix(name, internalChannelSuffix); ok {
+			internal[base] = c.ID
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+
+	var imported []importedProject
+	for _, c := range candidates {
+		p := inferProject(s, c)
+		p.InternalChannelID = internal[p.Name]
+		imported = append(imported, importedProject{
+			ChannelID:  p.ChannelID,
+			Name:       p.Name,
+			Status:     p.Status,
+			ClientName: p.ClientName,
+			Template:   p.Template,
+			Members:    len(p.Members),
+			Internal:   p.InternalChannelID != "",
+		})
+		if dryRun {
+			continue
+		}
+		if err := updateProject(c.ID, func(stored *project) { *stored = p }); err != nil {
+			return imported, err
+		}
+	}
+	return imported, nil
+}
+
+// Import the guild's existing project channels into the registry, or show what would be imported.
+func importExistingCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	dryRun := false
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		dryRun = options[0].BoolValue()
+	}
+
+	// Looking up every member can take a while.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}))
+
+	imported, err := importExistingChannels(s, dryRun)
+	content := importContent(imported, dryRun)
+	if err != nil {
+		log.Printf("Error importing channels: %v", err)
+		content = "Error importing channels: " + describeError(err) + "\n" + content
+	}
+	if !dryRun && len(imported) > 0 {
+		log.Printf("%s imported %d existing channels.", i.Member.User, len(imported))
+		postAudit(s, auditEntry{
+			Action:  auditChannelAdopted,
+			ActorID: i.Member.User.ID,
+			Summary: fmt.Sprintf("<@%s> imported %d existing channels into the registry.", i.Member.User.ID, len(imported)),
+		})
+	}
+	content = truncate(content, 2000)
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+	}
+}
+
+// Describe the projects an import found.
+func importContent(imported []importedProject, dryRun bool) string {
+	if len(imported) == 0 {
+		return "There are no channels to import. Every project channel is already in the registry."
+	}
+	verb := "Imported"
+	if dryRun {
+		verb = "Would import"
+	}
+	lines := []string{fmt.Sprintf("%s %d channels:", verb, len(imported))}
+	for _, p := range imported {
+		line := fmt.Sprintf("- <#%s>: %d members", p.ChannelID, p.Members)
+		if p.ClientName != "" {
+			line += ", client " + p.ClientName
+		}
+		if p.Template != "" {
+			line += ", template " + p.Template
+		}
+		if p.Internal {
+			line += ", with its internal channel"
+		}
+		if p.Status != "" {
+			line += ", " + p.Status
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Import the guild's existing project channels over the API. ?dry-run=true only lists them.
+func apiImportExisting(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry-run") == "true"
+	imported, err := importExistingChannels(s, dryRun)
+	if err != nil {
+		recordFailure("importing existing channels", err)
+		http.Error(w, "could not import channels", http.StatusBadGateway)
+		return
+	}
+	if !dryRun && len(imported) > 0 {
+		log.Printf("Imported %d existing channels over the API.", len(imported))
+		postAudit(s, auditEntry{
+			Action:  auditChannelAdopted,
+			Summary: fmt.Sprintf("Imported %d existing channels into the registry over the API.", len(imported)),
+		})
+	}
+	if imported == nil {
+		imported = []importedProject{}
+	}
+	writeJSON(w, http.StatusOK, imported)
+}
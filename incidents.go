@@ -0,0 +1,477 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How quickly staff aim to respond to an escalation, unless ESCALATION_RESPONSE_TIME says otherwise.
+const defaultEscalationResponseTime = 30 * time.Minute
+
+// Something that went wrong and needs staff attention. IDs count up from 1.
+type incident struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	// The project the incident is about, if any.
+	ProjectID string    `json:"projectId,omitempty"`
+	OpenedBy  string    `json:"openedBy"`
+	OpenedAt  time.Time `json:"openedAt"`
+	// Who on staff took it on, and when.
+	AcknowledgedBy string    `json:"acknowledgedBy,omitempty"`
+	AcknowledgedAt time.Time `json:"acknowledgedAt,omitempty"`
+	// Whether someone was paged because nobody took the escalation in time.
+	SLABreached bool `json:"slaBreached,omitempty"`
+	// The private channel responders work in, while the incident is open.
+	ChannelID string `json:"channelId,omitempty"`
+	// What happened, in order: messages marked with 📌 in the incident channel, and the bot's own notes.
+	Timeline []timelineEntry `json:"timeline,omitempty"`
+	ClosedBy string          `json:"closedBy,omitempty"`
+	ClosedAt time.Time       `json:"closedAt,omitempty"`
+}
+
+// Something that happened during an incident.
+type timelineEntry struct {
+	At time.Time `json:"at"`
+	// The message the entry was made from, if any.
+	MessageID string `json:"messageId,omitempty"`
+	Author    string `json:"author"`
+	Content   string `json:"content"`
+}
+
+// How quickly staff aim to respond to an escalation.
+func escalationResponseTime() time.Duration {
+	if v := os.Getenv("ESCALATION_RESPONSE_TIME"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Ignoring malformed ESCALATION_RESPONSE_TIME %q", v)
+	}
+	return defaultEscalationResponseTime
+}
+
+// Format a duration for people, like 30 minutes or 2 hours.
+func formatWait(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		if d == time.Hour {
+			return "an hour"
+		}
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	}
+	return fmt.Sprintf("%d minutes", int(d.Minutes()))
+}
+
+// Open an incident and add it to the store, returning it with its ID filled in.
+func openIncident(inc incident) (incident, error) {
+	err := updateStore(func(d *storeData) {
+		inc.ID = len(d.Incidents) + 1
+		d.Incidents = append(d.Incidents, &inc)
+	})
+	return inc, err
+}
+
+// Open an escalation for a project, unless one is still waiting for staff to take it, which is returned instead.
+// Checking and opening happen under one lock, so clients escalating at the same time open a single incident.
+func openEscalation(inc incident) (opened incident, pending *incident, err error) {
+	err = updateStore(func(d *storeData) {
+		for _, existing := range d.Incidents {
+			if existing.ProjectID == inc.ProjectID && existing.AcknowledgedBy == "" && existing.ClosedAt.IsZero() {
+				c := *existing
+				pending = &c
+				return
+			}
+		}
+		inc.ID = len(d.Incidents) + 1
+		d.Incidents = append(d.Incidents, &inc)
+	})
+	return inc, pending, err
+}
+
+// Let clients page staff about something urgent in their project. Staff are pinged in the internal channel with a
+// button to take it on, and the client is told how soon to expect a response.
+func escalateCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	projectID := workspaceProjectID(i.ChannelID)
+	p, ok := getProject(projectID)
+	userID := i.Member.User.ID
+	reason := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+
+	var content string
+	ephemeral := true
+	switch {
+	case !ok:
+		content = "This channel is not a registered project."
+	case !slices.Contains(i.Member.Roles, JuiceworksRoleId) && p.Members[userID] == "":
+		content = "Only members of this project can escalate it."
+	default:
+		inc, pending, err := openEscalation(incident{
+			Title:     truncate(reason, 200),
+			ProjectID: projectID,
+			OpenedBy:  userID,
+			OpenedAt:  time.Now(),
+		})
+		if err != nil {
+			log.Printf("Error saving incident: %v", err)
+			content = "Error saving incident: " + describeError(err)
+			break
+		}
+		if pending != nil {
+			content = fmt.Sprintf("This project was already escalated <t:%d:R>, and staff will respond soon.", pending.OpenedAt.Unix())
+			break
+		}
+		log.Printf("%s escalated project %s as incident %d.", i.Member.User, projectID, inc.ID)
+
+		page := fmt.Sprintf("**Escalation #%d** from <@%s> in <#%s>.", inc.ID, userID, i.ChannelID)
+		allowed := &discordgo.MessageAllowedMentions{}
+		if roleID := os.Getenv("ESCALATION_ROLE_ID"); roleID != "" {
+			page = "<@&" + roleID + "> " + page
+			allowed.Roles = []string{roleID}
+		}
+		if onPointID, ok := onPoint(projectID); ok {
+			page += fmt.Sprintf(" <@%s> is on point.", onPointID)
+			allowed.Users = []string{onPointID}
+		}
+		page += "\n>>> " + reason
+		_, err = s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{
+			Content: page,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "Take it", Style: discordgo.PrimaryButton, CustomID: "escalation-ack:" + strconv.Itoa(inc.ID)},
+				}},
+			},
+			AllowedMentions: allowed,
+		})
+		if err != nil {
+			log.Printf("Error paging staff: %v", err)
+			content = "Error paging staff: " + describeError(err)
+			break
+		}
+		content = fmt.Sprintf("<@%s> escalated this to the Juiceworks team. Someone will respond within %s.",
+			userID, formatWait(escalationResponseTime()))
+		ephemeral = false
+	}
+
+	data := &discordgo.InteractionResponseData{Content: content, AllowedMentions: &discordgo.MessageAllowedMentions{}}
+	if ephemeral {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	}))
+}
+
+// Take on an escalation from its page in the internal channel, and let the client know someone's on it.
+func acknowledgeEscalation(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, id, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	n, _ := strconv.Atoi(id)
+	userID := i.Member.User.ID
+
+	var inc incident
+	var found, taken bool
+	err := updateStore(func(d *storeData) {
+		if n < 1 || n > len(d.Incidents) {
+			return
+		}
+		found = true
+		stored := d.Incidents[n-1]
+		if stored.AcknowledgedBy != "" {
+			taken = true
+		} else {
+			stored.AcknowledgedBy, stored.AcknowledgedAt = userID, time.Now()
+		}
+		inc = *stored
+	})
+
+	if err != nil || !found || taken {
+		content := fmt.Sprintf("<@%s> already took this <t:%d:R>.", inc.AcknowledgedBy, inc.AcknowledgedAt.Unix())
+		switch {
+		case err != nil:
+			log.Printf("Error saving incident: %v", err)
+			content = "Error saving incident: " + describeError(err)
+		case !found:
+			content = "That incident no longer exists."
+		}
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	log.Printf("%s acknowledged incident %d.", i.Member.User, inc.ID)
+	if inc.SLABreached {
+		go resolveAlert(alertSLABreach, fmt.Sprintf("escalation-%d", inc.ID))
+	}
+	_, err = s.ChannelMessageSendComplex(inc.ProjectID, &discordgo.MessageSend{
+		Content:         fmt.Sprintf("<@%s> from the Juiceworks team is looking into this now.", userID),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		recordFailure("announcing escalation response", err)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:         fmt.Sprintf("%s\n<@%s> took this <t:%d:R>.", i.Message.Content, userID, inc.AcknowledgedAt.Unix()),
+			Components:      []discordgo.MessageComponent{},
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}))
+}
+
+// Find the open incident with its channel at a channel ID. The returned copy is safe to use without holding the
+// store lock.
+func incidentInChannel(channelID string) (incident, bool) {
+	var inc incident
+	var ok bool
+	readStore(func(d *storeData) {
+		for _, stored := range d.Incidents {
+			if stored.ChannelID == channelID && stored.ClosedAt.IsZero() {
+				inc, ok = *stored, true
+				inc.Timeline = slices.Clone(stored.Timeline)
+			}
+		}
+	})
+	return inc, ok
+}
+
+// Open and close incident channels.
+func incidentCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	var content string
+	switch sub.Name {
+	case "open":
+		content = openIncidentChannel(s, i, options)
+	case "close":
+		inc, ok := incidentInChannel(i.ChannelID)
+		if !ok {
+			content = "This isn't an open incident's channel."
+			break
+		}
+		summary := ""
+		if o, ok := options["summary"]; ok {
+			summary = o.StringValue()
+		}
+		// Respond before the channel is deleted, since there's nowhere to respond after.
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: "Closing the incident and exporting its timeline…"},
+		}))
+		if err := closeIncident(s, inc, i.Member.User, summary); err != nil {
+			log.Printf("Error closing incident: %v", err)
+			content = "Error closing incident: " + describeError(err)
+			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+			logResponseErr(err)
+		}
+		return
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Make a private channel for an incident and let the responders in, returning the response to show the caller. The
+// incident can be new, or one opened by /escalate.
+func openIncidentChannel(s *discordgo.Session, i *discordgo.InteractionCreate, options map[string]*discordgo.ApplicationCommandInteractionDataOption) string {
+	user := i.Member.User
+	responders := []string{user.ID}
+	for _, name := range []string{"responder", "responder2", "responder3"} {
+		if o, ok := options[name]; ok && !slices.Contains(responders, o.UserValue(nil).ID) {
+			responders = append(responders, o.UserValue(nil).ID)
+		}
+	}
+
+	var inc incident
+	if o, ok := options["escalation"]; ok {
+		var problem string
+		readStore(func(d *storeData) {
+			n := int(o.IntValue())
+			switch {
+			case n < 1 || n > len(d.Incidents):
+				problem = fmt.Sprintf("There's no incident #%d.", n)
+			case d.Incidents[n-1].ChannelID != "":
+				problem = fmt.Sprintf("Incident #%d already has a channel.", n)
+			default:
+				inc = *d.Incidents[n-1]
+			}
+		})
+		if problem != "" {
+			return problem
+		}
+	} else {
+		o, ok := options["title"]
+		if !ok {
+			return "Give the incident a title, or the number of an escalation to open a channel for."
+		}
+		var err error
+		inc, err = openIncident(incident{Title: o.StringValue(), OpenedBy: user.ID, OpenedAt: time.Now()})
+		if err != nil {
+			log.Printf("Error saving incident: %v", err)
+			return "Error saving incident: " + describeError(err)
+		}
+	}
+
+	overwrites := []*discordgo.PermissionOverwrite{
+		{ID: JuiceworksGuildId, Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionViewChannel},
+	}
+	for _, userID := range responders {
+		overwrites = append(overwrites, &discordgo.PermissionOverwrite{
+			ID:    userID,
+			Type:  discordgo.PermissionOverwriteTypeMember,
+			Allow: discordgo.PermissionViewChannel | discordgo.PermissionSendMessages | discordgo.PermissionReadMessageHistory,
+		})
+	}
+	channel, err := s.GuildChannelCreateComplex(JuiceworksGuildId, discordgo.GuildChannelCreateData{
+		Name:                 fmt.Sprintf("incident-%d", inc.ID),
+		Type:                 discordgo.ChannelTypeGuildText,
+		Topic:                truncate(inc.Title, 1024),
+		ParentID:             os.Getenv("INCIDENT_CATEGORY_ID"),
+		PermissionOverwrites: overwrites,
+	})
+	if err != nil {
+		return channelErrorContent(s, "creating incident channel", err, "Manage Channels and Manage Roles", channelLocation(os.Getenv("INCIDENT_CATEGORY_ID")))
+	}
+
+	opened := timelineEntry{At: time.Now(), Author: user.Username, Content: "Opened the incident channel."}
+	err = updateStore(func(d *storeData) {
+		stored := d.Incidents[inc.ID-1]
+		stored.ChannelID = channel.ID
+		stored.Timeline = append(stored.Timeline, opened)
+	})
+	if err != nil {
+		log.Printf("Error saving incident: %v", err)
+		return "Error saving incident: " + describeError(err)
+	}
+	log.Printf("%s opened channel %s for incident %d.", user, channel.ID, inc.ID)
+
+	mentions := make([]string, len(responders))
+	for n, userID := range responders {
+		mentions[n] = "<@" + userID + ">"
+	}
+	intro := fmt.Sprintf("**Incident #%d: %s**\nResponders: %s\nReact with 📌 to add a message to the timeline. "+
+		"Run `/incident close` when it's resolved to export the timeline for the postmortem.", inc.ID, inc.Title, strings.Join(mentions, " "))
+	if inc.ProjectID != "" {
+		intro += fmt.Sprintf("\nEscalated from <#%s>.", inc.ProjectID)
+	}
+	_, err = s.ChannelMessageSendComplex(channel.ID, &discordgo.MessageSend{
+		Content:         intro,
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: responders},
+	})
+	if err != nil {
+		recordFailure("introducing incident channel", err)
+	}
+	return fmt.Sprintf("Opened <#%s> for incident #%d.", channel.ID, inc.ID)
+}
+
+// Add messages marked with 📌 in an incident channel to the incident's timeline.
+func addToTimeline(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if r.GuildID != JuiceworksGuildId || r.UserID == s.State.User.ID || r.Emoji.Name != "📌" {
+		return
+	}
+	inc, ok := incidentInChannel(r.ChannelID)
+	if !ok || slices.ContainsFunc(inc.Timeline, func(e timelineEntry) bool { return e.MessageID == r.MessageID }) {
+		return
+	}
+	m, err := s.ChannelMessage(r.ChannelID, r.MessageID)
+	if err != nil {
+		log.Printf("Error fetching timeline message: %v", err)
+		return
+	}
+	content := m.Content
+	for _, a := range m.Attachments {
+		content += "\n" + a.URL
+	}
+	entry := timelineEntry{At: m.Timestamp, MessageID: m.ID, Author: m.Author.Username, Content: strings.TrimSpace(content)}
+
+	err = updateStore(func(d *storeData) {
+		stored := d.Incidents[inc.ID-1]
+		if slices.ContainsFunc(stored.Timeline, func(e timelineEntry) bool { return e.MessageID == entry.MessageID }) {
+			return
+		}
+		stored.Timeline = append(stored.Timeline, entry)
+		slices.SortStableFunc(stored.Timeline, func(a, b timelineEntry) int { return a.At.Compare(b.At) })
+	})
+	if err != nil {
+		log.Printf("Error saving timeline: %v", err)
+		return
+	}
+	log.Printf("Added message %s to the timeline of incident %d.", m.ID, inc.ID)
+}
+
+// Write out an incident's timeline as Markdown, to start the postmortem from.
+func incidentExport(inc incident) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Incident #%d: %s\n\n", inc.ID, inc.Title)
+	fmt.Fprintf(&b, "- Opened: %s\n", inc.OpenedAt.UTC().Format(time.RFC1123))
+	if !inc.AcknowledgedAt.IsZero() {
+		fmt.Fprintf(&b, "- Acknowledged: %s\n", inc.AcknowledgedAt.UTC().Format(time.RFC1123))
+	}
+	fmt.Fprintf(&b, "- Closed: %s\n", inc.ClosedAt.UTC().Format(time.RFC1123))
+	fmt.Fprintf(&b, "- Duration: %s\n\n## Timeline\n\n", inc.ClosedAt.Sub(inc.OpenedAt).Round(time.Minute))
+	for _, e := range inc.Timeline {
+		fmt.Fprintf(&b, "- **%s** %s: %s\n", e.At.UTC().Format("2006-01-02 15:04 MST"), e.Author,
+			strings.ReplaceAll(e.Content, "\n", "\n  "))
+	}
+	return b.String()
+}
+
+// Close an incident: post its timeline to the internal channel for the postmortem, then delete its channel.
+func closeIncident(s *discordgo.Session, inc incident, user *discordgo.User, summary string) error {
+	now := time.Now()
+	closing := timelineEntry{At: now, Author: user.Username, Content: "Closed the incident."}
+	if summary != "" {
+		closing.Content += " " + summary
+	}
+	inc.Timeline = append(inc.Timeline, closing)
+	inc.ClosedBy, inc.ClosedAt = user.ID, now
+
+	_, err := s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{
+		Content: fmt.Sprintf("Incident #%d (%s) was closed by <@%s> after %s. Here's the timeline for the postmortem.",
+			inc.ID, inc.Title, user.ID, now.Sub(inc.OpenedAt).Round(time.Minute)),
+		Files: []*discordgo.File{{
+			Name:        fmt.Sprintf("incident-%d.md", inc.ID),
+			ContentType: "text/markdown",
+			Reader:      strings.NewReader(incidentExport(inc)),
+		}},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = updateStore(func(d *storeData) {
+		stored := d.Incidents[inc.ID-1]
+		stored.Timeline = append(stored.Timeline, closing)
+		stored.ClosedBy, stored.ClosedAt = inc.ClosedBy, inc.ClosedAt
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("%s closed incident %d.", user, inc.ID)
+
+	if _, err := s.ChannelDelete(inc.ChannelID); err != nil {
+		recordFailure("deleting incident channel", err)
+	}
+	return nil
+}
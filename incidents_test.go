@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Escalations and their SLA breaches go to whoever is on point for the project, not just the escalation role.
+func TestEscalationPingsOnPoint(t *testing.T) {
+	f, s := newTestBot(t)
+	t.Setenv("ESCALATION_RESPONSE_TIME", "1ns")
+	staff := addStaff(f)
+	client := f.addMember("3000000000000000001", "client")
+	channelID := addProject(t, f, "acme")
+	err := updateProject(channelID, func(p *project) {
+		p.Members = map[string]string{client.User.ID: client.User.Username}
+		p.Rotation = &rotation{UserIDs: []string{staff.User.ID}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handleInteraction(s, commandInteraction(channelID, client, "escalate", stringOption("reason", "The site is down")))
+	checkEscalationSLAs(s)
+
+	posted := f.posted(InternalChannelId)
+	if len(posted) != 2 {
+		t.Fatalf("posted %q in the internal channel, want the escalation and its SLA breach", posted)
+	}
+	for _, content := range posted {
+		if !strings.Contains(content, "<@"+staff.User.ID+"> is on point.") {
+			t.Errorf("%q doesn't ping who's on point", content)
+		}
+	}
+	requests, _ := f.history()
+	for _, r := range requests {
+		if r.Path == fakeAPIPrefix+"channels/"+InternalChannelId+"/messages" && !strings.Contains(string(r.Body), `"users":["`+staff.User.ID+`"]`) {
+			t.Errorf("the mention of who's on point isn't allowed: %s", r.Body)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A service the bot talks to, with whether it's set up and a cheap call that shows whether it can be reached. check
+// is nil when there's no call that doesn't have side effects.
+type integration struct {
+	name       string
+	configured func() bool
+	check      func() error
+}
+
+// The integrations shown by /integrations status, in the order they're listed.
+var integrations = []integration{
+	{
+		name: "Airtable",
+		configured: func() bool {
+			return os.Getenv("AIRTABLE_TOKEN") != "" && os.Getenv("AIRTABLE_BASE_ID") != "" && os.Getenv("AIRTABLE_TABLE") != ""
+		},
+		check: func() error {
+			return checkEndpoint("Airtable", "https://api.airtable.com/v0/meta/whoami", func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+os.Getenv("AIRTABLE_TOKEN"))
+			})
+		},
+	},
+	{
+		name: "Dropbox Sign",
+		configured: func() bool {
+			return os.Getenv("DROPBOX_SIGN_API_KEY") != "" && os.Getenv("DROPBOX_SIGN_TEMPLATE_ID") != ""
+		},
+		check: func() error {
+			return checkEndpoint("Dropbox Sign", "https://api.hellosign.com/v3/account", func(req *http.Request) {
+				req.SetBasicAuth(os.Getenv("DROPBOX_SIGN_API_KEY"), "")
+			})
+		},
+	},
+	{
+		name: "Email",
+		configured: func() bool {
+			if os.Getenv("EMAIL_PROVIDER") == "smtp" {
+				return os.Getenv("SMTP_ADDR") != ""
+			}
+			return os.Getenv("MAILGUN_API_KEY") != "" && os.Getenv("EMAIL_DOMAIN") != ""
+		},
+		check: func() error {
+			if os.Getenv("EMAIL_PROVIDER") == "smtp" {
+				return checkSMTP()
+			}
+			return checkEndpoint("Email", "https://api.mailgun.net/v3/domains/"+url.PathEscape(os.Getenv("EMAIL_DOMAIN")), func(req *http.Request) {
+				req.SetBasicAuth("api", os.Getenv("MAILGUN_API_KEY"))
+			})
+		},
+	},
+	{
+		name:       "Figma",
+		configured: func() bool { return os.Getenv("FIGMA_TOKEN") != "" },
+		check: func() error {
+			return checkEndpoint("Figma", "https://api.figma.com/v1/me", func(req *http.Request) {
+				req.Header.Set("X-Figma-Token", os.Getenv("FIGMA_TOKEN"))
+			})
+		},
+	},
+	{
+		name: "Google Calendar",
+		configured: func() bool {
+			return os.Getenv("GOOGLE_CALENDAR_ID") != "" && os.Getenv("GOOGLE_API_KEY") != ""
+		},
+		check: func() error {
+			_, err := upcomingEvents("", 1)
+			return err
+		},
+	},
+	{
+		name:       "HubSpot",
+		configured: func() bool { return os.Getenv("HUBSPOT_TOKEN") != "" },
+		check: func() error {
+			var out any
+			return hubspotAPI("", "/crm/v3/objects/deals?limit=1", &out)
+		},
+	},
+	{
+		name: "Matrix",
+		configured: func() bool {
+			return os.Getenv("MATRIX_HOMESERVER") != "" && os.Getenv("MATRIX_ACCESS_TOKEN") != ""
+		},
+		check: func() error {
+			return matrixRequest(http.MethodGet, "/_matrix/client/v3/account/whoami", nil, nil)
+		},
+	},
+	{
+		// The Events API has no call that doesn't page someone or resolve an alert, so there's no test.
+		name:       "PagerDuty",
+		configured: func() bool { return len(pagerDutyRoutingKeys()) > 0 },
+	},
+	{
+		name:       "Project secrets",
+		configured: func() bool { return os.Getenv("SECRETS_KMS_KEY_ID") != "" },
+		check: func() error {
+			_, err := awsJSONRequest(&http.Client{Timeout: 10 * time.Second}, "kms", "TrentService.DescribeKey",
+				map[string]string{"KeyId": os.Getenv("SECRETS_KMS_KEY_ID")})
+			return err
+		},
+	},
+	{
+		name:       "S3 storage",
+		configured: s3Configured,
+		check:      s3CheckBucket,
+	},
+	{
+		name:       "Secret manager",
+		configured: func() bool { return secretsProvider() != secretsEnv },
+		check: func() error {
+			_, err := fetchSecrets()
+			return err
+		},
+	},
+	{
+		name:       "Slack",
+		configured: func() bool { return os.Getenv("SLACK_BOT_TOKEN") != "" },
+		check: func() error {
+			return slackAPI("", "auth.test", url.Values{}, nil)
+		},
+	},
+}
+
+// How calls to an integration have gone since the bot started.
+type integrationHealth struct {
+	calls       int
+	errors      int
+	lastSuccess time.Time
+	lastError   time.Time
+	lastErrMsg  string
+}
+
+// Health of each integration by name. It only lives in memory, so it starts over when the bot restarts.
+var (
+	integrationHealthMu sync.Mutex
+	integrationHealths  = make(map[string]*integrationHealth)
+)
+
+// Record how a call to an integration went. The helpers that call each integration's API do this, so tests through
+// the Test button count too.
+func noteIntegrationCall(name string, err error) {
+	integrationHealthMu.Lock()
+	defer integrationHealthMu.Unlock()
+	h, ok := integrationHealths[name]
+	if !ok {
+		h = &integrationHealth{}
+		integrationHealths[name] = h
+	}
+	h.calls++
+	if err != nil {
+		h.errors++
+		h.lastError = time.Now()
+		h.lastErrMsg = err.Error()
+		return
+	}
+	h.lastSuccess = time.Now()
+}
+
+// GET an integration's endpoint, with auth adding its credentials, succeeding if it answers 200.
+func checkEndpoint(name, endpoint string, auth func(*http.Request)) (err error) {
+	defer func() { noteIntegrationCall(name, err) }()
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	auth(req)
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+// Connect to the SMTP server in SMTP_ADDR and hang up.
+func checkSMTP() (err error) {
+	defer func() { noteIntegrationCall("Email", err) }()
+	c, err := smtp.Dial(os.Getenv("SMTP_ADDR"))
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Show each configured integration with how calls to it have gone since the bot started, and a button to test it.
+func integrationsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var lines, unconfigured []string
+	var buttons []discordgo.MessageComponent
+	integrationHealthMu.Lock()
+	for _, in := range integrations {
+		if !in.configured() {
+			unconfigured = append(unconfigured, in.name)
+			continue
+		}
+		lines = append(lines, integrationStatusLine(in.name, integrationHealths[in.name]))
+		if in.check != nil {
+			buttons = append(buttons, discordgo.Button{
+				Label:    "Test " + in.name,
+				Style:    discordgo.SecondaryButton,
+				CustomID: "integration-test:" + in.name,
+			})
+		}
+	}
+	integrationHealthMu.Unlock()
+
+	content := "No integrations are set up."
+	if len(lines) > 0 {
+		content = "**Integrations**, since the bot started:\n" + strings.Join(lines, "\n")
+	}
+	if len(unconfigured) > 0 {
+		content += "\nNot set up: " + strings.Join(unconfigured, ", ") + "."
+	}
+
+	// Buttons go five to a row, and a message can have five rows.
+	var rows []discordgo.MessageComponent
+	for len(buttons) > 0 && len(rows) < 5 {
+		n := min(len(buttons), 5)
+		rows = append(rows, discordgo.ActionsRow{Components: buttons[:n]})
+		buttons = buttons[n:]
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    truncate(content, 2000),
+			Flags:      discordgo.MessageFlagsEphemeral,
+			Components: rows,
+		},
+	}))
+}
+
+// A line of /integrations status for one integration. The caller holds integrationHealthMu.
+func integrationStatusLine(name string, h *integrationHealth) string {
+	if h == nil || h.calls == 0 {
+		return fmt.Sprintf("⚪ **%s**: no calls yet", name)
+	}
+	status := "🟢"
+	if h.lastError.After(h.lastSuccess) {
+		status = "🔴"
+	}
+	line := fmt.Sprintf("%s **%s**: %d calls, %d errors", status, name, h.calls, h.errors)
+	if !h.lastSuccess.IsZero() {
+		line += fmt.Sprintf(", last success <t:%d:R>", h.lastSuccess.Unix())
+	}
+	if !h.lastError.IsZero() {
+		line += fmt.Sprintf(", last error <t:%d:R>: %s", h.lastError.Unix(), truncate(strings.TrimSpace(h.lastErrMsg), 200))
+	}
+	return line
+}
+
+// Run an integration's connectivity check from its Test button.
+func testIntegration(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, name, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	var in *integration
+	for n := range integrations {
+		if integrations[n].name == name {
+			in = &integrations[n]
+		}
+	}
+	if in == nil || in.check == nil {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "There's no test for " + name + ".",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	// Some checks take a while to time out, so defer the response.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}))
+	err := in.check()
+	content := fmt.Sprintf("🟢 %s is reachable.", in.name)
+	if err != nil {
+		log.Printf("Error testing %s: %v", in.name, err)
+		content = fmt.Sprintf("🔴 Error testing %s: %s", in.name, err.Error())
+	}
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+	}
+}
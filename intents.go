@@ -0,0 +1,203 @@
+package main
+
+import (
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A feature that runs on gateway events, with the intents it needs them delivered with and its event handlers. The
+// bot only asks for the intents of features that are on, so it isn't sent events nobody handles, and the privileged
+// intents Discord reviews are only requested by the features that use them.
+type gatewayFeature struct {
+	// The name DISABLED_FEATURES turns the feature off with. Features without one are always on.
+	name        string
+	description string
+	intents     discordgo.Intent
+	handlers    []any
+	// Whether the feature is set up, for features that can't do anything without configuration. Nil means it
+	// doesn't need any, since it's set up with commands.
+	configured func() bool
+}
+
+// Every feature that needs gateway events. Add to it, rather than calling s.AddHandler in main, when a feature starts
+// handling a new event.
+var gatewayFeatures = []gatewayFeature{
+	{
+		description: "Channels, roles and client categories",
+		intents:     discordgo.IntentGuilds,
+		handlers:    []any{cleanUpClientCategoriesOnUpdate, cleanUpClientCategoriesOnDelete},
+	},
+	{
+		// Huddles are cleaned up once nobody is in them, going by the voice states in the state cache.
+		description: "Huddles",
+		intents:     discordgo.IntentGuildVoiceStates,
+	},
+	{
+		name:        "bridges",
+		description: "Relaying messages to bridged Slack and Matrix channels",
+		intents:     discordgo.IntentGuildMessages | discordgo.IntentMessageContent,
+		handlers:    []any{relayToBridge},
+		configured: func() bool {
+			return os.Getenv("SLACK_BOT_TOKEN") != "" ||
+				os.Getenv("MATRIX_HOMESERVER") != "" && os.Getenv("MATRIX_ACCESS_TOKEN") != ""
+		},
+	},
+	{
+		name:        "email",
+		description: "Relaying messages to email threads",
+		intents:     discordgo.IntentGuildMessages | discordgo.IntentMessageContent,
+		handlers:    []any{relayToEmail},
+		configured:  func() bool { return os.Getenv("EMAIL_DOMAIN") != "" },
+	},
+	{
+		// Only who posted where is needed, not what they said.
+		name:        "auto-reply",
+		description: "Replying to clients outside business hours",
+		intents:     discordgo.IntentGuildMessages,
+		handlers:    []any{autoReply},
+	},
+	{
+		// Attachments are message content as far as Discord is concerned.
+		name:        "attachment-archive",
+		description: "Keeping copies of files posted in project channels",
+		intents:     discordgo.IntentGuildMessages | discordgo.IntentMessageContent,
+		handlers:    []any{archiveAttachments},
+		configured:  s3Configured,
+	},
+	{
+		name:        "screening",
+		description: "Screening new members",
+		intents:     discordgo.IntentGuildMembers,
+		handlers:    []any{screenNewMember},
+		configured:  func() bool { return os.Getenv("SCREENING_ROLE_ID") != "" },
+	},
+	{
+		name:        "reaction-roles",
+		description: "Granting and removing reaction roles",
+		intents:     discordgo.IntentGuildMessageReactions,
+		handlers:    []any{grantReactionRole, revokeReactionRole},
+	},
+	{
+		// Only projects that enforce nicknames need to hear about nickname changes, so GUILD_MEMBERS isn't asked for
+		// until one does.
+		name:        "nicknames",
+		description: "Enforcing client nicknames",
+		intents:     discordgo.IntentGuildMembers,
+		handlers:    []any{enforceClientNickname},
+		configured:  nicknamesEnforced,
+	},
+	{
+		name:        "calls",
+		description: "Logging time spent in project voice channels",
+		intents:     discordgo.IntentGuildVoiceStates,
+		handlers:    []any{logVoiceState},
+	},
+	{
+		name:        "townhalls",
+		description: "Taking town hall attendance",
+		intents:     discordgo.IntentGuildVoiceStates,
+		handlers:    []any{trackTownhallAttendance},
+	},
+	{
+		name:        "incident-timelines",
+		description: "Adding messages marked with 📌 to incident timelines",
+		intents:     discordgo.IntentGuildMessageReactions,
+		handlers:    []any{addToTimeline},
+	},
+}
+
+// The names Discord shows for intents. The privileged ones have to be turned on in the Developer Portal, and for bots
+// in 100 or more guilds, approved by Discord.
+var intentNames = []struct {
+	intent     discordgo.Intent
+	name       string
+	privileged bool
+}{
+	{discordgo.IntentGuilds, "GUILDS", false},
+	{discordgo.IntentGuildMembers, "GUILD_MEMBERS", true},
+	{discordgo.IntentGuildVoiceStates, "GUILD_VOICE_STATES", false},
+	{discordgo.IntentGuildMessages, "GUILD_MESSAGES", false},
+	{discordgo.IntentGuildMessageReactions, "GUILD_MESSAGE_REACTIONS", false},
+	{discordgo.IntentMessageContent, "MESSAGE_CONTENT", true},
+}
+
+// The features turned off with DISABLED_FEATURES, a comma-separated list of names from gatewayFeatures.
+func disabledFeatures() []string {
+	var names []string
+	for _, name := range strings.Split(os.Getenv("DISABLED_FEATURES"), ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Whether a gateway feature is on: set up, and not turned off.
+func (f gatewayFeature) enabled() bool {
+	if f.name != "" && slices.Contains(disabledFeatures(), f.name) {
+		return false
+	}
+	return f.configured == nil || f.configured()
+}
+
+// The intents every feature that's on needs.
+func gatewayIntents() discordgo.Intent {
+	var intents discordgo.Intent
+	for _, f := range gatewayFeatures {
+		if f.enabled() {
+			intents |= f.intents
+		}
+	}
+	return intents
+}
+
+// Describe intents by name, marking the privileged ones.
+func describeIntents(intents discordgo.Intent) []string {
+	var names []string
+	for _, n := range intentNames {
+		if intents&n.intent == 0 {
+			continue
+		}
+		if n.privileged {
+			names = append(names, n.name+" (privileged)")
+		} else {
+			names = append(names, n.name)
+		}
+	}
+	return names
+}
+
+// The names of the features that were on when the bot connected. Features that are set up later only start once the
+// bot is restarted, since the intents are fixed for the life of the gateway connection.
+var runningFeatures = make(map[string]bool)
+
+// Ask for the intents of the features that are on, and add their event handlers. Features that are off don't get
+// their handlers added, since the events they handle may not arrive.
+func configureGateway(s *discordgo.Session) {
+	for _, name := range disabledFeatures() {
+		if !slices.ContainsFunc(gatewayFeatures, func(f gatewayFeature) bool { return f.name == name }) {
+			log.Printf("DISABLED_FEATURES names %q, which isn't a feature that can be turned off.", name)
+		}
+	}
+
+	s.Identify.Intents = gatewayIntents()
+	var off []string
+	for _, f := range gatewayFeatures {
+		if !f.enabled() {
+			off = append(off, f.description)
+			continue
+		}
+		runningFeatures[f.name] = true
+		for _, h := range f.handlers {
+			s.AddHandler(h)
+		}
+	}
+	log.Printf("Gateway intents: %s", strings.Join(describeIntents(s.Identify.Intents), ", "))
+	if len(off) > 0 {
+		log.Printf("Turned off or not set up: %s", strings.Join(off, "; "))
+	}
+}
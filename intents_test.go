@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// GUILD_MEMBERS is only asked for nicknames once a project enforces them.
+func TestNicknameIntent(t *testing.T) {
+	f, _ := newTestBot(t)
+	channelID := addProject(t, f, "acme")
+	if gatewayIntents()&discordgo.IntentGuildMembers != 0 {
+		t.Error("GUILD_MEMBERS was asked for with no project enforcing nicknames")
+	}
+	if err := updateProject(channelID, func(p *project) { p.NicknameMode = nicknamesEnforce }); err != nil {
+		t.Fatal(err)
+	}
+	if gatewayIntents()&discordgo.IntentGuildMembers == 0 {
+		t.Error("GUILD_MEMBERS wasn't asked for with a project enforcing nicknames")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How the bot can receive interactions, set with INTERACTIONS_MODE. Over the gateway, the interactions endpoint is
+// still served if it's configured, so switching the application's Interactions Endpoint URL back and forth needs no
+// restart. In HTTP mode the bot never connects to the gateway, so anything driven by gateway events, like bridges,
+// screening and reaction roles, is off.
+const (
+	interactionsGateway = "gateway"
+	interactionsHTTP    = "http"
+)
+
+// How the bot receives interactions. Defaults to the gateway.
+func interactionsMode() string {
+	if os.Getenv("INTERACTIONS_MODE") == interactionsHTTP {
+		return interactionsHTTP
+	}
+	return interactionsGateway
+}
+
+// Get ready to serve interactions over HTTP without connecting to the gateway. The bot's own user normally comes
+// from the gateway, so it's looked up over REST instead.
+func startWithoutGateway(s *discordgo.Session) error {
+	if os.Getenv("HTTP_ADDR") == "" || os.Getenv("DISCORD_PUBLIC_KEY") == "" {
+		return errors.New("HTTP_ADDR and DISCORD_PUBLIC_KEY must be set to receive interactions over HTTP")
+	}
+	u, err := s.User("@me")
+	if err != nil {
+		return err
+	}
+	s.State.User = u
+	log.Printf("Logged in as: %s, serving interactions over HTTP only\n", u)
+	return nil
+}
+
+// Call the appropriate command or component handler for an interaction, whether it came over the gateway or to the
+// interactions endpoint.
+func handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	received := time.Now()
+	var name string
+	var h func(s *discordgo.Session, i *discordgo.InteractionCreate)
+	var policies map[string]policy
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		name = resolveCommand(baseCommandName(i.ApplicationCommandData().Name))
+		h, policies = commandHandlers[name], commandPolicies
+	case discordgo.InteractionMessageComponent:
+		name, _, _ = strings.Cut(i.MessageComponentData().CustomID, ":")
+		h, policies = componentHandlers[name], componentPolicies
+	case discordgo.InteractionModalSubmit:
+		name, _, _ = strings.Cut(i.ModalSubmitData().CustomID, ":")
+		h, policies = componentHandlers[name], componentPolicies
+	}
+	if h == nil || !authorize(s, i, name, policies) || !checkMaintenance(s, i, name) {
+		return
+	}
+	if i.Type == discordgo.InteractionApplicationCommand && !checkCooldown(s, i, name) {
+		return
+	}
+	if i.Type == discordgo.InteractionApplicationCommand {
+		startInteractionTimer(s, i, name, received)
+	}
+	h(s, i)
+}
+
+// Receive interactions from Discord over HTTP, for when the application's Interactions Endpoint URL points at the
+// bot. Unlike the gateway, this keeps working behind a load balancer while the gateway reconnects. Requests are
+// verified against DISCORD_PUBLIC_KEY. Handlers respond through the interaction callback endpoint just as they do
+// for gateway interactions, so the request itself gets an empty reply once the handler is done.
+func interactionsWebhook(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	key, err := hex.DecodeString(os.Getenv("DISCORD_PUBLIC_KEY"))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		http.Error(w, "interactions endpoint not configured", http.StatusNotFound)
+		return
+	}
+	if !discordgo.VerifyInteraction(r, ed25519.PublicKey(key)) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "could not read body", http.StatusBadRequest)
+		return
+	}
+	var interaction discordgo.Interaction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		log.Printf("Error decoding interaction: %v", err)
+		http.Error(w, "invalid interaction", http.StatusBadRequest)
+		return
+	}
+
+	// Discord pings the endpoint when it's configured, and won't save it unless the bot pongs back.
+	if interaction.Type == discordgo.InteractionPing {
+		writeJSON(w, http.StatusOK, discordgo.InteractionResponse{Type: discordgo.InteractionResponsePong})
+		return
+	}
+
+	handleInteraction(s, &discordgo.InteractionCreate{Interaction: &interaction})
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The kinds of background job /jobs knows about.
+const (
+	jobReminder            = "reminder"
+	jobAirtableSync        = "airtable-sync"
+	jobCalendarSync        = "calendar-sync"
+	jobPermissionMigration = "permission-migration"
+	jobHookDelivery        = "hook-delivery"
+	jobBridgeRelay         = "bridge-relay"
+)
+
+// The most failed jobs kept. The oldest are dropped past this.
+const maxFailedJobs = 1000
+
+// Background work that failed, kept so it can be retried with /jobs retry once whatever broke is fixed.
+type job struct {
+	ID          int    `json:"id"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	// What the job's runner needs to try it again.
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	Error    string          `json:"error"`
+	Attempts int             `json:"attempts"`
+	FailedAt time.Time       `json:"failedAt"`
+}
+
+// Background work in progress that can be cancelled with /jobs cancel.
+type runningJob struct {
+	kind        string
+	description string
+	startedBy   string
+	startedAt   time.Time
+	progress    string
+	cancel      func()
+}
+
+// Running jobs, keyed by job ID. These only live in memory, since a restart stops them anyway.
+var (
+	runningJobsMu sync.Mutex
+	runningJobs   = make(map[int]*runningJob)
+)
+
+// How to try each kind of failed job again, from its payload. Kinds without a runner can only be dropped.
+var jobRunners = map[string]func(s *discordgo.Session, payload json.RawMessage) error{
+	jobReminder: func(s *discordgo.Session, payload json.RawMessage) error {
+		var r reminder
+		if err := json.Unmarshal(payload, &r); err != nil {
+			return err
+		}
+		return deliverReminder(s, r)
+	},
+	jobAirtableSync: func(s *discordgo.Session, payload json.RawMessage) error {
+		var channelID string
+		if err := json.Unmarshal(payload, &channelID); err != nil {
+			return err
+		}
+		defer lockProjectSync(channelID)()
+		p, ok := getProject(channelID)
+		if !ok {
+			// The project's gone, so there's nothing left to sync.
+			return nil
+		}
+		return syncProjectToAirtable(&p)
+	},
+	jobCalendarSync: func(s *discordgo.Session, payload json.RawMessage) error {
+		var channelID string
+		if err := json.Unmarshal(payload, &channelID); err != nil {
+			return err
+		}
+		defer lockProjectSync(channelID)()
+		p, ok := getProject(channelID)
+		if !ok {
+			return nil
+		}
+		return syncProjectToCalendar(&p)
+	},
+	jobPermissionMigration: retryPermissionMigration,
+	jobHookDelivery: func(s *discordgo.Session, payload json.RawMessage) error {
+		var h hookDelivery
+		if err := json.Unmarshal(payload, &h); err != nil {
+			return err
+		}
+		var sub hookSubscription
+		ok := false
+		readStore(func(d *storeData) {
+			if found, exists := d.HookSubscriptions[h.SubscriptionID]; exists {
+				sub, ok = *found, true
+			}
+		})
+		if !ok {
+			// The subscriber unsubscribed, so it doesn't want the event any more.
+			return nil
+		}
+		return postEvent(sub, h.Body)
+	},
+	jobBridgeRelay: func(s *discordgo.Session, payload json.RawMessage) error {
+		var r bridgeRelay
+		if err := json.Unmarshal(payload, &r); err != nil {
+			return err
+		}
+		b, ok := bridges[r.Platform]
+		if !ok {
+			return userErrorf(errCodeJobTargetGone, "there's no %s bridge any more", r.Platform)
+		}
+		return b.send(r.Room, &bridgeMessage{channelID: r.ChannelID, author: r.Author, avatarURL: r.AvatarURL, text: r.Text})
+	},
+}
+
+// Keep a failed background job for /jobs. A job of the same kind with the same payload, like a project that keeps
+// failing to sync, is updated instead of added again.
+func recordFailedJob(kind, description string, payload any, jobErr error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error encoding job: %v", err)
+		return
+	}
+	err = updateStore(func(d *storeData) {
+		if n := slices.IndexFunc(d.Jobs, func(j *job) bool { return j.Kind == kind && bytes.Equal(j.Payload, body) }); n >= 0 {
+			d.Jobs[n].Error = jobErr.Error()
+			d.Jobs[n].Attempts++
+			d.Jobs[n].FailedAt = time.Now()
+			return
+		}
+		d.LastJobID++
+		d.Jobs = append(d.Jobs, &job{
+			ID:          d.LastJobID,
+			Kind:        kind,
+			Description: description,
+			Payload:     body,
+			Error:       jobErr.Error(),
+			Attempts:    1,
+			FailedAt:    time.Now(),
+		})
+		if len(d.Jobs) > maxFailedJobs {
+			d.Jobs = slices.Delete(d.Jobs, 0, len(d.Jobs)-maxFailedJobs)
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving failed job: %v", err)
+	}
+}
+
+// Register a job that's starting, returning its ID for updating its progress and ending it.
+func startJob(kind, description, userID string, cancel func()) int {
+	var id int
+	err := updateStore(func(d *storeData) {
+		d.LastJobID++
+		id = d.LastJobID
+	})
+	if err != nil {
+		log.Printf("Error saving job ID: %v", err)
+	}
+	runningJobsMu.Lock()
+	runningJobs[id] = &runningJob{kind: kind, description: description, startedBy: userID, startedAt: time.Now(), cancel: cancel}
+	runningJobsMu.Unlock()
+	return id
+}
+
+// Note how far a running job has got, for /jobs list.
+func updateJobProgress(id int, progress string) {
+	runningJobsMu.Lock()
+	defer runningJobsMu.Unlock()
+	if j, ok := runningJobs[id]; ok {
+		j.progress = progress
+	}
+}
+
+// Stop listing a job as running.
+func endJob(id int) {
+	runningJobsMu.Lock()
+	delete(runningJobs, id)
+	runningJobsMu.Unlock()
+}
+
+// List running and failed background jobs, show or retry a failed one, replay every failed job of a kind, or cancel a
+// running one or drop a failed one.
+func jobsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	var id int
+	if len(sub.Options) > 0 && sub.Options[0].Type == discordgo.ApplicationCommandOptionInteger {
+		id = int(sub.Options[0].IntValue())
+	}
+
+	var content string
+	switch sub.Name {
+	case "list":
+		content = jobsList()
+	case "retry":
+		// Retrying can take as long as the job did.
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+		}))
+		content = retryJob(s, id, i.Member.User)
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			log.Printf("Error responding to interaction: %v", err)
+		}
+		return
+	case "show":
+		content = showJob(id)
+	case "replay":
+		// Replaying can take a while when a lot failed.
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+		}))
+		content = replayJobs(s, sub.Options[0].StringValue(), i.Member.User)
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			log.Printf("Error responding to interaction: %v", err)
+		}
+		return
+	case "cancel":
+		content = cancelJob(id, i.Member.User)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Describe the running jobs, then the failed ones, newest first.
+func jobsList() string {
+	var lines []string
+	runningJobsMu.Lock()
+	ids := make([]int, 0, len(runningJobs))
+	for id := range runningJobs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		j := runningJobs[id]
+		line := fmt.Sprintf("- **#%d** %s, running since <t:%d:R> for <@%s>: %s", id, j.kind, j.startedAt.Unix(), j.startedBy, j.description)
+		if j.progress != "" {
+			line += " (" + j.progress + ")"
+		}
+		lines = append(lines, line)
+	}
+	runningJobsMu.Unlock()
+
+	readStore(func(d *storeData) {
+		for n := len(d.Jobs) - 1; n >= 0; n-- {
+			j := d.Jobs[n]
+			lines = append(lines, fmt.Sprintf("- **#%d** %s, failed <t:%d:R> after %d attempts: %s\n  `%s`",
+				j.ID, j.Kind, j.FailedAt.Unix(), j.Attempts, j.Description, truncate(strings.TrimSpace(j.Error), 200)))
+		}
+	})
+	if len(lines) == 0 {
+		return "There are no running or failed jobs."
+	}
+	content := strings.Join(lines, "\n")
+	if len(content) > 2000 {
+		content = truncate(content, 1990) + "\n…"
+	}
+	return content
+}
+
+// Run a failed job again, dropping it if it works. Returns the response to show the caller.
+func retryJob(s *discordgo.Session, id int, user *discordgo.User) string {
+	var j job
+	found := false
+	readStore(func(d *storeData) {
+		if n := slices.IndexFunc(d.Jobs, func(j *job) bool { return j.ID == id }); n >= 0 {
+			j, found = *d.Jobs[n], true
+		}
+	})
+	if !found {
+		return fmt.Sprintf("There's no failed job #%d. Running jobs can't be retried.", id)
+	}
+	if _, ok := jobRunners[j.Kind]; !ok {
+		return fmt.Sprintf("Job #%d can't be retried. Cancel it to drop it.", id)
+	}
+	if err := runFailedJob(s, j); err != nil {
+		log.Printf("Error retrying job %d: %v", id, err)
+		return fmt.Sprintf("Job #%d failed again: %s", id, describeError(err))
+	}
+	log.Printf("%s retried job %d.", user, id)
+	return fmt.Sprintf("Job #%d worked this time: %s", id, j.Description)
+}
+
+// Run a failed job again with its kind's runner, dropping it if it works and noting the new error if it doesn't.
+func runFailedJob(s *discordgo.Session, j job) error {
+	id := j.ID
+	err := jobRunners[j.Kind](s, j.Payload)
+	storeErr := updateStore(func(d *storeData) {
+		n := slices.IndexFunc(d.Jobs, func(j *job) bool { return j.ID == id })
+		switch {
+		case n < 0:
+		case err == nil:
+			d.Jobs = slices.Delete(d.Jobs, n, n+1)
+		default:
+			d.Jobs[n].Error = err.Error()
+			d.Jobs[n].Attempts++
+			d.Jobs[n].FailedAt = time.Now()
+		}
+	})
+	if storeErr != nil {
+		log.Printf("Error saving job: %v", storeErr)
+	}
+	return err
+}
+
+// Replay every failed job of a kind, oldest first, like the events a subscriber missed while it was down. Returns
+// the response to show the caller.
+func replayJobs(s *discordgo.Session, kind string, user *discordgo.User) string {
+	var due []job
+	readStore(func(d *storeData) {
+		for _, j := range d.Jobs {
+			if j.Kind == kind {
+				due = append(due, *j)
+			}
+		}
+	})
+	if len(due) == 0 {
+		return fmt.Sprintf("There are no failed %s jobs.", kind)
+	}
+	var failed []string
+	for _, j := range due {
+		if err := runFailedJob(s, j); err != nil {
+			log.Printf("Error replaying job %d: %v", j.ID, err)
+			failed = append(failed, fmt.Sprintf("#%d", j.ID))
+		}
+	}
+	log.Printf("%s replayed %d %s jobs, %d failed.", user, len(due), kind, len(failed))
+	content := fmt.Sprintf("Replayed %d failed %s jobs. %d worked.", len(due), kind, len(due)-len(failed))
+	if len(failed) > 0 {
+		content += " These failed again: " + strings.Join(failed, ", ")
+	}
+	return truncate(content, 2000)
+}
+
+// Show everything kept about a failed job, including what it'll send when it's retried.
+func showJob(id int) string {
+	var j job
+	found := false
+	readStore(func(d *storeData) {
+		if n := slices.IndexFunc(d.Jobs, func(j *job) bool { return j.ID == id }); n >= 0 {
+			j, found = *d.Jobs[n], true
+		}
+	})
+	if !found {
+		return fmt.Sprintf("There's no failed job #%d.", id)
+	}
+	var payload bytes.Buffer
+	if err := json.Indent(&payload, j.Payload, "", "  "); err != nil {
+		payload.Write(j.Payload)
+	}
+	return fmt.Sprintf("**Job #%d** %s: %s\nFailed <t:%d:R> after %d attempts: `%s`\n```json\n%s\n```",
+		j.ID, j.Kind, j.Description, j.FailedAt.Unix(), j.Attempts, truncate(strings.TrimSpace(j.Error), 300),
+		truncate(payload.String(), 1400))
+}
+
+// Cancel a running job, or drop a failed one. Returns the response to show the caller.
+func cancelJob(id int, user *discordgo.User) string {
+	runningJobsMu.Lock()
+	running, ok := runningJobs[id]
+	runningJobsMu.Unlock()
+	if ok {
+		running.cancel()
+		log.Printf("%s cancelled job %d.", user, id)
+		return fmt.Sprintf("Cancelling job #%d. It stops once it's done with what it's working on.", id)
+	}
+
+	var dropped bool
+	err := updateStore(func(d *storeData) {
+		n := slices.IndexFunc(d.Jobs, func(j *job) bool { return j.ID == id })
+		if n >= 0 {
+			d.Jobs = slices.Delete(d.Jobs, n, n+1)
+			dropped = true
+		}
+	})
+	switch {
+	case err != nil:
+		log.Printf("Error dropping job: %v", err)
+		return "Error dropping job: " + describeError(err)
+	case !dropped:
+		return fmt.Sprintf("There's no running or failed job #%d.", id)
+	}
+	log.Printf("%s dropped failed job %d.", user, id)
+	return fmt.Sprintf("Dropped failed job #%d. It won't be retried.", id)
+}
+
+// What's needed to run a permission migration again: the roles it moves between or the template it updates, and the
+// channels that failed.
+type migrationJob struct {
+	FromRoleID string   `json:"fromRoleId,omitempty"`
+	ToRoleID   string   `json:"toRoleId,omitempty"`
+	Template   string   `json:"template,omitempty"`
+	ChannelIDs []string `json:"channelIds"`
+}
+
+// Run a failed permission migration again on the channels that failed.
+func retryPermissionMigration(s *discordgo.Session, payload json.RawMessage) error {
+	var spec migrationJob
+	if err := json.Unmarshal(payload, &spec); err != nil {
+		return err
+	}
+	var m *permissionMigration
+	if spec.Template != "" {
+		tmpl, ok := getTemplate(spec.Template)
+		if !ok {
+			return userErrorf(errCodeJobTargetGone, "there's no project template named %s any more", spec.Template)
+		}
+		m = templateMigration(spec.Template, tmpl)
+	} else {
+		m = roleMigration(&discordgo.Role{ID: spec.FromRoleID}, &discordgo.Role{ID: spec.ToRoleID})
+	}
+
+	var errs []error
+	for n, channelID := range spec.ChannelIDs {
+		if n > 0 {
+			time.Sleep(migrationDelay)
+		}
+		if _, err := m.migrate(s, channelID); err != nil {
+			errs = append(errs, fmt.Errorf("<#%s>: %w", channelID, err))
+		}
+	}
+	if len(errs) == 0 && m.finish != nil {
+		m.finish()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long kickoff calls are booked for, how long a half-finished /kickoff is kept, and how often reminders are
+// checked.
+const (
+	kickoffLength           = time.Hour
+	kickoffDraftLifetime    = 30 * time.Minute
+	kickoffReminderInterval = 5 * time.Minute
+)
+
+// The agenda posted for a kickoff when /kickoff isn't given one.
+const defaultKickoffAgenda = `1. Introductions: who's who on both sides
+2. Goals and what success looks like
+3. Scope, milestones and deadlines
+4. How we'll communicate and how often
+5. Access, accounts and assets we need
+6. Questions and next steps`
+
+// A kickoff call scheduled for a project.
+type kickoff struct {
+	EventID   string    `json:"eventId"`
+	StartsAt  time.Time `json:"startsAt"`
+	Attendees []string  `json:"attendees"`
+	// Which reminders have gone out.
+	RemindedDay  bool `json:"remindedDay,omitempty"`
+	RemindedHour bool `json:"remindedHour,omitempty"`
+}
+
+// A /kickoff in progress. Drafts only live in memory: they're abandoned if they aren't finished quickly.
+type kickoffDraft struct {
+	userID    string
+	channelID string
+	agenda    string
+	attendees []string
+	startsAt  time.Time
+	createdAt time.Time
+}
+
+var (
+	kickoffDraftsMu sync.Mutex
+	kickoffDrafts   = make(map[string]*kickoffDraft)
+)
+
+// Find the caller's kickoff draft from a component's custom ID.
+func kickoffDraftFor(i *discordgo.InteractionCreate) (*kickoffDraft, string, bool) {
+	_, id, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	kickoffDraftsMu.Lock()
+	defer kickoffDraftsMu.Unlock()
+	d, ok := kickoffDrafts[id]
+	if !ok || d.userID != i.Member.User.ID || time.Since(d.createdAt) > kickoffDraftLifetime {
+		return nil, id, false
+	}
+	return d, id, true
+}
+
+// The times offered for a kickoff: the next few working days during business hours, in the business hours' time
+// zone. Without business hours, weekdays from 9 to 5 local time are used.
+func kickoffSlots(now time.Time) []time.Time {
+	hours := businessHours{Start: "09:00", End: "17:00", Weekdays: []time.Weekday{1, 2, 3, 4, 5}}
+	readStore(func(d *storeData) {
+		if d.BusinessHours != nil {
+			hours = *d.BusinessHours
+		}
+	})
+	loc := time.Local
+	if hours.Timezone != "" {
+		if l, err := time.LoadLocation(hours.Timezone); err == nil {
+			loc = l
+		}
+	}
+	start, _ := time.Parse("15:04", hours.Start)
+	end, _ := time.Parse("15:04", hours.End)
+
+	// Offer the start of the day and every two hours after, as long as the call ends before closing.
+	var slots []time.Time
+	now = now.In(loc)
+	for day := 1; day <= 14 && len(slots) < 25; day++ {
+		date := now.AddDate(0, 0, day)
+		if !slices.Contains(hours.Weekdays, date.Weekday()) {
+			continue
+		}
+		closes := time.Date(date.Year(), date.Month(), date.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+		for t := time.Date(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), 0, 0, loc); !t.Add(kickoffLength).After(closes); t = t.Add(2 * time.Hour) {
+			slots = append(slots, t)
+		}
+	}
+	return slots[:min(len(slots), 25)]
+}
+
+// Start scheduling a kickoff for the project the command was called from. The steps that follow are handled by the
+// kickoff components, and each replaces this message.
+func kickoffCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	projectID := workspaceProjectID(i.ChannelID)
+	p, ok := getProject(projectID)
+	var problem string
+	switch {
+	case !ok:
+		problem = "This channel is not a registered project."
+	case p.Contract != nil && !p.Contract.completed():
+		problem = fmt.Sprintf("The contract sent to %s is %s. The kickoff can be scheduled once it's signed.", p.Contract.Email, p.Contract.Status)
+	case p.Kickoff != nil && p.Kickoff.StartsAt.After(time.Now()):
+		problem = fmt.Sprintf("A kickoff is already scheduled for <t:%d:F>.", p.Kickoff.StartsAt.Unix())
+	}
+	if problem != "" {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: problem,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	agenda := defaultKickoffAgenda
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		agenda = options[0].StringValue()
+	}
+	kickoffDraftsMu.Lock()
+	for id, d := range kickoffDrafts {
+		if time.Since(d.createdAt) > kickoffDraftLifetime {
+			delete(kickoffDrafts, id)
+		}
+	}
+	kickoffDrafts[i.ID] = &kickoffDraft{userID: i.Member.User.ID, channelID: projectID, agenda: agenda, createdAt: time.Now()}
+	kickoffDraftsMu.Unlock()
+
+	// Suggest the project's members as attendees.
+	var defaults []discordgo.SelectMenuDefaultValue
+	for userID := range p.Members {
+		if len(defaults) < 25 {
+			defaults = append(defaults, discordgo.SelectMenuDefaultValue{ID: userID, Type: discordgo.SelectMenuDefaultValueUser})
+		}
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "**Kickoff, step 1 of 3:** who's attending?",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						MenuType:      discordgo.UserSelectMenu,
+						CustomID:      "kickoff-attendees:" + i.ID,
+						Placeholder:   "Pick the attendees",
+						MaxValues:     25,
+						DefaultValues: defaults,
+					},
+				}},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Record the kickoff's attendees and offer times for it.
+func pickKickoffAttendees(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	d, id, ok := kickoffDraftFor(i)
+	if !ok {
+		expiredKickoff(s, i)
+		return
+	}
+	kickoffDraftsMu.Lock()
+	d.attendees = i.MessageComponentData().Values
+	kickoffDraftsMu.Unlock()
+
+	var options []discordgo.SelectMenuOption
+	for _, t := range kickoffSlots(time.Now()) {
+		options = append(options, discordgo.SelectMenuOption{
+			Label:       t.Format("Mon 2 Jan, 15:04 MST"),
+			Description: t.UTC().Format("15:04 UTC"),
+			Value:       strconv.FormatInt(t.Unix(), 10),
+		})
+	}
+	if len(options) == 0 {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "There are no open times in the next two weeks. Check the business hours.",
+				Components: []discordgo.MessageComponent{},
+			},
+		}))
+		return
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("**Kickoff, step 2 of 3:** when? Times are in the team's time zone, for %d attendees.", len(d.attendees)),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						CustomID:    "kickoff-time:" + id,
+						Placeholder: "Pick a time",
+						Options:     options,
+					},
+				}},
+			},
+		},
+	}))
+}
+
+// Record the kickoff's time and ask for confirmation.
+func pickKickoffTime(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	d, id, ok := kickoffDraftFor(i)
+	if !ok {
+		expiredKickoff(s, i)
+		return
+	}
+	unix, _ := strconv.ParseInt(i.MessageComponentData().Values[0], 10, 64)
+	kickoffDraftsMu.Lock()
+	d.startsAt = time.Unix(unix, 0)
+	kickoffDraftsMu.Unlock()
+
+	attendees := make([]string, len(d.attendees))
+	for n, userID := range d.attendees {
+		attendees[n] = "<@" + userID + ">"
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: truncate(fmt.Sprintf("**Kickoff, step 3 of 3:** create it?\n<t:%d:F> with %s\n\n**Agenda**\n%s",
+				unix, strings.Join(attendees, ", "), d.agenda), 2000),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "Create kickoff", Style: discordgo.PrimaryButton, CustomID: "kickoff-confirm:" + id},
+				}},
+			},
+		},
+	}))
+}
+
+// Create the kickoff's scheduled event, post its agenda in the project channel, and start its reminders.
+func confirmKickoff(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	d, id, ok := kickoffDraftFor(i)
+	if !ok || d.startsAt.IsZero() {
+		expiredKickoff(s, i)
+		return
+	}
+	kickoffDraftsMu.Lock()
+	delete(kickoffDrafts, id)
+	kickoffDraftsMu.Unlock()
+
+	content := createKickoff(s, d)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	}))
+}
+
+// Create a kickoff from a finished draft, returning the response to show the caller.
+func createKickoff(s *discordgo.Session, d *kickoffDraft) string {
+	p, ok := getProject(d.channelID)
+	if !ok {
+		return "This channel is not a registered project."
+	}
+	end := d.startsAt.Add(kickoffLength)
+	event, err := s.GuildScheduledEventCreate(JuiceworksGuildId, &discordgo.GuildScheduledEventParams{
+		Name:               truncate("Kickoff: "+p.Name, 100),
+		Description:        truncate(d.agenda, 1000),
+		ScheduledStartTime: &d.startsAt,
+		ScheduledEndTime:   &end,
+		PrivacyLevel:       discordgo.GuildScheduledEventPrivacyLevelGuildOnly,
+		EntityType:         discordgo.GuildScheduledEventEntityTypeExternal,
+		EntityMetadata: &discordgo.GuildScheduledEventEntityMetadata{
+			Location: fmt.Sprintf("https://discord.com/channels/%s/%s", JuiceworksGuildId, p.ChannelID),
+		},
+	})
+	if err != nil {
+		log.Printf("Error creating kickoff event: %v", err)
+		return "Error creating kickoff event: " + describeError(err)
+	}
+
+	err = updateProject(p.ChannelID, func(p *project) {
+		p.Kickoff = &kickoff{EventID: event.ID, StartsAt: d.startsAt, Attendees: d.attendees}
+	})
+	if err != nil {
+		log.Printf("Error saving kickoff: %v", err)
+	}
+
+	mentions := make([]string, len(d.attendees))
+	for n, userID := range d.attendees {
+		mentions[n] = "<@" + userID + ">"
+	}
+	m, err := s.ChannelMessageSendComplex(p.ChannelID, &discordgo.MessageSend{
+		Content: truncate(fmt.Sprintf("**Kickoff** <t:%d:F> (<t:%d:R>)\n%s\nhttps://discord.com/events/%s/%s\n\n**Agenda**\n%s",
+			d.startsAt.Unix(), d.startsAt.Unix(), strings.Join(mentions, " "), JuiceworksGuildId, event.ID, d.agenda), 2000),
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: d.attendees},
+	})
+	if err == nil {
+		err = s.ChannelMessagePin(p.ChannelID, m.ID)
+	}
+	if err != nil {
+		log.Printf("Error posting kickoff agenda: %v", err)
+	}
+
+	log.Printf("Scheduled kickoff for channel %s at %s.", p.ChannelID, d.startsAt)
+	return fmt.Sprintf("Scheduled the kickoff for <t:%d:F> and posted the agenda. Attendees will be reminded a day and an hour before.", d.startsAt.Unix())
+}
+
+// Tell the caller their kickoff draft is gone.
+func expiredKickoff(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    "This kickoff has expired or isn't yours. Run /kickoff again.",
+			Components: []discordgo.MessageComponent{},
+		},
+	}))
+}
+
+// Check for kickoffs coming up.
+func startKickoffReminders(s *discordgo.Session) {
+	go func() {
+		for range time.Tick(kickoffReminderInterval) {
+			if !paused("kickoff reminders") {
+				remindKickoffs(s)
+			}
+		}
+	}()
+}
+
+// Remind attendees in the project channel a day and an hour before their kickoff.
+func remindKickoffs(s *discordgo.Session) {
+	type reminder struct {
+		channelID string
+		kickoff   kickoff
+	}
+	var due []reminder
+	now := time.Now()
+	err := updateStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			k := p.Kickoff
+			if k == nil || !k.StartsAt.After(now) {
+				continue
+			}
+			switch until := k.StartsAt.Sub(now); {
+			case until <= time.Hour && !k.RemindedHour:
+				k.RemindedDay, k.RemindedHour = true, true
+			case until <= 24*time.Hour && !k.RemindedDay:
+				k.RemindedDay = true
+			default:
+				continue
+			}
+			due = append(due, reminder{p.ChannelID, *k})
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving kickoff reminders: %v", err)
+		return
+	}
+
+	for _, r := range due {
+		mentions := make([]string, len(r.kickoff.Attendees))
+		for n, userID := range r.kickoff.Attendees {
+			mentions[n] = "<@" + userID + ">"
+		}
+		_, err := s.ChannelMessageSendComplex(r.channelID, &discordgo.MessageSend{
+			Content: fmt.Sprintf("Reminder: the kickoff starts <t:%d:R>. %s\nhttps://discord.com/events/%s/%s",
+				r.kickoff.StartsAt.Unix(), strings.Join(mentions, " "), JuiceworksGuildId, r.kickoff.EventID),
+			AllowedMentions: &discordgo.MessageAllowedMentions{Users: r.kickoff.Attendees},
+		})
+		if err != nil {
+			recordFailure("sending kickoff reminder", err)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord gives up on an interaction that hasn't had a response in 3 seconds, so commands should answer in 2.
+const latencyBudget = 2 * time.Second
+
+// How many recent first-response times are kept for each command, and how many it needs before it can be switched to
+// deferred responses.
+const (
+	latencySamples    = 100
+	latencyMinSamples = 20
+)
+
+// How long an interaction can be answered for. Interactions still waiting after this are forgotten.
+const interactionTokenLifetime = 15 * time.Minute
+
+// Recent first-response times for a command, and what the bot has learned about how it responds.
+type commandLatency struct {
+	samples []time.Duration
+	count   int
+	total   time.Duration
+	// Whether the command is slow enough that it's deferred before its handler runs, and so someone's been alerted.
+	autoDefer bool
+	// Whether its responses are ephemeral, so deferred responses can be too.
+	ephemeral bool
+	// Whether it responds with a modal, which can't follow a deferred response.
+	modal bool
+}
+
+// A command interaction that hasn't had its first response yet.
+type pendingInteraction struct {
+	command  string
+	received time.Time
+	appID    string
+	token    string
+	// Whether the bot deferred the response itself, so the handler's response has to be turned into an edit.
+	deferred bool
+}
+
+// Marks the request context of a response the bot deferred itself, so it isn't taken for the handler's.
+type autoDeferKey struct{}
+
+var (
+	latencyMu           sync.Mutex
+	commandLatencies    = make(map[string]*commandLatency)
+	pendingInteractions = make(map[string]*pendingInteraction)
+)
+
+// The 95th percentile of a command's recent first-response times.
+func (l *commandLatency) p95() time.Duration {
+	sorted := slices.Clone(l.samples)
+	slices.Sort(sorted)
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[(len(sorted)*95+99)/100-1]
+}
+
+// Start timing a command interaction, from when it was received. If the command has been too slow, its response is
+// deferred right away, and the handler's response is turned into an edit of it when it comes.
+func startInteractionTimer(s *discordgo.Session, i *discordgo.InteractionCreate, name string, received time.Time) {
+	latencyMu.Lock()
+	for id, p := range pendingInteractions {
+		if time.Since(p.received) > interactionTokenLifetime {
+			delete(pendingInteractions, id)
+		}
+	}
+	pendingInteractions[i.ID] = &pendingInteraction{command: name, received: received, appID: i.AppID, token: i.Token}
+	l := commandLatencies[name]
+	deferIt := l != nil && l.autoDefer && !l.modal
+	ephemeral := l != nil && l.ephemeral
+	latencyMu.Unlock()
+	if !deferIt {
+		return
+	}
+
+	data := &discordgo.InteractionResponseData{}
+	if ephemeral {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+	ctx := context.WithValue(context.Background(), autoDeferKey{}, true)
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: data,
+	}, discordgo.WithContext(ctx))
+	if err != nil {
+		// The handler still gets to respond normally.
+		log.Printf("Error deferring response to /%s: %v", name, err)
+		return
+	}
+	latencyMu.Lock()
+	if p, ok := pendingInteractions[i.ID]; ok {
+		p.deferred = true
+	}
+	latencyMu.Unlock()
+}
+
+// Record how long a command took to respond for the first time, switching it to deferred responses, and alerting,
+// when its 95th percentile goes over the budget, and back when it comes under again.
+func recordLatency(name string, took time.Duration, response *discordgo.InteractionResponse) {
+	if took > latencyBudget {
+		log.Printf("/%s took %s to respond.", name, took.Round(time.Millisecond))
+	}
+
+	latencyMu.Lock()
+	l, ok := commandLatencies[name]
+	if !ok {
+		l = &commandLatency{}
+		commandLatencies[name] = l
+	}
+	l.count++
+	l.total += took
+	l.samples = append(l.samples, took)
+	if len(l.samples) > latencySamples {
+		l.samples = l.samples[len(l.samples)-latencySamples:]
+	}
+	if response != nil {
+		switch response.Type {
+		case discordgo.InteractionResponseModal:
+			l.modal = true
+		case discordgo.InteractionResponseChannelMessageWithSource, discordgo.InteractionResponseDeferredChannelMessageWithSource:
+			l.ephemeral = response.Data != nil && response.Data.Flags&discordgo.MessageFlagsEphemeral != 0
+		}
+	}
+	p95 := l.p95()
+	was := l.autoDefer
+	if len(l.samples) >= latencyMinSamples {
+		l.autoDefer = p95 > latencyBudget
+	}
+	now := l.autoDefer
+	latencyMu.Unlock()
+
+	switch {
+	case now && !was:
+		log.Printf("Deferring /%s from now on, since it's taking %s to respond at p95.", name, p95.Round(time.Millisecond))
+		go triggerAlert(alertSlowCommands, alertSlowCommands+"-"+name, fmt.Sprintf(
+			"/%s is taking %s to respond at p95, over the %s budget, so its responses are being deferred.", name,
+			p95.Round(time.Millisecond), latencyBudget))
+	case was && !now:
+		log.Printf("No longer deferring /%s, since it's back to %s at p95.", name, p95.Round(time.Millisecond))
+		go resolveAlert(alertSlowCommands, alertSlowCommands+"-"+name)
+	}
+}
+
+// Times the first response to each command interaction, and turns the response of a command the bot deferred into an
+// edit of the deferred message.
+type latencyWatcher struct {
+	base http.RoundTripper
+}
+
+func (w latencyWatcher) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Interaction callbacks are POST .../interactions/{id}/{token}/callback.
+	parts := strings.Split(strings.TrimSuffix(req.URL.Path, "/"), "/")
+	if req.Method != http.MethodPost || len(parts) < 4 || parts[len(parts)-1] != "callback" || parts[len(parts)-4] != "interactions" ||
+		req.Context().Value(autoDeferKey{}) != nil {
+		return w.base.RoundTrip(req)
+	}
+	id := parts[len(parts)-3]
+
+	latencyMu.Lock()
+	p, ok := pendingInteractions[id]
+	deferring := ok && p.deferred
+	if ok && !p.deferred {
+		delete(pendingInteractions, id)
+	}
+	latencyMu.Unlock()
+	if !ok {
+		return w.base.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response, payload, rebuild, err := decodeInteractionCallback(req, body)
+	if err != nil {
+		// Let Discord reject it as it would have.
+		response = nil
+	}
+
+	// The bot already deferred this one, so the handler's response becomes an edit of the deferred message. A handler
+	// that defers itself is already covered.
+	if deferring && response != nil && response.Type != discordgo.InteractionResponseModal {
+		latencyMu.Lock()
+		delete(pendingInteractions, id)
+		latencyMu.Unlock()
+		recordLatency(p.command, time.Since(p.received), nil)
+		if response.Type == discordgo.InteractionResponseDeferredChannelMessageWithSource {
+			return &http.Response{StatusCode: http.StatusNoContent, Body: io.NopCloser(bytes.NewReader(nil)), Request: req}, nil
+		}
+		edit, contentType, err := rebuild(payload)
+		if err != nil {
+			return nil, err
+		}
+		u := *req.URL
+		u.Path = strings.Join(parts[:len(parts)-4], "/") + "/webhooks/" + p.appID + "/" + p.token + "/messages/@original"
+		editReq, err := http.NewRequestWithContext(req.Context(), http.MethodPatch, u.String(), bytes.NewReader(edit))
+		if err != nil {
+			return nil, err
+		}
+		editReq.Header = req.Header.Clone()
+		editReq.Header.Set("Content-Type", contentType)
+		return w.base.RoundTrip(editReq)
+	}
+
+	if !deferring {
+		recordLatency(p.command, time.Since(p.received), response)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return w.base.RoundTrip(req)
+}
+
+// Decode an interaction callback body, which is JSON, or multipart with the JSON in payload_json when there are
+// files. Also returns the response's data as JSON, and a function that rebuilds the body with new JSON in its place,
+// in the same format, with its content type.
+func decodeInteractionCallback(req *http.Request, body []byte) (*discordgo.InteractionResponse, []byte, func([]byte) ([]byte, string, error), error) {
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	var response discordgo.InteractionResponse
+	mediaType, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, nil, nil, err
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, nil, nil, err
+		}
+		return &response, envelope.Data, func(b []byte) ([]byte, string, error) { return b, "application/json", nil }, nil
+	}
+
+	// Keep the files, and swap the payload.
+	type part struct {
+		header map[string][]string
+		body   []byte
+	}
+	var parts []part
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		b, err := io.ReadAll(p)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if p.FormName() == "payload_json" {
+			if err := json.Unmarshal(b, &envelope); err != nil {
+				return nil, nil, nil, err
+			}
+			if err := json.Unmarshal(b, &response); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		parts = append(parts, part{header: p.Header, body: b})
+	}
+	rebuild := func(payload []byte) ([]byte, string, error) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for _, p := range parts {
+			b := p.body
+			if strings.Contains(strings.Join(p.header["Content-Disposition"], ""), `name="payload_json"`) {
+				b = payload
+			}
+			pw, err := mw.CreatePart(p.header)
+			if err != nil {
+				return nil, "", err
+			}
+			if _, err := pw.Write(b); err != nil {
+				return nil, "", err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), mw.FormDataContentType(), nil
+	}
+	return &response, envelope.Data, rebuild, nil
+}
+
+// Time interaction responses, and defer the slow ones.
+func watchInteractionLatency(s *discordgo.Session) {
+	base := s.Client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	s.Client.Transport = latencyWatcher{base: base}
+}
+
+// Serve each command's first-response times in the Prometheus text format.
+func writeLatencyMetrics(w io.Writer) {
+	latencyMu.Lock()
+	defer latencyMu.Unlock()
+	names := make([]string, 0, len(commandLatencies))
+	for name := range commandLatencies {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	fmt.Fprintf(w, "# HELP juiceworks_interaction_first_response_seconds Time from receiving a command to its first response.\n")
+	fmt.Fprintf(w, "# TYPE juiceworks_interaction_first_response_seconds summary\n")
+	for _, name := range names {
+		l := commandLatencies[name]
+		fmt.Fprintf(w, "juiceworks_interaction_first_response_seconds{command=%q,quantile=\"0.95\"} %v\n", name, l.p95().Seconds())
+		fmt.Fprintf(w, "juiceworks_interaction_first_response_seconds_sum{command=%q} %v\n", name, l.total.Seconds())
+		fmt.Fprintf(w, "juiceworks_interaction_first_response_seconds_count{command=%q} %d\n", name, l.count)
+	}
+	fmt.Fprintf(w, "# HELP juiceworks_interaction_auto_deferred Whether a command is slow enough that its responses are deferred.\n")
+	fmt.Fprintf(w, "# TYPE juiceworks_interaction_auto_deferred gauge\n")
+	for _, name := range names {
+		deferred := 0
+		if commandLatencies[name].autoDefer {
+			deferred = 1
+		}
+		fmt.Fprintf(w, "juiceworks_interaction_auto_deferred{command=%q} %d\n", name, deferred)
+	}
+}
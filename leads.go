@@ -0,0 +1,323 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// What a lead that staff decided not to take on is marked as.
+const leadDeclined = "declined"
+
+// Someone who asked to work with Juiceworks through /work-with-us.
+type lead struct {
+	ID        int       `json:"id"`
+	UserID    string    `json:"userId"`
+	Name      string    `json:"name"`
+	Company   string    `json:"company,omitempty"`
+	Email     string    `json:"email"`
+	Details   string    `json:"details"`
+	Timeline  string    `json:"timeline,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// statusLead until staff review it, then statusScoping or leadDeclined.
+	Status     string    `json:"status"`
+	ReviewedBy string    `json:"reviewedBy,omitempty"`
+	ReviewedAt time.Time `json:"reviewedAt,omitempty"`
+	// The scoping channel made for the lead, if any.
+	ChannelID string `json:"channelId,omitempty"`
+}
+
+// Ask someone who wants to work with Juiceworks about their project in a modal. Anyone in the server can, including
+// people who aren't clients yet, but only with one lead waiting for review at a time.
+func workWithUsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	open := false
+	readStore(func(d *storeData) {
+		open = slices.ContainsFunc(d.Leads, func(l *lead) bool { return l.UserID == user.ID && l.Status == statusLead })
+	})
+	if open {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "We already have your project details, and the Juiceworks team will be in touch soon.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	input := func(id, label string, style discordgo.TextInputStyle, required bool, maxLength int) discordgo.MessageComponent {
+		return discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.TextInput{CustomID: id, Label: label, Style: style, Required: required, MaxLength: maxLength},
+		}}
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "work-with-us-submit",
+			Title:    "Work with Juiceworks",
+			Components: []discordgo.MessageComponent{
+				input("name", "Your name", discordgo.TextInputShort, true, 100),
+				input("company", "Company", discordgo.TextInputShort, false, 100),
+				input("email", "Email", discordgo.TextInputShort, true, 254),
+				input("details", "What would you like to build?", discordgo.TextInputParagraph, true, 2000),
+				input("timeline", "Budget and timeline", discordgo.TextInputShort, false, 200),
+			},
+		},
+	}))
+}
+
+// Record a lead from the /work-with-us modal and post it in the internal channel, with buttons for staff to start
+// scoping it or decline it.
+func submitLead(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	l := lead{UserID: user.ID, CreatedAt: time.Now(), Status: statusLead}
+	for _, row := range i.ModalSubmitData().Components {
+		for _, c := range row.(*discordgo.ActionsRow).Components {
+			switch input := c.(*discordgo.TextInput); input.CustomID {
+			case "name":
+				l.Name = strings.TrimSpace(input.Value)
+			case "company":
+				l.Company = strings.TrimSpace(input.Value)
+			case "email":
+				l.Email = strings.TrimSpace(input.Value)
+			case "details":
+				l.Details = input.Value
+			case "timeline":
+				l.Timeline = input.Value
+			}
+		}
+	}
+	err := updateStore(func(d *storeData) {
+		l.ID = len(d.Leads) + 1
+		d.Leads = append(d.Leads, &l)
+	})
+	if err != nil {
+		log.Printf("Error saving lead: %v", err)
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Error saving your details: " + describeError(err),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Name", Value: truncate(l.Name, 1024), Inline: true},
+		{Name: "Email", Value: truncate(l.Email, 1024), Inline: true},
+	}
+	if l.Company != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Company", Value: truncate(l.Company, 1024), Inline: true})
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{Name: "Project", Value: truncate(l.Details, 1024)})
+	if l.Timeline != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Budget and timeline", Value: truncate(l.Timeline, 1024)})
+	}
+	id := strconv.Itoa(l.ID)
+	_, err = s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{
+		Content: fmt.Sprintf("New lead #%d from %s.", l.ID, user.Mention()),
+		Embed: themed(i.GuildID, &discordgo.MessageEmbed{
+			Title:     "Lead #" + id,
+			Thumbnail: &discordgo.MessageEmbedThumbnail{URL: user.AvatarURL("")},
+			Fields:    fields,
+			Timestamp: l.CreatedAt.Format(time.RFC3339),
+		}),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Start scoping", Style: discordgo.SuccessButton, CustomID: "lead-scope:" + id},
+				discordgo.Button{Label: "Decline", Style: discordgo.DangerButton, CustomID: "lead-decline:" + id},
+			}},
+		},
+	})
+	if err != nil {
+		// The lead is saved, so staff can still find it, but nobody's been told about it.
+		recordFailure("posting lead", err)
+	}
+
+	log.Printf("Received lead %d from %s.", l.ID, user)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "Thanks! The Juiceworks team will be in touch soon.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Handle the Start scoping and Decline buttons on a lead. Starting scoping makes a private scoping channel for the
+// lead, registers it as a project in scoping, and adds the lead to it if they're in the server.
+func reviewLead(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	action, id, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	n, _ := strconv.Atoi(id)
+	var l lead
+	found := false
+	readStore(func(d *storeData) {
+		if n >= 1 && n <= len(d.Leads) {
+			l, found = *d.Leads[n-1], true
+		}
+	})
+	var problem string
+	switch {
+	case !found:
+		problem = "There's no lead #" + id + "."
+	case l.Status != statusLead:
+		problem = fmt.Sprintf("Lead #%d was already reviewed by <@%s>.", l.ID, l.ReviewedBy)
+	}
+	if problem != "" {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: problem,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	message := "Thanks for getting in touch with Juiceworks. Sorry, we aren't able to take on your project right now."
+	outcome := "Declined"
+	status := leadDeclined
+	var channelID string
+	if action == "lead-scope" {
+		channel, err := createScopingChannel(s, l, i.Member.User.ID)
+		if err != nil {
+			logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: channelErrorContent(s, "creating scoping channel", err, "Manage Channels and Manage Roles", channelLocation(os.Getenv("LEADS_CATEGORY_ID"))),
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			}))
+			return
+		}
+		channelID, status = channel.ID, statusScoping
+		outcome = fmt.Sprintf("Scoping in <#%s>", channel.ID)
+		message = fmt.Sprintf("Thanks for getting in touch with Juiceworks! We'd like to hear more, so we've made <#%s> to scope your project together.", channel.ID)
+		if note := addLeadToChannel(s, l, channel.ID, i.Member.User.ID); note != "" {
+			outcome += ". " + note
+		}
+	}
+
+	err := updateStore(func(d *storeData) {
+		stored := d.Leads[l.ID-1]
+		stored.Status = status
+		stored.ReviewedBy = i.Member.User.ID
+		stored.ReviewedAt = time.Now()
+		stored.ChannelID = channelID
+	})
+	if err != nil {
+		log.Printf("Error saving lead: %v", err)
+	}
+
+	// Let the lead know the outcome.
+	dm, err := s.UserChannelCreate(l.UserID)
+	if err == nil {
+		_, err = s.ChannelMessageSend(dm.ID, message)
+	}
+	if err != nil {
+		log.Printf("Error sending lead outcome: %v", err)
+	}
+
+	// Replace the buttons with the outcome so the lead can't be reviewed twice.
+	log.Printf("%s lead %d by %s.", outcome, l.ID, i.Member.User)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:         fmt.Sprintf("%s\n**%s** by %s.", i.Message.Content, outcome, i.Member.User.Mention()),
+			Components:      []discordgo.MessageComponent{},
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}))
+}
+
+// Make a private channel to scope a lead's project in, in LEADS_CATEGORY_ID if it's set, and register it as a
+// project in scoping for the client the lead works for.
+func createScopingChannel(s *discordgo.Session, l lead, userID string) (*discordgo.Channel, error) {
+	name := sanitizeChannelName(l.Company)
+	if len([]rune(name)) < 2 {
+		name = sanitizeChannelName(l.Name)
+	}
+	name = truncate("scoping-"+name, 100)
+	channel, err := s.GuildChannelCreateComplex(JuiceworksGuildId, discordgo.GuildChannelCreateData{
+		Name:     name,
+		Type:     discordgo.ChannelTypeGuildText,
+		Topic:    truncate(fmt.Sprintf("Scoping lead #%d: %s", l.ID, l.Details), 1024),
+		ParentID: os.Getenv("LEADS_CATEGORY_ID"),
+		PermissionOverwrites: []*discordgo.PermissionOverwrite{
+			{ID: JuiceworksRoleId, Type: discordgo.PermissionOverwriteTypeRole, Allow: discordgo.PermissionViewChannel | discordgo.PermissionSendMessages},
+			{ID: JuiceworksGuildId, Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionViewChannel},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = updateProject(channel.ID, func(p *project) {
+		p.Name = channel.Name
+		p.CreatedAt = now
+		p.CreatedBy = userID
+		p.ClientName = l.Company
+		p.Status = statusScoping
+		p.StatusChangedAt = now
+	})
+	if err != nil {
+		log.Printf("Error recording project: %v", err)
+	}
+	emitEvent("project.created", projectCreatedEvent{ChannelID: channel.ID, Name: channel.Name, CreatedBy: userID})
+	auditProjectCreation(s, userID, channel.ID)
+	return channel, nil
+}
+
+// Add a lead to their scoping channel, sending them the terms first if they have to accept them. Returns a note for
+// staff if they couldn't be added.
+func addLeadToChannel(s *discordgo.Session, l lead, channelID, addedBy string) string {
+	member, err := s.GuildMember(JuiceworksGuildId, l.UserID)
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMember {
+		return "They aren't in the server, so invite them and add them with /add-member."
+	} else if err != nil {
+		log.Printf("Error reading member roles: %v", err)
+		return "Error reading member roles: " + describeError(err)
+	}
+
+	// Neither the terms nor the channel need the interaction, which is answered once the lead is reviewed.
+	if requiresTerms(member) {
+		return "Add them with /add-member, which sends them the terms first."
+	}
+	for _, id := range workspaceChannels(channelID) {
+		err := s.ChannelPermissionSet(id, l.UserID, discordgo.PermissionOverwriteTypeMember,
+			discordgo.PermissionViewChannel|discordgo.PermissionSendMessages, 0)
+		if err != nil {
+			log.Printf("Error adding lead to channel: %v", err)
+			return "Error adding them to the channel: " + describeError(err)
+		}
+	}
+	err = updateProject(channelID, func(p *project) {
+		if p.Members == nil {
+			p.Members = make(map[string]string)
+		}
+		p.Members[l.UserID] = member.User.Username
+	})
+	if err != nil {
+		log.Printf("Error recording project member: %v", err)
+	}
+	emitEvent("member.added", memberAddedEvent{ChannelID: channelID, UserID: l.UserID, Username: member.User.Username})
+	auditMemberChange(s, auditMemberAdded, addedBy, l.UserID, channelID)
+	return ""
+}
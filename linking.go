@@ -0,0 +1,306 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long someone has to finish linking their account once they've left the portal.
+const accountLinkLifetime = 10 * time.Minute
+
+// The cookie holding the nonce that ties an account link to the browser that started it.
+const accountLinkCookie = "juiceworks_link"
+
+// A Discord user's account in the client portal.
+type accountLink struct {
+	PortalAccountID string    `json:"portalAccountId"`
+	Username        string    `json:"username"`
+	LinkedAt        time.Time `json:"linkedAt"`
+}
+
+// A link waiting for someone to confirm it should replace the portal account their Discord account is linked to.
+type pendingLink struct {
+	userID    string
+	link      accountLink
+	expiresAt time.Time
+}
+
+// Links waiting for confirmation, keyed by the token in the confirmation form.
+var (
+	pendingLinksMu sync.Mutex
+	pendingLinks   = make(map[string]pendingLink)
+)
+
+// Where Discord sends people back to after they authorize the link.
+func accountLinkRedirectURI() string {
+	return strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/") + "/oauth/discord/callback"
+}
+
+// Set or clear the account link cookie. It's only sent back to the OAuth endpoints, and only on top-level navigation
+// from other sites, which is how Discord sends people back.
+func setAccountLinkCookie(w http.ResponseWriter, nonce string) {
+	maxAge := int(accountLinkLifetime.Seconds())
+	if nonce == "" {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     accountLinkCookie,
+		Value:    nonce,
+		Path:     "/oauth/discord/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(os.Getenv("PUBLIC_URL"), "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// A random hex token.
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Start linking a portal account to a Discord account. The portal sends people here with its account ID, an expiry
+// and a signature of both made with LINK_SIGNING_KEY, and they're sent on to Discord to authorize the bot to see who
+// they are. The signed values ride along in the OAuth state, so nothing has to be kept until they come back. The state
+// is also signed with a nonce kept in a cookie, so it only works in the browser that started the link: a forwarded
+// authorization link can't link someone else's account.
+func startAccountLink(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	account, expires := q.Get("account"), q.Get("expires")
+	at, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || account == "" || !validLinkSignature(account+"|"+expires, q.Get("sig")) {
+		http.Error(w, "This link is invalid. Start again from the portal.", http.StatusForbidden)
+		return
+	}
+	if time.Now().Unix() > at {
+		http.Error(w, "This link has expired. Start again from the portal.", http.StatusGone)
+		return
+	}
+	clientID := os.Getenv("DISCORD_CLIENT_ID")
+	if clientID == "" || os.Getenv("DISCORD_CLIENT_SECRET") == "" {
+		http.Error(w, "Account linking isn't set up.", http.StatusNotFound)
+		return
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		log.Printf("Error starting account link: %v", err)
+		http.Error(w, "Couldn't start linking your account. Please try again.", http.StatusInternalServerError)
+		return
+	}
+	setAccountLinkCookie(w, nonce)
+
+	stateExpires := strconv.FormatInt(time.Now().Add(accountLinkLifetime).Unix(), 10)
+	state := account + "|" + stateExpires + "|" + signLink("oauth|"+account+"|"+stateExpires+"|"+nonce)
+	params := url.Values{
+		"client_id":     {clientID},
+		"redirect_uri":  {accountLinkRedirectURI()},
+		"response_type": {"code"},
+		"scope":         {"identify"},
+		"state":         {state},
+	}
+	http.Redirect(w, r, "https://discord.com/oauth2/authorize?"+params.Encode(), http.StatusFound)
+}
+
+// Finish linking an account when Discord sends the person back: swap the code for a token, find out who they are on
+// Discord, and record the link. A Discord account already linked to a different portal account is only moved over
+// once the person confirms it.
+func finishAccountLink(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	parts := strings.Split(q.Get("state"), "|")
+	cookie, err := r.Cookie(accountLinkCookie)
+	if len(parts) != 3 || err != nil || !validLinkSignature("oauth|"+parts[0]+"|"+parts[1]+"|"+cookie.Value, parts[2]) {
+		http.Error(w, "This link is invalid or was started in another browser. Start again from the portal.", http.StatusForbidden)
+		return
+	}
+	setAccountLinkCookie(w, "")
+	if at, err := strconv.ParseInt(parts[1], 10, 64); err != nil || time.Now().Unix() > at {
+		http.Error(w, "This link has expired. Start again from the portal.", http.StatusGone)
+		return
+	}
+	if q.Get("code") == "" {
+		http.Error(w, "Discord didn't authorize the link.", http.StatusBadRequest)
+		return
+	}
+
+	user, err := discordOAuthUser(q.Get("code"))
+	if err != nil {
+		log.Printf("Error linking account: %v", err)
+		http.Error(w, "Couldn't check your Discord account. Please try again.", http.StatusBadGateway)
+		return
+	}
+	link := accountLink{PortalAccountID: parts[0], Username: user.Username}
+	var existing accountLink
+	var linked bool
+	readStore(func(d *storeData) {
+		existing, linked = d.AccountLinks[user.ID]
+	})
+	if linked && existing.PortalAccountID != link.PortalAccountID {
+		token, err := randomToken()
+		if err != nil {
+			log.Printf("Error linking account: %v", err)
+			http.Error(w, "Couldn't link your account. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		pendingLinksMu.Lock()
+		for t, p := range pendingLinks {
+			if time.Now().After(p.expiresAt) {
+				delete(pendingLinks, t)
+			}
+		}
+		pendingLinks[token] = pendingLink{userID: user.ID, link: link, expiresAt: time.Now().Add(accountLinkLifetime)}
+		pendingLinksMu.Unlock()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<p>Your Discord account %s is already linked to portal account <code>%s</code>. Link it to portal
+account <code>%s</code> instead?</p>
+<form method="post" action="/oauth/discord/confirm"><input type="hidden" name="token" value="%s"><button>Link it</button></form>`,
+			html.EscapeString(user.Username), html.EscapeString(existing.PortalAccountID), html.EscapeString(link.PortalAccountID), token)
+		return
+	}
+	saveAccountLink(w, r, user.ID, link)
+}
+
+// Replace an existing account link once the person confirms it.
+func confirmAccountLink(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	pendingLinksMu.Lock()
+	p, ok := pendingLinks[token]
+	delete(pendingLinks, token)
+	pendingLinksMu.Unlock()
+	if !ok || time.Now().After(p.expiresAt) {
+		http.Error(w, "This link has expired. Start again from the portal.", http.StatusGone)
+		return
+	}
+	saveAccountLink(w, r, p.userID, p.link)
+}
+
+// Record that a Discord user is linked to a portal account, and send them back to the portal.
+func saveAccountLink(w http.ResponseWriter, r *http.Request, userID string, link accountLink) {
+	link.LinkedAt = time.Now()
+	err := updateStore(func(d *storeData) {
+		if d.AccountLinks == nil {
+			d.AccountLinks = make(map[string]accountLink)
+		}
+		d.AccountLinks[userID] = link
+	})
+	if err != nil {
+		log.Printf("Error saving account link: %v", err)
+		http.Error(w, "Couldn't save the link. Please try again.", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Linked %s (%s) to portal account %s.", link.Username, userID, link.PortalAccountID)
+	if portal := os.Getenv("PORTAL_URL"); portal != "" {
+		http.Redirect(w, r, portal, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "Your Discord account %s is now linked. You can close this page.", link.Username)
+}
+
+// Swap an OAuth code for a token and look up the Discord user it belongs to.
+func discordOAuthUser(code string) (*discordgo.User, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.PostForm("https://discord.com/api/oauth2/token", url.Values{
+		"client_id":     {os.Getenv("DISCORD_CLIENT_ID")},
+		"client_secret": {os.Getenv("DISCORD_CLIENT_SECRET")},
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {accountLinkRedirectURI()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("discord returned %s: %s", resp.Status, msg)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://discord.com/api/users/@me", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	resp, err = client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("discord returned %s: %s", resp.Status, msg)
+	}
+	var user discordgo.User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Look up the portal account linked to a Discord user, so other systems can attribute their records.
+func apiAccountLink(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	var link accountLink
+	var ok bool
+	readStore(func(d *storeData) {
+		link, ok = d.AccountLinks[r.PathValue("user")]
+	})
+	if !ok {
+		http.Error(w, "not linked", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, link)
+}
+
+// Show the caller which portal account their Discord account is linked to.
+func whoamiCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	var link accountLink
+	var ok bool
+	readStore(func(d *storeData) {
+		link, ok = d.AccountLinks[user.ID]
+	})
+
+	content := fmt.Sprintf("You're %s (`%s`) on Discord, and not linked to a portal account yet.", user.Username, user.ID)
+	if portal := os.Getenv("PORTAL_URL"); portal != "" {
+		content += " Link your account from " + portal + "."
+	}
+	if ok {
+		content = fmt.Sprintf("You're %s (`%s`) on Discord, linked to portal account `%s` since <t:%d:D>.",
+			user.Username, user.ID, link.PortalAccountID, link.LinkedAt.Unix())
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
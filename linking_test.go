@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Start linking a portal account, returning the redirect to Discord and the cookie set for the browser.
+func startLink(t *testing.T, mux *http.ServeMux, account string) (*url.URL, *http.Cookie) {
+	t.Helper()
+	expires := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+	q := url.Values{"account": {account}, "expires": {expires}, "sig": {signLink(account + "|" + expires)}}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/link/discord?"+q.Encode(), nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("starting the link = %d %s", w.Code, w.Body)
+	}
+	to, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("cookies = %+v, want one HttpOnly, SameSite=Lax cookie", cookies)
+	}
+	return to, cookies[0]
+}
+
+// The OAuth state only works in the browser that started the link, so a forwarded authorization link is useless.
+func TestAccountLinkNeedsTheStartingBrowser(t *testing.T) {
+	_, s := newTestBot(t)
+	t.Setenv("LINK_SIGNING_KEY", "test-key")
+	t.Setenv("DISCORD_CLIENT_ID", "client")
+	t.Setenv("DISCORD_CLIENT_SECRET", "secret")
+	mux := httpMux(s)
+
+	to, _ := startLink(t, mux, "portal-1")
+	if to.Query().Has("prompt") {
+		t.Errorf("the authorization link %s skips Discord's consent screen", to)
+	}
+	_, other := startLink(t, mux, "portal-1")
+
+	for _, c := range []struct {
+		name   string
+		cookie *http.Cookie
+	}{{"no cookie", nil}, {"another browser's cookie", other}} {
+		r := httptest.NewRequest(http.MethodGet, "/oauth/discord/callback?"+url.Values{
+			"state": {to.Query().Get("state")}, "code": {"code"}}.Encode(), nil)
+		if c.cookie != nil {
+			r.AddCookie(c.cookie)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: callback = %d, want %d", c.name, w.Code, http.StatusForbidden)
+		}
+	}
+}
+
+// Moving a Discord account to a different portal account only happens once it's confirmed, and only once.
+func TestConfirmAccountRelink(t *testing.T) {
+	_, s := newTestBot(t)
+	mux := httpMux(s)
+	pendingLinksMu.Lock()
+	pendingLinks["token"] = pendingLink{
+		userID:    "3000000000000000001",
+		link:      accountLink{PortalAccountID: "portal-2", Username: "client"},
+		expiresAt: time.Now().Add(time.Minute),
+	}
+	pendingLinksMu.Unlock()
+
+	for n, want := range []int{http.StatusOK, http.StatusGone} {
+		r := httptest.NewRequest(http.MethodPost, "/oauth/discord/confirm", strings.NewReader("token=token"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("confirmation %d = %d, want %d", n+1, w.Code, want)
+		}
+	}
+	var link accountLink
+	readStore(func(d *storeData) { link = d.AccountLinks["3000000000000000001"] })
+	if link.PortalAccountID != "portal-2" {
+		t.Errorf("the account is linked to %q, want portal-2", link.PortalAccountID)
+	}
+}
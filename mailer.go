@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// A service that can send email.
+type mailer interface {
+	// Send an email, returning its Message-Id.
+	send(e *outgoingEmail) (string, error)
+}
+
+// An email sent by the bot.
+type outgoingEmail struct {
+	from    string
+	to      []string
+	subject string
+	text    string
+	headers map[string]string
+}
+
+// The supported email backends, selected with EMAIL_PROVIDER.
+var mailers = map[string]mailer{
+	"mailgun": mailgunMailer{},
+	"smtp":    smtpMailer{},
+}
+
+// Send an email through the configured backend, defaulting to Mailgun.
+func sendEmail(e *outgoingEmail) (id string, err error) {
+	defer func() { noteIntegrationCall("Email", err) }()
+	provider := os.Getenv("EMAIL_PROVIDER")
+	if provider == "" {
+		provider = "mailgun"
+	}
+	m, ok := mailers[provider]
+	if !ok {
+		return "", fmt.Errorf("unknown EMAIL_PROVIDER %q", provider)
+	}
+	// A line break ends a header, so one in a value copied from inbound mail, like References, would let the sender
+	// add headers of their own.
+	for k, v := range e.headers {
+		if strings.ContainsAny(v, "\r\n") {
+			return "", fmt.Errorf("email header %s contains a line break", k)
+		}
+	}
+	for _, to := range e.to {
+		if strings.ContainsAny(to, "\r\n") {
+			return "", fmt.Errorf("email recipient %q contains a line break", to)
+		}
+	}
+	return m.send(e)
+}
+
+// Sends email through the Mailgun API for EMAIL_DOMAIN.
+type mailgunMailer struct{}
+
+func (mailgunMailer) send(e *outgoingEmail) (string, error) {
+	params := url.Values{
+		"from":    {e.from},
+		"to":      e.to,
+		"subject": {e.subject},
+		"text":    {e.text},
+	}
+	for k, v := range e.headers {
+		params.Set("h:"+k, v)
+	}
+
+	endpoint := "https://api.mailgun.net/v3/" + os.Getenv("EMAIL_DOMAIN") + "/messages"
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth("api", os.Getenv("MAILGUN_API_KEY"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("mailgun returned %s: %s", resp.Status, body)
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+// Sends email through the SMTP server at SMTP_ADDR (host:port), authenticating with SMTP_USERNAME and SMTP_PASSWORD
+// if they are set.
+type smtpMailer struct{}
+
+func (smtpMailer) send(e *outgoingEmail) (string, error) {
+	from, err := mail.ParseAddress(e.from)
+	if err != nil {
+		return "", err
+	}
+	_, domain, _ := strings.Cut(from.Address, "@")
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	messageID := "<" + hex.EncodeToString(id) + "@" + domain + ">"
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", e.subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-Id: %s\r\n", messageID)
+	for k, v := range e.headers {
+		fmt.Fprintf(&msg, "%s: %s\r\n", k, v)
+	}
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(e.text, "\n", "\r\n"))
+
+	// Envelope recipients must be bare addresses.
+	to := make([]string, len(e.to))
+	for n, addr := range e.to {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil {
+			return "", err
+		}
+		to[n] = parsed.Address
+	}
+
+	addr := os.Getenv("SMTP_ADDR")
+	var auth smtp.Auth
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	if err := smtp.SendMail(addr, auth, from.Address, to, []byte(msg.String())); err != nil {
+		return "", err
+	}
+	return messageID, nil
+}
@@ -1,44 +1,164 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/joho/godotenv"
 )
 
-const (
-	JuiceworksGuildId    = "1256628364987600977"
-	InternalChannelId    = "1256628365771669556"
-	JuiceworksRoleId     = "1257752490372370503"
-	ProjectCreatorRoleId = "1259262543034060830"
-	ServicesRoleId       = "1260738526425780264"
-)
-
 var commandHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
-	"make-channel": makeChannel,
-	"add-member":   addMember,
+	"make-channel":        makeChannel,
+	"add-member":          addMember,
+	"remove-member":       removeMember,
+	"upcoming":            upcoming,
+	"book":                book,
+	"booking-link":        bookingLinkCommand,
+	"bridge":              bridgeChannel,
+	"email-address":       emailAddress,
+	"digest-email":        digestEmail,
+	"project-info":        projectInfo,
+	"link-deal":           linkDeal,
+	"link-figma":          linkFigma,
+	"milestone":           milestoneCommand,
+	"rate-card":           rateCardCommand,
+	"assign":              assign,
+	"budget":              budgetCommand,
+	"terms":               termsCommand,
+	"role-menu":           roleMenuCommand,
+	"reaction-role":       reactionRoleCommand,
+	"notifications":       notificationsCommand,
+	"health-report":       healthReport,
+	"business-hours":      businessHoursCommand,
+	"rotation":            rotationCommand,
+	"command-channels":    commandChannelsCommand,
+	"help":                help,
+	"undo":                undo,
+	"quarantine":          quarantineUser,
+	"unquarantine":        unquarantineUser,
+	"nicknames":           nicknamesCommand,
+	"emoji":               emojiCommand,
+	"call-log":            callLogCommand,
+	"huddle-button":       huddleButton,
+	"townhall":            townhallCommand,
+	"files":               filesCommand,
+	"deliver":             deliver,
+	"send-contract":       sendContractCommand,
+	"project-template":    projectTemplateCommand,
+	"make-workspace":      makeWorkspace,
+	"merge-projects":      mergeProjects,
+	"rename-project":      renameProject,
+	"find-project":        findProject,
+	"client":              clientCommand,
+	"status":              statusCommand,
+	"kickoff":             kickoffCommand,
+	"snippet":             snippetCommand,
+	"embed":               embedCommand,
+	"pin":                 pinCommand,
+	"unpin":               unpinCommand,
+	"pins":                pinsCommand,
+	"Bookmark":            bookmarkMessage,
+	"bookmarks":           bookmarksCommand,
+	"Remind me":           remindMeMessage,
+	"escalate":            escalateCommand,
+	"incident":            incidentCommand,
+	"whoami":              whoamiCommand,
+	"api-token":           apiTokenCommand,
+	"audit":               auditCommand,
+	"invite-bot":          inviteBotCommand,
+	"permissions-audit":   permissionsAuditCommand,
+	"migrate-permissions": migratePermissionsCommand,
+	"transfer-creator":    transferCreatorCommand,
+	"internal-channel":    internalChannelCommand,
+	"note":                noteCommand,
+	"import-existing":     importExistingCommand,
+	"delete-project":      deleteProjectCommand,
+	"restore-project":     restoreProjectCommand,
+	"who-can-see":         whoCanSeeCommand,
+	"check-access":        checkAccessCommand,
+	"access-button":       accessButtonCommand,
+	"work-with-us":        workWithUsCommand,
+	"sudo":                sudoCommand,
+	"maintenance":         maintenanceCommand,
+	"jobs":                jobsCommand,
+	"integrations":        integrationsCommand,
+	"theme":               themeCommand,
+	"templates":           templatesCommand,
+	"Share to internal":   shareToInternalMessage,
+	"Share to client":     shareToClientMessage,
+	"retention":           retentionCommand,
+	"user-data":           userDataCommand,
+	"secret":              secretCommand,
+}
+
+// Handlers for buttons, other message components and modals, keyed by the part of the custom ID before the first
+// colon. The rest of the custom ID carries the component's arguments.
+var componentHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+	"terms-accept":          acceptTerms,
+	"screening-start":       startScreening,
+	"screening-submit":      submitScreening,
+	"screening-approve":     reviewScreening,
+	"screening-reject":      reviewScreening,
+	"role-menu":             pickRoles,
+	"help":                  helpDetails,
+	"undo":                  confirmUndo,
+	"huddle-start":          startHuddle,
+	"townhall-speak":        requestToSpeak,
+	"deliverable-approve":   approveDeliverable,
+	"deliverable-changes":   requestChanges,
+	"deliverable-feedback":  submitChanges,
+	"find-join":             joinFoundProject,
+	"status-archive":        archiveFromPrompt,
+	"kickoff-attendees":     pickKickoffAttendees,
+	"kickoff-time":          pickKickoffTime,
+	"kickoff-confirm":       confirmKickoff,
+	"snippet-save":          saveSnippet,
+	"embed-post":            postEmbed,
+	"remind-me":             setReminder,
+	"escalation-ack":        acknowledgeEscalation,
+	"audit-page":            auditPageButton,
+	"user-data-delete":      confirmUserDataDelete,
+	"migrate-permissions":   startPermissionMigration,
+	"adopt-channel":         adoptFromPrompt,
+	"access-request":        startAccessRequest,
+	"access-request-submit": submitAccessRequest,
+	"access-approve":        reviewAccessRequest,
+	"access-deny":           reviewAccessRequest,
+	"work-with-us-submit":   submitLead,
+	"lead-scope":            reviewLead,
+	"lead-decline":          reviewLead,
+	"sudo-confirm":          confirmSudo,
+	"integration-test":      testIntegration,
+	"templates-save":        saveTemplate,
 }
 
 func main() {
-	// Load the Discord token from the environment or .env file
-	var discordToken string
-	if t := os.Getenv("DISCORD_TOKEN"); t != "" {
-		discordToken = t
-	} else {
-		if _, err := os.Stat(".env"); err != nil {
-			log.Fatalf("Could not load .env file: %s\n", err)
-		}
-		godotenv.Load(".env")
-		discordToken = os.Getenv("DISCORD_TOKEN")
-		if discordToken == "" {
-			log.Fatalln("Could not find DISCORD_TOKEN in .env file or environment.")
-		}
+	// Load the Discord token from the secret manager, the environment or .env file. Variables already in the
+	// environment win over .env, and the secret manager wins over both.
+	godotenv.Load(".env")
+	if _, err := loadSecrets(); err != nil {
+		log.Fatalf("Could not load secrets: %s\n", err)
+	}
+	discordToken := os.Getenv("DISCORD_TOKEN")
+	if discordToken == "" {
+		log.Fatalln("Could not find DISCORD_TOKEN in the secret manager, .env file or environment.")
+	}
+	if err := checkEnvironment(); err != nil {
+		log.Fatalf("Invalid environment: %s\n", err)
+	}
+
+	// Load persisted data.
+	if err := loadStore(); err != nil {
+		log.Fatalf("Could not load data file: %s\n", err)
 	}
 
 	// Create the Discord session.
@@ -55,33 +175,116 @@ func main() {
 		log.Printf("Logged in as: %s\n", s.State.User)
 	})
 
-	// Call the appropriate command handler when an interaction is created.
-	s.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
-			h(s, i)
+	// Call the appropriate command or component handler when an interaction is created.
+	s.AddHandler(handleInteraction)
+
+	// Ask only for the gateway events the features that are on need, and handle them.
+	configureGateway(s)
+
+	// Keep track of the gateway connection, and page someone if it stays disconnected.
+	s.AddHandler(trackGatewayConnect)
+	s.AddHandler(trackGatewayDisconnect)
+	s.AddHandler(trackGatewayReady)
+	s.AddHandler(trackGatewayResumed)
+
+	// Pick up a rotated bot token when Discord rejects the old one.
+	watchForAuthFailures(s)
+	s.AddHandler(checkDiscordToken)
+
+	// Time how long commands take to respond, and defer the ones that are too slow.
+	watchInteractionLatency(s)
+
+	// Open the Discord session, unless interactions only come over HTTP.
+	if interactionsMode() == interactionsHTTP {
+		if err = startWithoutGateway(s); err != nil {
+			log.Fatalf("Could not start without the gateway: %s\n", err)
 		}
-	})
+	} else {
+		if err = s.Open(); err != nil {
+			log.Fatalf("Could not open Discord session: %s\n", err)
+		}
+		defer s.Close()
+	}
+
+	// Warn about permissions the bot is missing or doesn't need.
+	checkBotPermissions(s)
+
+	// Start receiving messages from bridged platforms.
+	startBridges(s)
+
+	// Send the weekly client digests.
+	startDigests(s)
+
+	// Remind staff about overdue milestones.
+	startMilestoneReminders(s)
+
+	// Alert staff as projects use up their budgets.
+	startBudgetChecks(s)
+
+	// Post the daily report in the internal channel.
+	startDailyReports(s)
+
+	// Rotate who's on point in each project.
+	startRotations(s)
+
+	// Delete huddles nobody is using.
+	startHuddleCleanup(s)
+
+	// Ask staff to archive projects that have been done for a while.
+	startArchivePrompts(s)
+
+	// Ask staff to adopt project channels missing from the registry.
+	startOrphanScans(s)
+
+	// Delete projects that have been in the trash long enough.
+	startTrashPurge(s)
+
+	// Remind attendees about upcoming kickoffs.
+	startKickoffReminders(s)
 
-	// Open the Discord session.
-	if err = s.Open(); err != nil {
-		log.Fatalf("Could not open Discord session: %s\n", err)
+	// DM people about messages they asked to be reminded about.
+	startReminders(s)
+
+	// Page someone about escalations nobody has taken in time.
+	startSLAChecks(s)
+
+	// Purge data that's past its retention policy.
+	startRetention()
+
+	// Pick up rotated secrets.
+	startSecretsRefresh(s)
+
+	// Serve webhooks and links, if configured.
+	if server := startHTTPServer(s); server != nil {
+		defer server.Close()
 	}
-	defer s.Close()
 
-	// Register slash commands in the Juiceworks guild.
-	registeredCommands := make([]*discordgo.ApplicationCommand, len(commands))
-	for i, v := range commands {
-		cmd, err := s.ApplicationCommandCreate(s.State.User.ID, JuiceworksGuildId, v)
-		if err != nil {
-			log.Panicf("Cannot create '%v' command: %v", v.Name, err)
+	// Serve the project API over gRPC, if configured.
+	if server := startGRPCServer(s); server != nil {
+		defer server.Stop()
+	}
+
+	// Register slash commands in this environment's guilds. Aliases are registered as copies of the commands they
+	// stand for.
+	toRegister := append(slices.Clone(commands), aliasCommands()...)
+	var registeredCommands []*discordgo.ApplicationCommand
+	for _, guildID := range commandGuilds() {
+		for _, v := range toRegister {
+			cmd, err := s.ApplicationCommandCreate(s.State.User.ID, guildID, environmentCommand(v))
+			if err != nil {
+				log.Panicf("Cannot create '%v' command: %v", v.Name, err)
+			}
+			registeredCommands = append(registeredCommands, cmd)
 		}
-		registeredCommands[i] = cmd
 	}
 
+	// Tell the uptime monitor the bot is alive, now that it's ready for commands.
+	startHeartbeat()
+
 	// Clean up the commands when the program exits.
 	defer func() {
 		for _, v := range registeredCommands {
-			err := s.ApplicationCommandDelete(s.State.User.ID, JuiceworksGuildId, v.ID)
+			err := s.ApplicationCommandDelete(s.State.User.ID, v.GuildID, v.ID)
 			if err != nil {
 				log.Panicf("Cannot delete '%v' command: %v", v.Name, err)
 			}
@@ -96,116 +299,295 @@ func main() {
 	log.Println("Shutting down...")
 }
 
-// Add a user to a private channel, and grant them the Project Creator role.
+// Add up to ten users to a private channel, and grant them the Project Creator role. Users who still have to accept the
+// terms are sent them instead.
 func addMember(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if err := checkCommandCaller(s, i); err != nil {
-		log.Printf("Command caller check failed on addMember: %v", err)
-		return
+	// Verify the command options.
+	var users []*discordgo.User
+	for _, o := range i.ApplicationCommandData().Options {
+		if o.Type != discordgo.ApplicationCommandOptionUser {
+			continue
+		}
+		user := o.UserValue(s)
+		if !slices.ContainsFunc(users, func(u *discordgo.User) bool { return u.ID == user.ID }) {
+			users = append(users, user)
+		}
 	}
-
-	// Prevent adding new members to the internal channel.
-	if i.ChannelID == InternalChannelId {
+	if len(users) == 0 {
 		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "This command cannot be used in the internal channel.",
+				Content: "This command requires a user.",
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		}))
 		return
 	}
 
-	// Verify the command options.
-	options := i.ApplicationCommandData().Options
-	if len(options) == 0 || options[0].Type != discordgo.ApplicationCommandOptionUser {
+	// What happened for each user, in the order they were given. Errors only say who they're for when there's more
+	// than one user.
+	var lines []string
+	failed := func(user *discordgo.User, step string, err error) {
+		log.Printf("Error %s: %v", step, err)
+		if len(users) > 1 {
+			step += " for " + user.Mention()
+		}
+		lines = append(lines, "Error "+step+": "+describeError(err))
+	}
+
+	// Get the users' roles. External users have to accept the terms before they can see the channel, so they're sent
+	// those, and everyone else is added, keeping what's needed to undo it.
+	projectID := workspaceProjectID(i.ChannelID)
+	var members []*discordgo.Member
+	var undos []func(s *discordgo.Session) error
+	for _, user := range users {
+		member, err := s.GuildMember(JuiceworksGuildId, user.ID)
+		if err != nil {
+			failed(user, "reading member roles", err)
+			continue
+		}
+		if requiresTerms(member) {
+			if err := deliverTerms(s, i.ChannelID, user); err != nil {
+				failed(user, "sending terms", err)
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("Sent the terms to %s. They'll be added to the channel once they agree.", user.Mention()))
+			continue
+		}
+		members = append(members, member)
+		undos = append(undos, undoAddingMember(s, projectID, user, member))
+	}
+
+	// Add them to the project the command was called from. In a workspace, that means all of its channels.
+	where := "the channel"
+	if len(workspaceChannels(projectID)) > 1 {
+		where = "the workspace"
+	}
+	var added []string
+	var reverts []func(s *discordgo.Session) error
+	for n, err := range addProjectMembers(s, projectID, members, i.Member.User.ID) {
+		user := members[n].User
+		var stepErr *memberChangeError
+		if errors.As(err, &stepErr) {
+			failed(user, stepErr.step, stepErr.err)
+			continue
+		}
+		log.Printf("Added %s (%s) to channel %s.", user, user.Mention(), projectID)
+		lines = append(lines, fmt.Sprintf("Added %s to %s.", user.Mention(), where))
+		added = append(added, user.Mention())
+		reverts = append(reverts, undos[n])
+	}
+	if len(added) > 0 {
+		pushUndo(i.Member.User.ID, fmt.Sprintf("Adding %s to <#%s>", strings.Join(added, ", "), projectID), func(s *discordgo.Session) error {
+			var errs []error
+			for _, revert := range reverts {
+				errs = append(errs, revert(s))
+			}
+			return errors.Join(errs...)
+		})
+	}
+
+	// Respond to the interaction.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: truncate(strings.Join(lines, "\n"), 2000),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Remove a user from the private channel the command was called from, or from every channel of its workspace, and put
+// back the nickname they had before they were added.
+func removeMember(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := i.ApplicationCommandData().Options[0].UserValue(s)
+
+	projectID := workspaceProjectID(i.ChannelID)
+	channels := workspaceChannels(projectID)
+	err := removeProjectMember(s, projectID, user.ID, i.Member.User.ID)
+	var failed *memberChangeError
+	if errors.As(err, &failed) {
+		log.Printf("Error %s: %v", failed.step, failed.err)
 		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "This command requires a user.",
+				Content: "Error " + failed.step + ": " + describeError(failed.err),
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		}))
 		return
 	}
-	user := options[0].UserValue(s)
 
-	// Get the user's roles
-	member, err := s.GuildMember(JuiceworksGuildId, user.ID)
-	if err != nil {
-		log.Printf("Error reading member roles: %v", err)
+	where := "the channel"
+	if len(channels) > 1 {
+		where = "the workspace"
+	}
+	log.Printf("Removed %s from channel %s.", user, projectID)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Removed %s from %s.", user.Mention(), where),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// A step of adding or removing a project member that failed, like "granting Project Creator role".
+type memberChangeError struct {
+	step string
+	err  error
+}
+
+func (e *memberChangeError) Error() string { return e.step + ": " + e.err.Error() }
+
+func (e *memberChangeError) Unwrap() error { return e.err }
+
+// Give a user access to a project channel with addProjectMember, on behalf of whoever used the interaction. If it
+// fails, respond to the interaction and return the error.
+func grantChannelAccess(s *discordgo.Session, i *discordgo.InteractionCreate, channelID string, user *discordgo.User, member *discordgo.Member) error {
+	actor := i.User
+	if i.Member != nil {
+		actor = i.Member.User
+	}
+	err := addProjectMember(s, channelID, user, member, actor.ID)
+	var failed *memberChangeError
+	if errors.As(err, &failed) {
+		log.Printf("Error %s: %v", failed.step, failed.err)
 		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Error reading member roles: " + err.Error(),
+				Content: "Error " + failed.step + ": " + describeError(failed.err),
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		}))
-		return
 	}
+	return err
+}
+
+// Give a user access to a project channel: grant them the Project Creator role unless they're a service provider,
+// add them to the channel and the rest of its workspace, and record them in the registry. The first client added
+// becomes the project's client lead. The change is audited as made by addedBy, or over the API if that's empty. Returns
+// a memberChangeError if a step fails.
+func addProjectMember(s *discordgo.Session, channelID string, user *discordgo.User, member *discordgo.Member, addedBy string) error {
+	m := *member
+	m.User = user
+	return addProjectMembers(s, channelID, []*discordgo.Member{&m}, addedBy)[0]
+}
+
+// Give several users access to a project channel at once, like addProjectMember. Every user's permission overwrites,
+// across every channel of the workspace, go out in one batch a few at a time, so onboarding a team takes a couple of
+// round trips rather than one per user. Returns each member's memberChangeError, or nil, in the order given.
+func addProjectMembers(s *discordgo.Session, channelID string, members []*discordgo.Member, addedBy string) []error {
+	errs := make([]error, len(members))
 
-	// Check if the user is a service provider
-	isServiceProvider := false
-	for _, roleID := range member.Roles {
-		if roleID == ServicesRoleId {
-			isServiceProvider = true
-			break
+	// Grant the Project Creator role to everyone who isn't a service provider.
+	for n, member := range members {
+		if slices.Contains(member.Roles, ServicesRoleId) {
+			continue
+		}
+		if err := s.GuildMemberRoleAdd(JuiceworksGuildId, member.User.ID, ProjectCreatorRoleId); err != nil {
+			errs[n] = &memberChangeError{"granting Project Creator role", err}
 		}
 	}
 
-	// If the user isn't a service provider, grant them the Project Creator role.
-	if !isServiceProvider {
-		if err := s.GuildMemberRoleAdd(JuiceworksGuildId, user.ID, ProjectCreatorRoleId); err != nil {
-			log.Printf("Error granting Project Creator role: %v", err)
-			logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "Error granting Project Creator role: " + err.Error(),
-					Flags:   discordgo.MessageFlagsEphemeral,
-				},
-			}))
-			return
+	// Add them to the channel, and the other channels in its workspace.
+	channelID = workspaceProjectID(channelID)
+	channels := workspaceChannels(channelID)
+	var changes []func() error
+	var owners []int
+	for n, member := range members {
+		if errs[n] == nil {
+			for _, change := range joinChanges(s, channels, member.User.ID) {
+				changes = append(changes, change)
+				owners = append(owners, n)
+			}
+		}
+	}
+	for k, err := range runOverwrites(changes) {
+		if n := owners[k]; err != nil && errs[n] == nil {
+			errs[n] = &memberChangeError{"adding member to channel", err}
 		}
 	}
 
-	// Add the user to the channel the command was called from.
-	if err := channelPermissions(&channelPermissionSetup{
-		s:           s,
-		channelID:   i.ChannelID,
-		targetID:    user.ID,
-		targetType:  discordgo.PermissionOverwriteTypeMember,
-		allow:       discordgo.PermissionViewChannel | discordgo.PermissionSendMessages,
-		deny:        0,
-		interaction: i,
-	}); err != nil {
-		log.Printf("Error adding member to channel: %v", err)
-		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Error adding member to channel: " + err.Error(),
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		}))
-		return
+	for n, member := range members {
+		if errs[n] == nil {
+			recordProjectMember(s, channelID, member, addedBy)
+		}
 	}
+	return errs
+}
 
-	// Respond to the interaction.
-	log.Printf("Added %s (%s) to channel %s.", user, user.Mention(), i.ChannelID)
-	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: fmt.Sprintf("Added %s to the channel.", user.Mention()),
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
-	}))
+// Record a member who was just given access to a project in the registry, make them its client lead if it has none,
+// and let anything following the project know.
+func recordProjectMember(s *discordgo.Session, channelID string, member *discordgo.Member, addedBy string) {
+	user := member.User
+	isServiceProvider := slices.Contains(member.Roles, ServicesRoleId)
+	becameCreator := false
+	err := updateProject(channelID, func(p *project) {
+		if p.Name == "" {
+			if channel, err := s.State.Channel(channelID); err == nil {
+				p.Name = channel.Name
+			}
+		}
+		if p.Members == nil {
+			p.Members = make(map[string]string)
+		}
+		p.Members[user.ID] = user.Username
+		if p.CreatorID == "" && !isServiceProvider {
+			p.CreatorID = user.ID
+			becameCreator = true
+		}
+	})
+	if err != nil {
+		log.Printf("Error recording project member: %v", err)
+	}
+	if roleID := os.Getenv("PROJECT_LEAD_ROLE_ID"); becameCreator && roleID != "" {
+		if err := s.GuildMemberRoleAdd(JuiceworksGuildId, user.ID, roleID); err != nil {
+			log.Printf("Error granting project lead role: %v", err)
+		}
+	}
+	applyClientNickname(s, channelID, member)
+	emitEvent("member.added", memberAddedEvent{ChannelID: channelID, UserID: user.ID, Username: user.Username})
+	auditMemberChange(s, auditMemberAdded, addedBy, user.ID, channelID)
 }
 
-// Make a private channel for a new project. Add the project creator and Juiceworks members to the channel.
-func makeChannel(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if err := checkCommandCaller(s, i); err != nil {
-		log.Printf("Command caller check failed on makeChannel: %v", err)
-		return
+// Take a user out of a project channel and the rest of its workspace, and out of the registry. A client lead who
+// leaves loses the project lead role unless they lead another project, and gets back the nickname they had before.
+// The change is audited as made by removedBy, or over the API if that's empty. Returns a memberChangeError if they
+// can't be taken out of the channels or the registry.
+func removeProjectMember(s *discordgo.Session, projectID, userID, removedBy string) error {
+	if err := leaveWorkspace(s, projectID, userID); err != nil {
+		return &memberChangeError{"removing member from channel", err}
+	}
+	if _, ok := getProject(projectID); !ok {
+		return nil
 	}
+	var username string
+	wasCreator := false
+	err := updateProject(projectID, func(p *project) {
+		username = p.Members[userID]
+		delete(p.Members, userID)
+		if p.CreatorID == userID {
+			p.CreatorID = ""
+			wasCreator = true
+		}
+	})
+	if err != nil {
+		return &memberChangeError{"removing member from registry", err}
+	}
+	if roleID := os.Getenv("PROJECT_LEAD_ROLE_ID"); wasCreator && roleID != "" && len(ledProjects(userID)) == 0 {
+		if err := s.GuildMemberRoleRemove(JuiceworksGuildId, userID, roleID); err != nil {
+			log.Printf("Error removing project lead role: %v", err)
+		}
+	}
+	revertClientNickname(s, projectID, userID)
+	emitEvent("member.removed", memberRemovedEvent{ChannelID: projectID, UserID: userID, Username: username})
+	auditMemberChange(s, auditMemberRemoved, removedBy, userID, projectID)
+	return nil
+}
 
+// Make a private channel for a new project. Add the project creator and Juiceworks members to the channel.
+func makeChannel(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	// Verify the command options.
 	options := i.ApplicationCommandData().Options
 	if len(options) == 0 || options[0].Type != discordgo.ApplicationCommandOptionString {
@@ -220,44 +602,65 @@ func makeChannel(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 
 	// Clean up the channel name.
-	channelName := options[0].StringValue()
-	channelName = strings.TrimSpace(channelName)
-	channelName = strings.ToLower(channelName)
-	channelName = strings.ReplaceAll(channelName, " ", "-")
-	if len(channelName) < 2 || len(channelName) > 100 {
+	channelName := sanitizeChannelName(options[0].StringValue())
+	if utf8.RuneCountInString(channelName) < 2 {
 		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Channel name must be between 2 and 100 characters.",
+				Content: "Channel name must have at least 2 letters, numbers or emoji.",
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		}))
 		return
 	}
 
-	// Create the channel.
-	channel, err := s.GuildChannelCreate(JuiceworksGuildId, channelName, discordgo.ChannelTypeGuildText)
-	if err != nil {
+	// Look up the template the project is set up from, if any.
+	tmpl := projectTemplate{HuddleButton: true}
+	var tmplName string
+	internal := false
+	for _, o := range options[1:] {
+		if o.Name == "internal" {
+			internal = o.BoolValue()
+			continue
+		}
+		if o.Name != "template" {
+			continue
+		}
+		tmplName = strings.ToLower(o.StringValue())
+		var ok bool
+		if tmpl, ok = getTemplate(o.StringValue()); !ok {
+			logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "There's no project template named " + o.StringValue() + ".",
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			}))
+			return
+		}
+	}
+
+	channel, err := createProjectChannel(s, channelName, i.Member.User.ID, tmplName, tmpl)
+	var failed *channelSetupError
+	if errors.As(err, &failed) {
 		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Error creating channel: " + err.Error(),
+				Content: channelErrorContent(s, failed.action, failed.err, failed.permission, failed.where),
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		}))
 		return
 	}
 
-	// Set the permissions for the channel.
-	permissionsToSet := []channelPermissionSetup{
-		// Add the Juiceworks role to the channel.
-		{s, channel.ID, JuiceworksRoleId, discordgo.PermissionOverwriteTypeRole, discordgo.PermissionViewChannel | discordgo.PermissionSendMessages, 0, i},
-		// Make the channel private.
-		{s, channel.ID, JuiceworksGuildId, discordgo.PermissionOverwriteTypeRole, 0, discordgo.PermissionViewChannel, i},
-	}
-	for _, p := range permissionsToSet {
-		if err := channelPermissions(&p); err != nil {
-			return
+	// Pair the channel with an internal one, if asked to.
+	content := "Created channel: #" + channel.Name
+	if internal {
+		p, _ := getProject(channel.ID)
+		if internalChannel, err := createInternalChannel(s, p); err != nil {
+			content += "\n" + channelErrorContent(s, "creating internal channel", err, "Manage Channels and Manage Roles", channelLocation(tmpl.CategoryID))
+		} else {
+			content += fmt.Sprintf(" and its internal channel <#%s>", internalChannel.ID)
 		}
 	}
 
@@ -266,82 +669,229 @@ func makeChannel(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: "Created channel: #" + channel.Name,
+			Content: content,
 			Flags:   discordgo.MessageFlagsEphemeral,
 		},
 	}))
 }
 
-// A struct to hold the parameters for setting channel permissions.
-type channelPermissionSetup struct {
-	s           *discordgo.Session
-	channelID   string
-	targetID    string
-	targetType  discordgo.PermissionOverwriteType
-	allow       int64
-	deny        int64
-	interaction *discordgo.InteractionCreate
+// A step of setting up a project channel that Discord refused: what the bot was doing, the permission it needs for
+// that, and where it needs it.
+type channelSetupError struct {
+	action     string
+	permission string
+	where      string
+	err        error
 }
 
-// Set the permissions for a channel. If it fails, respond to the interaction and log/return the error.
-func channelPermissions(cps *channelPermissionSetup) error {
-	err := cps.s.ChannelPermissionSet(cps.channelID, cps.targetID, cps.targetType, cps.allow, cps.deny)
+func (e *channelSetupError) Error() string { return e.action + ": " + e.err.Error() }
+
+func (e *channelSetupError) Unwrap() error { return e.err }
+
+// Create a project channel from a template, make it private to Juiceworks members and the template's roles, record it
+// in the registry, and post the template's pinned messages. The name must already be sanitized. Returns a
+// channelSetupError if Discord refuses a step.
+func createProjectChannel(s *discordgo.Session, name, createdBy, tmplName string, tmpl projectTemplate) (*discordgo.Channel, error) {
+	// Create the channel.
+	channel, err := s.GuildChannelCreateComplex(JuiceworksGuildId, discordgo.GuildChannelCreateData{
+		Name:     name,
+		Type:     discordgo.ChannelTypeGuildText,
+		Topic:    strings.ReplaceAll(tmpl.Topic, "{name}", name),
+		ParentID: tmpl.CategoryID,
+	})
 	if err != nil {
-		logResponseErr(cps.s.InteractionRespond(cps.interaction.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Error setting channel permissions: " + err.Error(),
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		}))
-		log.Printf("Error setting channel permissions: %v", err)
-		return err
+		return nil, &channelSetupError{"creating channel", "Manage Channels", channelLocation(tmpl.CategoryID), err}
 	}
 
-	return nil
+	// Set the permissions for the channel.
+	type overwrite struct {
+		roleID      string
+		allow, deny int64
+	}
+	overwrites := []overwrite{
+		// Add the Juiceworks role to the channel.
+		{JuiceworksRoleId, discordgo.PermissionViewChannel | discordgo.PermissionSendMessages, 0},
+		// Make the channel private.
+		{JuiceworksGuildId, 0, discordgo.PermissionViewChannel},
+	}
+	// Share the channel with the template's roles.
+	for _, roleID := range tmpl.RoleIDs {
+		overwrites = append(overwrites, overwrite{roleID, discordgo.PermissionViewChannel | discordgo.PermissionSendMessages, 0})
+	}
+	for _, o := range overwrites {
+		if err := s.ChannelPermissionSet(channel.ID, o.roleID, discordgo.PermissionOverwriteTypeRole, o.allow, o.deny); err != nil {
+			return nil, &channelSetupError{"setting channel permissions", "Manage Roles", "<#" + channel.ID + ">", err}
+		}
+	}
+
+	// Record the project in the registry.
+	err = updateProject(channel.ID, func(p *project) {
+		p.Name = channel.Name
+		p.CreatedAt = time.Now()
+		p.CreatedBy = createdBy
+		p.TopicTemplate = tmpl.Topic
+		p.Template = tmplName
+		p.TemplateRoleIDs = tmpl.RoleIDs
+	})
+	if err != nil {
+		log.Printf("Error recording project: %v", err)
+	}
+	emitEvent("project.created", projectCreatedEvent{ChannelID: channel.ID, Name: channel.Name, CreatedBy: createdBy})
+	auditProjectCreation(s, createdBy, channel.ID)
+
+	// Post the template's pinned messages, and let the project's members start a huddle.
+	postTemplatePins(s, channel.ID, tmpl)
+	if tmpl.HuddleButton {
+		if err := postHuddleButton(s, channel.ID); err != nil {
+			log.Printf("Error posting huddle button: %v", err)
+		}
+	}
+	return channel, nil
 }
 
 // Wrapper to log an error if responding to an interaction fails.
 func logResponseErr(err error) {
 	if err != nil {
 		log.Printf("Error responding to interaction: %v", err)
+		noteCommandError()
 	}
 }
 
-// Make sure a command is being called in the Juiceworks Discord server, by a Juiceworks member.
-func checkCommandCaller(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	// Check if the command was called in the Juiceworks Discord server.
-	if i.GuildID != JuiceworksGuildId || i.Member == nil {
-		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "This command can only be used in the Juiceworks Discord server.",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		}))
-		return fmt.Errorf("command was called outside of the Juiceworks Discord server")
+// Shorten a string to at most n characters, ending it with an ellipsis if it was cut.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
 	}
+	return string(r[:n-1]) + "…"
+}
 
-	// Check if the caller has the Juiceworks role.
-	callerHasJuiceworksRole := false
-	for _, role := range i.Member.Roles {
-		if role == JuiceworksRoleId {
-			callerHasJuiceworksRole = true
-			break
-		}
-	}
-	if !callerHasJuiceworksRole {
-		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "This command can only be used by Juiceworks members.",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		}))
-		return fmt.Errorf("command was called by a non-Juiceworks member")
-	}
+// Hide admin commands from members without the Administrator permission.
+var adminPermissions int64 = discordgo.PermissionAdministrator
 
-	return nil
+// Who can use each command, and where. Commands without a policy can't be used.
+var commandPolicies = map[string]policy{
+	"make-channel":        {staff: true, notInProjects: true},
+	"add-member":          projectPolicy,
+	"remove-member":       projectPolicy,
+	"upcoming":            staffPolicy,
+	"book":                staffPolicy,
+	"booking-link":        staffPolicy,
+	"bridge":              projectPolicy,
+	"email-address":       projectPolicy,
+	"digest-email":        staffPolicy,
+	"project-info":        staffPolicy,
+	"link-deal":           staffPolicy,
+	"link-figma":          staffPolicy,
+	"milestone":           staffPolicy,
+	"rate-card":           adminPolicy,
+	"assign":              staffPolicy,
+	"budget":              adminPolicy,
+	"terms":               adminPolicy,
+	"role-menu":           adminPolicy,
+	"reaction-role":       adminPolicy,
+	"notifications":       memberPolicy,
+	"health-report":       staffPolicy,
+	"business-hours":      adminPolicy,
+	"rotation":            staffPolicy,
+	"command-channels":    adminPolicy,
+	"help":                memberPolicy,
+	"undo":                memberPolicy,
+	"quarantine":          adminPolicy,
+	"unquarantine":        adminPolicy,
+	"nicknames":           projectPolicy,
+	"emoji":               staffPolicy,
+	"call-log":            staffPolicy,
+	"huddle-button":       projectPolicy,
+	"townhall":            adminPolicy,
+	"files":               projectPolicy,
+	"deliver":             projectPolicy,
+	"send-contract":       projectPolicy,
+	"project-template":    adminPolicy,
+	"make-workspace":      {staff: true, notInProjects: true},
+	"merge-projects":      projectPolicy,
+	"rename-project":      projectPolicy,
+	"find-project":        staffPolicy,
+	"client":              staffPolicy,
+	"status":              projectPolicy,
+	"kickoff":             projectPolicy,
+	"snippet":             staffPolicy,
+	"embed":               staffPolicy,
+	"pin":                 projectPolicy,
+	"unpin":               projectPolicy,
+	"pins":                projectPolicy,
+	"Bookmark":            memberPolicy,
+	"bookmarks":           memberPolicy,
+	"Remind me":           memberPolicy,
+	"escalate":            {notInternal: true},
+	"incident":            staffPolicy,
+	"whoami":              anyonePolicy,
+	"api-token":           adminPolicy,
+	"audit":               adminPolicy,
+	"invite-bot":          adminPolicy,
+	"permissions-audit":   adminPolicy,
+	"migrate-permissions": adminPolicy,
+	"transfer-creator":    projectPolicy,
+	"internal-channel":    projectPolicy,
+	"note":                projectPolicy,
+	"import-existing":     adminPolicy,
+	"delete-project":      adminPolicy,
+	"restore-project":     adminPolicy,
+	"who-can-see":         staffPolicy,
+	"check-access":        staffPolicy,
+	"access-button":       adminPolicy,
+	"work-with-us":        anyonePolicy,
+	"sudo":                staffPolicy,
+	"maintenance":         adminPolicy,
+	"jobs":                adminPolicy,
+	"integrations":        adminPolicy,
+	"theme":               adminPolicy,
+	"templates":           adminPolicy,
+	"Share to internal":   staffPolicy,
+	"Share to client":     staffPolicy,
+	"retention":           adminPolicy,
+	"user-data":           adminPolicy,
+	"secret":              {staff: true, admin: true, notInternal: true},
+}
+
+// Who can use each component handler. New members answer screening questions and accept the terms in DMs.
+var componentPolicies = map[string]policy{
+	"terms-accept":          anyonePolicy,
+	"screening-start":       anyonePolicy,
+	"screening-submit":      anyonePolicy,
+	"screening-approve":     staffPolicy,
+	"screening-reject":      staffPolicy,
+	"role-menu":             memberPolicy,
+	"help":                  memberPolicy,
+	"undo":                  memberPolicy,
+	"huddle-start":          memberPolicy,
+	"townhall-speak":        staffPolicy,
+	"deliverable-approve":   memberPolicy,
+	"deliverable-changes":   memberPolicy,
+	"deliverable-feedback":  memberPolicy,
+	"find-join":             staffPolicy,
+	"status-archive":        staffPolicy,
+	"kickoff-attendees":     projectPolicy,
+	"kickoff-time":          projectPolicy,
+	"kickoff-confirm":       projectPolicy,
+	"snippet-save":          staffPolicy,
+	"embed-post":            staffPolicy,
+	"remind-me":             memberPolicy,
+	"escalation-ack":        staffPolicy,
+	"audit-page":            adminPolicy,
+	"user-data-delete":      adminPolicy,
+	"migrate-permissions":   adminPolicy,
+	"adopt-channel":         staffPolicy,
+	"access-request":        memberPolicy,
+	"access-request-submit": memberPolicy,
+	"access-approve":        staffPolicy,
+	"access-deny":           staffPolicy,
+	"work-with-us-submit":   anyonePolicy,
+	"lead-scope":            staffPolicy,
+	"lead-decline":          staffPolicy,
+	"sudo-confirm":          staffPolicy,
+	"integration-test":      adminPolicy,
+	"templates-save":        adminPolicy,
 }
 
 // The slash commands to register in the Juiceworks guild.
@@ -358,12 +908,22 @@ var commands = []*discordgo.ApplicationCommand{
 				Description: "What to name the channel",
 				Required:    true,
 			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "template",
+				Description: "The project template to set the channel up from",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "internal",
+				Description: "Also create an internal channel only Juiceworks members can see",
+			},
 		},
 	},
 	{
 		Type:        discordgo.ChatApplicationCommand,
 		Name:        "add-member",
-		Description: "Add a member to this channel. Use in a channel to add someone.",
+		Description: "Add members to this channel. Use in a channel to add up to ten people at once.",
 		GuildID:     JuiceworksGuildId,
 		Options: []*discordgo.ApplicationCommandOption{
 			{
@@ -372,6 +932,2149 @@ var commands = []*discordgo.ApplicationCommand{
 				Description: "The user to add to the channel",
 				Required:    true,
 			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user2",
+				Description: "Another user to add",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user3",
+				Description: "Another user to add",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user4",
+				Description: "Another user to add",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user5",
+				Description: "Another user to add",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user6",
+				Description: "Another user to add",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user7",
+				Description: "Another user to add",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user8",
+				Description: "Another user to add",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user9",
+				Description: "Another user to add",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user10",
+				Description: "Another user to add",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "remove-member",
+		Description: "Remove a user from this channel.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "The user to remove",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "upcoming",
+		Description: "List the next calendar events for this project.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "book",
+		Description: "Post the link for booking a call with the project's lead.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "booking-link",
+		Description: "Set the scheduling link /book posts in projects you lead.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "url",
+				Description: "Your Cal.com or Calendly link, or \"none\" to remove it",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "bridge",
+		Description: "Bridge this channel to another chat platform. Leave the room empty to remove the bridge.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "platform",
+				Description: "The platform to bridge to",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Slack", Value: "slack"},
+					{Name: "Matrix", Value: "matrix"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "room",
+				Description: "The Slack channel ID or Matrix room ID to bridge to",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "email-address",
+		Description: "Show the email address that posts to this channel.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "digest-email",
+		Description: "Send this project's weekly digest to a client. Leave the email empty to stop sending it.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "email",
+				Description: "The client's email address",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "project-info",
+		Description: "Show the registry entry for this project.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "link-deal",
+		Description: "Link this project to a HubSpot deal.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "deal-id",
+				Description: "The HubSpot deal ID",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "link-figma",
+		Description: "Link a Figma file to this project.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "file",
+				Description: "The Figma file link or key",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "milestone",
+		Description: "Manage this project's billing milestones.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Add a billing milestone.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "What the milestone is for",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "amount",
+						Description: "The amount to invoice",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "due",
+						Description: "The payment due date, like 2024-12-31",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "mark-paid",
+				Description: "Mark a milestone as paid.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The milestone's name",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List milestones and the outstanding balance.",
+			},
+		},
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "rate-card",
+		Description:              "Manage members' standard hourly rates.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Set a member's hourly rate.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "The member",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "hourly-rate",
+						Description: "Their hourly rate",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove a member's rate card.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "The member",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List all rate cards.",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "assign",
+		Description: "Assign a contractor to this project.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "The contractor to assign",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "role",
+				Description: "Their role on the project",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "rate",
+				Description: "The hourly rate for this project, if different from their rate card (administrators only)",
+			},
+		},
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "budget",
+		Description:              "Manage this project's budget for call time.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Set the budget, and the hourly rate for staff who aren't assigned.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "amount",
+						Description: "The budget",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "hourly-rate",
+						Description: "The hourly rate for staff who aren't assigned to the project",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "clear",
+				Description: "Remove the budget.",
+			},
+		},
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "terms",
+		Description:              "Manage the terms external users accept before joining a project.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Set the terms.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "text",
+						Description: "The terms external users have to agree to",
+						Required:    true,
+						MaxLength:   4000,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "clear",
+				Description: "Stop asking external users to agree to terms.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "show",
+				Description: "Show the current terms.",
+			},
+		},
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "role-menu",
+		Description:              "Manage the menu members use to pick their own roles.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Offer a role in the menu.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "The role to offer",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "description",
+						Description: "A short description shown under the role",
+						MaxLength:   100,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Stop offering a role in the menu.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "The role to remove",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "post",
+				Description: "Post the menu in this channel, replacing the previous one.",
+			},
+		},
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "reaction-role",
+		Description:              "Grant roles to members who react to a message.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Grant a role to members who react with an emoji.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "message",
+						Description: "A link to the message, or its ID if it's in this channel",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "emoji",
+						Description: "The emoji to react with",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "The role to grant",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Stop granting a role for an emoji.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "message",
+						Description: "A link to the message, or its ID if it's in this channel",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "emoji",
+						Description: "The emoji to react with",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List all reaction roles.",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "notifications",
+		Description: "Choose how much the bot pings you about this project.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "level",
+				Description: "What to be pinged about",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Nothing", Value: notifyMute},
+					{Name: "Messages for me", Value: notifyMentions},
+					{Name: "Everything", Value: notifyAll},
+				},
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "health-report",
+		Description: "List the projects that need the most attention.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "business-hours",
+		Description:              "Manage the team's business hours.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Set the business hours.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "start",
+						Description: "Opening time, like 09:00",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "end",
+						Description: "Closing time, like 17:00",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "timezone",
+						Description: "Time zone, like America/New_York",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "weekdays",
+						Description: "Working days, like mon,tue,wed,thu,fri (the default)",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "auto-reply",
+						Description: "Auto-reply to clients outside business hours (on by default)",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "clear",
+				Description: "Treat the team as always available.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "show",
+				Description: "Show the business hours.",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "rotation",
+		Description: "Manage who's on point for this project.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Set the staff who take turns being on point, one week each.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user1",
+						Description: "The member on point first",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user2",
+						Description: "The next member",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user3",
+						Description: "The next member",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user4",
+						Description: "The next member",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user5",
+						Description: "The next member",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user6",
+						Description: "The next member",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user7",
+						Description: "The next member",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user8",
+						Description: "The next member",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "clear",
+				Description: "Remove the rotation.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "show",
+				Description: "Show the rotation.",
+			},
+		},
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "command-channels",
+		Description:              "Manage which channels each command can be used in.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "allow",
+				Description: "Only allow a command in this channel or category, and any others allowed.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "command",
+						Description: "The command, like make-channel",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "The channel or category",
+						Required:     true,
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildCategory},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "deny",
+				Description: "Block a command in a channel or category.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "command",
+						Description: "The command, like make-channel",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "The channel or category",
+						Required:     true,
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildCategory},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reset",
+				Description: "Go back to a command's default channels.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "command",
+						Description: "The command, like make-channel",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the channel rules.",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "help",
+		Description: "List the commands you can use here.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "undo",
+		Description: "Undo your most recent action from the last 10 minutes.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "quarantine",
+		Description:              "Remove a possibly compromised user's roles and project access.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "The user to quarantine",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "unquarantine",
+		Description:              "Restore a quarantined user's roles and project access.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "The user to restore",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "nicknames",
+		Description: "Prefix the nicknames of clients added to this project with the client's name.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "mode",
+				Description: "Whether to prefix nicknames when clients are added, or also keep them prefixed",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "off", Value: nicknamesOff},
+					{Name: "prefix", Value: nicknamesPrefix},
+					{Name: "enforce", Value: nicknamesEnforce},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "client",
+				Description: "The client's name, like Acme",
+				MaxLength:   20,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "emoji",
+		Description: "Manage the server's custom emoji.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "upload",
+				Description: "Upload an image as a custom emoji.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The emoji's name, like juice",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionAttachment,
+						Name:        "image",
+						Description: "A PNG, JPEG, GIF or WebP image under 256 KB",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove a custom emoji.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The emoji's name",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the custom emoji.",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "call-log",
+		Description: "Track time spent in this project's voice channels.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "track",
+				Description: "Count time in a voice channel towards this project.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "The voice channel",
+						Required:     true,
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice, discordgo.ChannelTypeGuildStageVoice},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "untrack",
+				Description: "Stop counting time in a voice channel towards this project.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "The voice channel",
+						Required:     true,
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice, discordgo.ChannelTypeGuildStageVoice},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "summary",
+				Description: "Show how long each person spent in calls.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "days",
+						Description: "How many days back to count, 30 by default",
+						MaxValue:    180,
+					},
+				},
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "huddle-button",
+		Description: "Post and pin a button to start a huddle in this project.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "townhall",
+		Description:              "Schedule and run town halls on a stage channel.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "schedule",
+				Description: "Schedule a town hall and announce it.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "topic",
+						Description: "What the town hall is about",
+						Required:    true,
+						MaxLength:   100,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "start",
+						Description: "When it starts, like 2024-07-01 16:00",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "stage",
+						Description:  "The stage channel to hold it in",
+						Required:     true,
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildStageVoice},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "description",
+						Description: "More about the town hall",
+						MaxLength:   1000,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "start",
+				Description: "Start the scheduled town hall.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "queue",
+				Description: "List who's waiting to speak.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "next",
+				Description: "Bring the next person in the queue up to speak.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "end",
+				Description: "End the town hall and post a summary.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "notes",
+						Description: "Notes for the summary, like a link to the recording",
+					},
+				},
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "files",
+		Description: "List the files archived from this project.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "deliver",
+		Description: "Hand a deliverable to the client for review.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "milestone",
+				Description: "The milestone it's for",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "title",
+				Description: "What's being delivered",
+				Required:    true,
+				MaxLength:   256,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionAttachment,
+				Name:        "file",
+				Description: "The file to deliver",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "link",
+				Description: "A link to the deliverable",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "send-contract",
+		Description: "Send the contract to the client to sign.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "name",
+				Description: "The signer's name",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "email",
+				Description: "The signer's email address",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "project-template",
+		Description:              "Manage the templates new projects can be set up from.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Create a template or change its settings.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The template's name",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "category",
+						Description:  "The category to create channels in",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildCategory},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "topic",
+						Description: "The channel topic. {name} is replaced with the channel's name",
+						MaxLength:   1024,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "huddle-button",
+						Description: "Whether to post the huddle button",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "role",
+				Description: "Share channels made from a template with a role, or stop sharing them.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The template's name",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "The role",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "pin",
+				Description: "Add a message to post and pin in channels made from a template.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The template's name",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "message",
+						Description: "The message",
+						Required:    true,
+						MaxLength:   2000,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "clear-pins",
+				Description: "Remove a template's pinned messages.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The template's name",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "delete",
+				Description: "Delete a template.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The template's name",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the templates.",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "make-workspace",
+		Description: "Create a category of channels for a new project, with shared membership.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "name",
+				Description: "What to name the workspace",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "channels",
+				Description: "Comma-separated channels to create. Defaults to general, dev, design, billing",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "merge-projects",
+		Description: "Merge a duplicate project into this one and archive its channel.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "source",
+				Description:  "The duplicate project's channel",
+				Required:     true,
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "copy-pins",
+				Description: "Re-post the duplicate's pinned messages here",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "rename-project",
+		Description: "Rename this project's channels and registry entry.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "name",
+				Description: "The new name",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "find-project",
+		Description: "Search projects by name, client or deal.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "query",
+				Description: "What to search for",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "client",
+		Description: "Manage the client directory.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Add a client.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The client's name",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "email",
+						Description: "Where to send invoices",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "billing",
+						Description: "Billing details, like an address or tax number",
+						MaxLength:   1024,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Change a client's email address or billing details.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The client's name",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "email",
+						Description: "Where to send invoices",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "billing",
+						Description: "Billing details, like an address or tax number",
+						MaxLength:   1024,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "contact",
+				Description: "Add or update a contact at a client.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The client's name",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "contact-name",
+						Description: "The contact's name",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "contact-email",
+						Description: "The contact's email address",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "role",
+						Description: "What they do",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "link",
+				Description: "Link this project to a client.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The client's name",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "group",
+				Description: "Keep a client's project channels in a category of their own.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The client's name",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "enabled",
+						Description: "Whether to group their channels",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "info",
+				Description: "Show a client's details, projects and outstanding invoices.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The client's name",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the clients.",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "status",
+		Description: "Show or change where this project is in its workflow.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "status",
+				Description: "The status to move the project to",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Lead", Value: "lead"},
+					{Name: "Scoping", Value: "scoping"},
+					{Name: "Active", Value: "active"},
+					{Name: "Review", Value: "review"},
+					{Name: "Done", Value: "done"},
+					{Name: "Archived", Value: "archived"},
+				},
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "kickoff",
+		Description: "Schedule this project's kickoff call.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "agenda",
+				Description: "The agenda to post, instead of the standard one",
+				MaxLength:   1000,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "snippet",
+		Description: "Save and send canned responses.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "save",
+				Description: "Save a snippet, or change one.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The snippet's name",
+						Required:    true,
+						MaxLength:   32,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "scope",
+						Description: "Just for you, or shared with the team. Defaults to personal",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Personal", Value: "personal"},
+							{Name: "Shared with the team", Value: "guild"},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "send",
+				Description: "Send a snippet here, with its placeholders filled in.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The snippet's name",
+						Required:    true,
+						MaxLength:   32,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "delete",
+				Description: "Delete a snippet.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The snippet's name",
+						Required:    true,
+						MaxLength:   32,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "scope",
+						Description: "Just for you, or shared with the team. Defaults to personal",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Personal", Value: "personal"},
+							{Name: "Shared with the team", Value: "guild"},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the snippets you can send.",
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "embed",
+		Description: "Post an announcement embed in this channel.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "pin",
+		Description: "Pin a message and file it under a label.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "message",
+				Description: "A link to the message, or its ID if it's in this channel",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "label",
+				Description: "What kind of thing it is, like brief, assets or invoices",
+				MaxLength:   32,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "unpin",
+		Description: "Unpin a message.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "message",
+				Description: "A link to the message, or its ID if it's in this channel",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "pins",
+		Description: "List this project's pinned messages by label.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "label",
+				Description: "Only list pins with this label",
+			},
+		},
+	},
+	{
+		Type:    discordgo.MessageApplicationCommand,
+		Name:    "Bookmark",
+		GuildID: JuiceworksGuildId,
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "bookmarks",
+		Description: "Messages you've bookmarked.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List your bookmarks.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove a bookmark.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "number",
+						Description: "The bookmark's number in /bookmarks list",
+						Required:    true,
+					},
+				},
+			},
+		},
+	},
+	{
+		Type:    discordgo.MessageApplicationCommand,
+		Name:    "Remind me",
+		GuildID: JuiceworksGuildId,
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "escalate",
+		Description: "Page the Juiceworks team about something urgent in this project.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "reason",
+				Description: "What's wrong",
+				Required:    true,
+				MaxLength:   1000,
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "incident",
+		Description: "Work through an incident in its own channel.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "open",
+				Description: "Open a private channel for an incident.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "title",
+						Description: "What's going on",
+						MaxLength:   200,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "escalation",
+						Description: "The number of an escalation to open a channel for, instead of a new incident",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "responder",
+						Description: "Someone to bring in",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "responder2",
+						Description: "Someone else to bring in",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "responder3",
+						Description: "Someone else to bring in",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "close",
+				Description: "Close the incident, export its timeline and delete this channel.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "summary",
+						Description: "How it was resolved",
+						MaxLength:   1000,
+					},
+				},
+			},
+		},
+	},
+	{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "whoami",
+		Description: "See which portal account your Discord account is linked to.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "api-token",
+		Description:              "Manage tokens for the bot's API.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "create",
+				Description: "Create a token.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "What the token is for, like juicectl or the ops dashboard",
+						Required:    true,
+						MaxLength:   100,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "scope",
+						Description: "What the token can do",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Read only", Value: scopeRead},
+							{Name: "Manage projects and hooks", Value: scopeProjectAdmin},
+							{Name: "Everything", Value: scopeAdmin},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "revoke",
+				Description: "Revoke a token.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "id",
+						Description: "The token's ID, from /api-token list",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the tokens.",
+			},
+		},
+	},
+	{
+		Name:                     "audit",
+		Description:              "Search the audit log.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "actor",
+				Description: "Only actions by this user",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "target",
+				Description: "Only actions done to this user",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionChannel,
+				Name:        "channel",
+				Description: "Only actions in this channel",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "action",
+				Description: "Only this kind of action",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Denied commands", Value: auditDenied},
+					{Name: "Emoji uploaded", Value: auditEmojiUploaded},
+					{Name: "Emoji removed", Value: auditEmojiRemoved},
+					{Name: "Projects merged", Value: auditMerged},
+					{Name: "Users quarantined", Value: auditQuarantined},
+					{Name: "Quarantines lifted", Value: auditUnquarantined},
+					{Name: "Projects renamed", Value: auditRenamed},
+					{Name: "User data deleted", Value: auditUserDataDeleted},
+					{Name: "Bot token rotated", Value: auditTokenRotated},
+					{Name: "Permissions migrated", Value: auditPermissionsMigrated},
+					{Name: "Channel adopted", Value: auditChannelAdopted},
+					{Name: "Project deleted", Value: auditProjectDeleted},
+					{Name: "Project restored", Value: auditProjectRestored},
+					{Name: "Sudo", Value: auditSudo},
+					{Name: "Client lead transferred", Value: auditCreatorTransferred},
+					{Name: "Members added", Value: auditMemberAdded},
+					{Name: "Members removed", Value: auditMemberRemoved},
+					{Name: "Projects created", Value: auditProjectCreated},
+					{Name: "API tokens created", Value: auditTokenCreated},
+					{Name: "API tokens revoked", Value: auditTokenRevoked},
+					{Name: "Terms accepted", Value: auditTermsAccepted},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "since",
+				Description: "Only actions on or after this day, like 2024-01-31 (UTC)",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "until",
+				Description: "Only actions on or before this day, like 2024-01-31 (UTC)",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "csv",
+				Description: "Export the results as CSV instead",
+			},
+		},
+	},
+	{
+		Name:                     "retention",
+		Description:              "Show the data retention policy and what the next purge would delete.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+	},
+	{
+		Name:                     "user-data",
+		Description:              "Export or delete everything the bot stores about a user.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "export",
+				Description: "Export everything stored about a user as JSON.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "The user",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "delete",
+				Description: "Delete everything stored about a user and anonymize them in the logs.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "The user",
+						Required:    true,
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:                     "secret",
+		Description:              "Manage the credentials this project's integrations use.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Set a secret, replacing any with the same name.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "What the secret is for: figma, hubspot or slack",
+						Required:    true,
+						MaxLength:   50,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "value",
+						Description: "The secret, like an API token",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "delete",
+				Description: "Delete a secret.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The secret's name",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the names of this project's secrets.",
+			},
+		},
+	},
+	{
+		Name:                     "invite-bot",
+		Description:              "Get a link to add the bot to a server with the permissions it needs.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "guild",
+				Description: "The ID of the server to add the bot to, if you know it",
+			},
+		},
+	},
+	{
+		Name:                     "permissions-audit",
+		Description:              "Check the bot's permissions against what its features need.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+	},
+	{
+		Name:                     "migrate-permissions",
+		Description:              "Update permissions on every managed channel after a role or template change.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "role",
+				Description: "Move a role's channel permissions to another role.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "from",
+						Description: "The role being replaced",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "to",
+						Description: "The role replacing it",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "template",
+				Description: "Share projects set up from a template with the template's current roles.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The template's name",
+						Required:    true,
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:        "transfer-creator",
+		Description: "Make another member the client lead of this project.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "The project member taking over as client lead",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "internal-channel",
+		Description: "Create an internal channel for this project that only Juiceworks members can see.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Name:        "note",
+		Description: "Share a key decision between this project's channel and its internal channel.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "text",
+				Description: "The decision to record",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Type:    discordgo.MessageApplicationCommand,
+		Name:    "Share to internal",
+		GuildID: JuiceworksGuildId,
+	},
+	{
+		Type:    discordgo.MessageApplicationCommand,
+		Name:    "Share to client",
+		GuildID: JuiceworksGuildId,
+	},
+	{
+		Name:                     "import-existing",
+		Description:              "Add the server's existing project channels to the registry.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "dry-run",
+				Description: "Only list the channels that would be imported",
+			},
+		},
+	},
+	{
+		Name:                     "delete-project",
+		Description:              "Move this project to the trash. Its channels are deleted for good after a grace period.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+	},
+	{
+		Name:                     "restore-project",
+		Description:              "Bring this project back out of the trash.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+	},
+	{
+		Name:        "who-can-see",
+		Description: "List everyone who can see this channel.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Name:        "check-access",
+		Description: "Explain whether someone can see and post in a channel.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "The person to check",
+				Required:    true,
+			},
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "channel",
+				Description:  "The channel to check, if not this one",
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildVoice},
+			},
+		},
+	},
+	{
+		Name:                     "access-button",
+		Description:              "Post and pin a button for requesting access to a project in this channel.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+	},
+	{
+		Name:        "work-with-us",
+		Description: "Tell us about a project you'd like Juiceworks to work on.",
+		GuildID:     JuiceworksGuildId,
+	},
+	{
+		Name:        "sudo",
+		Description: "Run a command your roles don't normally allow, for a short while, with a reason.",
+		GuildID:     JuiceworksGuildId,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "command",
+				Description: "The command to run, like delete-project",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "reason",
+				Description: "Why you need it, for the audit log",
+				Required:    true,
+				MaxLength:   500,
+			},
+		},
+	},
+	{
+		Name:                     "maintenance",
+		Description:              "Turn maintenance mode on or off for migrations.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "on",
+				Description: "Turn away commands that change anything and pause scheduled jobs.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "message",
+						Description: "What to tell people whose commands are turned away",
+						MaxLength:   500,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "off",
+				Description: "Let every command and job run again.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "status",
+				Description: "Show whether maintenance mode is on.",
+			},
+		},
+	},
+	{
+		Name:                     "jobs",
+		Description:              "See and manage the bot's background jobs.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List running jobs and failed ones waiting to be retried.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "retry",
+				Description: "Run a failed job again.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "The job's number, from /jobs list",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "show",
+				Description: "Show a failed job's error and what it sends when it's retried.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "The job's number, from /jobs list",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "replay",
+				Description: "Retry every failed job of a kind, once what it talks to is back.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "kind",
+						Description: "The kind of job to replay",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Outgoing webhooks", Value: jobHookDelivery},
+							{Name: "Bridged messages", Value: jobBridgeRelay},
+							{Name: "Airtable syncs", Value: jobAirtableSync},
+							{Name: "Google Calendar syncs", Value: jobCalendarSync},
+							{Name: "Reminders", Value: jobReminder},
+							{Name: "Permission migrations", Value: jobPermissionMigration},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "cancel",
+				Description: "Stop a running job, or drop a failed one.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "The job's number, from /jobs list",
+						Required:    true,
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:                     "integrations",
+		Description:              "See how the services the bot talks to are doing.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "status",
+				Description: "Show each integration's recent calls and errors, with buttons to test them.",
+			},
+		},
+	},
+	{
+		Name:                     "theme",
+		Description:              "Change how the bot's embeds look.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "show",
+				Description: "Preview the current theme.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Change parts of the theme. Anything left out stays as it is.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "color",
+						Description: "The accent color, as a hex code like #f7931e",
+						MaxLength:   7,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "footer",
+						Description: "Footer text for embeds without their own, or none",
+						MaxLength:   200,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "logo",
+						Description: "An https:// link to the logo shown on embeds, or none",
+						MaxLength:   512,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "timestamps",
+						Description: "Whether embeds show when they were sent, in each reader's own time",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reset",
+				Description: "Go back to the default Juiceworks theme.",
+			},
+		},
+	},
+	{
+		Name:                     "templates",
+		Description:              "Change the wording of messages the bot sends.",
+		GuildID:                  JuiceworksGuildId,
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the messages that have templates.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "preview",
+				Description: "Show a template, the variables it can use and how it looks.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The template",
+						Required:    true,
+						Choices:     templateChoices,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "edit",
+				Description: "Change a template.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The template",
+						Required:    true,
+						Choices:     templateChoices,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reset",
+				Description: "Go back to a template's default wording.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The template",
+						Required:    true,
+						Choices:     templateChoices,
+					},
+				},
+			},
 		},
 	},
 }
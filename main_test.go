@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/juiceworks/juiceworks-discord/projectapi"
+)
+
+// A Juiceworks member who can run staff commands.
+func addStaff(f *fakeDiscord) *discordgo.Member {
+	return f.addMember("2000000000000000001", "staff", JuiceworksRoleId)
+}
+
+// A Juiceworks member who is also a server administrator, for admin commands.
+func addAdmin(f *fakeDiscord) *discordgo.Member {
+	admin := addStaff(f)
+	admin.Permissions = discordgo.PermissionAdministrator
+	return admin
+}
+
+// A registered project channel.
+func addProject(t *testing.T, f *fakeDiscord, name string) string {
+	t.Helper()
+	channelID := f.addChannel(name, "")
+	if err := updateProject(channelID, func(p *project) { p.Name = name }); err != nil {
+		t.Fatal(err)
+	}
+	return channelID
+}
+
+// The permission overwrite for a role or member on a channel.
+func overwrite(c discordgo.Channel, id string) *discordgo.PermissionOverwrite {
+	n := slices.IndexFunc(c.PermissionOverwrites, func(o *discordgo.PermissionOverwrite) bool { return o.ID == id })
+	if n < 0 {
+		return nil
+	}
+	return c.PermissionOverwrites[n]
+}
+
+func TestMakeChannel(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	lobby := f.addChannel("lobby", "")
+
+	handleInteraction(s, commandInteraction(lobby, staff, "make-channel", &discordgo.ApplicationCommandInteractionDataOption{
+		Type: discordgo.ApplicationCommandOptionString, Name: "channel-name", Value: "Acme Site!",
+	}))
+
+	if got := f.lastResponse(t); got != "Created channel: #acme-site" {
+		t.Errorf("response = %q, want %q", got, "Created channel: #acme-site")
+	}
+	var channelID string
+	readStore(func(d *storeData) {
+		for id, p := range d.Projects {
+			if p.Name == "acme-site" {
+				channelID = id
+			}
+		}
+	})
+	if channelID == "" {
+		t.Fatal("the project wasn't recorded")
+	}
+	p, _ := getProject(channelID)
+	if p.CreatedBy != staff.User.ID {
+		t.Errorf("project created by %q, want %q", p.CreatedBy, staff.User.ID)
+	}
+
+	c, ok := f.channel(channelID)
+	if !ok {
+		t.Fatal("the channel wasn't created")
+	}
+	if o := overwrite(c, JuiceworksRoleId); o == nil || o.Allow&discordgo.PermissionViewChannel == 0 {
+		t.Errorf("Juiceworks members can't see the channel: %+v", o)
+	}
+	if o := overwrite(c, JuiceworksGuildId); o == nil || o.Deny&discordgo.PermissionViewChannel == 0 {
+		t.Errorf("the channel isn't private: %+v", o)
+	}
+}
+
+func TestMakeChannelMissingPermissions(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	lobby := f.addChannel("lobby", "")
+	f.fail(http.MethodPost, "guilds/"+JuiceworksGuildId+"/channels", http.StatusForbidden, discordgo.ErrCodeMissingPermissions)
+
+	handleInteraction(s, commandInteraction(lobby, staff, "make-channel", &discordgo.ApplicationCommandInteractionDataOption{
+		Type: discordgo.ApplicationCommandOptionString, Name: "channel-name", Value: "acme",
+	}))
+
+	got := f.lastResponse(t)
+	if !strings.HasPrefix(got, "Error creating channel: I'm missing the **Manage Channels** permission in the server.") {
+		t.Errorf("response = %q, want it to explain the missing permission", got)
+	}
+	readStore(func(d *storeData) {
+		if len(d.Projects) != 0 {
+			t.Errorf("%d projects were recorded, want none", len(d.Projects))
+		}
+	})
+}
+
+func TestAddMember(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	channelID := addProject(t, f, "acme")
+	client := f.addMember("3000000000000000001", "client")
+
+	handleInteraction(s, commandInteraction(channelID, staff, "add-member", &discordgo.ApplicationCommandInteractionDataOption{
+		Type: discordgo.ApplicationCommandOptionUser, Name: "user", Value: client.User.ID,
+	}))
+
+	if got, want := f.lastResponse(t), "Added <@"+client.User.ID+"> to the channel."; got != want {
+		t.Errorf("response = %q, want %q", got, want)
+	}
+	c, _ := f.channel(channelID)
+	if o := overwrite(c, client.User.ID); o == nil || o.Allow&discordgo.PermissionViewChannel == 0 {
+		t.Errorf("the client can't see the channel: %+v", o)
+	}
+	if m, _ := f.member(client.User.ID); !slices.Contains(m.Roles, ProjectCreatorRoleId) {
+		t.Errorf("the client's roles are %v, want the Project Creator role", m.Roles)
+	}
+	p, _ := getProject(channelID)
+	if _, ok := p.Members[client.User.ID]; !ok {
+		t.Errorf("the client wasn't recorded as a member: %v", p.Members)
+	}
+	if p.CreatorID != client.User.ID {
+		t.Errorf("client lead = %q, want %q", p.CreatorID, client.User.ID)
+	}
+}
+
+func TestAddMemberMissingPermissions(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	channelID := addProject(t, f, "acme")
+	client := f.addMember("3000000000000000001", "client")
+	f.fail(http.MethodPut, "channels/"+channelID+"/permissions/"+client.User.ID, http.StatusForbidden, discordgo.ErrCodeMissingPermissions)
+
+	handleInteraction(s, commandInteraction(channelID, staff, "add-member", &discordgo.ApplicationCommandInteractionDataOption{
+		Type: discordgo.ApplicationCommandOptionUser, Name: "user", Value: client.User.ID,
+	}))
+
+	if got := f.lastResponse(t); !strings.HasPrefix(got, "Error adding member to channel: ") {
+		t.Errorf("response = %q, want an error adding the member", got)
+	}
+	c, _ := f.channel(channelID)
+	if o := overwrite(c, client.User.ID); o != nil {
+		t.Errorf("the client was given an overwrite anyway: %+v", o)
+	}
+	p, _ := getProject(channelID)
+	if _, ok := p.Members[client.User.ID]; ok {
+		t.Error("the client was recorded as a member even though they weren't added")
+	}
+}
+
+func TestCommandFromAnotherGuild(t *testing.T) {
+	f, s := newTestBot(t)
+	channelID := addProject(t, f, "acme")
+	client := f.addMember("3000000000000000001", "client")
+	i := commandInteraction(channelID, addStaff(f), "add-member", userOption("user", client.User.ID))
+	i.GuildID = "4000000000000000001"
+
+	handleInteraction(s, i)
+
+	if got, want := f.lastResponse(t), "This command can only be used in the Juiceworks Discord server."; got != want {
+		t.Errorf("response = %q, want %q", got, want)
+	}
+	if p, _ := getProject(channelID); len(p.Members) != 0 {
+		t.Errorf("members were added from another guild: %v", p.Members)
+	}
+}
+
+func TestCommandGuildsOnlyTheManagedGuild(t *testing.T) {
+	t.Setenv("ENVIRONMENT", envProd)
+	t.Setenv("COMMAND_GUILD_IDS", JuiceworksGuildId+",4000000000000000001")
+	if err := checkEnvironment(); err == nil {
+		t.Error("commands could be registered in a guild the bot doesn't act on")
+	}
+	t.Setenv("COMMAND_GUILD_IDS", JuiceworksGuildId)
+	if err := checkEnvironment(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	channelID := addProject(t, f, "acme")
+	client := f.addMember("3000000000000000001", "client")
+	handleInteraction(s, commandInteraction(channelID, staff, "add-member", userOption("user", client.User.ID)))
+
+	handleInteraction(s, commandInteraction(channelID, staff, "remove-member", userOption("user", client.User.ID)))
+
+	if got, want := f.lastResponse(t), "Removed <@"+client.User.ID+"> from the channel."; got != want {
+		t.Errorf("response = %q, want %q", got, want)
+	}
+	c, _ := f.channel(channelID)
+	if o := overwrite(c, client.User.ID); o != nil {
+		t.Errorf("the client can still see the channel: %+v", o)
+	}
+	if p, _ := getProject(channelID); len(p.Members) != 0 || p.CreatorID != "" {
+		t.Errorf("the client is still recorded: members %v, client lead %q", p.Members, p.CreatorID)
+	}
+	for _, action := range []string{auditMemberAdded, auditMemberRemoved} {
+		entries := searchAudit(auditFilter{targetID: client.User.ID, action: action})
+		if len(entries) != 1 || entries[0].ActorID != staff.User.ID || entries[0].ChannelID != channelID {
+			t.Errorf("audit log has %+v for %s, want one entry by the staff member", entries, action)
+		}
+	}
+	report := buildDailyReport(s, time.Now().AddDate(0, 0, 1))
+	n := slices.IndexFunc(report.Fields, func(f *discordgo.MessageEmbedField) bool { return f.Name == "Members removed" })
+	if want := "- <@" + client.User.ID + "> from <#" + channelID + ">"; n < 0 || report.Fields[n].Value != want {
+		t.Errorf("the daily report doesn't list the removal: %+v", report.Fields)
+	}
+}
+
+func TestRemoveMemberRegistryError(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	channelID := addProject(t, f, "acme")
+	client := f.addMember("3000000000000000001", "client")
+	handleInteraction(s, commandInteraction(channelID, staff, "add-member", userOption("user", client.User.ID)))
+	// Saving the store fails once the data file's directory is gone.
+	t.Setenv("DATA_FILE", filepath.Join(t.TempDir(), "missing", "data.json"))
+
+	handleInteraction(s, commandInteraction(channelID, staff, "remove-member", userOption("user", client.User.ID)))
+
+	if got := f.lastResponse(t); !strings.HasPrefix(got, "Error removing member from registry: ") {
+		t.Errorf("response = %q, want an error saving the registry", got)
+	}
+}
+
+func TestAddMembers(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	channelID := addProject(t, f, "acme")
+	var clients []*discordgo.Member
+	for _, id := range []string{"3000000000000000001", "3000000000000000002", "3000000000000000003"} {
+		clients = append(clients, f.addMember(id, "client-"+id[len(id)-1:]))
+	}
+	failing := clients[1].User.ID
+	f.fail(http.MethodPut, "channels/"+channelID+"/permissions/"+failing, http.StatusForbidden, discordgo.ErrCodeMissingPermissions)
+
+	handleInteraction(s, commandInteraction(channelID, staff, "add-member",
+		userOption("user", clients[0].User.ID), userOption("user2", clients[1].User.ID), userOption("user3", clients[2].User.ID)))
+
+	lines := strings.Split(f.lastResponse(t), "\n")
+	if len(lines) != 3 || lines[0] != "Added <@"+clients[0].User.ID+"> to the channel." ||
+		!strings.HasPrefix(lines[1], "Error adding member to channel for <@"+failing+">: ") ||
+		lines[2] != "Added <@"+clients[2].User.ID+"> to the channel." {
+		t.Errorf("response = %q, want the first and last added and the second failed", lines)
+	}
+	c, _ := f.channel(channelID)
+	p, _ := getProject(channelID)
+	for _, client := range clients {
+		_, listed := p.Members[client.User.ID]
+		o := overwrite(c, client.User.ID)
+		if added := client.User.ID != failing; listed != added || (o != nil) != added {
+			t.Errorf("%s: listed %v with overwrite %+v, want added %v", client.User.Username, listed, o, added)
+		}
+	}
+}
+
+func TestAddMembersOverAPI(t *testing.T) {
+	f, s := newTestBot(t)
+	t.Setenv("API_KEY", "test-key")
+	channelID := addProject(t, f, "acme")
+	client := f.addMember("3000000000000000001", "client")
+
+	r := httptest.NewRequest(http.MethodPost, "/api/projects/"+channelID+"/members",
+		strings.NewReader(`{"userIds": ["`+client.User.ID+`", "3000000000000000009"]}`))
+	r.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	httpMux(s).ServeHTTP(w, r)
+
+	var results []projectapi.MemberResult
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("response = %d %s", w.Code, w.Body)
+	}
+	want := []projectapi.MemberResult{{UserID: client.User.ID}, {UserID: "3000000000000000009", Error: "unknown member"}}
+	if !slices.Equal(results, want) {
+		t.Errorf("results = %+v, want %+v", results, want)
+	}
+	if p, _ := getProject(channelID); p.Members[client.User.ID] == "" {
+		t.Error("the client wasn't recorded as a member")
+	}
+}
+
+// Deleting a project posts its history in the internal channel and takes its members' access away, and restoring it
+// gives the access back.
+func TestDeleteAndRestoreProject(t *testing.T) {
+	f, s := newTestBot(t)
+	admin := addAdmin(f)
+	client := f.addMember("3000000000000000001", "client")
+	channelID := addProject(t, f, "acme")
+	handleInteraction(s, commandInteraction(channelID, admin, "add-member", userOption("user", client.User.ID)))
+	if _, err := s.ChannelMessageSend(channelID, "Kickoff notes"); err != nil {
+		t.Fatal(err)
+	}
+
+	handleInteraction(s, commandInteraction(channelID, admin, "delete-project"))
+	if got := f.lastResponse(t); !strings.HasPrefix(got, "Moved #acme to the trash") {
+		t.Fatalf("response = %q, want the project moved to the trash", got)
+	}
+	if posted := f.posted(InternalChannelId); !slices.ContainsFunc(posted, func(m string) bool {
+		return strings.Contains(m, "deleted #acme")
+	}) {
+		t.Errorf("the internal channel has %q, want the export of #acme", posted)
+	}
+	c, _ := f.channel(channelID)
+	if p, _ := getProject(channelID); p.Trash == nil || c.Name != "archived-acme" || overwrite(c, client.User.ID) != nil {
+		t.Fatalf("after deleting, the channel is %q with the client's overwrite %+v, want it archived without it",
+			c.Name, overwrite(c, client.User.ID))
+	}
+
+	handleInteraction(s, commandInteraction(channelID, admin, "restore-project"))
+	if got := f.lastResponse(t); !strings.HasPrefix(got, "Restored") {
+		t.Fatalf("response = %q, want the project restored", got)
+	}
+	c, _ = f.channel(channelID)
+	if p, _ := getProject(channelID); p.Trash != nil || c.Name != "acme" || overwrite(c, client.User.ID) == nil {
+		t.Errorf("after restoring, the channel is %q with the client's overwrite %+v, want it back as it was",
+			c.Name, overwrite(c, client.User.ID))
+	}
+}
+
+func TestWhoCanSee(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	client := f.addMember("3000000000000000001", "client")
+	f.addMember("3000000000000000002", "outsider")
+	channelID := addProject(t, f, "acme")
+	err := s.ChannelPermissionSet(channelID, JuiceworksGuildId, discordgo.PermissionOverwriteTypeRole, 0, discordgo.PermissionViewChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handleInteraction(s, commandInteraction(channelID, staff, "add-member", userOption("user", client.User.ID)))
+
+	handleInteraction(s, commandInteraction(channelID, staff, "who-can-see"))
+	got := f.lastResponse(t)
+	if !strings.Contains(got, "<@"+client.User.ID+">") || strings.Contains(got, "<@3000000000000000002>") {
+		t.Errorf("response = %q, want the client listed and not the outsider", got)
+	}
+}
+
+// Channels that look like the bot's but aren't registered are reported once, and adopting one registers its members.
+func TestAdoptOrphanedChannel(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	client := f.addMember("3000000000000000001", "client")
+	channelID := f.addChannel("acme", "")
+	for _, o := range []struct {
+		id          string
+		kind        discordgo.PermissionOverwriteType
+		allow, deny int64
+	}{
+		{JuiceworksGuildId, discordgo.PermissionOverwriteTypeRole, 0, discordgo.PermissionViewChannel},
+		{JuiceworksRoleId, discordgo.PermissionOverwriteTypeRole, discordgo.PermissionViewChannel, 0},
+		{client.User.ID, discordgo.PermissionOverwriteTypeMember, discordgo.PermissionViewChannel, 0},
+	} {
+		if err := s.ChannelPermissionSet(channelID, o.id, o.kind, o.allow, o.deny); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reportOrphanedChannels(s)
+	reportOrphanedChannels(s)
+	posted := f.posted(InternalChannelId)
+	if len(posted) != 1 || !strings.Contains(posted[0], "<#"+channelID+"> looks like a project channel") {
+		t.Fatalf("the internal channel has %q, want one report of <#%s>", posted, channelID)
+	}
+
+	handleInteraction(s, componentInteraction(InternalChannelId, staff, "adopt-channel:"+channelID))
+	if got := f.lastResponse(t); !strings.Contains(got, "Adopted with 1 members") {
+		t.Errorf("response = %q, want the channel adopted with the client", got)
+	}
+	if p, ok := getProject(channelID); !ok || p.Members[client.User.ID] == "" {
+		t.Error("the channel wasn't registered with the client as a member")
+	}
+}
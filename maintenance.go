@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Commands that only look things up, so they keep working in maintenance mode.
+var readOnlyCommands = []string{
+	"upcoming", "book", "project-info", "health-report", "help", "files", "find-project", "pins", "whoami", "audit",
+	"permissions-audit", "invite-bot", "who-can-see", "check-access", "maintenance", "integrations",
+}
+
+// Components that only look things up, so they keep working in maintenance mode.
+var readOnlyComponents = []string{"help", "audit-page", "integration-test"}
+
+// How long HTTP callers turned away in maintenance mode are asked to wait before trying again.
+const maintenanceRetryAfter = 5 * time.Minute
+
+// Maintenance mode turned on with /maintenance.
+type maintenance struct {
+	Since time.Time `json:"since"`
+	By    string    `json:"by"`
+	// Shown to people whose commands are turned away, if set.
+	Message string `json:"message,omitempty"`
+}
+
+// Whether the bot is in maintenance mode, from MAINTENANCE_MODE or /maintenance, and the message to show for it.
+// While it is, commands, API calls and webhooks that change anything are turned away and scheduled jobs are paused,
+// for migrations.
+func inMaintenance() (bool, string) {
+	if os.Getenv("MAINTENANCE_MODE") == "true" {
+		return true, ""
+	}
+	var m *maintenance
+	readStore(func(d *storeData) {
+		m = d.Maintenance
+	})
+	if m == nil {
+		return false, ""
+	}
+	return true, m.Message
+}
+
+// Whether a scheduled job should skip its run for maintenance mode, logging it if so.
+func paused(job string) bool {
+	if on, _ := inMaintenance(); on {
+		log.Printf("Skipped %s for maintenance mode.", job)
+		return true
+	}
+	return false
+}
+
+// Turn away an interaction that could change something while the bot is in maintenance mode. Returns true if the
+// interaction may go ahead.
+func checkMaintenance(s *discordgo.Session, i *discordgo.InteractionCreate, name string) bool {
+	on, message := inMaintenance()
+	if !on {
+		return true
+	}
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		if slices.Contains(readOnlyCommands, name) {
+			return true
+		}
+	case discordgo.InteractionMessageComponent:
+		if slices.Contains(readOnlyComponents, name) {
+			return true
+		}
+	}
+
+	content := "Sorry, this is temporarily unavailable while the bot is down for maintenance. Please try again a little later."
+	if message != "" {
+		content += "\n> " + message
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+	return false
+}
+
+// Turn away an HTTP request while the bot is in maintenance mode, with a 503 asking the caller to try again later.
+// Webhook providers retry deliveries that fail, so their events are applied once maintenance is over instead of being
+// lost. Returns true if the request may go ahead.
+func checkMaintenanceHTTP(w http.ResponseWriter, r *http.Request) bool {
+	if on, _ := inMaintenance(); !on {
+		return true
+	}
+	log.Printf("Turned away %s %s for maintenance mode.", r.Method, r.URL.Path)
+	w.Header().Set("Retry-After", strconv.Itoa(int(maintenanceRetryAfter.Seconds())))
+	http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	return false
+}
+
+// Turn maintenance mode on or off, or show whether it's on.
+func maintenanceCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	var content string
+	switch sub.Name {
+	case "on":
+		m := &maintenance{Since: time.Now(), By: i.Member.User.ID}
+		if len(sub.Options) > 0 {
+			m.Message = sub.Options[0].StringValue()
+		}
+		err := updateStore(func(d *storeData) {
+			d.Maintenance = m
+		})
+		if err != nil {
+			log.Printf("Error saving maintenance mode: %v", err)
+			content = "Error saving maintenance mode: " + describeError(err)
+			break
+		}
+		log.Printf("%s turned maintenance mode on.", i.Member.User)
+		content = "Maintenance mode is on. Commands, API calls and webhooks that change anything are turned away and " +
+			"scheduled jobs are paused until you run `/maintenance off`."
+
+	case "off":
+		err := updateStore(func(d *storeData) {
+			d.Maintenance = nil
+		})
+		if err != nil {
+			log.Printf("Error saving maintenance mode: %v", err)
+			content = "Error saving maintenance mode: " + describeError(err)
+			break
+		}
+		log.Printf("%s turned maintenance mode off.", i.Member.User)
+		content = "Maintenance mode is off."
+		if os.Getenv("MAINTENANCE_MODE") == "true" {
+			content = "Maintenance mode is still on, because MAINTENANCE_MODE is set. Unset it and restart the bot to turn it off."
+		}
+
+	case "status":
+		var m *maintenance
+		readStore(func(d *storeData) {
+			m = d.Maintenance
+		})
+		switch {
+		case os.Getenv("MAINTENANCE_MODE") == "true":
+			content = "Maintenance mode is on, because MAINTENANCE_MODE is set."
+		case m != nil:
+			content = fmt.Sprintf("Maintenance mode has been on since <t:%d:f>, when <@%s> turned it on.", m.Since.Unix(), m.By)
+		default:
+			content = "Maintenance mode is off."
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
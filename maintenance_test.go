@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	juiceworksv1 "github.com/juiceworks/juiceworks-discord/gen/juiceworks/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// In maintenance mode nothing outside commands may change the store or Discord either, while lookups keep working.
+func TestMaintenanceTurnsAwayWrites(t *testing.T) {
+	_, s := newTestBot(t)
+	t.Setenv("API_KEY", "test-key")
+	if err := updateStore(func(d *storeData) { d.Maintenance = &maintenance{Since: time.Now()} }); err != nil {
+		t.Fatal(err)
+	}
+	mux := httpMux(s)
+
+	for _, c := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/api/projects", http.StatusOK},
+		{http.MethodGet, "/digest/unsubscribe", http.StatusServiceUnavailable},
+		{http.MethodGet, "/link/discord", http.StatusServiceUnavailable},
+		{http.MethodGet, "/oauth/discord/callback", http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/projects/1/members", http.StatusServiceUnavailable},
+		{http.MethodDelete, "/api/projects/1/members/2", http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/reconcile", http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/tokens", http.StatusServiceUnavailable},
+		{http.MethodPost, "/hooks", http.StatusServiceUnavailable},
+		{http.MethodPost, "/webhooks/hubspot", http.StatusServiceUnavailable},
+	} {
+		r := httptest.NewRequest(c.method, c.path, nil)
+		r.Header.Set("X-API-Key", "test-key")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != c.want {
+			t.Errorf("%s %s = %d, want %d", c.method, c.path, w.Code, c.want)
+		}
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "test-key"))
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return nil, nil
+	}
+	_, err := authenticateGRPC(ctx, nil, &grpc.UnaryServerInfo{FullMethod: juiceworksv1.ProjectsService_AddMember_FullMethodName}, handler)
+	if status.Code(err) != codes.Unavailable || called {
+		t.Errorf("AddMember = %v, want it turned away as unavailable", err)
+	}
+	_, err = authenticateGRPC(ctx, nil, &grpc.UnaryServerInfo{FullMethod: juiceworksv1.ProjectsService_ListProjects_FullMethodName}, handler)
+	if err != nil || !called {
+		t.Errorf("ListProjects = %v, want it to go ahead", err)
+	}
+}
+
+// A read-only endpoint that's renamed would quietly start being turned away, so every one listed has to exist.
+func TestReadOnlyHandlersExist(t *testing.T) {
+	for _, pattern := range readOnlyHTTPHandlers {
+		if _, ok := httpHandlers[pattern]; !ok {
+			t.Errorf("%s is listed as read-only but isn't served", pattern)
+		}
+	}
+}
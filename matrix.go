@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long the homeserver may hold a sync request open waiting for new events.
+const matrixSyncTimeout = 30 * time.Second
+
+// Bridges project channels to Matrix rooms. The bot logs in as a regular Matrix user and long-polls /sync for new
+// messages in the rooms it has joined.
+type matrixBridge struct {
+	userID string
+	txnID  atomic.Int64
+}
+
+// A room event returned by /sync.
+type matrixEvent struct {
+	Type    string `json:"type"`
+	Sender  string `json:"sender"`
+	Content struct {
+		MsgType string `json:"msgtype"`
+		Body    string `json:"body"`
+		URL     string `json:"url"`
+		Info    struct {
+			Size int `json:"size"`
+		} `json:"info"`
+	} `json:"content"`
+}
+
+// Call the Matrix client-server API and decode the response into out, which may be nil.
+func matrixRequest(method, path string, body, out any) (err error) {
+	defer func() { noteIntegrationCall("Matrix", err) }()
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(os.Getenv("MATRIX_HOMESERVER"), "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("MATRIX_ACCESS_TOKEN"))
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: matrixSyncTimeout + 30*time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("matrix returned %s", resp.Status)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// Start syncing with the homeserver. The bridge is disabled unless MATRIX_HOMESERVER and MATRIX_ACCESS_TOKEN are set.
+func (mb *matrixBridge) start(s *discordgo.Session) error {
+	if os.Getenv("MATRIX_HOMESERVER") == "" || os.Getenv("MATRIX_ACCESS_TOKEN") == "" {
+		return nil
+	}
+
+	var whoami struct {
+		UserID string `json:"user_id"`
+	}
+	if err := matrixRequest(http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &whoami); err != nil {
+		return err
+	}
+	mb.userID = whoami.UserID
+
+	go mb.sync(s)
+	log.Printf("Matrix bridge logged in as %s", mb.userID)
+	return nil
+}
+
+// Long-poll the homeserver for new messages and relay them into Discord. The first sync only fetches a position in
+// the event stream, so history from before startup isn't replayed.
+func (mb *matrixBridge) sync(s *discordgo.Session) {
+	since := ""
+	for {
+		params := url.Values{}
+		if since != "" {
+			params.Set("since", since)
+			params.Set("timeout", fmt.Sprint(matrixSyncTimeout.Milliseconds()))
+		}
+
+		var resp struct {
+			NextBatch string `json:"next_batch"`
+			Rooms     struct {
+				Join map[string]struct {
+					Timeline struct {
+						Events []matrixEvent `json:"events"`
+					} `json:"timeline"`
+				} `json:"join"`
+			} `json:"rooms"`
+		}
+		if err := matrixRequest(http.MethodGet, "/_matrix/client/v3/sync?"+params.Encode(), nil, &resp); err != nil {
+			log.Printf("Error syncing with Matrix: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		if since != "" {
+			for roomID, room := range resp.Rooms.Join {
+				for _, ev := range room.Timeline.Events {
+					if ev.Type == "m.room.message" && ev.Sender != mb.userID {
+						mb.relay(s, roomID, ev)
+					}
+				}
+			}
+		}
+		since = resp.NextBatch
+	}
+}
+
+// Post a Matrix message into its bridged Discord channel, copying any attached media.
+func (mb *matrixBridge) relay(s *discordgo.Session, roomID string, ev matrixEvent) {
+	// The bot syncs every room it's in, and anyone can invite it to one. Only bridged rooms are relayed, so don't look
+	// up senders or download media for any other.
+	channelID := bridgedChannel("matrix", roomID)
+	if channelID == "" {
+		return
+	}
+
+	m := &bridgeMessage{channelID: channelID, author: ev.Sender, text: ev.Content.Body}
+	var profile struct {
+		DisplayName string `json:"displayname"`
+	}
+	if err := matrixRequest(http.MethodGet, "/_matrix/client/v3/profile/"+url.PathEscape(ev.Sender), nil, &profile); err != nil {
+		log.Printf("Error reading Matrix profile: %v", err)
+	} else if profile.DisplayName != "" {
+		m.author = profile.DisplayName
+	}
+
+	switch ev.Content.MsgType {
+	case "m.image", "m.file", "m.video", "m.audio":
+		data, err := downloadMatrixMedia(ev.Content.URL, ev.Content.Info.Size)
+		if err != nil {
+			log.Printf("Error downloading Matrix media %q: %v", ev.Content.Body, err)
+			break
+		}
+		m.files = append(m.files, &discordgo.File{Name: ev.Content.Body, Reader: bytes.NewReader(data)})
+		m.text = ""
+	case "m.emote":
+		m.text = "*" + m.text + "*"
+	}
+
+	relayFromBridge(s, "matrix", roomID, m)
+}
+
+// Post a message to a Matrix room. Matrix doesn't support per-message display names, so the author is prefixed.
+func (mb *matrixBridge) send(room string, m *bridgeMessage) error {
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%d-%d",
+		url.PathEscape(room), time.Now().UnixNano(), mb.txnID.Add(1))
+	return matrixRequest(http.MethodPut, path, map[string]string{
+		"msgtype": "m.text",
+		"body":    m.author + ": " + m.text,
+	}, nil)
+}
+
+// Download media from an mxc:// URL through the homeserver.
+func downloadMatrixMedia(mxc string, size int) ([]byte, error) {
+	if size > maxBridgedFileSize {
+		return nil, fmt.Errorf("file is too large to copy (%d bytes)", size)
+	}
+	serverAndID, ok := strings.CutPrefix(mxc, "mxc://")
+	if !ok {
+		return nil, fmt.Errorf("invalid media URL %q", mxc)
+	}
+	req, err := http.NewRequest(http.MethodGet,
+		strings.TrimSuffix(os.Getenv("MATRIX_HOMESERVER"), "/")+"/_matrix/client/v1/media/download/"+serverAndID, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("MATRIX_ACCESS_TOKEN"))
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("matrix returned %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBridgedFileSize))
+}
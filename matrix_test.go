@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// Messages from rooms that aren't bridged are dropped before the homeserver is asked for profiles or media.
+func TestMatrixMessageFromUnbridgedRoom(t *testing.T) {
+	f, s := newTestBot(t)
+	var requests atomic.Int32
+	homeserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer homeserver.Close()
+	t.Setenv("MATRIX_HOMESERVER", homeserver.URL)
+
+	var ev matrixEvent
+	ev.Type, ev.Sender = "m.room.message", "@someone:example.com"
+	ev.Content.MsgType, ev.Content.Body, ev.Content.URL = "m.file", "notes.txt", "mxc://example.com/notes"
+	(&matrixBridge{userID: "@bot:example.com"}).relay(s, "!room:example.com", ev)
+
+	if n := requests.Load(); n != 0 {
+		t.Errorf("%d requests were made to the homeserver, want none", n)
+	}
+	if requests, _ := f.history(); len(requests) != 0 {
+		t.Errorf("%d requests were made to Discord, want none", len(requests))
+	}
+}
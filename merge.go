@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Fold a duplicate project into the one the command was called from: move its members and registry data over,
+// optionally re-post its pinned messages, and archive its channel.
+func mergeProjects(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var sourceID string
+	var copyPins bool
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "source":
+			sourceID = o.ChannelValue(s).ID
+		case "copy-pins":
+			copyPins = o.BoolValue()
+		}
+	}
+	targetID := workspaceProjectID(i.ChannelID)
+	source, sourceOK := getProject(sourceID)
+	target, targetOK := getProject(targetID)
+
+	var problem string
+	switch {
+	case !targetOK:
+		problem = "This channel is not a registered project."
+	case !sourceOK:
+		problem = fmt.Sprintf("<#%s> is not a registered project.", sourceID)
+	case sourceID == targetID:
+		problem = "A project can't be merged into itself."
+	case target.Trash != nil:
+		problem = "This project is in the trash. Restore it with /restore-project before merging into it."
+	case source.Trash != nil:
+		problem = fmt.Sprintf("<#%s> is in the trash. Restore it with /restore-project before merging it.", sourceID)
+	}
+	if problem != "" {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: problem,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	// Adding members and copying pins takes a request each.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}))
+	content := mergeProject(s, source, targetID, copyPins)
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+	}
+	postAudit(s, auditEntry{
+		Action:    auditMerged,
+		ActorID:   i.Member.User.ID,
+		ChannelID: targetID,
+		Summary:   fmt.Sprintf("<@%s> merged #%s into <#%s>.", i.Member.User.ID, source.Name, targetID),
+	})
+}
+
+// Merge a project into another, returning the response for /merge-projects.
+func mergeProject(s *discordgo.Session, source project, targetID string, copyPins bool) string {
+	// Give the source's members access to the target, and its workspace.
+	var problems []string
+	type grant struct{ userID, channelID string }
+	var grants []grant
+	var changes []func() error
+	for userID := range source.Members {
+		for _, channelID := range workspaceChannels(targetID) {
+			grants = append(grants, grant{userID, channelID})
+			changes = append(changes, func() error {
+				return s.ChannelPermissionSet(channelID, userID, discordgo.PermissionOverwriteTypeMember,
+					discordgo.PermissionViewChannel|discordgo.PermissionSendMessages, 0)
+			})
+		}
+	}
+	for n, err := range runOverwrites(changes) {
+		if err != nil {
+			log.Printf("Error adding member to channel: %v", err)
+			problems = append(problems, fmt.Sprintf("couldn't add <@%s> to <#%s>: %s", grants[n].userID, grants[n].channelID, describeError(err)))
+		}
+	}
+
+	// Move the registry data over. The target's settings win where both projects have one.
+	var carriedInternal, targetName string
+	err := updateStore(func(d *storeData) {
+		t := d.Projects[targetID]
+		if t.Members == nil {
+			t.Members = make(map[string]string)
+		}
+		for userID, name := range source.Members {
+			t.Members[userID] = name
+		}
+		for _, key := range source.FigmaFiles {
+			if !slices.Contains(t.FigmaFiles, key) {
+				t.FigmaFiles = append(t.FigmaFiles, key)
+			}
+		}
+		t.Milestones = append(t.Milestones, source.Milestones...)
+		for _, a := range source.Assignments {
+			if !slices.ContainsFunc(t.Assignments, func(b assignment) bool { return b.UserID == a.UserID }) {
+				t.Assignments = append(t.Assignments, a)
+			}
+		}
+		for userID, level := range source.Notifications {
+			if _, ok := t.Notifications[userID]; !ok {
+				if t.Notifications == nil {
+					t.Notifications = make(map[string]string)
+				}
+				t.Notifications[userID] = level
+			}
+		}
+		for userID, nickname := range source.Nicknames {
+			if _, ok := t.Nicknames[userID]; !ok {
+				if t.Nicknames == nil {
+					t.Nicknames = make(map[string]string)
+				}
+				t.Nicknames[userID] = nickname
+			}
+		}
+		t.Workspace = append(t.Workspace, source.Workspace...)
+		t.VoiceChannels = append(t.VoiceChannels, source.VoiceChannels...)
+		t.Calls = append(t.Calls, source.Calls...)
+		t.Files = append(t.Files, source.Files...)
+		// Deliverable IDs only count up within a project, so the source's are renumbered after the target's.
+		next := 1
+		if len(t.Deliverables) > 0 {
+			next = t.Deliverables[len(t.Deliverables)-1].ID + 1
+		}
+		for n, del := range source.Deliverables {
+			del.ID = next + n
+			t.Deliverables = append(t.Deliverables, del)
+		}
+		if t.DealID == "" {
+			t.DealID = source.DealID
+		}
+		if t.ClientName == "" {
+			t.ClientName = source.ClientName
+		}
+		if t.Rotation == nil {
+			t.Rotation = source.Rotation
+		}
+		if t.Contract == nil {
+			t.Contract = source.Contract
+		}
+		// The source's internal channel carries on as the target's if the target has none. Otherwise it's archived
+		// along with the source's channel.
+		if t.InternalChannelID == "" && source.InternalChannelID != "" {
+			t.InternalChannelID = source.InternalChannelID
+			source.InternalChannelID = ""
+			carriedInternal, targetName = t.InternalChannelID, t.Name
+		}
+		if _, ok := d.DigestRecipients[targetID]; !ok && d.DigestRecipients[source.ChannelID] != nil {
+			d.DigestRecipients[targetID] = d.DigestRecipients[source.ChannelID]
+		}
+		delete(d.DigestRecipients, source.ChannelID)
+		for _, h := range d.Huddles {
+			if h.ProjectChannelID == source.ChannelID {
+				h.ProjectChannelID = targetID
+			}
+		}
+		for _, thread := range d.EmailThreads {
+			if thread.ChannelID == source.ChannelID {
+				thread.ChannelID = targetID
+			}
+		}
+		delete(d.Projects, source.ChannelID)
+	})
+	if err != nil {
+		log.Printf("Error merging projects: %v", err)
+		return "Error merging projects: " + describeError(err)
+	}
+	go projectChanged(targetID)
+	if carriedInternal != "" {
+		name := truncate(targetName, 100-len(internalChannelSuffix)) + internalChannelSuffix
+		if _, err := s.ChannelEdit(carriedInternal, &discordgo.ChannelEdit{Name: name}); err != nil {
+			problems = append(problems, "couldn't rename the internal channel: "+describeError(err))
+		}
+	}
+
+	pins := 0
+	if copyPins {
+		var err error
+		if pins, err = copyPinnedMessages(s, source.ChannelID, targetID); err != nil {
+			problems = append(problems, "couldn't copy all pinned messages: "+describeError(err))
+		}
+	}
+	note := fmt.Sprintf("This project was merged into <#%s> <t:%d:R>. Carry on there.", targetID, time.Now().Unix())
+	if err := archiveChannel(s, source, note); err != nil {
+		problems = append(problems, "couldn't archive the old channel: "+describeError(err))
+	}
+
+	log.Printf("Merged channel %s into %s.", source.ChannelID, targetID)
+	content := fmt.Sprintf("Merged #%s into this project: %d members", source.Name, len(source.Members))
+	if copyPins {
+		content += fmt.Sprintf(", %d pinned messages", pins)
+	}
+	content += "."
+	if len(problems) > 0 {
+		content += "\nSome of it didn't work:\n- " + strings.Join(problems, "\n- ")
+	}
+	return truncate(content, 2000)
+}
+
+// Re-post the pinned messages of one channel in another, oldest first, and pin them there, returning how many were
+// copied.
+func copyPinnedMessages(s *discordgo.Session, fromID, toID string) (int, error) {
+	pinned, err := s.ChannelMessagesPinned(fromID)
+	if err != nil {
+		return 0, err
+	}
+	slices.Reverse(pinned)
+	for n, m := range pinned {
+		content := fmt.Sprintf("📌 Pinned in <#%s>, from <@%s> <t:%d:f>:\n%s", fromID, m.Author.ID, m.Timestamp.Unix(), m.Content)
+		for _, a := range m.Attachments {
+			content += "\n" + a.URL
+		}
+		copied, err := s.ChannelMessageSendComplex(toID, &discordgo.MessageSend{
+			Content:         truncate(content, 2000),
+			Embeds:          m.Embeds,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err == nil {
+			err = s.ChannelMessagePin(toID, copied.ID)
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return len(pinned), nil
+}
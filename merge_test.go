@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func channelOption(name, channelID string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionChannel, Name: name, Value: channelID}
+}
+
+// Projects in the trash can't be merged, in either direction, since purging would take the merged data with it.
+func TestMergeTrashedProjects(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	acme := addProject(t, f, "acme")
+	globex := addProject(t, f, "globex")
+	if err := updateProject(globex, func(p *project) { p.Trash = &trashedProject{DeletedAt: time.Now()} }); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct{ from, into string }{{globex, acme}, {acme, globex}} {
+		handleInteraction(s, commandInteraction(c.into, staff, "merge-projects", channelOption("source", c.from)))
+		if got := f.lastResponse(t); !strings.Contains(got, "in the trash") {
+			t.Errorf("merging %s into %s = %q, want it refused", c.from, c.into, got)
+		}
+	}
+	if _, ok := getProject(acme); !ok {
+		t.Error("acme was merged away")
+	}
+	if _, ok := getProject(globex); !ok {
+		t.Error("globex was merged away")
+	}
+}
+
+// A source's internal channel becomes the target's when the target has none, so staff notes aren't left unregistered.
+func TestMergeCarriesInternalChannelOver(t *testing.T) {
+	f, s := newTestBot(t)
+	staff := addStaff(f)
+	acme := addProject(t, f, "acme")
+	globex := addProject(t, f, "globex")
+	internal := f.addChannel("globex"+internalChannelSuffix, "")
+	if err := updateProject(globex, func(p *project) { p.InternalChannelID = internal }); err != nil {
+		t.Fatal(err)
+	}
+
+	handleInteraction(s, commandInteraction(acme, staff, "merge-projects", channelOption("source", globex)))
+	if p, _ := getProject(acme); p.InternalChannelID != internal {
+		t.Errorf("acme's internal channel is %q, want %s", p.InternalChannelID, internal)
+	}
+	if c, _ := f.channel(internal); c.Name != "acme"+internalChannelSuffix {
+		t.Errorf("the internal channel is named %q, want it renamed for acme", c.Name)
+	}
+}
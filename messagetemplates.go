@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The copy of a message the bot sends, as a text/template that /templates can change without a deploy.
+type messageTemplate struct {
+	description string
+	// The template used until someone changes it, and whenever a changed one fails to render.
+	text string
+	// The variables the template is given, with what they are, for /templates preview.
+	variables map[string]string
+	// Example data to preview the template with and check changes against.
+	sample any
+}
+
+// The data for messages to a member.
+type memberMessage struct {
+	Name    string
+	Mention string
+}
+
+// The data for a project's weekly digest email.
+type digestMessage struct {
+	Channel        string
+	Messages       int
+	Decisions      []string
+	Completed      []string
+	Deadlines      []digestEvent
+	Events         []digestEvent
+	UnsubscribeURL string
+}
+
+// An upcoming deadline or calendar event in a digest.
+type digestEvent struct {
+	When    string
+	Summary string
+}
+
+// The data for a reminder DM.
+type reminderMessage struct {
+	AuthorID  string
+	ChannelID string
+	Content   string
+}
+
+// The data for a deal stage announcement.
+type dealStageMessage struct {
+	Deal  string
+	Stage string
+}
+
+// The messages that can be changed with /templates, by name.
+var messageTemplates = map[string]messageTemplate{
+	"welcome": {
+		description: "The DM new members get asking them to answer the screening questions.",
+		text:        "Welcome to Juiceworks! Please answer a few questions so we can let you in.",
+		variables: map[string]string{
+			"Name":    "The member's username",
+			"Mention": "A mention of the member",
+		},
+		sample: memberMessage{Name: "ada", Mention: "<@1>"},
+	},
+	"screening-approved": {
+		description: "The DM a member gets when their screening answers are approved.",
+		text:        "You've been approved. Welcome to Juiceworks!",
+		variables: map[string]string{
+			"Name":    "The member's username",
+			"Mention": "A mention of the member",
+		},
+		sample: memberMessage{Name: "ada", Mention: "<@1>"},
+	},
+	"screening-rejected": {
+		description: "The DM a member gets when their screening answers are rejected.",
+		text:        "Sorry, we weren't able to approve your request to join Juiceworks.",
+		variables: map[string]string{
+			"Name":    "The member's username",
+			"Mention": "A mention of the member",
+		},
+		sample: memberMessage{Name: "ada", Mention: "<@1>"},
+	},
+	"digest": {
+		description: "The weekly digest emailed to a project's client.",
+		text: "Here's what happened in #{{.Channel}} this week.\n\n" +
+			"Messages: {{.Messages}}\n" +
+			"{{if .Decisions}}\nDecisions:\n{{range .Decisions}}- {{.}}\n{{end}}{{end}}" +
+			"{{if .Completed}}\nCompleted:\n{{range .Completed}}- {{.}}\n{{end}}{{end}}" +
+			"{{if .Deadlines}}\nDeadlines:\n{{range .Deadlines}}- {{.When}}: {{.Summary}}\n{{end}}{{end}}" +
+			"{{if .Events}}\nComing up:\n{{range .Events}}- {{.When}}: {{.Summary}}\n{{end}}{{end}}" +
+			"\nTo stop receiving these emails, visit {{.UnsubscribeURL}}\n",
+		variables: map[string]string{
+			"Channel":        "The project channel's name",
+			"Messages":       "How many messages people sent in the channel this week",
+			"Decisions":      "Decisions recorded with /note this week",
+			"Completed":      "Deliverables the client approved this week",
+			"Deadlines":      "Unpaid milestones due in the next two weeks, each with .When and .Summary",
+			"Events":         "Upcoming calendar events, each with .When and .Summary",
+			"UnsubscribeURL": "The link that stops the digest",
+		},
+		sample: digestMessage{
+			Channel:        "acme-website",
+			Messages:       42,
+			Decisions:      []string{"Launch with the blue theme"},
+			Completed:      []string{"Homepage design"},
+			Deadlines:      []digestEvent{{When: "Fri Jan 6", Summary: "Design handoff"}},
+			Events:         []digestEvent{{When: "Mon Jan 2, 15:04 UTC", Summary: "Design review"}},
+			UnsubscribeURL: "https://example.com/digest/unsubscribe",
+		},
+	},
+	"reminder": {
+		description: "The DM sent for /remind-me, about the message someone asked to be reminded of.",
+		text:        "<@{{.AuthorID}}> in <#{{.ChannelID}}>:\n{{.Content}}",
+		variables: map[string]string{
+			"AuthorID":  "The user ID of whoever wrote the message",
+			"ChannelID": "The ID of the channel the message is in",
+			"Content":   "The message's text",
+		},
+		sample: reminderMessage{AuthorID: "1", ChannelID: "2", Content: "Can someone look at the staging build?"},
+	},
+	"deal-stage": {
+		description: "The announcement in a project channel when its HubSpot deal changes stage.",
+		text:        "Deal \"{{.Deal}}\" moved to **{{.Stage}}**.",
+		variables: map[string]string{
+			"Deal":  "The deal's name",
+			"Stage": "The stage it moved to",
+		},
+		sample: dealStageMessage{Deal: "Acme website", Stage: "Contract sent"},
+	},
+}
+
+// The choices for /templates' name option, one for each template.
+var templateChoices = func() []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for name := range messageTemplates {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+	}
+	slices.SortFunc(choices, func(a, b *discordgo.ApplicationCommandOptionChoice) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return choices
+}()
+
+// Render a message from its template. A template changed with /templates that fails to render, or renders nothing,
+// falls back to the default, so a bad edit can't stop messages going out.
+func renderMessage(name string, data any) string {
+	t := messageTemplates[name]
+	var custom string
+	readStore(func(d *storeData) {
+		custom = d.MessageTemplates[name]
+	})
+	if custom != "" {
+		text, err := executeTemplate(name, custom, data)
+		if err == nil {
+			return text
+		}
+		log.Printf("Error rendering %s template, using the default: %v", name, err)
+	}
+	text, err := executeTemplate(name, t.text, data)
+	if err != nil {
+		log.Printf("Error rendering default %s template: %v", name, err)
+	}
+	return text
+}
+
+// Parse and execute a template, failing if it renders nothing.
+func executeTemplate(name, text string, data any) (string, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(b.String()) == "" {
+		return "", fmt.Errorf("the template renders nothing")
+	}
+	return b.String(), nil
+}
+
+// List, preview, edit or reset the templates for the messages the bot sends.
+func templatesCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	var name string
+	if len(sub.Options) > 0 {
+		name = sub.Options[0].StringValue()
+	}
+	t, ok := messageTemplates[name]
+	var custom string
+	readStore(func(d *storeData) {
+		custom = d.MessageTemplates[name]
+	})
+
+	var content string
+	switch {
+	case sub.Name == "list":
+		var customized map[string]string
+		readStore(func(d *storeData) {
+			customized = d.MessageTemplates
+		})
+		names := make([]string, 0, len(messageTemplates))
+		for n := range messageTemplates {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		var lines []string
+		for _, n := range names {
+			line := fmt.Sprintf("**%s**: %s", n, messageTemplates[n].description)
+			if customized[n] != "" {
+				line += " *(changed)*"
+			}
+			lines = append(lines, line)
+		}
+		content = strings.Join(lines, "\n")
+
+	case !ok:
+		content = fmt.Sprintf("There's no %s template.", name)
+
+	case sub.Name == "preview":
+		text := t.text
+		if custom != "" {
+			text = custom
+		}
+		vars := make([]string, 0, len(t.variables))
+		for v, desc := range t.variables {
+			vars = append(vars, fmt.Sprintf("`{{.%s}}`: %s", v, desc))
+		}
+		slices.Sort(vars)
+		content = fmt.Sprintf("**%s**: %s\n\n**Variables**\n%s\n\n**Template**\n```\n%s\n```\n**With example data**\n```\n%s\n```",
+			name, t.description, strings.Join(vars, "\n"), strings.ReplaceAll(strings.TrimSpace(text), "```", "'''"),
+			strings.ReplaceAll(strings.TrimSpace(renderMessage(name, t.sample)), "```", "'''"))
+		if custom == "" {
+			content += "\nThis is the default."
+		}
+
+	case sub.Name == "edit":
+		text := t.text
+		if custom != "" {
+			text = custom
+		}
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseModal,
+			Data: &discordgo.InteractionResponseData{
+				CustomID: "templates-save:" + name,
+				Title:    truncate("Edit the "+name+" template", 45),
+				Components: []discordgo.MessageComponent{
+					discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:  "text",
+							Label:     "Template",
+							Style:     discordgo.TextInputParagraph,
+							Value:     text,
+							Required:  true,
+							MaxLength: 4000,
+						},
+					}},
+				},
+			},
+		}))
+		return
+
+	case sub.Name == "reset":
+		err := updateStore(func(d *storeData) {
+			delete(d.MessageTemplates, name)
+		})
+		if err != nil {
+			log.Printf("Error saving template: %v", err)
+			content = "Error saving template: " + describeError(err)
+			break
+		}
+		log.Printf("%s reset the %s template.", i.Member.User, name)
+		content = fmt.Sprintf("The %s template is back to the default.", name)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: truncate(content, 2000),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Save a template from the /templates edit form, once it renders with the example data.
+func saveTemplate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	_, name, _ := strings.Cut(data.CustomID, ":")
+	text := data.Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
+
+	var content string
+	t, ok := messageTemplates[name]
+	if !ok {
+		content = fmt.Sprintf("There's no %s template.", name)
+	} else if preview, err := executeTemplate(name, text, t.sample); err != nil {
+		content = fmt.Sprintf("The template wasn't saved, because it doesn't work: %v", err)
+	} else {
+		err := updateStore(func(d *storeData) {
+			if d.MessageTemplates == nil {
+				d.MessageTemplates = make(map[string]string)
+			}
+			if text == t.text {
+				delete(d.MessageTemplates, name)
+			} else {
+				d.MessageTemplates[name] = text
+			}
+		})
+		if err != nil {
+			log.Printf("Error saving template: %v", err)
+			content = "Error saving template: " + describeError(err)
+		} else {
+			log.Printf("%s changed the %s template.", i.Member.User, name)
+			content = fmt.Sprintf("Saved the %s template. With example data, it looks like:\n```\n%s\n```", name,
+				strings.ReplaceAll(strings.TrimSpace(preview), "```", "'''"))
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: truncate(content, 2000),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
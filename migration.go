@@ -0,0 +1,322 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long a previewed permission migration can be started for.
+const migrationLifetime = 15 * time.Minute
+
+// How long a permission migration waits between channels, so it doesn't eat into the rate limits interactions need.
+const migrationDelay = 500 * time.Millisecond
+
+// How many channels a permission migration updates between progress reports.
+const migrationProgressEvery = 10
+
+// A permission migration previewed with /migrate-permissions, waiting to be confirmed.
+type permissionMigration struct {
+	userID      string
+	description string
+	channelIDs  []string
+	// Bring one channel's overwrites up to date, returning whether anything changed.
+	migrate func(s *discordgo.Session, channelID string) (bool, error)
+	// Record that the migration finished, if it needs to.
+	finish func()
+	// How to run it again on the channels that fail.
+	spec      migrationJob
+	createdAt time.Time
+}
+
+var (
+	migrationsMu sync.Mutex
+	migrations   = make(map[string]*permissionMigration)
+)
+
+// Every channel and category the bot manages permissions on: project channels, their workspaces, internal channels,
+// voice channels and categories, client categories, and open incident channels.
+func managedChannels() []string {
+	var ids []string
+	readStore(func(d *storeData) {
+		for id, p := range d.Projects {
+			ids = append(ids, id, p.CategoryID, p.InternalChannelID)
+			ids = append(ids, p.Workspace...)
+			ids = append(ids, p.VoiceChannels...)
+		}
+		for _, c := range d.Clients {
+			ids = append(ids, c.Categories...)
+		}
+		for _, inc := range d.Incidents {
+			if inc.ClosedAt.IsZero() {
+				ids = append(ids, inc.ChannelID)
+			}
+		}
+	})
+	ids = slices.DeleteFunc(ids, func(id string) bool { return id == "" })
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// Move the overwrites for one role to another on every managed channel, for when the staff role is replaced.
+func roleMigration(from, to *discordgo.Role) *permissionMigration {
+	return &permissionMigration{
+		description: fmt.Sprintf("Move %s's channel permissions to %s", from.Mention(), to.Mention()),
+		channelIDs:  managedChannels(),
+		spec:        migrationJob{FromRoleID: from.ID, ToRoleID: to.ID},
+		migrate: func(s *discordgo.Session, channelID string) (bool, error) {
+			c, err := s.Channel(channelID)
+			if err != nil {
+				return false, err
+			}
+			for _, o := range c.PermissionOverwrites {
+				if o.ID != from.ID || o.Type != discordgo.PermissionOverwriteTypeRole {
+					continue
+				}
+				if err := s.ChannelPermissionSet(channelID, to.ID, discordgo.PermissionOverwriteTypeRole, o.Allow, o.Deny); err != nil {
+					return false, err
+				}
+				return true, s.ChannelPermissionDelete(channelID, from.ID)
+			}
+			return false, nil
+		},
+	}
+}
+
+// Share the channels of every project set up from a template with the template's current roles, and unshare them
+// from roles it no longer has. Only projects made since projects started recording their template are found.
+func templateMigration(name string, tmpl projectTemplate) *permissionMigration {
+	// The roles each channel was shared with, from its project.
+	previous := make(map[string][]string)
+	var projectIDs []string
+	readStore(func(d *storeData) {
+		for id, p := range d.Projects {
+			if p.Template != name {
+				continue
+			}
+			projectIDs = append(projectIDs, id)
+			for _, channelID := range append([]string{id}, p.Workspace...) {
+				previous[channelID] = slices.Clone(p.TemplateRoleIDs)
+			}
+		}
+	})
+	channelIDs := make([]string, 0, len(previous))
+	for id := range previous {
+		channelIDs = append(channelIDs, id)
+	}
+	slices.Sort(channelIDs)
+
+	return &permissionMigration{
+		description: fmt.Sprintf("Update the role permissions of %d projects set up from the %s template", len(projectIDs), name),
+		channelIDs:  channelIDs,
+		spec:        migrationJob{Template: name},
+		migrate: func(s *discordgo.Session, channelID string) (bool, error) {
+			c, err := s.Channel(channelID)
+			if err != nil {
+				return false, err
+			}
+			shared := func(roleID string) bool {
+				return slices.ContainsFunc(c.PermissionOverwrites, func(o *discordgo.PermissionOverwrite) bool {
+					return o.ID == roleID && o.Type == discordgo.PermissionOverwriteTypeRole
+				})
+			}
+			changed := false
+			for _, roleID := range tmpl.RoleIDs {
+				if shared(roleID) {
+					continue
+				}
+				err := s.ChannelPermissionSet(channelID, roleID, discordgo.PermissionOverwriteTypeRole,
+					discordgo.PermissionViewChannel|discordgo.PermissionSendMessages, 0)
+				if err != nil {
+					return changed, err
+				}
+				changed = true
+			}
+			for _, roleID := range previous[channelID] {
+				if slices.Contains(tmpl.RoleIDs, roleID) || !shared(roleID) {
+					continue
+				}
+				if err := s.ChannelPermissionDelete(channelID, roleID); err != nil {
+					return changed, err
+				}
+				changed = true
+			}
+			return changed, nil
+		},
+		finish: func() {
+			for _, id := range projectIDs {
+				err := updateProject(id, func(p *project) { p.TemplateRoleIDs = slices.Clone(tmpl.RoleIDs) })
+				if err != nil {
+					log.Printf("Error recording template roles: %v", err)
+				}
+			}
+		},
+	}
+}
+
+// Preview a bulk permission migration, with a button to start it.
+func migratePermissionsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	var m *permissionMigration
+	var content string
+	switch sub.Name {
+	case "role":
+		from, to := options["from"].RoleValue(s, i.GuildID), options["to"].RoleValue(s, i.GuildID)
+		if from.ID == to.ID {
+			content = "Pick two different roles."
+			break
+		}
+		m = roleMigration(from, to)
+	case "template":
+		name := strings.ToLower(options["name"].StringValue())
+		tmpl, ok := getTemplate(name)
+		if !ok {
+			content = "There's no project template named " + name + "."
+			break
+		}
+		m = templateMigration(name, tmpl)
+	}
+	if m != nil && len(m.channelIDs) == 0 {
+		content = "There are no channels to migrate."
+		m = nil
+	}
+
+	var components []discordgo.MessageComponent
+	if m != nil {
+		m.userID = i.Member.User.ID
+		m.createdAt = time.Now()
+		migrationsMu.Lock()
+		for id, old := range migrations {
+			if time.Since(old.createdAt) > migrationLifetime {
+				delete(migrations, id)
+			}
+		}
+		migrations[i.ID] = m
+		migrationsMu.Unlock()
+
+		estimate := time.Duration(len(m.channelIDs)) * migrationDelay
+		content = fmt.Sprintf("%s: %d channels, which takes at least %s. Channels that are already up to date are "+
+			"left alone, so it's safe to run again if it stops partway.", m.description, len(m.channelIDs), formatWait(estimate))
+		components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Start migration", Style: discordgo.DangerButton, CustomID: "migrate-permissions:" + i.ID},
+			}},
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Start a previewed permission migration, reporting progress in the preview message as it goes.
+func startPermissionMigration(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, id, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	migrationsMu.Lock()
+	m, ok := migrations[id]
+	if ok && m.userID == i.Member.User.ID && time.Since(m.createdAt) <= migrationLifetime {
+		delete(migrations, id)
+	} else {
+		m = nil
+	}
+	migrationsMu.Unlock()
+
+	if m == nil {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "This migration has expired or already started. Run /migrate-permissions again.",
+				Components: []discordgo.MessageComponent{},
+			},
+		}))
+		return
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    fmt.Sprintf("%s: starting on %d channels…", m.description, len(m.channelIDs)),
+			Components: []discordgo.MessageComponent{},
+		},
+	}))
+	go runPermissionMigration(s, i, m)
+}
+
+// Work through a permission migration one channel at a time, editing the response with progress. Discord only lets
+// the response be edited for 15 minutes, so the audit log has the outcome of longer migrations. It's listed in /jobs
+// while it runs, and can be cancelled from there. If any channels fail, it's kept as a failed job to retry on them.
+func runPermissionMigration(s *discordgo.Session, i *discordgo.InteractionCreate, m *permissionMigration) {
+	progress := func(content string) {
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			log.Printf("Error reporting migration progress: %v", err)
+		}
+	}
+	var cancelled atomic.Bool
+	jobID := startJob(jobPermissionMigration, m.description, i.Member.User.ID, func() { cancelled.Store(true) })
+	defer endJob(jobID)
+
+	changed, done := 0, 0
+	var problems, failed []string
+	for n, channelID := range m.channelIDs {
+		if cancelled.Load() {
+			break
+		}
+		if n > 0 {
+			time.Sleep(migrationDelay)
+		}
+		ok, err := m.migrate(s, channelID)
+		if err != nil {
+			log.Printf("Error migrating permissions of channel %s: %v", channelID, err)
+			problems = append(problems, fmt.Sprintf("<#%s>: %s", channelID, describeError(err)))
+			failed = append(failed, channelID)
+		} else if ok {
+			changed++
+		}
+		done++
+		updateJobProgress(jobID, fmt.Sprintf("%d of %d channels done", done, len(m.channelIDs)))
+		if done%migrationProgressEvery == 0 && done < len(m.channelIDs) {
+			progress(fmt.Sprintf("%s: %d of %d channels done, %d updated, %d failed…",
+				m.description, done, len(m.channelIDs), changed, len(problems)))
+		}
+	}
+	if m.finish != nil && len(problems) == 0 && !cancelled.Load() {
+		m.finish()
+	}
+	if len(failed) > 0 {
+		spec := m.spec
+		spec.ChannelIDs = failed
+		recordFailedJob(jobPermissionMigration, m.description, spec, errors.New(strings.Join(problems, "; ")))
+	}
+
+	summary := fmt.Sprintf("%s: done. Updated %d of %d channels.", m.description, changed, len(m.channelIDs))
+	if cancelled.Load() {
+		summary = fmt.Sprintf("%s: cancelled after %d of %d channels. Updated %d.", m.description, done, len(m.channelIDs), changed)
+	}
+	log.Printf("%s migrated permissions. %s", i.Member.User, summary)
+	postAudit(s, auditEntry{
+		Action:  auditPermissionsMigrated,
+		ActorID: i.Member.User.ID,
+		Summary: fmt.Sprintf("<@%s> migrated permissions. %s %d failed.", i.Member.User.ID, summary, len(problems)),
+	})
+	if len(problems) > 0 {
+		summary += fmt.Sprintf(" %d failed, so retry it with /jobs once they're fixed:\n%s", len(problems), strings.Join(problems, "\n"))
+	}
+	progress(truncate(summary, 2000))
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How often overdue milestones are checked.
+const milestoneReminderInterval = time.Hour
+
+// A billable project milestone. Amounts are stored in cents.
+type milestone struct {
+	Name     string    `json:"name"`
+	Amount   int64     `json:"amount"`
+	Due      time.Time `json:"due,omitempty"`
+	PaidAt   time.Time `json:"paidAt,omitempty"`
+	Reminded bool      `json:"reminded,omitempty"`
+}
+
+// Format an amount in cents in the billing currency, which defaults to USD.
+func formatAmount(cents int64) string {
+	currency := os.Getenv("BILLING_CURRENCY")
+	if currency == "" {
+		currency = "USD"
+	}
+	return fmt.Sprintf("%d.%02d %s", cents/100, cents%100, currency)
+}
+
+// The total billed, paid, and outstanding amounts for a project's milestones.
+func milestoneTotals(milestones []milestone) (total, paid, outstanding int64) {
+	for _, m := range milestones {
+		total += m.Amount
+		if m.PaidAt.IsZero() {
+			outstanding += m.Amount
+		} else {
+			paid += m.Amount
+		}
+	}
+	return total, paid, outstanding
+}
+
+// Manage the billing milestones of the project the command was called from.
+func milestoneCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	var content string
+	_, ok := getProject(i.ChannelID)
+	switch {
+	case !ok:
+		content = "This channel is not a registered project."
+	case sub.Name == "add":
+		content = addMilestone(i.ChannelID, options)
+	case sub.Name == "mark-paid":
+		content = markMilestonePaid(i.ChannelID, options["name"].StringValue())
+	case sub.Name == "list":
+		content = listMilestones(i.ChannelID)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Add a milestone to a project, returning the response to show the caller.
+func addMilestone(channelID string, options map[string]*discordgo.ApplicationCommandInteractionDataOption) string {
+	m := milestone{
+		Name:   strings.TrimSpace(options["name"].StringValue()),
+		Amount: int64(math.Round(options["amount"].FloatValue() * 100)),
+	}
+	if m.Amount < 0 {
+		return "The amount can't be negative."
+	}
+	if o, ok := options["due"]; ok {
+		due, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(o.StringValue()), time.Local)
+		if err != nil {
+			return "The due date must look like 2024-12-31."
+		}
+		m.Due = due
+	}
+
+	duplicate := false
+	err := updateProject(channelID, func(p *project) {
+		for _, existing := range p.Milestones {
+			if strings.EqualFold(existing.Name, m.Name) {
+				duplicate = true
+				return
+			}
+		}
+		p.Milestones = append(p.Milestones, m)
+	})
+	if err != nil {
+		log.Printf("Error saving milestone: %v", err)
+		return "Error saving milestone: " + describeError(err)
+	}
+	if duplicate {
+		return fmt.Sprintf("This project already has a milestone called \"%s\".", m.Name)
+	}
+
+	log.Printf("Added milestone %q to channel %s.", m.Name, channelID)
+	return fmt.Sprintf("Added milestone \"%s\" for %s.", m.Name, formatAmount(m.Amount))
+}
+
+// Mark a project's milestone as paid, returning the response to show the caller.
+func markMilestonePaid(channelID, name string) string {
+	found, alreadyPaid := false, false
+	err := updateProject(channelID, func(p *project) {
+		for n := range p.Milestones {
+			if strings.EqualFold(p.Milestones[n].Name, strings.TrimSpace(name)) {
+				found = true
+				alreadyPaid = !p.Milestones[n].PaidAt.IsZero()
+				if !alreadyPaid {
+					p.Milestones[n].PaidAt = time.Now()
+				}
+				return
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving milestone: %v", err)
+		return "Error saving milestone: " + describeError(err)
+	}
+	if !found {
+		return fmt.Sprintf("This project has no milestone called \"%s\".", name)
+	}
+	if alreadyPaid {
+		return fmt.Sprintf("\"%s\" is already marked as paid.", name)
+	}
+
+	log.Printf("Marked milestone %q in channel %s as paid.", name, channelID)
+	return fmt.Sprintf("Marked \"%s\" as paid.", name)
+}
+
+// List a project's milestones with their totals.
+func listMilestones(channelID string) string {
+	p, _ := getProject(channelID)
+	if len(p.Milestones) == 0 {
+		return "This project has no milestones."
+	}
+
+	var b strings.Builder
+	for _, m := range p.Milestones {
+		status := "unpaid"
+		if !m.PaidAt.IsZero() {
+			status = fmt.Sprintf("paid <t:%d:D>", m.PaidAt.Unix())
+		} else if !m.Due.IsZero() {
+			status = fmt.Sprintf("due <t:%d:D>", m.Due.Unix())
+		}
+		fmt.Fprintf(&b, "- **%s**: %s (%s)\n", m.Name, formatAmount(m.Amount), status)
+	}
+	total, paid, outstanding := milestoneTotals(p.Milestones)
+	fmt.Fprintf(&b, "\nTotal: %s\nPaid: %s\nOutstanding: %s", formatAmount(total), formatAmount(paid), formatAmount(outstanding))
+	return b.String()
+}
+
+// Check for overdue milestones in the background.
+func startMilestoneReminders(s *discordgo.Session) {
+	go func() {
+		for range time.Tick(milestoneReminderInterval) {
+			if !paused("milestone reminders") {
+				remindOverdueMilestones(s)
+			}
+		}
+	}()
+}
+
+// Remind staff in the internal channel about milestones that are past due and still unpaid. Each milestone is only
+// reminded about once. Reminders aren't urgent, so they wait until business hours.
+func remindOverdueMilestones(s *discordgo.Session) {
+	if open, _ := openAt(time.Now()); !open {
+		return
+	}
+
+	type overdue struct {
+		channelID string
+		milestone milestone
+	}
+	var due []overdue
+	now := time.Now()
+	err := updateStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			for n, m := range p.Milestones {
+				if !m.Due.IsZero() && m.Due.Before(now) && m.PaidAt.IsZero() && !m.Reminded {
+					p.Milestones[n].Reminded = true
+					due = append(due, overdue{p.ChannelID, m})
+				}
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving milestone reminders: %v", err)
+		return
+	}
+
+	for _, o := range due {
+		content := fmt.Sprintf("Milestone \"%s\" in <#%s> (%s) was due <t:%d:R> and is still unpaid.",
+			o.milestone.Name, o.channelID, formatAmount(o.milestone.Amount), o.milestone.Due.Unix())
+		if userID, ok := onPoint(o.channelID); ok {
+			content += fmt.Sprintf(" <@%s> is on point.", userID)
+		}
+		_, err := s.ChannelMessageSend(InternalChannelId, content)
+		if err != nil {
+			recordFailure("sending milestone reminder", err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How a project treats the nicknames of clients added to it.
+const (
+	// Leave nicknames alone.
+	nicknamesOff = "off"
+	// Prefix the client's name when they're added.
+	nicknamesPrefix = "prefix"
+	// Prefix the client's name when they're added, and put it back if they change their nickname.
+	nicknamesEnforce = "enforce"
+)
+
+// The nickname a client gets in a project, like "Acme — Jane". Discord caps nicknames at 32 characters.
+func clientNickname(client, name string) string {
+	return truncate(client+" — "+name, 32)
+}
+
+// The name a member goes by in the guild: their nickname, display name or username, whichever is set first.
+func nameInGuild(user *discordgo.User, nick string) string {
+	switch {
+	case nick != "":
+		return nick
+	case user.GlobalName != "":
+		return user.GlobalName
+	default:
+		return user.Username
+	}
+}
+
+// Give a client added to a project a nickname starting with the project's client name, if the project asks for it.
+// Their previous nickname is saved so it can be put back when they're removed. Juiceworks members are left alone.
+func applyClientNickname(s *discordgo.Session, channelID string, member *discordgo.Member) {
+	p, ok := getProject(channelID)
+	if !ok || p.ClientName == "" || (p.NicknameMode != nicknamesPrefix && p.NicknameMode != nicknamesEnforce) {
+		return
+	}
+	if slices.Contains(member.Roles, JuiceworksRoleId) || strings.HasPrefix(member.Nick, p.ClientName+" — ") {
+		return
+	}
+
+	nick := clientNickname(p.ClientName, nameInGuild(member.User, member.Nick))
+	if err := s.GuildMemberNickname(JuiceworksGuildId, member.User.ID, nick); err != nil {
+		recordFailure("setting client nickname", err)
+		return
+	}
+
+	err := updateProject(channelID, func(p *project) {
+		if p.Nicknames == nil {
+			p.Nicknames = make(map[string]string)
+		}
+		if _, ok := p.Nicknames[member.User.ID]; !ok {
+			p.Nicknames[member.User.ID] = member.Nick
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving previous nickname: %v", err)
+	}
+	log.Printf("Set the nickname of %s to %q in project %s.", member.User, nick, channelID)
+}
+
+// Put back the nickname a client had before they were added to a project.
+func revertClientNickname(s *discordgo.Session, channelID, userID string) {
+	p, ok := getProject(channelID)
+	if !ok {
+		return
+	}
+	previous, ok := p.Nicknames[userID]
+	if !ok {
+		return
+	}
+	if err := s.GuildMemberNickname(JuiceworksGuildId, userID, previous); err != nil {
+		recordFailure("reverting client nickname", err)
+		return
+	}
+	err := updateProject(channelID, func(p *project) {
+		delete(p.Nicknames, userID)
+	})
+	if err != nil {
+		log.Printf("Error forgetting previous nickname: %v", err)
+	}
+	log.Printf("Reverted the nickname of user %s in project %s.", userID, channelID)
+}
+
+// Whether any project enforces client nicknames.
+func nicknamesEnforced() bool {
+	enforced := false
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if p.NicknameMode == nicknamesEnforce {
+				enforced = true
+			}
+		}
+	})
+	return enforced
+}
+
+// Put the client name back when a client changes their nickname in a project that enforces it.
+func enforceClientNickname(s *discordgo.Session, m *discordgo.GuildMemberUpdate) {
+	if m.GuildID != JuiceworksGuildId {
+		return
+	}
+	var projects []project
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if _, ok := p.Nicknames[m.User.ID]; ok && p.NicknameMode == nicknamesEnforce {
+				projects = append(projects, copyProject(p))
+			}
+		}
+	})
+	for _, p := range projects {
+		if strings.HasPrefix(m.Nick, p.ClientName+" — ") {
+			continue
+		}
+		if err := s.GuildMemberNickname(JuiceworksGuildId, m.User.ID, clientNickname(p.ClientName, nameInGuild(m.User, m.Nick))); err != nil {
+			recordFailure("enforcing client nickname", err)
+			continue
+		}
+		log.Printf("Restored the client nickname of %s in project %s.", m.User, p.ChannelID)
+		// A member only needs one prefix, even if they're in several enforcing projects.
+		return
+	}
+}
+
+// Set the client name and nickname mode for the project the command was called from.
+func nicknamesCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range i.ApplicationCommandData().Options {
+		options[o.Name] = o
+	}
+
+	var content string
+	p, ok := getProject(i.ChannelID)
+	mode := options["mode"].StringValue()
+	client := p.ClientName
+	if o, ok := options["client"]; ok {
+		client = strings.TrimSpace(o.StringValue())
+	}
+	switch {
+	case !ok:
+		content = "This channel is not a registered project."
+	case mode != nicknamesOff && client == "":
+		content = "Set the client name to prefix nicknames with."
+	default:
+		err := updateProject(i.ChannelID, func(p *project) {
+			p.ClientName = client
+			p.NicknameMode = mode
+		})
+		if err != nil {
+			log.Printf("Error saving nickname settings: %v", err)
+			content = "Error saving nickname settings: " + describeError(err)
+			break
+		}
+		log.Printf("Set nicknames in project %s to %s for %q.", i.ChannelID, mode, client)
+		content = map[string]string{
+			nicknamesOff:     "Client nicknames will be left alone.",
+			nicknamesPrefix:  fmt.Sprintf("Clients added from now on will be nicknamed like %q.", clientNickname(client, "Jane")),
+			nicknamesEnforce: fmt.Sprintf("Clients added from now on will be nicknamed like %q, and kept that way.", clientNickname(client, "Jane")),
+		}[mode]
+		if mode == nicknamesEnforce && !runningFeatures["nicknames"] && !slices.Contains(disabledFeatures(), "nicknames") {
+			content += " Nickname changes will only be put back once the bot is restarted, since no project enforced " +
+				"nicknames when it started."
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
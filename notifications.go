@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How much the bot pings a member about a project. Members who haven't chosen are only pinged when a message is
+// addressed to them.
+const (
+	notifyMute     = "mute"
+	notifyMentions = "mentions"
+	notifyAll      = "all"
+)
+
+// Work out who a bot message in a project should ping: the users it's addressed to, unless they've muted the
+// project, and everyone who asked to hear about everything. Returns the mentions to add to the message for the
+// latter, and the allowed mentions to send it with.
+func projectPings(channelID string, addressed ...string) (string, *discordgo.MessageAllowedMentions) {
+	p, _ := getProject(channelID)
+	allowed := &discordgo.MessageAllowedMentions{Users: []string{}}
+	var mentions []string
+	for _, userID := range addressed {
+		if p.Notifications[userID] != notifyMute {
+			allowed.Users = append(allowed.Users, userID)
+		}
+	}
+	for userID, level := range p.Notifications {
+		if level == notifyAll && !slices.Contains(addressed, userID) {
+			allowed.Users = append(allowed.Users, userID)
+			mentions = append(mentions, "<@"+userID+">")
+		}
+	}
+	slices.Sort(mentions)
+	return strings.Join(mentions, " "), allowed
+}
+
+// Set how much the bot pings the caller about the project the command was called from. Clients can use this too.
+func notificationsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if _, ok := getProject(i.ChannelID); !ok {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "This channel is not a registered project.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	level := i.ApplicationCommandData().Options[0].StringValue()
+	err := updateProject(i.ChannelID, func(p *project) {
+		if p.Notifications == nil {
+			p.Notifications = make(map[string]string)
+		}
+		if level == notifyMentions {
+			delete(p.Notifications, i.Member.User.ID)
+		} else {
+			p.Notifications[i.Member.User.ID] = level
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving notification preference: %v", err)
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Error saving notification preference: " + describeError(err),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	content := map[string]string{
+		notifyMute:     "The bot won't ping you in this channel.",
+		notifyMentions: "The bot will only ping you in this channel when a message is for you.",
+		notifyAll:      "The bot will ping you about everything it posts in this channel.",
+	}[level]
+	log.Printf("Set notifications for %s in channel %s to %s.", i.Member.User, i.ChannelID, level)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
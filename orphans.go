@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How often the guild is scanned for project channels missing from the registry.
+const orphanScanInterval = 6 * time.Hour
+
+// Look for orphaned project channels now and then.
+func startOrphanScans(s *discordgo.Session) {
+	go func() {
+		for {
+			if !paused("scanning for orphaned channels") {
+				reportOrphanedChannels(s)
+			}
+			time.Sleep(orphanScanInterval)
+		}
+	}()
+}
+
+// Whether a channel looks like a project channel made by /make-channel: a text channel with a name the bot would have
+// picked, shared with the Juiceworks role and hidden from everyone else. Archived and internal channels don't count.
+func looksLikeProjectChannel(c *discordgo.Channel) bool {
+	if c.Type != discordgo.ChannelTypeGuildText || c.ID == InternalChannelId || sanitizeChannelName(c.Name) != c.Name ||
+		strings.HasPrefix(c.Name, "archived-") || strings.HasSuffix(c.Name, internalChannelSuffix) {
+		return false
+	}
+	return staffOnly(c)
+}
+
+// Whether a channel is shared with the Juiceworks role and hidden from everyone else, like the bot's channels.
+func staffOnly(c *discordgo.Channel) bool {
+	var staff, private bool
+	for _, o := range c.PermissionOverwrites {
+		switch {
+		case o.ID == JuiceworksRoleId && o.Allow&discordgo.PermissionViewChannel != 0:
+			staff = true
+		case o.ID == JuiceworksGuildId && o.Deny&discordgo.PermissionViewChannel != 0:
+			private = true
+		}
+	}
+	return staff && private
+}
+
+// The guild's channels that look like project channels but aren't in the registry.
+func orphanedChannels(s *discordgo.Session) ([]*discordgo.Channel, error) {
+	channels, err := s.GuildChannels(JuiceworksGuildId)
+	if err != nil {
+		return nil, err
+	}
+	known := managedChannels()
+	var orphans []*discordgo.Channel
+	for _, c := range channels {
+		if looksLikeProjectChannel(c) && !slices.Contains(known, c.ID) {
+			orphans = append(orphans, c)
+		}
+	}
+	return orphans, nil
+}
+
+// Ask staff in the internal channel whether to adopt orphaned channels into the registry. Each channel is only asked
+// about once.
+func reportOrphanedChannels(s *discordgo.Session) {
+	orphans, err := orphanedChannels(s)
+	if err != nil {
+		recordFailure("scanning for orphaned channels", err)
+		return
+	}
+	var due []*discordgo.Channel
+	err = updateStore(func(d *storeData) {
+		for _, c := range orphans {
+			if _, reported := d.OrphansReported[c.ID]; reported {
+				continue
+			}
+			if d.OrphansReported == nil {
+				d.OrphansReported = make(map[string]time.Time)
+			}
+			d.OrphansReported[c.ID] = time.Now()
+			due = append(due, c)
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving orphaned channels: %v", err)
+		return
+	}
+
+	for _, c := range due {
+		members := 0
+		for _, o := range c.PermissionOverwrites {
+			if o.Type == discordgo.PermissionOverwriteTypeMember {
+				members++
+			}
+		}
+		_, err := s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{
+			Content: fmt.Sprintf("<#%s> looks like a project channel, but it isn't in the registry. It was created <t:%d:D> "+
+				"and is shared with %d members. Adopt it?", c.ID, channelCreatedAt(c.ID).Unix(), members),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "Adopt", Style: discordgo.PrimaryButton, CustomID: "adopt-channel:" + c.ID},
+				}},
+			},
+		})
+		if err != nil {
+			recordFailure("reporting orphaned channel", err)
+		}
+	}
+}
+
+// When a channel was created, from its ID.
+func channelCreatedAt(channelID string) time.Time {
+	t, err := discordgo.SnowflakeTimestamp(channelID)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Adopt a channel from an orphaned channel report.
+func adoptFromPrompt(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, channelID, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	content := adoptChannel(s, channelID, i.Member.User.ID)
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    fmt.Sprintf("<#%s>: %s", channelID, content),
+			Components: []discordgo.MessageComponent{},
+		},
+	}))
+}
+
+// Add a channel to the registry as a project, inferring what it can from the channel with inferProject. Returns the
+// response to show the caller.
+func adoptChannel(s *discordgo.Session, channelID, actorID string) string {
+	c, err := s.Channel(channelID)
+	if err != nil {
+		log.Printf("Error reading channel: %v", err)
+		return "Error reading channel: " + describeError(err)
+	}
+	if _, ok := getProject(c.ID); ok {
+		return "This channel is already a registered project."
+	}
+
+	inferred := inferProject(s, c)
+	if err := updateProject(c.ID, func(p *project) { *p = inferred }); err != nil {
+		log.Printf("Error adopting channel: %v", err)
+		return "Error adopting channel: " + describeError(err)
+	}
+
+	log.Printf("%s adopted channel %s with %d members.", actorID, c.ID, len(inferred.Members))
+	postAudit(s, auditEntry{
+		Action:    auditChannelAdopted,
+		ActorID:   actorID,
+		ChannelID: c.ID,
+		Summary:   fmt.Sprintf("<@%s> adopted <#%s> into the registry with %d members.", actorID, c.ID, len(inferred.Members)),
+	})
+	return fmt.Sprintf("Adopted with %d members. Check /project-info and set a client lead with /transfer-creator.", len(inferred.Members))
+}
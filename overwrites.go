@@ -0,0 +1,41 @@
+package main
+
+import "sync"
+
+// How many permission overwrites are changed at once. Discord rate limits overwrites per channel, and discordgo waits
+// out a limit when it hits one, so a few at a time finishes a bulk change in a couple of round trips without spending
+// the whole bucket.
+const overwriteWorkers = 4
+
+// Run a batch of permission overwrite changes, a few at a time, and return each one's error in the order they were
+// given.
+func runOverwrites(changes []func() error) []error {
+	errs := make([]error, len(changes))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range min(overwriteWorkers, len(changes)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range work {
+				errs[n] = changes[n]()
+			}
+		}()
+	}
+	for n := range changes {
+		work <- n
+	}
+	close(work)
+	wg.Wait()
+	return errs
+}
+
+// The first error from runOverwrites, if any.
+func firstOverwriteErr(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The kinds of alert that can page someone through PagerDuty.
+const (
+	alertGatewayDown   = "gateway-down"
+	alertCommandErrors = "command-errors"
+	alertSLABreach     = "sla-breach"
+	alertSlowCommands  = "slow-commands"
+)
+
+// Defaults for when alerts fire, unless GATEWAY_DOWN_ALERT_AFTER and COMMAND_ERROR_ALERT say otherwise.
+const (
+	defaultGatewayDownAlertAfter = 5 * time.Minute
+	defaultCommandErrorAlerts    = 5
+	defaultCommandErrorWindow    = 10 * time.Minute
+)
+
+// How often unacknowledged escalations are checked against the response time.
+const slaCheckInterval = time.Minute
+
+// The PagerDuty Events API endpoint.
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Gateway and command error state used to decide when to alert.
+var (
+	alertsMu sync.Mutex
+	// The timer that alerts if the gateway stays disconnected, and whether it went off.
+	gatewayDownTimer *time.Timer
+	gatewayDownPaged bool
+	// When recent interaction responses failed, oldest first.
+	commandErrors []time.Time
+)
+
+// The PagerDuty routing key for each alert type, from PAGERDUTY_ROUTING_KEYS, a comma-separated list like
+// gateway-down=<key>,sla-breach=<key>. Alert types without a key don't page anyone.
+func pagerDutyRoutingKeys() map[string]string {
+	keys := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv("PAGERDUTY_ROUTING_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		alert, key, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			log.Printf("Ignoring malformed PAGERDUTY_ROUTING_KEYS entry %q", entry)
+			continue
+		}
+		keys[strings.TrimSpace(alert)] = strings.TrimSpace(key)
+	}
+	return keys
+}
+
+// Send an event to PagerDuty for an alert type. The action is trigger or resolve, and events with the same dedup key
+// belong to the same PagerDuty alert. Does nothing if the alert type has no routing key.
+func sendPagerDutyEvent(alert, action, dedupKey, summary string) (err error) {
+	key, ok := pagerDutyRoutingKeys()[alert]
+	if !ok {
+		return nil
+	}
+	defer func() { noteIntegrationCall("PagerDuty", err) }()
+	event := map[string]any{
+		"routing_key":  key,
+		"event_action": action,
+		"dedup_key":    dedupKey,
+	}
+	if action == "trigger" {
+		event["payload"] = map[string]any{
+			"summary":   summary,
+			"source":    "juiceworks-discord",
+			"severity":  "critical",
+			"component": alert,
+		}
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(pagerDutyEventsURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("pagerduty returned %s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+// Page someone about an alert. Failures are only logged, since recording them could trigger more alerts.
+func triggerAlert(alert, dedupKey, summary string) {
+	log.Printf("Alert %s: %s", alert, summary)
+	if err := sendPagerDutyEvent(alert, "trigger", dedupKey, summary); err != nil {
+		log.Printf("Error triggering PagerDuty alert: %v", err)
+	}
+}
+
+// Resolve an alert that was triggered earlier.
+func resolveAlert(alert, dedupKey string) {
+	if err := sendPagerDutyEvent(alert, "resolve", dedupKey, ""); err != nil {
+		log.Printf("Error resolving PagerDuty alert: %v", err)
+	}
+}
+
+// How long the gateway can be disconnected before someone is paged.
+func gatewayDownAlertAfter() time.Duration {
+	if v := os.Getenv("GATEWAY_DOWN_ALERT_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Ignoring malformed GATEWAY_DOWN_ALERT_AFTER %q", v)
+	}
+	return defaultGatewayDownAlertAfter
+}
+
+// How many failed interaction responses in what window page someone, from COMMAND_ERROR_ALERT, like 5/10m.
+func commandErrorAlertLimit() (int, time.Duration) {
+	if v := os.Getenv("COMMAND_ERROR_ALERT"); v != "" {
+		count, window, _ := strings.Cut(v, "/")
+		n, err := strconv.Atoi(count)
+		d, err2 := time.ParseDuration(window)
+		if err == nil && err2 == nil && n > 0 && d > 0 {
+			return n, d
+		}
+		log.Printf("Ignoring malformed COMMAND_ERROR_ALERT %q", v)
+	}
+	return defaultCommandErrorAlerts, defaultCommandErrorWindow
+}
+
+// Start the clock on paging someone about a gateway outage.
+func startGatewayDownClock(since time.Time) {
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+	if gatewayDownTimer != nil {
+		return
+	}
+	after := gatewayDownAlertAfter()
+	gatewayDownTimer = time.AfterFunc(after, func() {
+		alertsMu.Lock()
+		gatewayDownPaged = true
+		alertsMu.Unlock()
+		triggerAlert(alertGatewayDown, alertGatewayDown, fmt.Sprintf("The Discord gateway has been disconnected since %s.", since.Format(time.RFC1123)))
+	})
+}
+
+// Stop the clock on a gateway outage when the bot reconnects, and resolve the alert if it went off.
+func stopGatewayDownClock() {
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+	if gatewayDownTimer != nil {
+		gatewayDownTimer.Stop()
+		gatewayDownTimer = nil
+	}
+	if gatewayDownPaged {
+		gatewayDownPaged = false
+		go resolveAlert(alertGatewayDown, alertGatewayDown)
+	}
+}
+
+// Count a failed interaction response, and page someone when too many fail close together.
+func noteCommandError() {
+	limit, window := commandErrorAlertLimit()
+	now := time.Now()
+
+	alertsMu.Lock()
+	for len(commandErrors) > 0 && now.Sub(commandErrors[0]) >= window {
+		commandErrors = commandErrors[1:]
+	}
+	commandErrors = append(commandErrors, now)
+	page := len(commandErrors) == limit
+	alertsMu.Unlock()
+
+	if page {
+		go triggerAlert(alertCommandErrors, fmt.Sprintf("%s-%d", alertCommandErrors, now.Unix()),
+			fmt.Sprintf("%d interaction responses failed in the last %s.", limit, window))
+	}
+}
+
+// Check for escalations nobody has taken within the response time.
+func startSLAChecks(s *discordgo.Session) {
+	go func() {
+		for range time.Tick(slaCheckInterval) {
+			if !paused("escalation checks") {
+				checkEscalationSLAs(s)
+			}
+		}
+	}()
+}
+
+// Page someone about each escalation that's waited longer than the response time, and tell staff in the internal
+// channel, pinging whoever's on point for the project. Each escalation only pages once.
+func checkEscalationSLAs(s *discordgo.Session) {
+	var breached []incident
+	limit := escalationResponseTime()
+	now := time.Now()
+	err := updateStore(func(d *storeData) {
+		for _, inc := range d.Incidents {
+			if inc.ProjectID != "" && inc.AcknowledgedBy == "" && inc.ClosedAt.IsZero() && !inc.SLABreached &&
+				now.Sub(inc.OpenedAt) > limit {
+				inc.SLABreached = true
+				breached = append(breached, *inc)
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving escalations: %v", err)
+		return
+	}
+	for _, inc := range breached {
+		triggerAlert(alertSLABreach, fmt.Sprintf("escalation-%d", inc.ID),
+			fmt.Sprintf("Escalation #%d has waited over %s without a response: %s", inc.ID, formatWait(limit), inc.Title))
+
+		content := fmt.Sprintf("Escalation #%d in <#%s> has waited over %s without a response.", inc.ID, inc.ProjectID, formatWait(limit))
+		allowed := &discordgo.MessageAllowedMentions{}
+		if userID, ok := onPoint(inc.ProjectID); ok {
+			content += fmt.Sprintf(" <@%s> is on point.", userID)
+			allowed.Users = []string{userID}
+		}
+		_, err := s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{Content: content, AllowedMentions: allowed})
+		if err != nil {
+			recordFailure("sending escalation SLA notice", err)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The OAuth2 scopes the bot is installed with: itself, and its slash commands.
+const botScopes = "bot applications.commands"
+
+// A feature of the bot and the permissions it needs in the guild for it, along with the commands it's used through,
+// if any. Features that set permission overwrites also need every permission they grant, since the bot can only grant
+// permissions it has itself.
+type permissionRequirement struct {
+	feature     string
+	commands    []string
+	permissions int64
+}
+
+// Every feature's permissions, which the bot's invite link and permission audits are computed from, and which is
+// served as JSON at /api/permissions. Add to it when a feature starts using a new part of the Discord API.
+var permissionManifest = []permissionRequirement{
+	{
+		feature: "Posting and reading messages in project channels",
+		permissions: discordgo.PermissionViewChannel | discordgo.PermissionSendMessages | discordgo.PermissionEmbedLinks |
+			discordgo.PermissionReadMessageHistory,
+	},
+	{
+		feature:     "Relaying files from bridged platforms, email and shared messages",
+		commands:    []string{"bridge", "email-address", "files", "Share to internal", "Share to client"},
+		permissions: discordgo.PermissionAttachFiles,
+	},
+	{
+		feature:     "Creating, renaming and moving project channels",
+		commands:    []string{"make-channel", "make-workspace", "internal-channel", "rename-project", "status", "rotation", "client", "project-template", "delete-project", "restore-project", "work-with-us"},
+		permissions: discordgo.PermissionManageChannels | discordgo.PermissionManageRoles,
+	},
+	{
+		feature:     "Adding and removing project members",
+		commands:    []string{"add-member", "remove-member", "merge-projects", "find-project", "undo"},
+		permissions: discordgo.PermissionManageRoles,
+	},
+	{
+		feature:     "Granting and removing roles",
+		commands:    []string{"quarantine", "unquarantine", "role-menu", "reaction-role", "undo", "transfer-creator"},
+		permissions: discordgo.PermissionManageRoles,
+	},
+	{
+		feature:     "Reaction roles",
+		commands:    []string{"reaction-role"},
+		permissions: discordgo.PermissionAddReactions | discordgo.PermissionManageMessages,
+	},
+	{
+		feature:     "Pinning and cleaning up messages",
+		commands:    []string{"pin", "unpin", "pins", "huddle-button", "kickoff", "merge-projects", "project-template", "role-menu", "access-button"},
+		permissions: discordgo.PermissionManageMessages,
+	},
+	{
+		feature:     "Client nicknames",
+		commands:    []string{"nicknames"},
+		permissions: discordgo.PermissionManageNicknames,
+	},
+	{
+		feature:     "Custom emoji",
+		commands:    []string{"emoji"},
+		permissions: discordgo.PermissionManageEmojis,
+	},
+	{
+		feature:  "Huddles",
+		commands: []string{"huddle-button"},
+		permissions: discordgo.PermissionManageChannels | discordgo.PermissionManageRoles | discordgo.PermissionVoiceConnect |
+			discordgo.PermissionVoiceSpeak | discordgo.PermissionVoiceStreamVideo,
+	},
+	{
+		feature:     "Incident channels",
+		commands:    []string{"incident", "escalate"},
+		permissions: discordgo.PermissionManageChannels | discordgo.PermissionManageRoles,
+	},
+	{
+		feature:     "Scheduling calls and kickoffs",
+		commands:    []string{"book", "kickoff"},
+		permissions: discordgo.PermissionManageEvents,
+	},
+	{
+		// Starting a stage event opens the stage, and bringing someone up to speak un-suppresses their voice state,
+		// both of which need the stage moderator permissions.
+		feature:  "Town halls",
+		commands: []string{"townhall"},
+		permissions: discordgo.PermissionManageEvents | discordgo.PermissionManageChannels |
+			discordgo.PermissionVoiceMuteMembers | discordgo.PermissionVoiceMoveMembers,
+	},
+	{
+		feature:     "Email threads",
+		commands:    []string{"email-address"},
+		permissions: discordgo.PermissionCreatePublicThreads | discordgo.PermissionSendMessagesInThreads,
+	},
+}
+
+// The names Discord shows for permissions, in the order it lists them.
+var permissionNames = []struct {
+	permission int64
+	name       string
+}{
+	{discordgo.PermissionAdministrator, "Administrator"},
+	{discordgo.PermissionViewChannel, "View Channels"},
+	{discordgo.PermissionManageChannels, "Manage Channels"},
+	{discordgo.PermissionManageRoles, "Manage Roles"},
+	{discordgo.PermissionManageEmojis, "Manage Expressions"},
+	{discordgo.PermissionViewAuditLogs, "View Audit Log"},
+	{discordgo.PermissionViewGuildInsights, "View Server Insights"},
+	{discordgo.PermissionManageWebhooks, "Manage Webhooks"},
+	{discordgo.PermissionManageServer, "Manage Server"},
+	{discordgo.PermissionCreateInstantInvite, "Create Invite"},
+	{discordgo.PermissionChangeNickname, "Change Nickname"},
+	{discordgo.PermissionManageNicknames, "Manage Nicknames"},
+	{discordgo.PermissionKickMembers, "Kick Members"},
+	{discordgo.PermissionBanMembers, "Ban Members"},
+	{discordgo.PermissionModerateMembers, "Timeout Members"},
+	{discordgo.PermissionSendMessages, "Send Messages"},
+	{discordgo.PermissionSendMessagesInThreads, "Send Messages in Threads"},
+	{discordgo.PermissionCreatePublicThreads, "Create Public Threads"},
+	{discordgo.PermissionCreatePrivateThreads, "Create Private Threads"},
+	{discordgo.PermissionEmbedLinks, "Embed Links"},
+	{discordgo.PermissionAttachFiles, "Attach Files"},
+	{discordgo.PermissionAddReactions, "Add Reactions"},
+	{discordgo.PermissionUseExternalEmojis, "Use External Emoji"},
+	{discordgo.PermissionUseExternalStickers, "Use External Stickers"},
+	{discordgo.PermissionMentionEveryone, "Mention @everyone"},
+	{discordgo.PermissionManageMessages, "Manage Messages"},
+	{discordgo.PermissionManageThreads, "Manage Threads"},
+	{discordgo.PermissionReadMessageHistory, "Read Message History"},
+	{discordgo.PermissionSendTTSMessages, "Send Text-to-Speech Messages"},
+	{discordgo.PermissionUseSlashCommands, "Use Application Commands"},
+	{discordgo.PermissionVoiceConnect, "Connect"},
+	{discordgo.PermissionVoiceSpeak, "Speak"},
+	{discordgo.PermissionVoiceStreamVideo, "Video"},
+	{discordgo.PermissionUseActivities, "Use Activities"},
+	{discordgo.PermissionVoiceUseVAD, "Use Voice Activity"},
+	{discordgo.PermissionVoicePrioritySpeaker, "Priority Speaker"},
+	{discordgo.PermissionVoiceMuteMembers, "Mute Members"},
+	{discordgo.PermissionVoiceDeafenMembers, "Deafen Members"},
+	{discordgo.PermissionVoiceMoveMembers, "Move Members"},
+	{discordgo.PermissionVoiceRequestToSpeak, "Request to Speak"},
+	{discordgo.PermissionManageEvents, "Manage Events"},
+}
+
+// Every permission the bot's features need.
+func requiredPermissions() int64 {
+	var permissions int64
+	for _, r := range permissionManifest {
+		permissions |= r.permissions
+	}
+	return permissions
+}
+
+// The names of a set of permissions. Permissions without a name are named by their bit.
+func describePermissions(permissions int64) []string {
+	var names []string
+	for _, p := range permissionNames {
+		if permissions&p.permission != 0 {
+			names = append(names, p.name)
+			permissions &^= p.permission
+		}
+	}
+	for bit := 0; bit < 64; bit++ {
+		if permissions&(1<<bit) != 0 {
+			names = append(names, fmt.Sprintf("permission 1<<%d", bit))
+		}
+	}
+	return names
+}
+
+// A link to add the bot to a guild, or re-authorize it, with the permissions it needs. With a guild ID, the guild is
+// picked for whoever follows it.
+func botInviteURL(s *discordgo.Session, guildID string) string {
+	u := fmt.Sprintf("https://discord.com/oauth2/authorize?client_id=%s&scope=%s&permissions=%d",
+		s.State.User.ID, strings.ReplaceAll(botScopes, " ", "+"), requiredPermissions())
+	if guildID != "" {
+		u += "&guild_id=" + guildID + "&disable_guild_select=true"
+	}
+	return u
+}
+
+// Post an invite link for the bot with exactly the scopes and permissions its features need, for installing it in a
+// new guild or granting permissions a new feature needs.
+func inviteBotCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	guildID := ""
+	for _, o := range i.ApplicationCommandData().Options {
+		if o.Name == "guild" {
+			guildID = strings.TrimSpace(o.StringValue())
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: inviteBotContent(s, guildID),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// The response to /invite-bot: the link, and what it asks for.
+func inviteBotContent(s *discordgo.Session, guildID string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Invite the bot](%s) with the `%s` scopes and these permissions:\n", botInviteURL(s, guildID), botScopes)
+	for _, name := range describePermissions(requiredPermissions()) {
+		fmt.Fprintf(&b, "- %s\n", name)
+	}
+	b.WriteString("Move the bot's role above any roles it has to grant, since Discord only lets it manage roles below its own.")
+	return b.String()
+}
+
+// Whether Discord refused a request because the bot is missing a permission.
+func isMissingPermissions(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeMissingPermissions
+}
+
+// Explain an error from managing a channel, like "Error creating channel: ...". When the bot is missing a permission,
+// say which one it needs where, and how to give it, instead of Discord's bare error.
+func channelErrorContent(s *discordgo.Session, action string, err error, permission, where string) string {
+	log.Printf("Error %s: %v", action, err)
+	if !isMissingPermissions(err) {
+		return "Error " + action + ": " + describeError(err)
+	}
+	return fmt.Sprintf("Error %s: I'm missing the **%s** permission in %s. Give the bot's role that permission there, "+
+		"along with any permission I'm granting members, since I can only grant permissions I have myself. If the "+
+		"bot's role is missing them server-wide, [re-invite the bot](%s) to grant them.", action, permission, where,
+		botInviteURL(s, JuiceworksGuildId))
+}
+
+// Where a channel is being created, for explaining errors: its category, or the server.
+func channelLocation(parentID string) string {
+	if parentID == "" {
+		return "the server"
+	}
+	return fmt.Sprintf("the <#%s> category", parentID)
+}
+
+// What the bot has compared to what its features need in a guild. Only guild-wide permissions from roles are counted,
+// not channel overwrites.
+type permissionAudit struct {
+	granted int64
+	missing int64
+	unused  int64
+}
+
+// Compare the permissions the bot's roles grant it in a guild against the manifest. Administrator grants everything,
+// so nothing is missing, but it's reported as unused since nothing needs it.
+func auditBotPermissions(s *discordgo.Session, guildID string) (permissionAudit, error) {
+	member, err := s.GuildMember(guildID, s.State.User.ID)
+	if err != nil {
+		return permissionAudit{}, err
+	}
+	roles, err := s.GuildRoles(guildID)
+	if err != nil {
+		return permissionAudit{}, err
+	}
+	var a permissionAudit
+	for _, r := range roles {
+		// The @everyone role has the guild's ID.
+		if r.ID == guildID || slices.Contains(member.Roles, r.ID) {
+			a.granted |= r.Permissions
+		}
+	}
+	required := requiredPermissions()
+	a.unused = a.granted &^ required
+	if a.granted&discordgo.PermissionAdministrator == 0 {
+		a.missing = required &^ a.granted
+	}
+	return a, nil
+}
+
+// Check the bot's permissions in each guild it registers commands in when it starts, logging what's missing or
+// granted without being needed.
+func checkBotPermissions(s *discordgo.Session) {
+	for _, guildID := range commandGuilds() {
+		a, err := auditBotPermissions(s, guildID)
+		if err != nil {
+			recordFailure("checking the bot's permissions in guild "+guildID, err)
+			continue
+		}
+		if a.missing != 0 {
+			log.Printf("The bot is missing permissions in guild %s: %s. Run /permissions-audit for what needs them.",
+				guildID, strings.Join(describePermissions(a.missing), ", "))
+		}
+		if a.unused != 0 {
+			log.Printf("The bot has permissions in guild %s that nothing needs: %s.", guildID, strings.Join(describePermissions(a.unused), ", "))
+		}
+	}
+}
+
+// Report which permissions the bot is missing in this guild and what needs them, and which it has without needing.
+func permissionsAuditCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var content string
+	if a, err := auditBotPermissions(s, i.GuildID); err != nil {
+		log.Printf("Error auditing permissions: %v", err)
+		content = "Error auditing permissions: " + describeError(err)
+	} else {
+		content = permissionsAuditContent(s, i.GuildID, a)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: truncate(content, 2000),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// The response to /permissions-audit.
+func permissionsAuditContent(s *discordgo.Session, guildID string, a permissionAudit) string {
+	var b strings.Builder
+	if a.missing == 0 {
+		b.WriteString("The bot has every permission its features need.\n")
+	} else {
+		fmt.Fprintf(&b, "**Missing:** %s\n", strings.Join(describePermissions(a.missing), ", "))
+		for _, r := range permissionManifest {
+			if r.permissions&a.missing == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "- %s needs %s", r.feature, strings.Join(describePermissions(r.permissions&a.missing), ", "))
+			if len(r.commands) > 0 {
+				fmt.Fprintf(&b, " (/%s)", strings.Join(r.commands, ", /"))
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[Re-invite the bot](%s) to grant them.\n", botInviteURL(s, guildID))
+	}
+	if a.unused != 0 {
+		fmt.Fprintf(&b, "**Granted but unused:** %s. Remove them from the bot's role to keep it to what it needs.\n",
+			strings.Join(describePermissions(a.unused), ", "))
+	}
+	b.WriteString("Channel permission overwrites aren't checked, only what the bot's roles grant across the server.")
+	return b.String()
+}
+
+// Serve the permission manifest as JSON, for checking installs and reviewing what the bot asks for, along with the
+// gateway intents the features that are on ask for.
+func apiPermissions(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	type feature struct {
+		Feature     string   `json:"feature"`
+		Commands    []string `json:"commands,omitempty"`
+		Permissions []string `json:"permissions"`
+		Bits        string   `json:"bits"`
+	}
+	type gatewayIntent struct {
+		Feature string   `json:"feature"`
+		Name    string   `json:"name,omitempty"`
+		Enabled bool     `json:"enabled"`
+		Intents []string `json:"intents"`
+	}
+	manifest := struct {
+		Scopes          []string        `json:"scopes"`
+		Permissions     []string        `json:"permissions"`
+		Bits            string          `json:"bits"`
+		Features        []feature       `json:"features"`
+		Intents         []string        `json:"intents"`
+		GatewayFeatures []gatewayIntent `json:"gatewayFeatures"`
+	}{
+		Scopes:      strings.Fields(botScopes),
+		Permissions: describePermissions(requiredPermissions()),
+		Bits:        strconv.FormatInt(requiredPermissions(), 10),
+		Intents:     describeIntents(gatewayIntents()),
+	}
+	for _, r := range permissionManifest {
+		manifest.Features = append(manifest.Features, feature{
+			Feature:     r.feature,
+			Commands:    r.commands,
+			Permissions: describePermissions(r.permissions),
+			Bits:        strconv.FormatInt(r.permissions, 10),
+		})
+	}
+	for _, f := range gatewayFeatures {
+		manifest.GatewayFeatures = append(manifest.GatewayFeatures, gatewayIntent{
+			Feature: f.description,
+			Name:    f.name,
+			Enabled: f.enabled(),
+			Intents: describeIntents(f.intents),
+		})
+	}
+	writeJSON(w, http.StatusOK, manifest)
+}
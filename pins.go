@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The label of pins that weren't given one.
+const defaultPinLabel = "other"
+
+// A message pinned with /pin, recorded so /pins can list it under its label.
+type pinnedMessage struct {
+	ChannelID string `json:"channelId"`
+	MessageID string `json:"messageId"`
+	Label     string `json:"label"`
+	// The start of the message, so the list makes sense without opening every link.
+	Preview  string    `json:"preview"`
+	PinnedBy string    `json:"pinnedBy"`
+	PinnedAt time.Time `json:"pinnedAt"`
+}
+
+// Pin a message in the project's workspace and record it under a label.
+func pinCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range i.ApplicationCommandData().Options {
+		options[o.Name] = o
+	}
+	label := defaultPinLabel
+	if o, ok := options["label"]; ok {
+		label = strings.ToLower(strings.TrimSpace(o.StringValue()))
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: pinMessage(s, i.ChannelID, options["message"], label, i.Member.User.ID),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Pin a message and record it on the project, returning the response to show the caller. Pinning a message that's
+// already recorded changes its label.
+func pinMessage(s *discordgo.Session, channelID string, o *discordgo.ApplicationCommandInteractionDataOption, label, userID string) string {
+	projectID := workspaceProjectID(channelID)
+	if _, ok := getProject(projectID); !ok {
+		return "This channel is not a registered project."
+	}
+	channelID, messageID := messageOption(channelID, o)
+	if !slices.Contains(workspaceChannels(projectID), channelID) {
+		return "That message isn't in this project."
+	}
+
+	m, err := s.ChannelMessage(channelID, messageID)
+	if err != nil {
+		log.Printf("Error fetching message: %v", err)
+		return "Error fetching message: " + describeError(err)
+	}
+	if !m.Pinned {
+		if err := s.ChannelMessagePin(channelID, messageID); err != nil {
+			log.Printf("Error pinning message: %v", err)
+			return "Error pinning message: " + describeError(err)
+		}
+	}
+
+	preview := m.Content
+	if preview == "" && len(m.Attachments) > 0 {
+		preview = m.Attachments[0].Filename
+	}
+	pin := pinnedMessage{
+		ChannelID: channelID,
+		MessageID: messageID,
+		Label:     label,
+		Preview:   truncate(strings.ReplaceAll(preview, "\n", " "), 80),
+		PinnedBy:  userID,
+		PinnedAt:  time.Now(),
+	}
+	err = updateProject(projectID, func(p *project) {
+		p.Pins = slices.DeleteFunc(p.Pins, func(existing pinnedMessage) bool { return existing.MessageID == messageID })
+		p.Pins = append(p.Pins, pin)
+	})
+	if err != nil {
+		log.Printf("Error saving pin: %v", err)
+		return "Error saving pin: " + describeError(err)
+	}
+
+	log.Printf("Pinned message %s in channel %s as %s.", messageID, channelID, label)
+	return fmt.Sprintf("Pinned the message under **%s**.", label)
+}
+
+// Unpin a message and forget its label.
+func unpinCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	projectID := workspaceProjectID(i.ChannelID)
+	channelID, messageID := messageOption(i.ChannelID, i.ApplicationCommandData().Options[0])
+
+	var content string
+	if _, ok := getProject(projectID); !ok {
+		content = "This channel is not a registered project."
+	} else if !slices.Contains(workspaceChannels(projectID), channelID) {
+		content = "That message isn't in this project."
+	} else if err := s.ChannelMessageUnpin(channelID, messageID); err != nil {
+		log.Printf("Error unpinning message: %v", err)
+		content = "Error unpinning message: " + describeError(err)
+	} else {
+		content = "Unpinned the message."
+		err := updateProject(projectID, func(p *project) {
+			p.Pins = slices.DeleteFunc(p.Pins, func(pin pinnedMessage) bool { return pin.MessageID == messageID })
+		})
+		if err != nil {
+			log.Printf("Error saving pins: %v", err)
+			content = "Unpinned the message, but couldn't remove it from the project's pins: " + describeError(err)
+		}
+		log.Printf("Unpinned message %s in channel %s.", messageID, channelID)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// List the project's pins grouped by label, optionally only one label's.
+func pinsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	p, ok := getProject(workspaceProjectID(i.ChannelID))
+	only := ""
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		only = strings.ToLower(strings.TrimSpace(options[0].StringValue()))
+	}
+
+	var content string
+	switch {
+	case !ok:
+		content = "This channel is not a registered project."
+	case len(p.Pins) == 0:
+		content = "Nothing has been pinned with /pin in this project yet."
+	default:
+		byLabel := make(map[string][]string)
+		for _, pin := range p.Pins {
+			if only != "" && pin.Label != only {
+				continue
+			}
+			byLabel[pin.Label] = append(byLabel[pin.Label], fmt.Sprintf("- https://discord.com/channels/%s/%s/%s %s",
+				JuiceworksGuildId, pin.ChannelID, pin.MessageID, pin.Preview))
+		}
+		labels := make([]string, 0, len(byLabel))
+		for label := range byLabel {
+			labels = append(labels, label)
+		}
+		slices.Sort(labels)
+		content = fmt.Sprintf("Nothing is pinned under %s.", only)
+		if len(labels) > 0 {
+			var sections []string
+			for _, label := range labels {
+				sections = append(sections, "**"+label+"**\n"+strings.Join(byLabel[label], "\n"))
+			}
+			content = strings.Join(sections, "\n")
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: truncate(content, 2000),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A project channel created by the bot.
+type project struct {
+	ChannelID string    `json:"channelId"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy"`
+	// Usernames of members added to the project, keyed by user ID.
+	Members map[string]string `json:"members,omitempty"`
+	// The client lead, who holds the project's Project Creator designation. The first client added becomes it.
+	CreatorID string `json:"creatorId,omitempty"`
+	// The linked HubSpot deal, if any.
+	DealID string `json:"dealId,omitempty"`
+	// Keys of the linked Figma files.
+	FigmaFiles []string `json:"figmaFiles,omitempty"`
+	// Billing milestones, in the order they were added.
+	Milestones []milestone `json:"milestones,omitempty"`
+	// Contractors assigned to the project.
+	Assignments []assignment `json:"assignments,omitempty"`
+	// Members' notification levels, keyed by user ID. Members using the default aren't listed.
+	Notifications map[string]string `json:"notifications,omitempty"`
+	// The staff rotation deciding who's on point, if any.
+	Rotation *rotation `json:"rotation,omitempty"`
+	// The key of the client in the directory the project is for, if any.
+	Client string `json:"client,omitempty"`
+	// The client's name, used to prefix client nicknames.
+	ClientName string `json:"clientName,omitempty"`
+	// Whether client nicknames are prefixed or enforced. Empty means off.
+	NicknameMode string `json:"nicknameMode,omitempty"`
+	// Nicknames clients had before the bot prefixed them, keyed by user ID.
+	Nicknames map[string]string `json:"nicknames,omitempty"`
+	// Voice channels whose calls count towards the project.
+	VoiceChannels []string `json:"voiceChannels,omitempty"`
+	// Time people spent in the project's voice channels, oldest first.
+	Calls []callSession `json:"calls,omitempty"`
+	// Attachments archived to object storage, oldest first.
+	Files []archivedFile `json:"files,omitempty"`
+	// Deliverables handed to the client, oldest first.
+	Deliverables []deliverable `json:"deliverables,omitempty"`
+	// The contract sent to the client for signing, if any.
+	Contract *contract `json:"contract,omitempty"`
+	// The category the project's workspace is in, if it has one.
+	CategoryID string `json:"categoryId,omitempty"`
+	// The workspace's other channels, which share the project's membership.
+	Workspace []string `json:"workspace,omitempty"`
+	// The project's internal channel, which only Juiceworks members can see, if it has one.
+	InternalChannelID string `json:"internalChannelId,omitempty"`
+	// Messages shared between the project's channel and its internal channel, oldest first.
+	CrossPosts []crossPost `json:"crossPosts,omitempty"`
+	// Set while the project is in the trash, waiting to be deleted for good.
+	Trash *trashedProject `json:"trash,omitempty"`
+	// The channel topic from the project's template, kept so renaming the project can update it.
+	TopicTemplate string `json:"topicTemplate,omitempty"`
+	// The template the project was set up from, and the roles it shared the channel with, so the channel can be
+	// brought up to date when the template's roles change.
+	Template        string   `json:"template,omitempty"`
+	TemplateRoleIDs []string `json:"templateRoleIds,omitempty"`
+	// Where the project is in its workflow, and since when. Empty means active.
+	Status          string    `json:"status,omitempty"`
+	StatusChangedAt time.Time `json:"statusChangedAt,omitempty"`
+	// Whether staff have been asked to archive the project since it was done.
+	ArchivePrompted bool `json:"archivePrompted,omitempty"`
+	// The project's kickoff call, once it's scheduled.
+	Kickoff *kickoff `json:"kickoff,omitempty"`
+	// Messages pinned with /pin, oldest first.
+	Pins []pinnedMessage `json:"pins,omitempty"`
+	// Credentials for the project's integrations, encrypted with the KMS key in SECRETS_KMS_KEY_ID, keyed by name.
+	Secrets map[string]string `json:"secrets,omitempty"`
+	// The budget for the project's call time, and the hourly rate staff who aren't assigned to it are billed at, in
+	// cents. Zero means it has none.
+	Budget     int64 `json:"budget,omitempty"`
+	HourlyRate int64 `json:"hourlyRate,omitempty"`
+	// The highest percentage of the budget staff have been alerted about.
+	BudgetAlerted int `json:"budgetAlerted,omitempty"`
+	// The random local part of the project's inbound email address, set the first time someone asks for it.
+	EmailToken string `json:"emailToken,omitempty"`
+}
+
+// Look up a project by channel ID. The returned copy is safe to use without holding the store lock.
+func getProject(channelID string) (project, bool) {
+	var p project
+	var ok bool
+	readStore(func(d *storeData) {
+		var stored *project
+		if stored, ok = d.Projects[channelID]; ok {
+			p = copyProject(stored)
+		}
+	})
+	return p, ok
+}
+
+// Create or modify a project in the registry, then notify anything mirroring the registry.
+func updateProject(channelID string, f func(p *project)) error {
+	err := updateStore(func(d *storeData) {
+		if d.Projects == nil {
+			d.Projects = make(map[string]*project)
+		}
+		p, ok := d.Projects[channelID]
+		if !ok {
+			p = &project{ChannelID: channelID}
+			d.Projects[channelID] = p
+		}
+		f(p)
+	})
+	if err != nil {
+		return err
+	}
+
+	go projectChanged(channelID)
+	return nil
+}
+
+// Deep copy a project so it can be used outside the store lock.
+func copyProject(p *project) project {
+	c := *p
+	c.Members = make(map[string]string, len(p.Members))
+	for id, name := range p.Members {
+		c.Members[id] = name
+	}
+	c.FigmaFiles = slices.Clone(p.FigmaFiles)
+	c.Milestones = slices.Clone(p.Milestones)
+	c.Assignments = slices.Clone(p.Assignments)
+	c.Notifications = maps.Clone(p.Notifications)
+	c.Nicknames = maps.Clone(p.Nicknames)
+	c.VoiceChannels = slices.Clone(p.VoiceChannels)
+	c.Calls = slices.Clone(p.Calls)
+	c.Files = slices.Clone(p.Files)
+	c.Deliverables = slices.Clone(p.Deliverables)
+	c.Workspace = slices.Clone(p.Workspace)
+	c.Pins = slices.Clone(p.Pins)
+	c.Secrets = maps.Clone(p.Secrets)
+	c.TemplateRoleIDs = slices.Clone(p.TemplateRoleIDs)
+	c.CrossPosts = slices.Clone(p.CrossPosts)
+	if p.Trash != nil {
+		t := *p.Trash
+		c.Trash = &t
+	}
+	if p.Rotation != nil {
+		r := *p.Rotation
+		r.UserIDs = slices.Clone(r.UserIDs)
+		c.Rotation = &r
+	}
+	if p.Contract != nil {
+		k := *p.Contract
+		c.Contract = &k
+	}
+	if p.Kickoff != nil {
+		k := *p.Kickoff
+		k.Attendees = slices.Clone(k.Attendees)
+		c.Kickoff = &k
+	}
+	return c
+}
+
+// Locks held while a project is pushed to the systems mirroring the registry, keyed by channel ID.
+var (
+	projectSyncMu    sync.Mutex
+	projectSyncLocks = make(map[string]*sync.Mutex)
+)
+
+// Wait for any other sync of a project to finish, so syncs of the same project run one at a time. Returns the
+// function that lets the next one start.
+func lockProjectSync(channelID string) func() {
+	projectSyncMu.Lock()
+	l, ok := projectSyncLocks[channelID]
+	if !ok {
+		l = &sync.Mutex{}
+		projectSyncLocks[channelID] = l
+	}
+	projectSyncMu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// Push a changed project to every system mirroring the registry. Changes made in quick succession each start a sync,
+// so they take turns, and each pushes the project as it is when its turn comes rather than as it was when it changed.
+// That way an older copy never lands after a newer one. Failures are recorded for staff and as jobs to retry. Returns
+// whether every system took the project.
+func projectChanged(channelID string) bool {
+	defer lockProjectSync(channelID)()
+	p, ok := getProject(channelID)
+	if !ok {
+		// The project's gone, so there's nothing left to sync.
+		return true
+	}
+	synced := true
+	if err := syncProjectToAirtable(&p); err != nil {
+		recordFailure(fmt.Sprintf("syncing project %s to Airtable", p.ChannelID), err)
+		recordFailedJob(jobAirtableSync, fmt.Sprintf("Sync <#%s> to Airtable", p.ChannelID), p.ChannelID, err)
+		synced = false
+	}
+	if err := syncProjectToCalendar(&p); err != nil {
+		recordFailure(fmt.Sprintf("syncing project %s to Google Calendar", p.ChannelID), err)
+		recordFailedJob(jobCalendarSync, fmt.Sprintf("Sync <#%s> to Google Calendar", p.ChannelID), p.ChannelID, err)
+		synced = false
+	}
+	return synced
+}
+
+// Show the registry entry for the project the command was called from.
+func projectInfo(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	p, ok := getProject(i.ChannelID)
+	if !ok {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "This channel is not a registered project.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	// Linked integrations can be slow to read, so defer the response.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+
+	members := make([]string, 0, len(p.Members))
+	for id := range p.Members {
+		members = append(members, "<@"+id+">")
+	}
+	slices.Sort(members)
+	if len(members) == 0 {
+		members = append(members, "None")
+	}
+
+	embed := themed(i.GuildID, &discordgo.MessageEmbed{
+		Title: "#" + p.Name,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Status", Value: projectStatus(p)},
+			{Name: "Members", Value: truncate(strings.Join(members, ", "), 1024)},
+		},
+	})
+	if !p.CreatedAt.IsZero() {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Created",
+			Value: fmt.Sprintf("<t:%d:D> by <@%s>", p.CreatedAt.Unix(), p.CreatedBy),
+		})
+	}
+	if p.CreatorID != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Client lead", Value: "<@" + p.CreatorID + ">"})
+	}
+	if len(p.Assignments) > 0 {
+		team := make([]string, len(p.Assignments))
+		for n, a := range p.Assignments {
+			team[n] = fmt.Sprintf("<@%s>: %s", a.UserID, a.Role)
+			if canSeeRates(i) {
+				team[n] += fmt.Sprintf(" (%s/h)", formatAmount(a.HourlyRate))
+			}
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Team", Value: truncate(strings.Join(team, "\n"), 1024)})
+	}
+	if p.Budget > 0 {
+		// What the budget's been spent on comes from rates, so only administrators see the amounts.
+		burn := fmt.Sprintf("%d%% used", burnPercent(p, time.Now()))
+		if canSeeRates(i) {
+			burn = describeBurn(p, time.Now())
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Budget", Value: burn})
+	}
+	if len(p.Milestones) > 0 {
+		total, _, outstanding := milestoneTotals(p.Milestones)
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Billing",
+			Value: fmt.Sprintf("Total: %s\nOutstanding: %s", formatAmount(total), formatAmount(outstanding)),
+		})
+	}
+	if p.DealID != "" {
+		value := "Deal " + p.DealID
+		if d, err := hubspotDeal(p.ChannelID, p.DealID); err != nil {
+			log.Printf("Error reading HubSpot deal: %v", err)
+		} else {
+			value = fmt.Sprintf("%s\nValue: %s\nStage: %s", d.name, d.amount, d.stage)
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Deal", Value: value})
+	}
+	if p.Contract != nil {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Contract",
+			Value: fmt.Sprintf("%s, sent to %s <t:%d:R>", p.Contract.Status, p.Contract.Email, p.Contract.SentAt.Unix()),
+		})
+	}
+	health := scoreProject(s, p, time.Now())
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Health", Value: truncate(health.String(), 1024)})
+
+	// Discord allows up to 10 embeds per message.
+	embeds := append([]*discordgo.MessageEmbed{embed}, figmaEmbeds(p.ChannelID, p.FigmaFiles)...)
+	if len(embeds) > 10 {
+		embeds = embeds[:10]
+	}
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &embeds})
+	logResponseErr(err)
+}
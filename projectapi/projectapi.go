@@ -0,0 +1,47 @@
+// Package projectapi has the shape of projects in the bot's API, shared by the bot and the tools that call it, like
+// juicectl. The fields use the same JSON names as the bot's data file, so tools can read either.
+package projectapi
+
+import "time"
+
+// A registered project, as GET /api/projects lists it.
+type Project struct {
+	ChannelID string    `json:"channelId"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy"`
+	// Usernames of members added to the project, keyed by user ID.
+	Members map[string]string `json:"members,omitempty"`
+	// The client lead, if the project has one.
+	CreatorID  string `json:"creatorId,omitempty"`
+	ClientName string `json:"clientName,omitempty"`
+	// Where the project is in its workflow. Empty means active.
+	Status string `json:"status,omitempty"`
+	// Set while the project is in the trash, waiting to be deleted for good.
+	Trash *Trash `json:"trash,omitempty"`
+}
+
+// When a project in the trash was deleted, and when it's deleted for good.
+type Trash struct {
+	DeletedAt time.Time `json:"deletedAt"`
+	DeletedBy string    `json:"deletedBy"`
+	PurgeAt   time.Time `json:"purgeAt"`
+}
+
+// The project's status, or "trashed" while it's in the trash. Projects made before statuses existed are active.
+func (p Project) State() string {
+	switch {
+	case p.Trash != nil:
+		return "trashed"
+	case p.Status == "":
+		return "active"
+	}
+	return p.Status
+}
+
+// What happened to one user in a batch add to POST /api/projects/{channel}/members, in the order they were given.
+type MemberResult struct {
+	UserID string `json:"userId"`
+	// Why the user wasn't added. Empty means they were.
+	Error string `json:"error,omitempty"`
+}
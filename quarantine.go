@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// What a quarantined user had before they were quarantined, so /unquarantine can put it back.
+type quarantine struct {
+	// The roles the user had.
+	Roles []string `json:"roles"`
+	// The user's permission overwrites in every channel belonging to a project, keyed by channel ID.
+	Overwrites    map[string]quarantinedOverwrite `json:"overwrites,omitempty"`
+	QuarantinedAt time.Time                       `json:"quarantinedAt"`
+	QuarantinedBy string                          `json:"quarantinedBy"`
+}
+
+// A permission overwrite removed from a quarantined user.
+type quarantinedOverwrite struct {
+	Allow int64 `json:"allow,string"`
+	Deny  int64 `json:"deny,string"`
+}
+
+// Find a user's permission overwrites in every channel belonging to a project: its workspace channels, its internal
+// channel, and its voice and huddle channels.
+func projectOverwrites(s *discordgo.Session, userID string) map[string]quarantinedOverwrite {
+	var channelIDs []string
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			channelIDs = append(channelIDs, p.ChannelID)
+			channelIDs = append(channelIDs, p.Workspace...)
+			channelIDs = append(channelIDs, p.VoiceChannels...)
+			if p.InternalChannelID != "" {
+				channelIDs = append(channelIDs, p.InternalChannelID)
+			}
+		}
+	})
+
+	overwrites := make(map[string]quarantinedOverwrite)
+	for _, channelID := range channelIDs {
+		channel, err := s.State.Channel(channelID)
+		if err != nil {
+			channel, err = s.Channel(channelID)
+		}
+		if err != nil {
+			log.Printf("Error reading channel %s: %v", channelID, err)
+			continue
+		}
+		for _, o := range channel.PermissionOverwrites {
+			if o.ID == userID && o.Type == discordgo.PermissionOverwriteTypeMember {
+				overwrites[channelID] = quarantinedOverwrite{Allow: o.Allow, Deny: o.Deny}
+			}
+		}
+	}
+	return overwrites
+}
+
+// Lock down a user who may be compromised: swap their roles for the role in QUARANTINE_ROLE_ID and remove them from
+// every project channel. What they had is saved first so /unquarantine can restore it.
+func quarantineUser(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := i.ApplicationCommandData().Options[0].UserValue(s)
+
+	// Reading every project channel can take a while, so defer the response.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+	content := quarantineContent(s, i.Member.User, user)
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+	logResponseErr(err)
+}
+
+// Quarantine a user, returning the response to show the caller.
+func quarantineContent(s *discordgo.Session, caller, user *discordgo.User) string {
+	roleID := os.Getenv("QUARANTINE_ROLE_ID")
+	if roleID == "" {
+		return "Quarantine isn't set up. Set QUARANTINE_ROLE_ID to the restricted role."
+	}
+	quarantined := false
+	readStore(func(d *storeData) {
+		_, quarantined = d.Quarantines[user.ID]
+	})
+	if quarantined {
+		return fmt.Sprintf("%s is already quarantined.", user.Mention())
+	}
+
+	member, err := s.GuildMember(JuiceworksGuildId, user.ID)
+	if err != nil {
+		log.Printf("Error reading member roles: %v", err)
+		return "Error reading member roles: " + describeError(err)
+	}
+	q := &quarantine{
+		Roles:         slices.Clone(member.Roles),
+		Overwrites:    projectOverwrites(s, user.ID),
+		QuarantinedAt: time.Now(),
+		QuarantinedBy: caller.ID,
+	}
+	err = updateStore(func(d *storeData) {
+		if d.Quarantines == nil {
+			d.Quarantines = make(map[string]*quarantine)
+		}
+		d.Quarantines[user.ID] = q
+	})
+	if err != nil {
+		log.Printf("Error saving quarantine snapshot: %v", err)
+		return "Error saving quarantine snapshot: " + describeError(err)
+	}
+	log.Printf("%s quarantined %s. Saved roles %v and overwrites in %d channels.", caller, user, q.Roles, len(q.Overwrites))
+
+	// Replace every role in one edit, then remove the overwrites.
+	var errs []error
+	if _, err := s.GuildMemberEdit(JuiceworksGuildId, user.ID, &discordgo.GuildMemberParams{Roles: &[]string{roleID}}); err != nil {
+		log.Printf("Error replacing roles of %s: %v", user, err)
+		errs = append(errs, err)
+	}
+	for channelID := range q.Overwrites {
+		if err := s.ChannelPermissionDelete(channelID, user.ID); err != nil {
+			log.Printf("Error removing %s from channel %s: %v", user, channelID, err)
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("Removed %s from channel %s.", user, channelID)
+	}
+
+	postAudit(s, auditEntry{
+		Action:   auditQuarantined,
+		ActorID:  caller.ID,
+		TargetID: user.ID,
+		Summary: fmt.Sprintf("%s quarantined %s, removing %d roles and access to %d project channels.",
+			caller.Mention(), user.Mention(), len(q.Roles), len(q.Overwrites)),
+	})
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Sprintf("Quarantined %s, but some changes failed: %s\nTheir previous access is saved, so /unquarantine can still restore it.", user.Mention(), err)
+	}
+	return fmt.Sprintf("Quarantined %s. They lost %d roles and access to %d project channels.", user.Mention(), len(q.Roles), len(q.Overwrites))
+}
+
+// Restore a quarantined user's roles and project channel access from the snapshot taken when they were quarantined.
+func unquarantineUser(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := i.ApplicationCommandData().Options[0].UserValue(s)
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+	content := unquarantineContent(s, i.Member.User, user)
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+	logResponseErr(err)
+}
+
+// Lift a user's quarantine, returning the response to show the caller. The snapshot is only dropped once everything
+// has been restored, so a failed restore can be retried.
+func unquarantineContent(s *discordgo.Session, caller, user *discordgo.User) string {
+	var q *quarantine
+	readStore(func(d *storeData) {
+		if stored, ok := d.Quarantines[user.ID]; ok {
+			c := *stored
+			c.Roles = slices.Clone(stored.Roles)
+			c.Overwrites = maps.Clone(stored.Overwrites)
+			q = &c
+		}
+	})
+	if q == nil {
+		return fmt.Sprintf("%s isn't quarantined.", user.Mention())
+	}
+
+	var errs []error
+	if _, err := s.GuildMemberEdit(JuiceworksGuildId, user.ID, &discordgo.GuildMemberParams{Roles: &q.Roles}); err != nil {
+		log.Printf("Error restoring roles of %s: %v", user, err)
+		errs = append(errs, err)
+	}
+	for channelID, o := range q.Overwrites {
+		if err := s.ChannelPermissionSet(channelID, user.ID, discordgo.PermissionOverwriteTypeMember, o.Allow, o.Deny); err != nil {
+			log.Printf("Error restoring %s to channel %s: %v", user, channelID, err)
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("Restored %s to channel %s.", user, channelID)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return "Error restoring access: " + describeError(err) + "\nThe snapshot is kept, so you can try again."
+	}
+
+	err := updateStore(func(d *storeData) {
+		delete(d.Quarantines, user.ID)
+	})
+	if err != nil {
+		log.Printf("Error removing quarantine snapshot: %v", err)
+	}
+	log.Printf("%s lifted the quarantine on %s.", caller, user)
+	postAudit(s, auditEntry{
+		Action:   auditUnquarantined,
+		ActorID:  caller.ID,
+		TargetID: user.ID,
+		Summary: fmt.Sprintf("%s lifted the quarantine on %s, restoring %d roles and access to %d project channels.",
+			caller.Mention(), user.Mention(), len(q.Roles), len(q.Overwrites)),
+	})
+	return fmt.Sprintf("Restored %s's roles and access to %d project channels.", user.Mention(), len(q.Overwrites))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Quarantine takes a user out of every channel of a project, not just the one it's registered under, and lifting it
+// puts them back in all of them.
+func TestQuarantineCoversWholeProject(t *testing.T) {
+	f, s := newTestBot(t)
+	t.Setenv("QUARANTINE_ROLE_ID", f.addRole("quarantined", 0))
+	staff := addStaff(f)
+	client := f.addMember("3000000000000000001", "client")
+	channelID := addProject(t, f, "acme")
+	workspace, internal, voice := f.addChannel("acme-files", ""), f.addChannel("acme-internal", ""), f.addChannel("acme-huddle", "")
+	err := updateProject(channelID, func(p *project) {
+		p.Workspace = []string{workspace}
+		p.InternalChannelID = internal
+		p.VoiceChannels = []string{voice}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels := []string{channelID, workspace, internal, voice}
+	for _, id := range channels {
+		if err := s.ChannelPermissionSet(id, client.User.ID, discordgo.PermissionOverwriteTypeMember, discordgo.PermissionViewChannel, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	quarantineContent(s, staff.User, client.User)
+	for _, id := range channels {
+		if c, _ := f.channel(id); overwrite(c, client.User.ID) != nil {
+			t.Errorf("the quarantined client can still see %s", c.Name)
+		}
+	}
+	unquarantineContent(s, staff.User, client.User)
+	for _, id := range channels {
+		if c, _ := f.channel(id); overwrite(c, client.User.ID) == nil {
+			t.Errorf("the client's access to %s wasn't restored", c.Name)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A member's standard hourly rate, in cents.
+type rateCard struct {
+	HourlyRate int64     `json:"hourlyRate"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// A contractor assigned to a project, with the role they play and the hourly rate billed for them, in cents.
+type assignment struct {
+	UserID     string `json:"userId"`
+	Role       string `json:"role"`
+	HourlyRate int64  `json:"hourlyRate"`
+}
+
+// Whether the caller of an interaction can see rates, which is only administrators.
+func canSeeRates(i *discordgo.InteractionCreate) bool {
+	return i.Member != nil && i.Member.Permissions&discordgo.PermissionAdministrator != 0
+}
+
+// Manage member rate cards. Only administrators can see or change rates.
+func rateCardCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	var content string
+	switch sub.Name {
+	case "set":
+		user := sub.Options[0].UserValue(s)
+		rate := int64(math.Round(sub.Options[1].FloatValue() * 100))
+		if rate < 0 {
+			content = "The hourly rate can't be negative."
+			break
+		}
+		err := updateStore(func(d *storeData) {
+			if d.RateCards == nil {
+				d.RateCards = make(map[string]*rateCard)
+			}
+			d.RateCards[user.ID] = &rateCard{HourlyRate: rate, UpdatedAt: time.Now()}
+		})
+		if err != nil {
+			log.Printf("Error saving rate card: %v", err)
+			content = "Error saving rate card: " + describeError(err)
+			break
+		}
+		log.Printf("Set rate card for %s to %d.", user, rate)
+		content = fmt.Sprintf("Set %s's rate to %s per hour.", user.Mention(), formatAmount(rate))
+
+	case "remove":
+		user := sub.Options[0].UserValue(s)
+		err := updateStore(func(d *storeData) {
+			delete(d.RateCards, user.ID)
+		})
+		if err != nil {
+			log.Printf("Error removing rate card: %v", err)
+			content = "Error removing rate card: " + describeError(err)
+			break
+		}
+		log.Printf("Removed rate card for %s.", user)
+		content = fmt.Sprintf("Removed %s's rate card.", user.Mention())
+
+	case "list":
+		var lines []string
+		readStore(func(d *storeData) {
+			for userID, r := range d.RateCards {
+				lines = append(lines, fmt.Sprintf("- <@%s>: %s per hour", userID, formatAmount(r.HourlyRate)))
+			}
+		})
+		slices.Sort(lines)
+		content = "No rate cards have been set."
+		if len(lines) > 0 {
+			content = truncate(strings.Join(lines, "\n"), 2000)
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Assign a contractor to the project the command was called from. The rate defaults to their rate card, and only
+// administrators can set a different one or see what it is.
+func assign(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var user *discordgo.User
+	var role string
+	rate, setRate := int64(-1), false
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "user":
+			user = o.UserValue(s)
+		case "role":
+			role = strings.TrimSpace(o.StringValue())
+		case "rate":
+			rate, setRate = int64(math.Round(o.FloatValue()*100)), true
+		}
+	}
+
+	_, ok := getProject(i.ChannelID)
+	problem := ""
+	switch {
+	case !ok:
+		problem = "This channel is not a registered project."
+	case setRate && !canSeeRates(i):
+		problem = "Only administrators can set rates."
+	case setRate && rate < 0:
+		problem = "The hourly rate can't be negative."
+	}
+	if problem != "" {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: problem,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	if !setRate {
+		readStore(func(d *storeData) {
+			if r, ok := d.RateCards[user.ID]; ok {
+				rate = r.HourlyRate
+			}
+		})
+	}
+	if rate < 0 {
+		content := fmt.Sprintf("%s has no rate card, so a rate is required.", user.Mention())
+		if !canSeeRates(i) {
+			content = fmt.Sprintf("%s has no rate card. An administrator has to set one with /rate-card first.", user.Mention())
+		}
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	err := updateProject(i.ChannelID, func(p *project) {
+		p.Assignments = slices.DeleteFunc(p.Assignments, func(a assignment) bool {
+			return a.UserID == user.ID
+		})
+		p.Assignments = append(p.Assignments, assignment{UserID: user.ID, Role: role, HourlyRate: rate})
+	})
+	if err != nil {
+		log.Printf("Error saving assignment: %v", err)
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Error saving assignment: " + describeError(err),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	log.Printf("Assigned %s to channel %s as %q.", user, i.ChannelID, role)
+	content := fmt.Sprintf("Assigned %s to this project as %s.", user.Mention(), role)
+	if canSeeRates(i) {
+		content = fmt.Sprintf("Assigned %s to this project as %s at %s per hour.", user.Mention(), role, formatAmount(rate))
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Matches a custom emoji as typed in a message, such as <:juice:123> or <a:juice:123>.
+var customEmojiPattern = regexp.MustCompile(`^<a?:(\w+):(\d+)>$`)
+
+// Matches a link to a message, such as https://discord.com/channels/<guild>/<channel>/<message>.
+var messageLinkPattern = regexp.MustCompile(`/channels/\d+/(\d+)/(\d+)$`)
+
+// A message members react to for roles, with the role granted for each emoji. Emoji are keyed by their API name:
+// the character for standard emoji, or name:id for custom ones.
+type reactionRoleMessage struct {
+	ChannelID string            `json:"channelId"`
+	Roles     map[string]string `json:"roles"`
+}
+
+// Convert an emoji typed in a command option to its API name.
+func emojiAPIName(emoji string) string {
+	emoji = strings.TrimSpace(emoji)
+	if m := customEmojiPattern.FindStringSubmatch(emoji); m != nil {
+		return m[1] + ":" + m[2]
+	}
+	return emoji
+}
+
+// Show an emoji's API name the way Discord renders it in a message.
+func formatEmoji(apiName string) string {
+	if strings.Contains(apiName, ":") {
+		return "<:" + apiName + ">"
+	}
+	return apiName
+}
+
+// Bind emoji to roles on a message. Only administrators can change them.
+func reactionRoleCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	var content string
+	switch sub.Name {
+	case "add":
+		content = addReactionRole(s, i.ChannelID, options)
+	case "remove":
+		content = removeReactionRole(s, i.ChannelID, options)
+	case "list":
+		content = listReactionRoles()
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Find the channel and message a command option refers to. It can be a message link, or the ID of a message in the
+// channel the command was called from.
+func messageOption(channelID string, o *discordgo.ApplicationCommandInteractionDataOption) (string, string) {
+	message := strings.TrimSpace(o.StringValue())
+	if m := messageLinkPattern.FindStringSubmatch(message); m != nil {
+		return m[1], m[2]
+	}
+	return channelID, message
+}
+
+// Bind an emoji to a role on a message, returning the response to show the caller. The bot reacts with the emoji
+// itself so members only have to click it.
+func addReactionRole(s *discordgo.Session, channelID string, options map[string]*discordgo.ApplicationCommandInteractionDataOption) string {
+	channelID, messageID := messageOption(channelID, options["message"])
+	emoji := emojiAPIName(options["emoji"].StringValue())
+	role := options["role"].RoleValue(s, JuiceworksGuildId)
+	if !selfAssignable(role.ID) {
+		return "That role can't be self-assigned."
+	}
+
+	// Reacting also checks that the message and emoji exist.
+	if err := s.MessageReactionAdd(channelID, messageID, emoji); err != nil {
+		log.Printf("Error adding reaction: %v", err)
+		return "Error adding reaction: " + describeError(err)
+	}
+
+	err := updateStore(func(d *storeData) {
+		if d.ReactionRoles == nil {
+			d.ReactionRoles = make(map[string]*reactionRoleMessage)
+		}
+		m, ok := d.ReactionRoles[messageID]
+		if !ok {
+			m = &reactionRoleMessage{ChannelID: channelID, Roles: make(map[string]string)}
+			d.ReactionRoles[messageID] = m
+		}
+		m.Roles[emoji] = role.ID
+	})
+	if err != nil {
+		log.Printf("Error saving reaction role: %v", err)
+		return "Error saving reaction role: " + describeError(err)
+	}
+
+	log.Printf("Bound %s to role %s on message %s.", emoji, role.ID, messageID)
+	return fmt.Sprintf("Reacting with %s on that message now grants %s.", formatEmoji(emoji), role.Mention())
+}
+
+// Unbind an emoji from a message, returning the response to show the caller.
+func removeReactionRole(s *discordgo.Session, channelID string, options map[string]*discordgo.ApplicationCommandInteractionDataOption) string {
+	channelID, messageID := messageOption(channelID, options["message"])
+	emoji := emojiAPIName(options["emoji"].StringValue())
+
+	found := false
+	err := updateStore(func(d *storeData) {
+		m, ok := d.ReactionRoles[messageID]
+		if !ok {
+			return
+		}
+		if _, found = m.Roles[emoji]; found {
+			delete(m.Roles, emoji)
+		}
+		if len(m.Roles) == 0 {
+			delete(d.ReactionRoles, messageID)
+		}
+	})
+	if err != nil {
+		log.Printf("Error removing reaction role: %v", err)
+		return "Error removing reaction role: " + describeError(err)
+	}
+	if !found {
+		return fmt.Sprintf("%s isn't bound to a role on that message.", formatEmoji(emoji))
+	}
+	if err := s.MessageReactionRemove(channelID, messageID, emoji, "@me"); err != nil {
+		log.Printf("Error removing reaction: %v", err)
+	}
+
+	log.Printf("Unbound %s on message %s.", emoji, messageID)
+	return fmt.Sprintf("Removed the %s reaction role from that message.", formatEmoji(emoji))
+}
+
+// List every reaction role binding.
+func listReactionRoles() string {
+	var lines []string
+	readStore(func(d *storeData) {
+		for messageID, m := range d.ReactionRoles {
+			for emoji, roleID := range m.Roles {
+				lines = append(lines, fmt.Sprintf("- https://discord.com/channels/%s/%s/%s %s → <@&%s>",
+					JuiceworksGuildId, m.ChannelID, messageID, formatEmoji(emoji), roleID))
+			}
+		}
+	})
+	if len(lines) == 0 {
+		return "No reaction roles have been set up."
+	}
+	slices.Sort(lines)
+	return truncate(strings.Join(lines, "\n"), 2000)
+}
+
+// Look up the role bound to a reaction, if any.
+func reactionRole(messageID string, emoji *discordgo.Emoji) (string, bool) {
+	var roleID string
+	readStore(func(d *storeData) {
+		if m, ok := d.ReactionRoles[messageID]; ok {
+			roleID = m.Roles[emoji.APIName()]
+		}
+	})
+	return roleID, roleID != ""
+}
+
+// Grant the bound role when a member reacts to a reaction role message.
+func grantReactionRole(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if r.GuildID != JuiceworksGuildId || r.UserID == s.State.User.ID {
+		return
+	}
+	roleID, ok := reactionRole(r.MessageID, &r.Emoji)
+	if !ok || !selfAssignable(roleID) {
+		return
+	}
+	if err := s.GuildMemberRoleAdd(JuiceworksGuildId, r.UserID, roleID); err != nil {
+		log.Printf("Error granting reaction role: %v", err)
+		return
+	}
+	log.Printf("Granted role %s to user %s by reaction.", roleID, r.UserID)
+}
+
+// Remove the bound role when a member takes their reaction back.
+func revokeReactionRole(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+	if r.GuildID != JuiceworksGuildId || r.UserID == s.State.User.ID {
+		return
+	}
+	roleID, ok := reactionRole(r.MessageID, &r.Emoji)
+	if !ok || !selfAssignable(roleID) {
+		return
+	}
+	if err := s.GuildMemberRoleRemove(JuiceworksGuildId, r.UserID, roleID); err != nil {
+		log.Printf("Error removing reaction role: %v", err)
+		return
+	}
+	log.Printf("Removed role %s from user %s by reaction.", roleID, r.UserID)
+}
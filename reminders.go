@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How often reminders are checked for being due.
+const reminderInterval = time.Minute
+
+// The delays people can pick from when asking to be reminded about a message, in the order they're offered.
+var reminderDelays = []struct {
+	label string
+	delay time.Duration
+}{
+	{"In 20 minutes", 20 * time.Minute},
+	{"In an hour", time.Hour},
+	{"In 3 hours", 3 * time.Hour},
+	{"Tomorrow", 24 * time.Hour},
+	{"Next week", 7 * 24 * time.Hour},
+}
+
+// A message someone asked to be reminded about.
+type reminder struct {
+	UserID    string `json:"userId"`
+	ChannelID string `json:"channelId"`
+	MessageID string `json:"messageId"`
+	AuthorID  string `json:"authorId"`
+	// The message as it was when the reminder was set, in case it's edited or deleted by the time it's due.
+	Content string    `json:"content"`
+	DueAt   time.Time `json:"dueAt"`
+}
+
+// Ask when to remind the caller about the message the context menu was opened on.
+func remindMeMessage(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	m := data.Resolved.Messages[data.TargetID]
+	options := make([]discordgo.SelectMenuOption, len(reminderDelays))
+	for n, d := range reminderDelays {
+		options[n] = discordgo.SelectMenuOption{Label: d.label, Value: d.delay.String()}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "When should I remind you about this message?",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						CustomID:    "remind-me:" + m.ChannelID + ":" + m.ID,
+						Placeholder: "Pick a time",
+						Options:     options,
+					},
+				}},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Save a reminder for the time picked from the Remind me menu.
+func setReminder(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.MessageComponentData()
+	parts := strings.SplitN(data.CustomID, ":", 3)
+	delay, _ := time.ParseDuration(data.Values[0])
+
+	var content string
+	m, err := s.ChannelMessage(parts[1], parts[2])
+	if err != nil {
+		log.Printf("Error fetching message: %v", err)
+		content = "Error fetching message: " + describeError(err)
+	} else {
+		snapshot := m.Content
+		for _, a := range m.Attachments {
+			snapshot += "\n" + a.URL
+		}
+		r := reminder{
+			UserID:    i.Member.User.ID,
+			ChannelID: m.ChannelID,
+			MessageID: m.ID,
+			AuthorID:  m.Author.ID,
+			Content:   truncate(strings.TrimSpace(snapshot), 1500),
+			DueAt:     time.Now().Add(delay),
+		}
+		err = updateStore(func(d *storeData) {
+			d.Reminders = append(d.Reminders, r)
+		})
+		if err != nil {
+			log.Printf("Error saving reminder: %v", err)
+			content = "Error saving reminder: " + describeError(err)
+		} else {
+			content = fmt.Sprintf("I'll DM you about it <t:%d:R>.", r.DueAt.Unix())
+			if open, back := openAt(r.DueAt); !open {
+				content = fmt.Sprintf("That's outside business hours, so I'll DM you about it when they start <t:%d:R>.", back.Unix())
+			}
+			log.Printf("%s set a reminder for message %s.", i.Member.User, m.ID)
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	}))
+}
+
+// Check for reminders that are due.
+func startReminders(s *discordgo.Session) {
+	go func() {
+		for range time.Tick(reminderInterval) {
+			if !paused("reminders") {
+				sendReminders(s)
+			}
+		}
+	}()
+}
+
+// DM people the messages they asked to be reminded about. Reminders that were due while the bot was down are sent as
+// soon as it's back. Reminders aren't urgent, so ones that come due outside business hours wait until they start.
+func sendReminders(s *discordgo.Session) {
+	if open, _ := openAt(time.Now()); !open {
+		return
+	}
+
+	var due []reminder
+	now := time.Now()
+	err := updateStore(func(d *storeData) {
+		d.Reminders = slices.DeleteFunc(d.Reminders, func(r reminder) bool {
+			if r.DueAt.After(now) {
+				return false
+			}
+			due = append(due, r)
+			return true
+		})
+	})
+	if err != nil {
+		log.Printf("Error saving reminders: %v", err)
+		return
+	}
+
+	for _, r := range due {
+		if err := deliverReminder(s, r); err != nil {
+			recordFailure("sending reminder", err)
+			recordFailedJob(jobReminder, fmt.Sprintf("Remind <@%s> about a message in <#%s>", r.UserID, r.ChannelID), r, err)
+		}
+	}
+}
+
+// DM someone the message they asked to be reminded about.
+func deliverReminder(s *discordgo.Session, r reminder) error {
+	dm, err := s.UserChannelCreate(r.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = s.ChannelMessageSendComplex(dm.ID, &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{themed(JuiceworksGuildId, &discordgo.MessageEmbed{
+			Title:       "Reminder",
+			URL:         fmt.Sprintf("https://discord.com/channels/%s/%s/%s", JuiceworksGuildId, r.ChannelID, r.MessageID),
+			Description: renderMessage("reminder", reminderMessage{AuthorID: r.AuthorID, ChannelID: r.ChannelID, Content: r.Content}),
+		})},
+	})
+	return err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Reminders that come due outside business hours wait until they start.
+func TestRemindersWaitForBusinessHours(t *testing.T) {
+	f, s := newTestBot(t)
+	member := f.addMember("3000000000000000001", "member")
+	// Open for a minute, a few hours from now, every day, so it's closed now.
+	opens := time.Now().UTC().Add(3 * time.Hour)
+	err := updateStore(func(d *storeData) {
+		d.BusinessHours = &businessHours{
+			Start:    opens.Format("15:04"),
+			End:      opens.Add(time.Minute).Format("15:04"),
+			Weekdays: []time.Weekday{0, 1, 2, 3, 4, 5, 6},
+			Timezone: "UTC",
+		}
+		d.Reminders = []reminder{{UserID: member.User.ID, ChannelID: "1", MessageID: "2", DueAt: time.Now()}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendReminders(s)
+	if requests, _ := f.history(); len(requests) != 0 {
+		t.Errorf("%d requests were made to Discord, want none", len(requests))
+	}
+	readStore(func(d *storeData) {
+		if len(d.Reminders) != 1 {
+			t.Errorf("%d reminders are waiting, want 1", len(d.Reminders))
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Rename the project the command was called from: its channel, the rest of its workspace, its topic and its registry
+// entry, so nothing that looks projects up by name goes stale.
+func renameProject(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	name := i.ApplicationCommandData().Options[0].StringValue()
+	name = sanitizeChannelName(name)
+	projectID := workspaceProjectID(i.ChannelID)
+	p, ok := getProject(projectID)
+
+	var content string
+	switch {
+	case !ok:
+		content = "This channel is not a registered project."
+	case utf8.RuneCountInString(name) < 2 || utf8.RuneCountInString(name) > 80:
+		content = "Project name must be between 2 and 80 characters."
+	default:
+		oldName := p.Name
+		if err := renameProjectChannels(s, p, name); err != nil {
+			log.Printf("Error renaming project: %v", err)
+			content = "Error renaming project: " + describeError(err)
+			break
+		}
+		p, _ = getProject(projectID)
+		emitEvent("project.renamed", projectRenamedEvent{ChannelID: projectID, OldName: oldName, Name: p.Name, RenamedBy: i.Member.User.ID})
+		postAudit(s, auditEntry{
+			Action:    auditRenamed,
+			ActorID:   i.Member.User.ID,
+			ChannelID: projectID,
+			Summary:   fmt.Sprintf("<@%s> renamed #%s to <#%s>.", i.Member.User.ID, oldName, projectID),
+		})
+		log.Printf("Renamed project %s from %s to %s.", projectID, oldName, p.Name)
+		content = fmt.Sprintf("Renamed #%s to #%s.", oldName, p.Name)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Rename a project's channels and update the registry. A workspace's category takes the new name, and its channels
+// keep their suffixes; a single channel takes the new name as it is.
+func renameProjectChannels(s *discordgo.Session, p project, name string) error {
+	channelName := name
+	if p.CategoryID != "" {
+		category, err := s.Channel(p.CategoryID)
+		if err != nil {
+			return err
+		}
+		if _, err := s.ChannelEdit(p.CategoryID, &discordgo.ChannelEdit{Name: name}); err != nil {
+			return err
+		}
+		for _, channelID := range workspaceChannels(p.ChannelID) {
+			channel, err := s.Channel(channelID)
+			if err != nil {
+				return err
+			}
+			renamed := name + strings.TrimPrefix(channel.Name, category.Name)
+			if _, err := s.ChannelEdit(channelID, &discordgo.ChannelEdit{Name: renamed}); err != nil {
+				return err
+			}
+			if channelID == p.ChannelID {
+				channelName = renamed
+			}
+		}
+	} else {
+		edit := &discordgo.ChannelEdit{Name: name}
+		if p.TopicTemplate != "" {
+			edit.Topic = strings.ReplaceAll(p.TopicTemplate, "{name}", name)
+		}
+		if _, err := s.ChannelEdit(p.ChannelID, edit); err != nil {
+			return err
+		}
+	}
+
+	if p.InternalChannelID != "" {
+		internalName := truncate(channelName, 100-len(internalChannelSuffix)) + internalChannelSuffix
+		if _, err := s.ChannelEdit(p.InternalChannelID, &discordgo.ChannelEdit{Name: internalName}); err != nil {
+			return err
+		}
+	}
+
+	return updateProject(p.ChannelID, func(p *project) {
+		p.Name = channelName
+	})
+}
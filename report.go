@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long emitted events and failed operations are kept for the daily report.
+const reportRetention = 8 * 24 * time.Hour
+
+// How long a project can go without messages before it's considered idle.
+const projectIdleAfter = 7 * 24 * time.Hour
+
+// An event emitted by the bot, kept so the daily report can summarize it.
+type loggedEvent struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+	At    time.Time       `json:"at"`
+}
+
+// A background operation that failed, kept so staff hear about it in the daily report.
+type failure struct {
+	Operation string    `json:"operation"`
+	Error     string    `json:"error"`
+	At        time.Time `json:"at"`
+}
+
+// Log a failed background operation and keep it for the daily report. The operation is described the way it would
+// follow "Error" in a log line, such as "syncing project to Airtable".
+func recordFailure(operation string, err error) {
+	log.Printf("Error %s: %v", operation, err)
+	f := failure{Operation: operation, Error: err.Error(), At: time.Now()}
+	err = updateStore(func(d *storeData) {
+		d.Failures = slices.DeleteFunc(d.Failures, func(old failure) bool {
+			return f.At.Sub(old.At) > reportRetention
+		})
+		d.Failures = append(d.Failures, f)
+	})
+	if err != nil {
+		log.Printf("Error saving failure: %v", err)
+	}
+}
+
+// Keep an emitted event for the daily report.
+func logEvent(event string, body []byte) {
+	now := time.Now()
+	err := updateStore(func(d *storeData) {
+		d.EventLog = slices.DeleteFunc(d.EventLog, func(e loggedEvent) bool {
+			return now.Sub(e.At) > reportRetention
+		})
+		d.EventLog = append(d.EventLog, loggedEvent{Event: event, Data: body, At: now})
+	})
+	if err != nil {
+		log.Printf("Error saving event: %v", err)
+	}
+}
+
+// Post the daily report in the internal channel every day at 8:00 in the bot's local time.
+func startDailyReports(s *discordgo.Session) {
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), 8, 0, 0, 0, now.Location())
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 1)
+			}
+			time.Sleep(time.Until(next))
+			if paused("posting the daily report") {
+				continue
+			}
+			if _, err := s.ChannelMessageSendEmbed(InternalChannelId, buildDailyReport(s, time.Now())); err != nil {
+				log.Printf("Error posting daily report: %v", err)
+			}
+		}
+	}()
+}
+
+// Summarize yesterday for staff: new projects, members added and removed, projects that went idle, milestones due this week, and
+// anything the bot failed to do.
+func buildDailyReport(s *discordgo.Session, now time.Time) *discordgo.MessageEmbed {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	yesterday := today.AddDate(0, 0, -1)
+	inYesterday := func(t time.Time) bool {
+		return !t.Before(yesterday) && t.Before(today)
+	}
+
+	var created, added, removed, failed, due []string
+	var projects []project
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			projects = append(projects, copyProject(p))
+		}
+		for _, e := range d.EventLog {
+			if !inYesterday(e.At) {
+				continue
+			}
+			switch e.Event {
+			case "member.added":
+				var m memberAddedEvent
+				if err := json.Unmarshal(e.Data, &m); err == nil {
+					added = append(added, fmt.Sprintf("- <@%s> to <#%s>", m.UserID, m.ChannelID))
+				}
+			case "member.removed":
+				var m memberRemovedEvent
+				if err := json.Unmarshal(e.Data, &m); err == nil {
+					removed = append(removed, fmt.Sprintf("- <@%s> from <#%s>", m.UserID, m.ChannelID))
+				}
+			}
+		}
+		for _, f := range d.Failures {
+			if inYesterday(f.At) {
+				failed = append(failed, fmt.Sprintf("- <t:%d:t> %s: %s", f.At.Unix(), f.Operation, f.Error))
+			}
+		}
+	})
+
+	var idle []string
+	weekEnd := today.AddDate(0, 0, 7)
+	for _, p := range projects {
+		if inYesterday(p.CreatedAt) {
+			created = append(created, fmt.Sprintf("- <#%s>", p.ChannelID))
+		}
+		for _, m := range p.Milestones {
+			if m.PaidAt.IsZero() && !m.Due.Before(today) && m.Due.Before(weekEnd) {
+				due = append(due, fmt.Sprintf("- <t:%d:D> \"%s\" in <#%s> (%s)", m.Due.Unix(), m.Name, p.ChannelID, formatAmount(m.Amount)))
+			}
+		}
+
+		// A project went idle yesterday if its last message is just past the idle threshold.
+		messages, err := s.ChannelMessages(p.ChannelID, 1, "", "", "")
+		if err != nil {
+			log.Printf("Error reading channel %s: %v", p.ChannelID, err)
+			continue
+		}
+		if len(messages) > 0 && inYesterday(messages[0].Timestamp.Add(projectIdleAfter)) {
+			idle = append(idle, fmt.Sprintf("- <#%s> (last message <t:%d:R>)", p.ChannelID, messages[0].Timestamp.Unix()))
+		}
+	}
+
+	field := func(name string, lines []string) *discordgo.MessageEmbedField {
+		value := "None"
+		if len(lines) > 0 {
+			slices.Sort(lines)
+			value = truncate(strings.Join(lines, "\n"), 1024)
+		}
+		return &discordgo.MessageEmbedField{Name: name, Value: value}
+	}
+	return themed(JuiceworksGuildId, &discordgo.MessageEmbed{
+		Title: "Daily report for " + yesterday.Format("Monday, January 2"),
+		Fields: []*discordgo.MessageEmbedField{
+			field("Projects created", created),
+			field("Members added", added),
+			field("Members removed", removed),
+			field("Projects gone idle", idle),
+			field("Milestones due this week", due),
+			field("Failed operations", failed),
+		},
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The kinds of stored data that can be purged after a while: audit log entries, attachments archived to object
+// storage, and the voice call sessions time is tracked from.
+const (
+	retentionAudit = "audit"
+	retentionFiles = "files"
+	retentionCalls = "calls"
+)
+
+var retentionTypes = []string{retentionAudit, retentionFiles, retentionCalls}
+
+// How often old data is purged.
+const retentionInterval = 24 * time.Hour
+
+// How many days each kind of data is kept, from RETENTION_DAYS, a comma-separated list like audit=365,files=730.
+// Kinds without an entry are kept forever.
+func retentionPolicies() map[string]int {
+	policies := make(map[string]int)
+	for _, entry := range strings.Split(os.Getenv("RETENTION_DAYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kind, days, _ := strings.Cut(entry, "=")
+		kind = strings.TrimSpace(kind)
+		n, err := strconv.Atoi(strings.TrimSpace(days))
+		if err != nil || n <= 0 || !slices.Contains(retentionTypes, kind) {
+			log.Printf("Ignoring malformed RETENTION_DAYS entry %q", entry)
+			continue
+		}
+		policies[kind] = n
+	}
+	return policies
+}
+
+// Purge old data every day.
+func startRetention() {
+	go func() {
+		for range time.Tick(retentionInterval) {
+			if paused("purging old data") {
+				continue
+			}
+			purged := purgeExpired(false)
+			log.Printf("Purged %d audit entries, %d archived files and %d call sessions past retention.",
+				purged[retentionAudit], purged[retentionFiles], purged[retentionCalls])
+		}
+	}()
+}
+
+// Delete the data that's older than its retention policy allows, returning how much of each kind was deleted. With
+// dryRun, nothing is deleted and the counts are what would have been. Archived files are only dropped from the
+// registry once they're gone from object storage.
+func purgeExpired(dryRun bool) map[string]int {
+	policies := retentionPolicies()
+	now := time.Now()
+	cutoff := func(kind string) (time.Time, bool) {
+		days, ok := policies[kind]
+		return now.AddDate(0, 0, -days), ok
+	}
+	auditCutoff, purgeAudit := cutoff(retentionAudit)
+	filesCutoff, purgeFiles := cutoff(retentionFiles)
+	callsCutoff, purgeCalls := cutoff(retentionCalls)
+	expiredCall := func(c callSession) bool {
+		return !c.LeftAt.IsZero() && c.LeftAt.Before(callsCutoff)
+	}
+
+	purged := make(map[string]int)
+	var expiredFiles []string
+	readStore(func(d *storeData) {
+		for _, e := range d.AuditLog {
+			if purgeAudit && e.At.Before(auditCutoff) {
+				purged[retentionAudit]++
+			}
+		}
+		for _, p := range d.Projects {
+			for _, f := range p.Files {
+				if purgeFiles && f.UploadedAt.Before(filesCutoff) {
+					expiredFiles = append(expiredFiles, f.Key)
+				}
+			}
+			for _, c := range p.Calls {
+				if purgeCalls && expiredCall(c) {
+					purged[retentionCalls]++
+				}
+			}
+		}
+	})
+	if dryRun {
+		purged[retentionFiles] = len(expiredFiles)
+		return purged
+	}
+
+	deleted := make(map[string]bool)
+	for _, key := range expiredFiles {
+		if err := s3Delete(key); err != nil {
+			recordFailure("deleting archived file "+key, err)
+			continue
+		}
+		deleted[key] = true
+	}
+	purged[retentionFiles] = len(deleted)
+
+	err := updateStore(func(d *storeData) {
+		if purgeAudit {
+			d.AuditLog = slices.DeleteFunc(d.AuditLog, func(e auditEntry) bool { return e.At.Before(auditCutoff) })
+		}
+		for _, p := range d.Projects {
+			p.Files = slices.DeleteFunc(p.Files, func(f archivedFile) bool { return deleted[f.Key] })
+			if purgeCalls {
+				p.Calls = slices.DeleteFunc(p.Calls, expiredCall)
+			}
+		}
+	})
+	if err != nil {
+		recordFailure("purging data past retention", err)
+	}
+	return purged
+}
+
+// Show the retention policy and what the next purge would delete, without deleting anything.
+func retentionCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	policies := retentionPolicies()
+	purged := purgeExpired(true)
+	names := map[string]string{
+		retentionAudit: "audit entries",
+		retentionFiles: "archived files",
+		retentionCalls: "call sessions",
+	}
+
+	lines := []string{"The next purge would delete:"}
+	for _, kind := range retentionTypes {
+		if days, ok := policies[kind]; ok {
+			lines = append(lines, fmt.Sprintf("- %d %s older than %d days", purged[kind], names[kind], days))
+		} else {
+			lines = append(lines, fmt.Sprintf("- No %s, since they're kept forever", names[kind]))
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: strings.Join(lines, "\n"),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The most options a select menu can hold.
+const maxRoleMenuRoles = 25
+
+// The self-service role menu: the roles members can pick, and the message showing the menu, if it has been posted.
+type roleMenu struct {
+	Roles     []roleMenuRole `json:"roles,omitempty"`
+	ChannelID string         `json:"channelId,omitempty"`
+	MessageID string         `json:"messageId,omitempty"`
+}
+
+// A role offered in the role menu.
+type roleMenuRole struct {
+	RoleID      string `json:"roleId"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Check whether members may give themselves a role. Staff roles can only be granted by staff, and the screening and
+// quarantine roles are only for the bot to hand out and take away, since members could otherwise drop them themselves.
+func selfAssignable(roleID string) bool {
+	restricted := []string{JuiceworksRoleId, ProjectCreatorRoleId, ServicesRoleId}
+	for _, name := range []string{"SCREENING_ROLE_ID", "QUARANTINE_ROLE_ID"} {
+		if id := os.Getenv(name); id != "" {
+			restricted = append(restricted, id)
+		}
+	}
+	return !slices.Contains(restricted, roleID)
+}
+
+// Build the role menu's message. Picking a role toggles it, so a member can add and remove roles from the same menu.
+func roleMenuMessage(menu roleMenu) (string, []discordgo.MessageComponent) {
+	options := make([]discordgo.SelectMenuOption, len(menu.Roles))
+	for n, r := range menu.Roles {
+		options[n] = discordgo.SelectMenuOption{Label: r.Name, Value: r.RoleID, Description: r.Description}
+	}
+	return "Pick the roles you're interested in. Picking a role you already have removes it.", []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    "role-menu",
+				Placeholder: "Choose roles",
+				MinValues:   new(int),
+				MaxValues:   len(options),
+				Options:     options,
+			},
+		}},
+	}
+}
+
+// Update the posted role menu after its roles change. The menu is removed if no roles are left.
+func refreshRoleMenu(s *discordgo.Session, menu roleMenu) error {
+	if menu.MessageID == "" {
+		return nil
+	}
+	if len(menu.Roles) == 0 {
+		return s.ChannelMessageDelete(menu.ChannelID, menu.MessageID)
+	}
+	content, components := roleMenuMessage(menu)
+	_, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         menu.MessageID,
+		Channel:    menu.ChannelID,
+		Content:    &content,
+		Components: &components,
+	})
+	return err
+}
+
+// Manage the self-service role menu. Only administrators can change it.
+func roleMenuCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	var content string
+	switch sub.Name {
+	case "add":
+		content = addRoleMenuRole(s, sub.Options)
+	case "remove":
+		content = removeRoleMenuRole(s, sub.Options[0].RoleValue(s, i.GuildID))
+	case "post":
+		content = postRoleMenu(s, i.ChannelID)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Add a role to the role menu, returning the response to show the caller.
+func addRoleMenuRole(s *discordgo.Session, options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	r := roleMenuRole{}
+	for _, o := range options {
+		switch o.Name {
+		case "role":
+			role := o.RoleValue(s, JuiceworksGuildId)
+			r.RoleID, r.Name = role.ID, role.Name
+		case "description":
+			r.Description = truncate(strings.TrimSpace(o.StringValue()), 100)
+		}
+	}
+
+	if !selfAssignable(r.RoleID) {
+		return "That role can't be self-assigned."
+	}
+
+	var menu roleMenu
+	full := false
+	err := updateStore(func(d *storeData) {
+		d.RoleMenu.Roles = slices.DeleteFunc(d.RoleMenu.Roles, func(existing roleMenuRole) bool {
+			return existing.RoleID == r.RoleID
+		})
+		if len(d.RoleMenu.Roles) >= maxRoleMenuRoles {
+			full = true
+			return
+		}
+		d.RoleMenu.Roles = append(d.RoleMenu.Roles, r)
+		menu = d.RoleMenu
+	})
+	if err != nil {
+		log.Printf("Error saving role menu: %v", err)
+		return "Error saving role menu: " + describeError(err)
+	}
+	if full {
+		return fmt.Sprintf("The role menu can hold at most %d roles.", maxRoleMenuRoles)
+	}
+	if err := refreshRoleMenu(s, menu); err != nil {
+		log.Printf("Error updating role menu: %v", err)
+		return "Added the role, but couldn't update the posted menu: " + describeError(err)
+	}
+
+	log.Printf("Added role %s to the role menu.", r.RoleID)
+	return fmt.Sprintf("Added <@&%s> to the role menu.", r.RoleID)
+}
+
+// Remove a role from the role menu, returning the response to show the caller.
+func removeRoleMenuRole(s *discordgo.Session, role *discordgo.Role) string {
+	var menu roleMenu
+	found := false
+	err := updateStore(func(d *storeData) {
+		n := len(d.RoleMenu.Roles)
+		d.RoleMenu.Roles = slices.DeleteFunc(d.RoleMenu.Roles, func(r roleMenuRole) bool {
+			return r.RoleID == role.ID
+		})
+		found = len(d.RoleMenu.Roles) < n
+		menu = d.RoleMenu
+		if len(d.RoleMenu.Roles) == 0 {
+			d.RoleMenu = roleMenu{}
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving role menu: %v", err)
+		return "Error saving role menu: " + describeError(err)
+	}
+	if !found {
+		return fmt.Sprintf("%s isn't in the role menu.", role.Mention())
+	}
+	if err := refreshRoleMenu(s, menu); err != nil {
+		log.Printf("Error updating role menu: %v", err)
+		return "Removed the role, but couldn't update the posted menu: " + describeError(err)
+	}
+
+	log.Printf("Removed role %s from the role menu.", role.ID)
+	return fmt.Sprintf("Removed %s from the role menu.", role.Mention())
+}
+
+// Post the role menu in a channel, replacing any previously posted menu.
+func postRoleMenu(s *discordgo.Session, channelID string) string {
+	var menu roleMenu
+	readStore(func(d *storeData) {
+		menu = d.RoleMenu
+	})
+	if len(menu.Roles) == 0 {
+		return "Add some roles to the role menu first."
+	}
+
+	content, components := roleMenuMessage(menu)
+	m, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Content: content, Components: components})
+	if err != nil {
+		log.Printf("Error posting role menu: %v", err)
+		return "Error posting role menu: " + describeError(err)
+	}
+	if menu.MessageID != "" {
+		if err := s.ChannelMessageDelete(menu.ChannelID, menu.MessageID); err != nil {
+			log.Printf("Error deleting old role menu: %v", err)
+		}
+	}
+
+	err = updateStore(func(d *storeData) {
+		d.RoleMenu.ChannelID, d.RoleMenu.MessageID = channelID, m.ID
+	})
+	if err != nil {
+		log.Printf("Error saving role menu: %v", err)
+		return "Error saving role menu: " + describeError(err)
+	}
+
+	log.Printf("Posted the role menu in channel %s.", channelID)
+	return "Posted the role menu."
+}
+
+// Toggle the roles a member picked from the role menu.
+func pickRoles(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Only toggle roles that are still in the menu, in case it changed after the member's client loaded it.
+	var offered []string
+	readStore(func(d *storeData) {
+		for _, r := range d.RoleMenu.Roles {
+			offered = append(offered, r.RoleID)
+		}
+	})
+
+	var added, removed []string
+	for _, roleID := range i.MessageComponentData().Values {
+		// Roles offered before they were restricted stay out of reach.
+		if !slices.Contains(offered, roleID) || !selfAssignable(roleID) {
+			continue
+		}
+		var err error
+		if slices.Contains(i.Member.Roles, roleID) {
+			err = s.GuildMemberRoleRemove(JuiceworksGuildId, i.Member.User.ID, roleID)
+			removed = append(removed, roleID)
+		} else {
+			err = s.GuildMemberRoleAdd(JuiceworksGuildId, i.Member.User.ID, roleID)
+			added = append(added, roleID)
+		}
+		if err != nil {
+			log.Printf("Error updating member roles: %v", err)
+			logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Error updating your roles: " + describeError(err),
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			}))
+			return
+		}
+	}
+
+	mentions := func(roleIDs []string) string {
+		return "<@&" + strings.Join(roleIDs, ">, <@&") + ">"
+	}
+	var lines []string
+	if len(added) > 0 {
+		lines = append(lines, "Added "+mentions(added)+".")
+	}
+	if len(removed) > 0 {
+		lines = append(lines, "Removed "+mentions(removed)+".")
+	}
+	content := "Your roles haven't changed."
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\nUse /undo to change them back."
+		pushUndoRoles(i.Member.User.ID, added, removed)
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Members can't pick the screening or quarantine roles off the menu, or take them off themselves, even if an
+// administrator offered them before they were set up.
+func TestRoleMenuRestrictedRoles(t *testing.T) {
+	f, s := newTestBot(t)
+	screening := f.addRole("screening", 0)
+	quarantine := f.addRole("quarantine", 0)
+	t.Setenv("SCREENING_ROLE_ID", screening)
+	t.Setenv("QUARANTINE_ROLE_ID", quarantine)
+	for _, roleID := range []string{screening, quarantine} {
+		if selfAssignable(roleID) {
+			t.Errorf("role %s is self-assignable", roleID)
+		}
+	}
+
+	err := updateStore(func(d *storeData) {
+		d.RoleMenu.Roles = []roleMenuRole{{RoleID: quarantine, Name: "quarantine"}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	member := f.addMember("3000000000000000001", "member")
+	member.Roles = append(member.Roles, quarantine)
+	i := componentInteraction(f.addChannel("roles", ""), member, "role-menu")
+	i.Data = discordgo.MessageComponentInteractionData{
+		CustomID:      "role-menu",
+		ComponentType: discordgo.SelectMenuComponent,
+		Values:        []string{quarantine},
+	}
+	handleInteraction(s, i)
+	requests, _ := f.history()
+	for _, r := range requests {
+		if strings.Contains(r.Path, "/roles/") {
+			t.Errorf("the member's role was changed: %s %s", r.Method, r.Path)
+		}
+	}
+}
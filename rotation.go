@@ -0,0 +1,184 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The prefix of the channel topic line naming who's on point.
+const onPointPrefix = "On point: "
+
+// A project's staff rotation. The member on point changes every week.
+type rotation struct {
+	UserIDs []string `json:"userIds"`
+	// The index in UserIDs of the member currently on point.
+	Current   int       `json:"current"`
+	RotatedAt time.Time `json:"rotatedAt"`
+}
+
+// The user on point for a project, if it has a rotation.
+func onPoint(channelID string) (string, bool) {
+	p, _ := getProject(channelID)
+	if p.Rotation == nil || len(p.Rotation.UserIDs) == 0 {
+		return "", false
+	}
+	return p.Rotation.UserIDs[p.Rotation.Current%len(p.Rotation.UserIDs)], true
+}
+
+// Show who's on point in a project channel's topic, replacing the line from the previous rotation.
+func updateOnPointTopic(s *discordgo.Session, channelID, userID string) error {
+	channel, err := s.Channel(channelID)
+	if err != nil {
+		return err
+	}
+	lines := slices.DeleteFunc(strings.Split(channel.Topic, "\n"), func(line string) bool {
+		return line == "" || strings.HasPrefix(line, onPointPrefix)
+	})
+	if userID != "" {
+		name := userID
+		if member, err := s.GuildMember(JuiceworksGuildId, userID); err == nil {
+			name = cmp.Or(member.Nick, member.User.GlobalName, member.User.Username)
+		}
+		lines = append([]string{onPointPrefix + name}, lines...)
+	}
+	topic := truncate(strings.Join(lines, "\n"), 1024)
+	_, err = s.ChannelEdit(channelID, &discordgo.ChannelEdit{Topic: topic})
+	return err
+}
+
+// Manage the staff rotation of the project the command was called from.
+func rotationCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if _, ok := getProject(i.ChannelID); !ok {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "This channel is not a registered project.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	sub := i.ApplicationCommandData().Options[0]
+	var content string
+	switch sub.Name {
+	case "set":
+		var userIDs []string
+		for _, o := range sub.Options {
+			if id := o.UserValue(nil).ID; !slices.Contains(userIDs, id) {
+				userIDs = append(userIDs, id)
+			}
+		}
+		content = setRotation(s, i.ChannelID, userIDs)
+	case "clear":
+		content = setRotation(s, i.ChannelID, nil)
+	case "show":
+		p, _ := getProject(i.ChannelID)
+		content = "This project has no rotation."
+		if p.Rotation != nil {
+			var b strings.Builder
+			for n, id := range p.Rotation.UserIDs {
+				marker := ""
+				if n == p.Rotation.Current {
+					marker = " (on point)"
+				}
+				fmt.Fprintf(&b, "%d. <@%s>%s\n", n+1, id, marker)
+			}
+			content = b.String()
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         content,
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}))
+}
+
+// Replace a project's rotation, putting the first member on point. An empty list removes the rotation. Returns the
+// response to show the caller.
+func setRotation(s *discordgo.Session, channelID string, userIDs []string) string {
+	err := updateProject(channelID, func(p *project) {
+		p.Rotation = nil
+		if len(userIDs) > 0 {
+			p.Rotation = &rotation{UserIDs: userIDs, RotatedAt: time.Now()}
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving rotation: %v", err)
+		return "Error saving rotation: " + describeError(err)
+	}
+
+	current := ""
+	if len(userIDs) > 0 {
+		current = userIDs[0]
+	}
+	if err := updateOnPointTopic(s, channelID, current); err != nil {
+		log.Printf("Error updating channel topic: %v", err)
+	}
+	if current == "" {
+		log.Printf("Cleared rotation in channel %s.", channelID)
+		return "Removed this project's rotation."
+	}
+	log.Printf("Set rotation in channel %s to %v.", channelID, userIDs)
+	return fmt.Sprintf("Set the rotation. <@%s> is on point this week.", current)
+}
+
+// Rotate every project's on-point member every Monday at 9:00 in the bot's local time.
+func startRotations(s *discordgo.Session) {
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), 9, 0, 0, 0, now.Location())
+			next = next.AddDate(0, 0, (int(time.Monday)-int(now.Weekday())+7)%7)
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 7)
+			}
+			time.Sleep(time.Until(next))
+			if !paused("rotating") {
+				rotate(s)
+			}
+		}
+	}()
+}
+
+// Move every rotation on to its next member and announce who's on point. Projects in the trash keep theirs as it is.
+func rotate(s *discordgo.Session) {
+	assignees := make(map[string]string)
+	err := updateStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if r := p.Rotation; r != nil && len(r.UserIDs) > 0 && p.Trash == nil {
+				r.Current = (r.Current + 1) % len(r.UserIDs)
+				r.RotatedAt = time.Now()
+				assignees[p.ChannelID] = r.UserIDs[r.Current]
+			}
+		}
+	})
+	if err != nil {
+		recordFailure("saving rotations", err)
+		return
+	}
+
+	for channelID, userID := range assignees {
+		if err := updateOnPointTopic(s, channelID, userID); err != nil {
+			recordFailure("updating channel topic for rotation", err)
+		}
+		pings, allowed := projectPings(channelID, userID)
+		msg := &discordgo.MessageSend{
+			Content:         strings.TrimSpace(fmt.Sprintf("<@%s> is on point this week. %s", userID, pings)),
+			AllowedMentions: allowed,
+		}
+		if _, err := s.ChannelMessageSendComplex(channelID, msg); err != nil {
+			recordFailure("announcing rotation", err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Whether S3-compatible storage is configured with S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY.
+func s3Configured() bool {
+	return os.Getenv("S3_ENDPOINT") != "" && os.Getenv("S3_BUCKET") != "" &&
+		os.Getenv("S3_ACCESS_KEY_ID") != "" && os.Getenv("S3_SECRET_ACCESS_KEY") != ""
+}
+
+// The region requests are signed for, from S3_REGION. Most S3-compatible services other than AWS accept any region,
+// so this defaults to us-east-1.
+func s3Region() string {
+	if r := os.Getenv("S3_REGION"); r != "" {
+		return r
+	}
+	return "us-east-1"
+}
+
+// Percent-encode a string the way AWS Signature Version 4 expects: everything but unreserved characters, and slashes
+// too unless they separate the segments of a path.
+func s3Escape(s string, path bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && path:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// The URL of an object, addressed path-style so it works with any S3-compatible service.
+func s3ObjectURL(key string) *url.URL {
+	u, _ := url.Parse(strings.TrimSuffix(os.Getenv("S3_ENDPOINT"), "/"))
+	u.Path = "/" + os.Getenv("S3_BUCKET") + "/" + key
+	u.RawPath = "/" + s3Escape(os.Getenv("S3_BUCKET"), false) + "/" + s3Escape(key, true)
+	return u
+}
+
+// The credential scope requests made at a given time are signed for.
+func s3Scope(now time.Time) string {
+	return awsScope(now, s3Region(), "s3")
+}
+
+// Sign a request to object storage.
+func s3Signature(method string, u *url.URL, query url.Values, headers map[string]string, payloadHash string, now time.Time) string {
+	return awsSignature(os.Getenv("S3_SECRET_ACCESS_KEY"), s3Region(), "s3", method, u, query, headers, payloadHash, now)
+}
+
+// The credential scope requests to an AWS service made at a given time are signed for.
+func awsScope(now time.Time, region, service string) string {
+	return now.Format("20060102") + "/" + region + "/" + service + "/aws4_request"
+}
+
+// Compute an AWS Signature Version 4 signature over a request to a service, given its query string and the headers
+// that are signed.
+func awsSignature(secret, region, service, method string, u *url.URL, query url.Values, headers map[string]string, payloadHash string, now time.Time) string {
+	var queryParts []string
+	for k, vs := range query {
+		for _, v := range vs {
+			queryParts = append(queryParts, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	slices.Sort(queryParts)
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		method, u.EscapedPath(), strings.Join(queryParts, "&"), canonicalHeaders.String(), strings.Join(names, ";"), payloadHash,
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + awsScope(now, region, service) + "\n" + hex.EncodeToString(requestHash[:])
+
+	// The signing key is derived from the secret and the scope, then used to sign the string.
+	key := []byte("AWS4" + secret)
+	for _, part := range []string{now.Format("20060102"), region, service, "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	return hex.EncodeToString(key)
+}
+
+// Upload an object.
+func s3Put(key, contentType string, body []byte) (err error) {
+	defer func() { noteIntegrationCall("S3 storage", err) }()
+	u := s3ObjectURL(key)
+	now := time.Now().UTC()
+	bodyHash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(bodyHash[:])
+	headers := map[string]string{
+		"host":                 u.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           now.Format("20060102T150405Z"),
+	}
+	signature := s3Signature(http.MethodPut, u, nil, headers, payloadHash, now)
+
+	req, err := http.NewRequest(http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", headers["x-amz-date"])
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=%s",
+		os.Getenv("S3_ACCESS_KEY_ID"), s3Scope(now), signature))
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("storage returned %s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+// Delete an object. Deleting one that doesn't exist isn't an error.
+func s3Delete(key string) (err error) {
+	defer func() { noteIntegrationCall("S3 storage", err) }()
+	u := s3ObjectURL(key)
+	now := time.Now().UTC()
+	emptyHash := sha256.Sum256(nil)
+	payloadHash := hex.EncodeToString(emptyHash[:])
+	headers := map[string]string{
+		"host":                 u.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           now.Format("20060102T150405Z"),
+	}
+	signature := s3Signature(http.MethodDelete, u, nil, headers, payloadHash, now)
+
+	req, err := http.NewRequest(http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", headers["x-amz-date"])
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=%s",
+		os.Getenv("S3_ACCESS_KEY_ID"), s3Scope(now), signature))
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("storage returned %s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+// Check that the bucket exists and the credentials can reach it.
+func s3CheckBucket() (err error) {
+	defer func() { noteIntegrationCall("S3 storage", err) }()
+	u := s3ObjectURL("")
+	u.Path, u.RawPath = strings.TrimSuffix(u.Path, "/"), strings.TrimSuffix(u.RawPath, "/")
+	now := time.Now().UTC()
+	emptyHash := sha256.Sum256(nil)
+	payloadHash := hex.EncodeToString(emptyHash[:])
+	headers := map[string]string{
+		"host":                 u.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           now.Format("20060102T150405Z"),
+	}
+	signature := s3Signature(http.MethodHead, u, nil, headers, payloadHash, now)
+
+	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", headers["x-amz-date"])
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=%s",
+		os.Getenv("S3_ACCESS_KEY_ID"), s3Scope(now), signature))
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("storage returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Make a link that downloads an object without credentials until it expires. S3 caps this at a week.
+func s3PresignedURL(key string, expires time.Duration) string {
+	u := s3ObjectURL(key)
+	now := time.Now().UTC()
+	query := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {os.Getenv("S3_ACCESS_KEY_ID") + "/" + s3Scope(now)},
+		"X-Amz-Date":          {now.Format("20060102T150405Z")},
+		"X-Amz-Expires":       {strconv.Itoa(int(expires.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	signature := s3Signature(http.MethodGet, u, query, map[string]string{"host": u.Host}, "UNSIGNED-PAYLOAD", now)
+
+	var parts []string
+	for k, vs := range query {
+		parts = append(parts, s3Escape(k, false)+"="+s3Escape(vs[0], false))
+	}
+	slices.Sort(parts)
+	u.RawQuery = strings.Join(parts, "&") + "&X-Amz-Signature=" + signature
+	return u.String()
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The questions new members answer before they're let in. A modal holds at most five.
+var screeningQuestions = []string{
+	"What's your name?",
+	"What company are you with?",
+	"Who at Juiceworks invited you?",
+	"What are you hoping to work on with us?",
+}
+
+// Welcome new members with a button that opens the screening questions. Modals can only be opened in response to
+// an interaction, so the DM can't show the questions directly. Screening is off unless SCREENING_ROLE_ID is set.
+func screenNewMember(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
+	if m.GuildID != JuiceworksGuildId || m.User.Bot || os.Getenv("SCREENING_ROLE_ID") == "" {
+		return
+	}
+
+	dm, err := s.UserChannelCreate(m.User.ID)
+	if err == nil {
+		_, err = s.ChannelMessageSendComplex(dm.ID, &discordgo.MessageSend{
+			Content: renderMessage("welcome", memberMessage{Name: m.User.Username, Mention: m.User.Mention()}),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Answer questions",
+						Style:    discordgo.PrimaryButton,
+						CustomID: "screening-start",
+					},
+				}},
+			},
+		})
+	}
+	if err != nil {
+		log.Printf("Error sending screening questions: %v", err)
+	}
+}
+
+// Show the screening questions in a modal.
+func startScreening(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	rows := make([]discordgo.MessageComponent, len(screeningQuestions))
+	for n, q := range screeningQuestions {
+		rows[n] = discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.TextInput{
+				CustomID:  fmt.Sprintf("q%d", n),
+				Label:     truncate(q, 45),
+				Style:     discordgo.TextInputParagraph,
+				Required:  true,
+				MaxLength: 1000,
+			},
+		}}
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   "screening-submit",
+			Title:      "Welcome to Juiceworks",
+			Components: rows,
+		},
+	}))
+}
+
+// Post a new member's answers in the internal channel for staff to approve or reject.
+func submitScreening(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := i.User
+	if user == nil {
+		return
+	}
+
+	embed := themed(i.GuildID, &discordgo.MessageEmbed{
+		Title:     "Screening answers from " + user.Username,
+		Thumbnail: &discordgo.MessageEmbedThumbnail{URL: user.AvatarURL("")},
+	})
+	for _, row := range i.ModalSubmitData().Components {
+		for _, c := range row.(*discordgo.ActionsRow).Components {
+			input := c.(*discordgo.TextInput)
+			var n int
+			if _, err := fmt.Sscanf(input.CustomID, "q%d", &n); err != nil || n >= len(screeningQuestions) {
+				continue
+			}
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+				Name:  screeningQuestions[n],
+				Value: truncate(input.Value, 1024),
+			})
+		}
+	}
+
+	_, err := s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{
+		Content: user.Mention() + " joined and answered the screening questions.",
+		Embed:   embed,
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Approve", Style: discordgo.SuccessButton, CustomID: "screening-approve:" + user.ID},
+				discordgo.Button{Label: "Reject", Style: discordgo.DangerButton, CustomID: "screening-reject:" + user.ID},
+			}},
+		},
+	})
+	if err != nil {
+		log.Printf("Error posting screening answers: %v", err)
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Error sending your answers: " + describeError(err),
+			},
+		}))
+		return
+	}
+
+	// Replace the button so the questions can't be answered twice.
+	log.Printf("Received screening answers from %s.", user)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    "Thanks! We'll let you know once someone has had a look.",
+			Components: []discordgo.MessageComponent{},
+		},
+	}))
+}
+
+// Handle the Approve and Reject buttons on a member's screening answers. Approving grants the role in
+// SCREENING_ROLE_ID.
+func reviewScreening(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	action, userID, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	approved := action == "screening-approve"
+	if approved {
+		if err := s.GuildMemberRoleAdd(JuiceworksGuildId, userID, os.Getenv("SCREENING_ROLE_ID")); err != nil {
+			log.Printf("Error granting screening role: %v", err)
+			logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Error granting screening role: " + describeError(err),
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			}))
+			return
+		}
+	}
+
+	// Let the member know the outcome.
+	to := memberMessage{Mention: "<@" + userID + ">"}
+	if u, err := s.User(userID); err == nil {
+		to.Name = u.Username
+	}
+	message := renderMessage("screening-approved", to)
+	outcome := "Approved"
+	if !approved {
+		message = renderMessage("screening-rejected", to)
+		outcome = "Rejected"
+	}
+	dm, err := s.UserChannelCreate(userID)
+	if err == nil {
+		_, err = s.ChannelMessageSend(dm.ID, message)
+	}
+	if err != nil {
+		log.Printf("Error sending screening outcome: %v", err)
+	}
+
+	// Replace the buttons with the outcome so the answers can't be reviewed twice.
+	log.Printf("%s screening of user %s by %s.", outcome, userID, i.Member.User)
+	content := fmt.Sprintf("%s\n**%s** by %s.", i.Message.Content, outcome, i.Member.User.Mention())
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:         content,
+			Components:      []discordgo.MessageComponent{},
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}))
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Where secrets can be fetched from, set with SECRETS_PROVIDER. The env provider leaves the environment and .env file
+// as they are.
+const (
+	secretsEnv   = "env"
+	secretsVault = "vault"
+	secretsAWS   = "aws"
+	secretsGCP   = "gcp"
+)
+
+// How often secrets are fetched again to pick up rotated values, unless SECRETS_REFRESH_INTERVAL says otherwise.
+const defaultSecretsRefresh = 5 * time.Minute
+
+// The secret manager secrets are fetched from.
+func secretsProvider() string {
+	if p := os.Getenv("SECRETS_PROVIDER"); p != "" {
+		return p
+	}
+	return secretsEnv
+}
+
+// Fetch the bot's secrets from the secret manager in SECRETS_PROVIDER, at SECRETS_PATH. The secret is a JSON object
+// of environment variable names to values, like {"DISCORD_TOKEN": "..."}, so the rest of the bot reads secrets from
+// the environment wherever they came from.
+func fetchSecrets() (_ map[string]string, err error) {
+	path := os.Getenv("SECRETS_PATH")
+	if path == "" {
+		return nil, errors.New("SECRETS_PATH isn't set")
+	}
+	defer func() { noteIntegrationCall("Secret manager", err) }()
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	var raw []byte
+	switch secretsProvider() {
+	case secretsVault:
+		raw, err = fetchVaultSecret(client, path)
+	case secretsAWS:
+		raw, err = fetchAWSSecret(client, path)
+	case secretsGCP:
+		raw, err = fetchGCPSecret(client, path)
+	default:
+		return nil, fmt.Errorf("unknown SECRETS_PROVIDER %q", secretsProvider())
+	}
+	if err != nil {
+		return nil, err
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal(raw, &secrets); err != nil {
+		return nil, fmt.Errorf("secret at %s isn't a JSON object of strings: %w", path, err)
+	}
+	return secrets, nil
+}
+
+// Make a request to a secret manager and read the response body.
+func secretManagerRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, truncate(string(body), 200))
+	}
+	return body, nil
+}
+
+// Read a secret from HashiCorp Vault at VAULT_ADDR with VAULT_TOKEN. Both KV version 1 and 2 paths work, like
+// secret/data/juiceworks.
+func fetchVaultSecret(client *http.Client, path string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	body, err := secretManagerRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	// KV version 2 nests the secret under data.data, next to its metadata.
+	if nested, ok := resp.Data["data"]; ok && resp.Data["metadata"] != nil {
+		return nested, nil
+	}
+	return json.Marshal(resp.Data)
+}
+
+// Read a secret from AWS Secrets Manager in AWS_REGION. The path is the secret's name or ARN.
+func fetchAWSSecret(client *http.Client, path string) ([]byte, error) {
+	body, err := awsJSONRequest(client, "secretsmanager", "secretsmanager.GetSecretValue", map[string]string{"SecretId": path})
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return []byte(resp.SecretString), nil
+}
+
+// Call an AWS JSON API action, like secretsmanager.GetSecretValue, in AWS_REGION, signed with AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN if there is one.
+func awsJSONRequest(client *http.Client, service, target string, input any) ([]byte, error) {
+	region := os.Getenv("AWS_REGION")
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	u, _ := url.Parse("https://" + service + "." + region + ".amazonaws.com/")
+	now := time.Now().UTC()
+	payloadSum := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(payloadSum[:])
+	headers := map[string]string{
+		"content-type": "application/x-amz-json-1.1",
+		"host":         u.Host,
+		"x-amz-date":   now.Format("20060102T150405Z"),
+		"x-amz-target": target,
+	}
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		headers["x-amz-security-token"] = token
+	}
+	signature := awsSignature(os.Getenv("AWS_SECRET_ACCESS_KEY"), region, service, http.MethodPost, u, nil, headers, payloadHash, now)
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	var signed []string
+	for name, value := range headers {
+		if name != "host" {
+			req.Header.Set(name, value)
+		}
+		signed = append(signed, name)
+	}
+	slices.Sort(signed)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		os.Getenv("AWS_ACCESS_KEY_ID"), awsScope(now, region, service), strings.Join(signed, ";"), signature))
+	return secretManagerRequest(client, req)
+}
+
+// Read the latest version of a secret from GCP Secret Manager, like projects/juiceworks/secrets/discord-bot, with the
+// credentials of the service account the bot runs as.
+func fetchGCPSecret(client *http.Client, path string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet,
+		"http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	body, err := secretManagerRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, err
+	}
+
+	req, err = http.NewRequest(http.MethodGet, "https://secretmanager.googleapis.com/v1/"+strings.TrimPrefix(path, "/")+"/versions/latest:access", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	body, err = secretManagerRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Payload.Data)
+}
+
+// Load secrets from the secret manager into the environment, overriding the environment and .env file. Returns the
+// names of the variables whose values changed.
+func loadSecrets() ([]string, error) {
+	if secretsProvider() == secretsEnv {
+		return nil, nil
+	}
+	secrets, err := fetchSecrets()
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for name, value := range secrets {
+		if os.Getenv(name) != value {
+			os.Setenv(name, value)
+			changed = append(changed, name)
+		}
+	}
+	return changed, nil
+}
+
+// Fetch secrets again every so often to pick up rotated values. A rotated bot token is switched to straight away.
+func startSecretsRefresh(s *discordgo.Session) {
+	if secretsProvider() == secretsEnv {
+		return
+	}
+	interval := defaultSecretsRefresh
+	if v := os.Getenv("SECRETS_REFRESH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err == nil && d > 0 {
+			interval = d
+		} else {
+			log.Printf("Ignoring malformed SECRETS_REFRESH_INTERVAL %q", v)
+		}
+	}
+	go func() {
+		for range time.Tick(interval) {
+			changed, err := loadSecrets()
+			if err != nil {
+				recordFailure("refreshing secrets", err)
+				continue
+			}
+			if len(changed) > 0 {
+				log.Printf("Secrets rotated: %s", strings.Join(changed, ", "))
+			}
+			if slices.Contains(changed, "DISCORD_TOKEN") {
+				rotateDiscordToken(s, os.Getenv("DISCORD_TOKEN"), "it was rotated in the secret manager")
+			}
+		}
+	}()
+}
+
+// Switch the session to a new bot token, reconnecting to the gateway with it unless interactions come over HTTP, and
+// audit the switch.
+func rotateDiscordToken(s *discordgo.Session, token, reason string) {
+	s.Lock()
+	s.Token = "Bot " + token
+	s.Identify.Token = s.Token
+	s.Unlock()
+	if interactionsMode() == interactionsGateway {
+		if err := s.Close(); err != nil {
+			log.Printf("Error closing gateway connection: %v", err)
+		}
+		// The gateway may already be reconnecting with the new token on its own.
+		if err := s.Open(); err != nil && !errors.Is(err, discordgo.ErrWSAlreadyOpen) {
+			recordFailure("reconnecting with the rotated bot token", err)
+			return
+		}
+	}
+	log.Printf("Switched to a new bot token, since %s.", reason)
+	postAudit(s, auditEntry{
+		Action:  auditTokenRotated,
+		Summary: fmt.Sprintf("The bot switched to a new token and re-established its session, since %s.", reason),
+	})
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// What a secret can be called, like github or stripe-webhook.
+var secretName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// Decrypted project secrets, keyed by their ciphertext, so integrations don't call KMS for every request. They only
+// ever live in memory.
+var (
+	secretsCacheMu sync.Mutex
+	secretsCache   = make(map[string]string)
+)
+
+// The AWS KMS key project secrets are encrypted with, from SECRETS_KMS_KEY_ID. The key never leaves KMS, so neither
+// the data file nor the bot's environment gives the secrets away.
+func secretsKMSKey() (string, error) {
+	key := os.Getenv("SECRETS_KMS_KEY_ID")
+	if key == "" {
+		return "", notConfigured("SECRETS_KMS_KEY_ID isn't set")
+	}
+	return key, nil
+}
+
+// The KMS encryption context for a project's secret. KMS only decrypts with the same context, so a ciphertext can't
+// be moved to another project or name in the data file and still decrypt.
+func secretContext(channelID, name string) map[string]string {
+	return map[string]string{"project": channelID, "secret": name}
+}
+
+// Encrypt a project's secret with KMS.
+func encryptSecret(channelID, name, value string) (_ string, err error) {
+	key, err := secretsKMSKey()
+	if err != nil {
+		return "", err
+	}
+	defer func() { noteIntegrationCall("Project secrets", err) }()
+	body, err := awsJSONRequest(&http.Client{Timeout: 10 * time.Second}, "kms", "TrentService.Encrypt", map[string]any{
+		"KeyId":             key,
+		"Plaintext":         base64.StdEncoding.EncodeToString([]byte(value)),
+		"EncryptionContext": secretContext(channelID, name),
+	})
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		CiphertextBlob string `json:"CiphertextBlob"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+	return resp.CiphertextBlob, nil
+}
+
+// Decrypt a project's secret with KMS.
+func decryptSecret(channelID, name, sealed string) (_ string, err error) {
+	key, err := secretsKMSKey()
+	if err != nil {
+		return "", err
+	}
+	defer func() { noteIntegrationCall("Project secrets", err) }()
+	body, err := awsJSONRequest(&http.Client{Timeout: 10 * time.Second}, "kms", "TrentService.Decrypt", map[string]any{
+		"KeyId":             key,
+		"CiphertextBlob":    sealed,
+		"EncryptionContext": secretContext(channelID, name),
+	})
+	if err != nil {
+		return "", fmt.Errorf("decrypting secret %s for project %s: %w", name, channelID, err)
+	}
+	var resp struct {
+		Plaintext string `json:"Plaintext"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+	value, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return "", fmt.Errorf("secret %s for project %s is corrupt", name, channelID)
+	}
+	return string(value), nil
+}
+
+// Read one of a project's secrets, like the token for an integration. Secrets are only ever decrypted in memory.
+func projectSecret(channelID, name string) (string, bool, error) {
+	p, ok := getProject(channelID)
+	if !ok {
+		return "", false, nil
+	}
+	sealed, ok := p.Secrets[name]
+	if !ok {
+		return "", false, nil
+	}
+	secretsCacheMu.Lock()
+	value, cached := secretsCache[sealed]
+	secretsCacheMu.Unlock()
+	if cached {
+		return value, true, nil
+	}
+
+	value, err := decryptSecret(channelID, name, sealed)
+	if err != nil {
+		return "", true, err
+	}
+	secretsCacheMu.Lock()
+	secretsCache[sealed] = value
+	secretsCacheMu.Unlock()
+	return value, true, nil
+}
+
+// The secrets integrations look for in a project, and the environment variables they fall back to.
+var integrationSecrets = map[string]string{
+	"figma":   "FIGMA_TOKEN",
+	"hubspot": "HUBSPOT_TOKEN",
+	"slack":   "SLACK_BOT_TOKEN",
+}
+
+// The credential an integration uses for a channel's project: the project's own secret if it has one, or the
+// bot-wide one from the environment. A secret that can't be decrypted falls back to the environment too.
+func integrationToken(channelID, secret string) string {
+	if channelID != "" {
+		value, ok, err := projectSecret(workspaceProjectID(channelID), secret)
+		if err != nil {
+			log.Printf("Error reading %s secret: %v", secret, err)
+		} else if ok {
+			return value
+		}
+	}
+	return os.Getenv(integrationSecrets[secret])
+}
+
+// Set, delete and list the credentials integrations use for the project the command is called from. Values are
+// never shown again once they're set.
+func secretCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	projectID := workspaceProjectID(i.ChannelID)
+	content := "This channel is not a registered project."
+	if _, ok := getProject(projectID); ok {
+		content = secretContent(i, projectID, i.ApplicationCommandData().Options[0])
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Run a /secret subcommand for a project, returning the response to show the caller.
+func secretContent(i *discordgo.InteractionCreate, projectID string, sub *discordgo.ApplicationCommandInteractionDataOption) string {
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	switch sub.Name {
+	case "set":
+		name := strings.ToLower(options["name"].StringValue())
+		if !secretName.MatchString(name) {
+			return "Secret names can only have lowercase letters, numbers and dashes, like `github`."
+		}
+		sealed, err := encryptSecret(projectID, name, options["value"].StringValue())
+		if err != nil {
+			log.Printf("Error encrypting secret: %v", err)
+			return "Error encrypting secret: " + describeError(err)
+		}
+		err = updateProject(projectID, func(p *project) {
+			if p.Secrets == nil {
+				p.Secrets = make(map[string]string)
+			}
+			p.Secrets[name] = sealed
+		})
+		if err != nil {
+			log.Printf("Error saving secret: %v", err)
+			return "Error saving secret: " + describeError(err)
+		}
+		log.Printf("%s set secret %s for project %s.", i.Member.User, name, projectID)
+		if _, used := integrationSecrets[name]; !used {
+			return fmt.Sprintf("Saved the `%s` secret for this project. No integration uses it yet.", name)
+		}
+		return fmt.Sprintf("Saved the `%s` secret for this project.", name)
+
+	case "delete":
+		name := strings.ToLower(options["name"].StringValue())
+		var found bool
+		err := updateProject(projectID, func(p *project) {
+			if _, found = p.Secrets[name]; found {
+				delete(p.Secrets, name)
+			}
+		})
+		switch {
+		case err != nil:
+			log.Printf("Error deleting secret: %v", err)
+			return "Error deleting secret: " + describeError(err)
+		case !found:
+			return fmt.Sprintf("This project has no `%s` secret.", name)
+		}
+		log.Printf("%s deleted secret %s for project %s.", i.Member.User, name, projectID)
+		return fmt.Sprintf("Deleted the `%s` secret.", name)
+
+	default:
+		p, _ := getProject(projectID)
+		var names []string
+		for name := range p.Secrets {
+			names = append(names, "`"+name+"`")
+		}
+		slices.Sort(names)
+		if len(names) == 0 {
+			return "This project has no secrets."
+		}
+		return "This project has secrets for " + strings.Join(names, ", ") + "."
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The largest webhook body the server will read. Inbound emails can carry attachments, so this is fairly generous.
+const maxWebhookBodySize = 25 << 20
+
+// The HTTP endpoints served by the bot, keyed by method and path.
+var httpHandlers = map[string]func(s *discordgo.Session, w http.ResponseWriter, r *http.Request){
+	"POST /webhooks/calcom":       calcomWebhook,
+	"POST /webhooks/calendly":     calendlyWebhook,
+	"POST /webhooks/slack":        slackWebhook,
+	"POST /webhooks/mailgun":      mailgunWebhook,
+	"POST /webhooks/ses":          sesWebhook,
+	"POST /webhooks/hubspot":      hubspotWebhook,
+	"POST /webhooks/figma":        figmaWebhook,
+	"POST /webhooks/dropbox-sign": dropboxSignWebhook,
+	"GET /digest/unsubscribe":     digestUnsubscribe,
+	"GET /files":                  downloadFile,
+	"GET /link/discord":           startAccountLink,
+	"GET /oauth/discord/callback": finishAccountLink,
+	"POST /oauth/discord/confirm": confirmAccountLink,
+	"POST /interactions":          interactionsWebhook,
+
+	// REST hooks, following the subscription pattern Zapier expects.
+	"POST /hooks":                requireAPIKey(scopeProjectAdmin, hooksSubscribe),
+	"DELETE /hooks/{id}":         requireAPIKey(scopeProjectAdmin, hooksUnsubscribe),
+	"GET /hooks/samples/{event}": requireAPIKey(scopeRead, hooksSample),
+	"GET /events/stream":         requireAPIKey(scopeRead, eventStream),
+
+	// Project management for juicectl and other internal tools.
+	"GET /api/projects":                             requireAPIKey(scopeRead, apiListProjects),
+	"POST /api/projects/{channel}/members":          requireAPIKey(scopeProjectAdmin, apiAddMember),
+	"DELETE /api/projects/{channel}/members/{user}": requireAPIKey(scopeProjectAdmin, apiRemoveMember),
+	"POST /api/reconcile":                           requireAPIKey(scopeAdmin, apiReconcile),
+	"POST /api/import":                              requireAPIKey(scopeAdmin, apiImportExisting),
+	"GET /api/links/{user}":                         requireAPIKey(scopeRead, apiAccountLink),
+	"GET /api/permissions":                          requireAPIKey(scopeRead, apiPermissions),
+
+	// Token management, for administrators.
+	"GET /api/tokens":         requireAPIKey(scopeAdmin, apiListTokens),
+	"POST /api/tokens":        requireAPIKey(scopeAdmin, apiCreateToken),
+	"DELETE /api/tokens/{id}": requireAPIKey(scopeAdmin, apiRevokeToken),
+
+	// Gateway stats for Prometheus.
+	"GET /metrics": requireAPIKey(scopeRead, gatewayMetrics),
+}
+
+// Serve the HTTP endpoints on HTTP_ADDR. The server is only started when HTTP_ADDR is set.
+func startHTTPServer(s *discordgo.Session) *http.Server {
+	addr := os.Getenv("HTTP_ADDR")
+	if addr == "" {
+		return nil
+	}
+
+	server := &http.Server{Addr: addr, Handler: httpMux(s)}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("HTTP server stopped: %v", err)
+		}
+	}()
+	log.Printf("HTTP server listening on %s", addr)
+	return server
+}
+
+// The HTTP endpoints that never change anything, which keep working in maintenance mode. Some GET endpoints, like
+// unsubscribing from the digest and linking accounts, do change the store, so this lists endpoints rather than methods.
+var readOnlyHTTPHandlers = []string{
+	"GET /files",
+	"GET /hooks/samples/{event}",
+	"GET /events/stream",
+	"GET /api/projects",
+	"GET /api/links/{user}",
+	"GET /api/permissions",
+	"GET /api/tokens",
+	"GET /metrics",
+}
+
+// Route requests to the HTTP endpoints. Everything but the read-only endpoints is turned away in maintenance mode,
+// except interactions, which commands check for themselves so read-only ones keep working.
+func httpMux(s *discordgo.Session) *http.ServeMux {
+	mux := http.NewServeMux()
+	for pattern, h := range httpHandlers {
+		checked := pattern != "POST /interactions" && !slices.Contains(readOnlyHTTPHandlers, pattern)
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if checked && !checkMaintenanceHTTP(w, r) {
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxWebhookBodySize)
+			h(s, w, r)
+		})
+	}
+	return mux
+}
+
+// Read a webhook body and verify its hex-encoded HMAC-SHA256 signature. The signed payload is the prefix followed by
+// the body, which covers providers that sign a timestamp along with the body.
+func readSignedBody(r *http.Request, secret, prefix, signature string) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading webhook body: %v", err)
+		return nil, false
+	}
+	if secret == "" {
+		log.Printf("Rejected webhook on %s: no signing secret configured", r.URL.Path)
+		return nil, false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(prefix))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		log.Printf("Rejected webhook on %s: invalid signature", r.URL.Path)
+		return nil, false
+	}
+	return body, true
+}
+
+// Sign a value embedded in a link the bot hands out, so the link can't be altered to act on something else.
+func signLink(value string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("LINK_SIGNING_KEY")))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Check a signature produced by signLink.
+func validLinkSignature(value, signature string) bool {
+	return os.Getenv("LINK_SIGNING_KEY") != "" && hmac.Equal([]byte(signLink(value)), []byte(signature))
+}
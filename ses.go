@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// An Amazon SNS message, as posted to HTTPS subscriptions. SES receipt rules publish inbound email to an SNS topic.
+type snsMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	SubscribeURL     string `json:"SubscribeURL"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+}
+
+// The certificates SNS signs messages with, keyed by URL.
+var (
+	snsCertsMu sync.Mutex
+	snsCerts   = make(map[string]*x509.Certificate)
+)
+
+// Handle a message from the SNS topic SES_TOPIC_ARN: confirming the subscription, or an email received by an SES
+// receipt rule, which is posted to the project channel it was addressed to.
+func sesWebhook(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	var msg snsMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		log.Printf("Error decoding SES webhook: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	sent, err := time.Parse(time.RFC3339, msg.Timestamp)
+	if os.Getenv("SES_TOPIC_ARN") == "" || msg.TopicArn != os.Getenv("SES_TOPIC_ARN") ||
+		err != nil || time.Since(sent).Abs() > emailWebhookMaxAge {
+		log.Printf("Rejected webhook on %s: wrong topic or stale timestamp", r.URL.Path)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if err := verifySNSMessage(&msg); err != nil {
+		log.Printf("Rejected webhook on %s: %v", r.URL.Path, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch msg.Type {
+	case "SubscriptionConfirmation":
+		// The subscribe URL came with the signed message, so it's Amazon's.
+		resp, err := (&http.Client{Timeout: 10 * time.Second}).Get(msg.SubscribeURL)
+		if err != nil {
+			log.Printf("Error confirming SNS subscription: %v", err)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		resp.Body.Close()
+		log.Printf("Confirmed the SNS subscription to %s.", msg.TopicArn)
+	case "Notification":
+		emails, err := parseSESNotification(msg.Message)
+		if err != nil {
+			log.Printf("Error decoding SES notification: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, e := range emails {
+			postEmail(s, e)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Check an SNS message's signature against the certificate it names, which has to be served by SNS itself.
+func verifySNSMessage(msg *snsMessage) error {
+	u, err := url.Parse(msg.SigningCertURL)
+	if err != nil || u.Scheme != "https" || !strings.HasPrefix(u.Host, "sns.") || !strings.HasSuffix(u.Host, ".amazonaws.com") {
+		return fmt.Errorf("signing certificate %q isn't from SNS", msg.SigningCertURL)
+	}
+	cert, err := snsCertificate(u.String())
+	if err != nil {
+		return err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("the signing certificate isn't an RSA certificate")
+	}
+	signature, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return err
+	}
+
+	// The signed string is the message's fields, each name and value on its own line, in alphabetical order.
+	fields := [][2]string{{"Message", msg.Message}, {"MessageId", msg.MessageID}}
+	if msg.Type == "Notification" {
+		if msg.Subject != "" {
+			fields = append(fields, [2]string{"Subject", msg.Subject})
+		}
+	} else {
+		fields = append(fields, [2]string{"SubscribeURL", msg.SubscribeURL})
+	}
+	fields = append(fields, [2]string{"Timestamp", msg.Timestamp})
+	if msg.Type != "Notification" {
+		fields = append(fields, [2]string{"Token", msg.Token})
+	}
+	fields = append(fields, [2]string{"TopicArn", msg.TopicArn}, [2]string{"Type", msg.Type})
+	var signed strings.Builder
+	for _, f := range fields {
+		signed.WriteString(f[0] + "\n" + f[1] + "\n")
+	}
+
+	switch msg.SignatureVersion {
+	case "1":
+		digest := sha1.Sum([]byte(signed.String()))
+		return rsa.VerifyPKCS1v15(key, crypto.SHA1, digest[:], signature)
+	case "2":
+		digest := sha256.Sum256([]byte(signed.String()))
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	}
+	return fmt.Errorf("unknown signature version %q", msg.SignatureVersion)
+}
+
+// Fetch an SNS signing certificate, or use the copy fetched before.
+func snsCertificate(certURL string) (*x509.Certificate, error) {
+	snsCertsMu.Lock()
+	defer snsCertsMu.Unlock()
+	if cert, ok := snsCerts[certURL]; ok {
+		return cert, nil
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Get(certURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("the signing certificate isn't PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	snsCerts[certURL] = cert
+	return cert, nil
+}
+
+// Decode an SES receipt notification published by an SNS action, which carries the raw email, into one inbound email
+// for each recipient.
+func parseSESNotification(message string) ([]*inboundEmail, error) {
+	var n struct {
+		NotificationType string `json:"notificationType"`
+		Mail             struct {
+			Source string `json:"source"`
+		} `json:"mail"`
+		Receipt struct {
+			Recipients []string `json:"recipients"`
+			Action     struct {
+				Encoding string `json:"encoding"`
+			} `json:"action"`
+		} `json:"receipt"`
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(message), &n); err != nil {
+		return nil, err
+	}
+	if n.NotificationType != "Received" {
+		return nil, nil
+	}
+	raw := []byte(n.Content)
+	if n.Receipt.Action.Encoding == "BASE64" {
+		var err error
+		if raw, err = base64.StdEncoding.DecodeString(n.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	var decoder mime.WordDecoder
+	header := func(name string) string {
+		v, err := decoder.DecodeHeader(m.Header.Get(name))
+		if err != nil {
+			return m.Header.Get(name)
+		}
+		return v
+	}
+	text, files, err := readEmailBody(m.Header.Get("Content-Type"), m.Header.Get("Content-Transfer-Encoding"), m.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []*inboundEmail
+	for _, recipient := range n.Receipt.Recipients {
+		emails = append(emails, &inboundEmail{
+			recipient:  recipient,
+			from:       header("From"),
+			sender:     n.Mail.Source,
+			subject:    header("Subject"),
+			text:       stripQuotedReply(text),
+			messageID:  m.Header.Get("Message-Id"),
+			references: strings.Fields(m.Header.Get("In-Reply-To") + " " + m.Header.Get("References")),
+			files:      copyEmailFiles(files),
+		})
+	}
+	return emails, nil
+}
+
+// Copy attachments, so an email to several projects can be posted to each of them.
+func copyEmailFiles(files []*discordgo.File) []*discordgo.File {
+	var copies []*discordgo.File
+	for _, f := range files {
+		data, _ := io.ReadAll(f.Reader)
+		f.Reader = bytes.NewReader(data)
+		copies = append(copies, &discordgo.File{Name: f.Name, ContentType: f.ContentType, Reader: bytes.NewReader(data)})
+	}
+	return copies
+}
+
+// Read the plain text and attachments of an email body, going into nested multipart sections.
+func readEmailBody(contentType, encoding string, body io.Reader) (string, []*discordgo.File, error) {
+	body = decodeTransferEncoding(encoding, body)
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return "", nil, err
+		}
+		if mediaType != "text/plain" {
+			return "", nil, nil
+		}
+		return string(b), nil, nil
+	}
+
+	var text string
+	var files []*discordgo.File
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		if name := p.FileName(); name != "" {
+			data, err := io.ReadAll(decodeTransferEncoding(p.Header.Get("Content-Transfer-Encoding"), p))
+			if err != nil {
+				return "", nil, err
+			}
+			partType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+			files = append(files, &discordgo.File{Name: name, ContentType: partType, Reader: bytes.NewReader(data)})
+			continue
+		}
+		partText, partFiles, err := readEmailBody(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p)
+		if err != nil {
+			return "", nil, err
+		}
+		if text == "" {
+			text = partText
+		}
+		files = append(files, partFiles...)
+	}
+	return text, files, nil
+}
+
+// Decode a part's content transfer encoding.
+func decodeTransferEncoding(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(encoding) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// Cut the quoted email a reply ends with, like Mailgun's stripped-text: everything from the "On ... wrote:" line or
+// the first quoted line.
+func stripQuotedReply(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for n, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, ">") || strings.HasPrefix(line, "On ") && strings.HasSuffix(line, "wrote:") {
+			lines = lines[:n]
+			break
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
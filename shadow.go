@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The suffix of a project's internal channel, like #acme-internal for #acme.
+const internalChannelSuffix = "-internal"
+
+// Create the internal channel for a project next to its channel, visible only to Juiceworks members, and record it.
+func createInternalChannel(s *discordgo.Session, p project) (*discordgo.Channel, error) {
+	parentID := p.CategoryID
+	if channel, err := s.Channel(p.ChannelID); err == nil {
+		parentID = channel.ParentID
+	}
+	channel, err := s.GuildChannelCreateComplex(JuiceworksGuildId, discordgo.GuildChannelCreateData{
+		Name:     truncate(p.Name, 100-len(internalChannelSuffix)) + internalChannelSuffix,
+		Type:     discordgo.ChannelTypeGuildText,
+		Topic:    fmt.Sprintf("Internal discussion for <#%s>. Clients can't see this channel.", p.ChannelID),
+		ParentID: parentID,
+		PermissionOverwrites: []*discordgo.PermissionOverwrite{
+			{ID: JuiceworksRoleId, Type: discordgo.PermissionOverwriteTypeRole, Allow: discordgo.PermissionViewChannel | discordgo.PermissionSendMessages},
+			{ID: JuiceworksGuildId, Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionViewChannel},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = updateProject(p.ChannelID, func(p *project) {
+		p.InternalChannelID = channel.ID
+	})
+	return channel, err
+}
+
+// The project a channel belongs to, whether it's one of the project's own channels or its internal channel.
+func pairedProject(channelID string) (project, bool) {
+	if p, ok := getProject(workspaceProjectID(channelID)); ok {
+		return p, true
+	}
+	var p project
+	var ok bool
+	readStore(func(d *storeData) {
+		for _, stored := range d.Projects {
+			if stored.InternalChannelID == channelID {
+				p, ok = copyProject(stored), true
+				return
+			}
+		}
+	})
+	return p, ok
+}
+
+// Create an internal channel for the project the command was called from, unless it already has one.
+func internalChannelCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	p, ok := getProject(workspaceProjectID(i.ChannelID))
+	var content string
+	switch {
+	case !ok:
+		content = "This channel is not a registered project."
+	case p.InternalChannelID != "":
+		content = fmt.Sprintf("This project's internal channel is <#%s>.", p.InternalChannelID)
+	default:
+		channel, err := createInternalChannel(s, p)
+		if err != nil {
+			content = channelErrorContent(s, "creating internal channel", err, "Manage Channels and Manage Roles", channelLocation(p.CategoryID))
+			break
+		}
+		log.Printf("Created internal channel %s for project %s.", channel.ID, p.ChannelID)
+		content = fmt.Sprintf("Created <#%s> for internal discussion. Only Juiceworks members can see it.", channel.ID)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Record a key decision in both a project's channel and its internal channel, from whichever it's used in.
+func noteCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	text := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+	p, ok := pairedProject(i.ChannelID)
+
+	var problem string
+	switch {
+	case !ok:
+		problem = "This channel is not a registered project."
+	case p.InternalChannelID == "":
+		problem = "This project has no internal channel. Create one with /internal-channel."
+	case text == "":
+		problem = "The note can't be empty."
+	}
+	if problem != "" {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: problem,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	target := p.InternalChannelID
+	if i.ChannelID == p.InternalChannelID {
+		target = p.ChannelID
+	}
+	embed := themed(i.GuildID, &discordgo.MessageEmbed{
+		Title:       "📝 Note",
+		Description: truncate(text, 4096),
+		Footer:      &discordgo.MessageEmbedFooter{Text: "Noted by " + i.Member.User.Username},
+	})
+	content := fmt.Sprintf("Also shared in <#%s>.", target)
+	if _, err := s.ChannelMessageSendComplex(target, &discordgo.MessageSend{
+		Content: fmt.Sprintf("Noted in <#%s>:", i.ChannelID),
+		Embeds:  []*discordgo.MessageEmbed{embed},
+	}); err != nil {
+		log.Printf("Error sharing note: %v", err)
+		content = fmt.Sprintf("Couldn't share this in <#%s>: %s", target, describeError(err))
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Embeds:  []*discordgo.MessageEmbed{embed},
+		},
+	}))
+}
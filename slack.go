@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The largest Slack file the bridge will copy into Discord. Larger files are linked instead.
+const maxBridgedFileSize = 8 << 20
+
+// Bridges project channels to Slack. Messages from Slack are pushed to slackWebhook by the Events API.
+type slackBridge struct{}
+
+func (slackBridge) start(s *discordgo.Session) error {
+	return nil
+}
+
+// Post a message to a Slack channel under the author's name and avatar.
+func (slackBridge) send(room string, m *bridgeMessage) error {
+	return slackAPI(m.channelID, "chat.postMessage", url.Values{
+		"channel":  {room},
+		"text":     {m.text},
+		"username": {m.author},
+		"icon_url": {m.avatarURL},
+	}, nil)
+}
+
+// Call a Slack Web API method for a project's bridged channel and decode the response into out, which may be nil. The
+// project's slack secret is used if it has one, for channels bridged to the client's own workspace, and
+// SLACK_BOT_TOKEN if it doesn't.
+func slackAPI(channelID, method string, params url.Values, out any) (err error) {
+	defer func() { noteIntegrationCall("Slack", err) }()
+	req, err := http.NewRequest(http.MethodPost, "https://slack.com/api/"+method, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+integrationToken(channelID, "slack"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// Slack reports errors in the body rather than the status code.
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("slack %s failed: %s", method, result.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(body, out)
+	}
+	return nil
+}
+
+// A message event delivered by the Slack Events API.
+type slackMessage struct {
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+	Channel string `json:"channel"`
+	User    string `json:"user"`
+	BotID   string `json:"bot_id"`
+	Text    string `json:"text"`
+	Files   []struct {
+		Name       string `json:"name"`
+		Size       int    `json:"size"`
+		URLPrivate string `json:"url_private"`
+		Permalink  string `json:"permalink"`
+	} `json:"files"`
+}
+
+// Handle a Slack Events API request, relaying messages from bridged Slack channels into Discord.
+func slackWebhook(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	// Reject stale requests so a captured request can't be replayed.
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	if ts, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(ts, 0)).Abs() > 5*time.Minute {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	signature := strings.TrimPrefix(r.Header.Get("X-Slack-Signature"), "v0=")
+	body, ok := readSignedBody(r, os.Getenv("SLACK_SIGNING_SECRET"), "v0:"+timestamp+":", signature)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var envelope struct {
+		Type      string       `json:"type"`
+		Challenge string       `json:"challenge"`
+		Event     slackMessage `json:"event"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		log.Printf("Error decoding Slack event: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Slack verifies the endpoint by asking it to echo a challenge.
+	if envelope.Type == "url_verification" {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, envelope.Challenge)
+		return
+	}
+
+	// Slack retries events that aren't acknowledged within 3 seconds, so relay in the background.
+	w.WriteHeader(http.StatusOK)
+	ev := envelope.Event
+	if envelope.Type != "event_callback" || ev.Type != "message" || ev.BotID != "" {
+		return
+	}
+	if ev.Subtype != "" && ev.Subtype != "file_share" {
+		return
+	}
+	go relayFromSlack(s, &ev)
+}
+
+// Post a Slack message into its bridged Discord channel, copying any attached files.
+func relayFromSlack(s *discordgo.Session, ev *slackMessage) {
+	// Slack sends events for every channel the app is in. Only bridged ones are relayed, so don't look up authors or
+	// download files for any other.
+	channelID := bridgedChannel("slack", ev.Channel)
+	if channelID == "" {
+		return
+	}
+
+	// Look up the author's display name.
+	m := &bridgeMessage{channelID: channelID, author: ev.User, text: ev.Text}
+	var info struct {
+		User struct {
+			Profile struct {
+				DisplayName string `json:"display_name"`
+				RealName    string `json:"real_name"`
+				Image       string `json:"image_72"`
+			} `json:"profile"`
+		} `json:"user"`
+	}
+	if err := slackAPI(channelID, "users.info", url.Values{"user": {ev.User}}, &info); err != nil {
+		log.Printf("Error reading Slack user: %v", err)
+	} else if info.User.Profile.DisplayName != "" {
+		m.author = info.User.Profile.DisplayName
+	} else if info.User.Profile.RealName != "" {
+		m.author = info.User.Profile.RealName
+	}
+	m.avatarURL = info.User.Profile.Image
+
+	for _, f := range ev.Files {
+		data, err := downloadSlackFile(channelID, f.URLPrivate, f.Size)
+		if err != nil {
+			log.Printf("Error downloading Slack file %q: %v", f.Name, err)
+			m.text += "\n" + f.Permalink
+			continue
+		}
+		m.files = append(m.files, &discordgo.File{Name: f.Name, Reader: bytes.NewReader(data)})
+	}
+
+	relayFromBridge(s, "slack", ev.Channel, m)
+}
+
+// Download a private Slack file using the token for the channel it's bridged to.
+func downloadSlackFile(channelID, fileURL string, size int) ([]byte, error) {
+	if size > maxBridgedFileSize {
+		return nil, fmt.Errorf("file is too large to copy (%d bytes)", size)
+	}
+	req, err := http.NewRequest(http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+integrationToken(channelID, "slack"))
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("slack returned %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBridgedFileSize))
+}
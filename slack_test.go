@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// Messages from channels that aren't bridged are dropped before their files are downloaded with the bot's token.
+func TestSlackMessageFromUnbridgedChannel(t *testing.T) {
+	f, s := newTestBot(t)
+	var downloads atomic.Int32
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+	}))
+	defer files.Close()
+
+	var ev slackMessage
+	err := json.Unmarshal([]byte(`{"type": "message", "channel": "C123", "user": "U123", "text": "hello", "files": [
+		{"name": "notes.txt", "size": 5, "url_private": "`+files.URL+`/notes.txt"}]}`), &ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayFromSlack(s, &ev)
+
+	if n := downloads.Load(); n != 0 {
+		t.Errorf("%d files were downloaded, want none", n)
+	}
+	if requests, _ := f.history(); len(requests) != 0 {
+		t.Errorf("%d requests were made to Discord, want none", len(requests))
+	}
+}
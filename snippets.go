@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Snippet scopes: shared with the whole team, or only usable by the person who saved it.
+const (
+	snippetGuild    = "guild"
+	snippetPersonal = "personal"
+)
+
+// A canned response staff can send with /snippet send.
+type snippet struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	// The user who owns a personal snippet. Empty for guild snippets.
+	OwnerID   string    `json:"ownerId,omitempty"`
+	UpdatedBy string    `json:"updatedBy"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Placeholders in snippets, like {client}.
+var snippetPlaceholder = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// The key a snippet is stored under. Personal snippets are keyed by their owner too, so everyone can have their own
+// version of a name.
+func snippetKey(ownerID, name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if ownerID == "" {
+		return name
+	}
+	return ownerID + "/" + name
+}
+
+// Find the snippet a user means by a name: their own if they have one, otherwise the guild's.
+func findSnippet(userID, name string) (snippet, bool) {
+	var sn snippet
+	var ok bool
+	readStore(func(d *storeData) {
+		var stored *snippet
+		if stored, ok = d.Snippets[snippetKey(userID, name)]; !ok {
+			stored, ok = d.Snippets[snippetKey("", name)]
+		}
+		if ok {
+			sn = *stored
+		}
+	})
+	return sn, ok
+}
+
+// The values a snippet's placeholders are filled with in a channel. Values the project doesn't have are left out.
+func snippetValues(channelID, userID string) map[string]string {
+	values := map[string]string{
+		"channel": "<#" + channelID + ">",
+		"me":      "<@" + userID + ">",
+	}
+	p, ok := getProject(workspaceProjectID(channelID))
+	if !ok {
+		return values
+	}
+	values["project"] = p.Name
+	if p.ClientName != "" {
+		values["client"] = p.ClientName
+	}
+	if _, _, outstanding := milestoneTotals(p.Milestones); outstanding > 0 {
+		values["outstanding"] = formatAmount(outstanding)
+	}
+	// The deadline is the next unpaid milestone with a due date.
+	var next *milestone
+	for n, m := range p.Milestones {
+		if m.PaidAt.IsZero() && !m.Due.IsZero() && (next == nil || m.Due.Before(next.Due)) {
+			next = &p.Milestones[n]
+		}
+	}
+	if next != nil {
+		values["deadline"] = fmt.Sprintf("<t:%d:D>", next.Due.Unix())
+		values["milestone"] = next.Name
+	}
+	return values
+}
+
+// Fill in a snippet's placeholders, returning the placeholders that couldn't be filled.
+func fillSnippet(content string, values map[string]string) (string, []string) {
+	var missing []string
+	filled := snippetPlaceholder.ReplaceAllStringFunc(content, func(match string) string {
+		name := match[1 : len(match)-1]
+		if v, ok := values[name]; ok {
+			return v
+		}
+		if !slices.Contains(missing, match) {
+			missing = append(missing, match)
+		}
+		return match
+	})
+	return filled, missing
+}
+
+// Manage and send canned responses.
+func snippetCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+	scope := snippetPersonal
+	if o, ok := options["scope"]; ok {
+		scope = o.StringValue()
+	}
+	userID := i.Member.User.ID
+
+	var content string
+	switch sub.Name {
+	case "save":
+		// Snippets are usually several lines long, which slash command options can't hold.
+		name := strings.ToLower(strings.TrimSpace(options["name"].StringValue()))
+		value := ""
+		ownerID := userID
+		if scope == snippetGuild {
+			ownerID = ""
+		}
+		readStore(func(d *storeData) {
+			if existing, ok := d.Snippets[snippetKey(ownerID, name)]; ok {
+				value = existing.Content
+			}
+		})
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseModal,
+			Data: &discordgo.InteractionResponseData{
+				CustomID: "snippet-save:" + scope + ":" + name,
+				Title:    truncate("Snippet: "+name, 45),
+				Components: []discordgo.MessageComponent{
+					discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "content",
+							Label:       "Message",
+							Style:       discordgo.TextInputParagraph,
+							Placeholder: "Hi {client}, a reminder that {milestone} is due {deadline}.",
+							Value:       value,
+							Required:    true,
+							MaxLength:   2000,
+						},
+					}},
+				},
+			},
+		}))
+		return
+
+	case "send":
+		sn, ok := findSnippet(userID, options["name"].StringValue())
+		if !ok {
+			content = fmt.Sprintf("There's no snippet named %s.", options["name"].StringValue())
+			break
+		}
+		filled, missing := fillSnippet(sn.Content, snippetValues(i.ChannelID, userID))
+		if len(missing) > 0 {
+			content = fmt.Sprintf("This channel has nothing to fill %s with, so the snippet wasn't sent.", strings.Join(missing, ", "))
+			break
+		}
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:         filled,
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		}))
+		return
+
+	case "delete":
+		ownerID := userID
+		if scope == snippetGuild {
+			ownerID = ""
+		}
+		var found bool
+		err := updateStore(func(d *storeData) {
+			key := snippetKey(ownerID, options["name"].StringValue())
+			if _, found = d.Snippets[key]; found {
+				delete(d.Snippets, key)
+			}
+		})
+		switch {
+		case err != nil:
+			log.Printf("Error deleting snippet: %v", err)
+			content = "Error deleting snippet: " + describeError(err)
+		case !found:
+			content = fmt.Sprintf("There's no %s snippet named %s.", scope, options["name"].StringValue())
+		default:
+			content = fmt.Sprintf("Deleted the %s snippet %s.", scope, options["name"].StringValue())
+		}
+
+	case "list":
+		var guild, personal []string
+		readStore(func(d *storeData) {
+			for _, sn := range d.Snippets {
+				line := fmt.Sprintf("- **%s**: %s", sn.Name, truncate(strings.ReplaceAll(sn.Content, "\n", " "), 80))
+				switch sn.OwnerID {
+				case "":
+					guild = append(guild, line)
+				case userID:
+					personal = append(personal, line)
+				}
+			}
+		})
+		slices.Sort(guild)
+		slices.Sort(personal)
+		content = "No snippets have been saved yet. Placeholders you can use: {client}, {project}, {deadline}, {milestone}, {outstanding}, {channel} and {me}."
+		if len(guild)+len(personal) > 0 {
+			content = ""
+			if len(personal) > 0 {
+				content += "**Yours**\n" + strings.Join(personal, "\n") + "\n"
+			}
+			if len(guild) > 0 {
+				content += "**Shared**\n" + strings.Join(guild, "\n")
+			}
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: truncate(content, 2000),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Save a snippet from the /snippet save form.
+func saveSnippet(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	parts := strings.SplitN(data.CustomID, ":", 3)
+	scope, name := parts[1], parts[2]
+	sn := &snippet{
+		Name:      name,
+		Content:   data.Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value,
+		UpdatedBy: i.Member.User.ID,
+		UpdatedAt: time.Now(),
+	}
+	if scope == snippetPersonal {
+		sn.OwnerID = i.Member.User.ID
+	}
+
+	content := fmt.Sprintf("Saved the %s snippet %s. Send it with `/snippet send name:%s`.", scope, name, name)
+	err := updateStore(func(d *storeData) {
+		if d.Snippets == nil {
+			d.Snippets = make(map[string]*snippet)
+		}
+		d.Snippets[snippetKey(sn.OwnerID, name)] = sn
+	})
+	if err != nil {
+		log.Printf("Error saving snippet: %v", err)
+		content = "Error saving snippet: " + describeError(err)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
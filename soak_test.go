@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The interactions a soak test replays unless SOAK_PAYLOADS names a file of others: cheap commands that only read,
+// and ones that create channels and incidents in the store.
+const defaultSoakPayloads = `[
+	{"type": 2, "data": {"type": 1, "name": "help"}},
+	{"type": 2, "data": {"type": 1, "name": "project-info"}},
+	{"type": 2, "data": {"type": 1, "name": "upcoming"}},
+	{"type": 2, "data": {"type": 1, "name": "escalate", "options": [{"type": 3, "name": "reason", "value": "soak test"}]}},
+	{"type": 2, "data": {"type": 1, "name": "make-channel", "options": [{"type": 3, "name": "channel-name", "value": "soak test"}]}}
+]`
+
+// How many staff members synthetic interactions come from, so per-user cooldowns don't throttle the test.
+const soakMembers = 50
+
+// How often a soak test reports how it's going.
+const soakReportInterval = 10 * time.Second
+
+// How often a soak test runs background jobs, so they race with handlers over the same project.
+const soakJobInterval = 100 * time.Millisecond
+
+// Measurements from a soak test, reset after each report.
+type soakStats struct {
+	mu        sync.Mutex
+	sent      int
+	completed int
+	panics    int
+	inFlight  int
+	peak      int
+	latencies []time.Duration
+}
+
+// Read SOAK_RATE, SOAK_DURATION and SOAK_PAYLOADS for a soak test.
+func soakSettings() (rate int, duration time.Duration, payloads []*discordgo.Interaction, err error) {
+	rate, duration = 20, time.Minute
+	if v := os.Getenv("SOAK_RATE"); v != "" {
+		if rate, err = strconv.Atoi(v); err != nil || rate <= 0 {
+			return 0, 0, nil, fmt.Errorf("SOAK_RATE must be a positive number of interactions a second, not %q", v)
+		}
+	}
+	if v := os.Getenv("SOAK_DURATION"); v != "" {
+		if duration, err = time.ParseDuration(v); err != nil || duration <= 0 {
+			return 0, 0, nil, fmt.Errorf("SOAK_DURATION must be a positive duration like 5m, not %q", v)
+		}
+	}
+
+	raw := []byte(defaultSoakPayloads)
+	if path := os.Getenv("SOAK_PAYLOADS"); path != "" {
+		if raw, err = os.ReadFile(path); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+	if err = json.Unmarshal(raw, &payloads); err != nil {
+		return 0, 0, nil, fmt.Errorf("payloads must be a JSON array of interactions: %w", err)
+	}
+	if len(payloads) == 0 {
+		return 0, 0, nil, fmt.Errorf("there are no payloads to replay")
+	}
+	return rate, duration, payloads, nil
+}
+
+// Replay synthetic interactions through the same dispatch as real ones, at SOAK_RATE a second for SOAK_DURATION,
+// against a fake Discord, and report throughput, latency, how many interactions are in flight at once, and memory.
+// Each interaction runs in its own goroutine, the way discordgo delivers them, while background jobs that only touch
+// the store run alongside. It only runs with SOAK_TEST=true, like:
+//
+//	SOAK_TEST=true SOAK_DURATION=5m go test -race -v -run TestSoak
+//
+// Memory includes the fake's record of every request, so compare runs of the same length. The store is a temporary
+// file, and nothing reaches Discord, but integrations configured in the environment are still called, so run it
+// without them.
+func TestSoak(t *testing.T) {
+	if os.Getenv("SOAK_TEST") != "true" {
+		t.Skip("set SOAK_TEST=true to run the soak test")
+	}
+	rate, duration, payloads, err := soakSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, s := newTestBot(t)
+	channelID := f.addChannel("soak", "")
+	if err := updateProject(channelID, func(p *project) { p.Name = "soak" }); err != nil {
+		t.Fatal(err)
+	}
+	var members []*discordgo.Member
+	for n := range soakMembers {
+		m := f.addMember(fmt.Sprintf("%d", 2000000000000000000+n), fmt.Sprintf("soak-%d", n), JuiceworksRoleId)
+		m.Permissions = discordgo.PermissionAdministrator
+		members = append(members, m)
+	}
+
+	// Handlers log every failure, which would drown out the report.
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	t.Logf("Replaying %d payloads at %d a second for %s.", len(payloads), rate, duration)
+
+	var stats soakStats
+	var wg sync.WaitGroup
+	start := time.Now()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for jobs := time.NewTicker(soakJobInterval); ; {
+			select {
+			case <-done:
+				jobs.Stop()
+				return
+			case <-jobs.C:
+				checkEscalationSLAs(s)
+				purgeExpired(true)
+			}
+		}
+	}()
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	report := time.NewTicker(soakReportInterval)
+	defer report.Stop()
+	panics := 0
+	for n := 0; time.Since(start) < duration; {
+		select {
+		case <-report.C:
+			panics += stats.report(t, time.Since(start))
+		case <-ticker.C:
+			i := *payloads[n%len(payloads)]
+			i.ID = fmt.Sprintf("%d", 3000000000000000000+n)
+			i.Token = "soak-" + i.ID
+			i.AppID, i.GuildID = f.botUser.ID, JuiceworksGuildId
+			if i.ChannelID == "" {
+				i.ChannelID = channelID
+			}
+			if i.Member == nil {
+				i.Member = members[n%len(members)]
+			}
+			n++
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				stats.run(t, s, &discordgo.InteractionCreate{Interaction: &i})
+			}()
+		}
+	}
+	wg.Wait()
+	panics += stats.report(t, time.Since(start))
+
+	requests, responses := f.history()
+	t.Logf("Done. Discord got %d requests and %d interaction responses.", len(requests), len(responses))
+	if panics > 0 {
+		t.Errorf("%d handlers panicked", panics)
+	}
+}
+
+// Dispatch an interaction and time it.
+func (st *soakStats) run(t testing.TB, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	st.mu.Lock()
+	st.sent++
+	st.inFlight++
+	st.peak = max(st.peak, st.inFlight)
+	st.mu.Unlock()
+	start := time.Now()
+	defer func() {
+		r := recover()
+		elapsed := time.Since(start)
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		st.inFlight--
+		if r != nil {
+			st.panics++
+			t.Logf("Handler panicked on %s: %v", interactionName(i), r)
+			return
+		}
+		st.completed++
+		st.latencies = append(st.latencies, elapsed)
+	}()
+	handleInteraction(s, i)
+}
+
+// Log what happened since the last report, and reset. Returns how many handlers panicked.
+func (st *soakStats) report(t testing.TB, elapsed time.Duration) int {
+	st.mu.Lock()
+	sent, completed, panics, inFlight, peak, latencies := st.sent, st.completed, st.panics, st.inFlight, st.peak, st.latencies
+	st.sent, st.completed, st.panics, st.peak, st.latencies = 0, 0, 0, st.inFlight, nil
+	st.mu.Unlock()
+
+	slices.Sort(latencies)
+	percentile := func(p int) time.Duration {
+		if len(latencies) == 0 {
+			return 0
+		}
+		return latencies[(len(latencies)-1)*p/100]
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	t.Logf("%6s  sent %d, done %d, panicked %d, in flight %d (peak %d), p50 %s, p99 %s, max %s, heap %d MiB, %d goroutines",
+		elapsed.Round(time.Second), sent, completed, panics, inFlight, peak,
+		percentile(50).Round(time.Microsecond), percentile(99).Round(time.Microsecond), percentile(100).Round(time.Microsecond),
+		mem.HeapAlloc>>20, runtime.NumGoroutine())
+	return panics
+}
+
+// The command or component an interaction is for, for reporting.
+func interactionName(i *discordgo.InteractionCreate) string {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		return "/" + i.ApplicationCommandData().Name
+	case discordgo.InteractionMessageComponent:
+		return i.MessageComponentData().CustomID
+	case discordgo.InteractionModalSubmit:
+		return i.ModalSubmitData().CustomID
+	}
+	return i.Type.String()
+}
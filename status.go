@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The stages a project goes through, in order.
+const (
+	statusLead     = "lead"
+	statusScoping  = "scoping"
+	statusActive   = "active"
+	statusReview   = "review"
+	statusDone     = "done"
+	statusArchived = "archived"
+)
+
+// The statuses a project can move to from each status. Projects can step back when scoping falls through, review
+// turns up more work, or a finished project is picked up again.
+var statusTransitions = map[string][]string{
+	statusLead:     {statusScoping, statusArchived},
+	statusScoping:  {statusActive, statusLead, statusArchived},
+	statusActive:   {statusReview},
+	statusReview:   {statusDone, statusActive},
+	statusDone:     {statusArchived, statusActive},
+	statusArchived: {},
+}
+
+// How long a project stays done before staff are asked to archive it, and how often that's checked.
+const (
+	archivePromptDelay    = 14 * 24 * time.Hour
+	archivePromptInterval = time.Hour
+)
+
+// A project's status. Projects made before statuses existed are active.
+func projectStatus(p project) string {
+	if p.Status == "" {
+		return statusActive
+	}
+	return p.Status
+}
+
+// Move the project the command was called from to a new status, if it can get there from its current one.
+func statusCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	projectID := workspaceProjectID(i.ChannelID)
+	p, ok := getProject(projectID)
+	options := i.ApplicationCommandData().Options
+
+	var content string
+	switch {
+	case !ok:
+		content = "This channel is not a registered project."
+	case len(options) == 0:
+		current := projectStatus(p)
+		content = fmt.Sprintf("This project is **%s**, since <t:%d:R>.", current, p.StatusChangedAt.Unix())
+		if p.StatusChangedAt.IsZero() {
+			content = fmt.Sprintf("This project is **%s**.", current)
+		}
+		if next := statusTransitions[current]; len(next) > 0 {
+			content += " It can move to " + strings.Join(next, " or ") + "."
+		}
+	default:
+		content = changeStatus(s, p, options[0].StringValue(), i.Member.User.ID)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Move a project to a new status, announce it, and run the new status's automations. Returns the response to show
+// the caller.
+func changeStatus(s *discordgo.Session, p project, status, userID string) string {
+	current := projectStatus(p)
+	if !slices.Contains(statusTransitions[current], status) {
+		if next := statusTransitions[current]; len(next) > 0 {
+			return fmt.Sprintf("A project can't go from %s to %s. From %s it can move to %s.", current, status, current, strings.Join(next, " or "))
+		}
+		return fmt.Sprintf("A project can't go from %s to %s.", current, status)
+	}
+
+	err := updateProject(p.ChannelID, func(p *project) {
+		p.Status = status
+		p.StatusChangedAt = time.Now()
+		p.ArchivePrompted = false
+	})
+	if err != nil {
+		log.Printf("Error saving project status: %v", err)
+		return "Error saving project status: " + describeError(err)
+	}
+	log.Printf("Moved project %s from %s to %s.", p.ChannelID, current, status)
+	emitEvent("project.status_changed", projectStatusChangedEvent{ChannelID: p.ChannelID, From: current, To: status, ChangedBy: userID})
+
+	announcement := fmt.Sprintf("<@%s> moved this project from **%s** to **%s**.", userID, current, status)
+	if status == statusDone {
+		announcement += fmt.Sprintf(" Staff will be asked to archive it in %d days.", int(archivePromptDelay.Hours()/24))
+	}
+	_, err = s.ChannelMessageSendComplex(p.ChannelID, &discordgo.MessageSend{
+		Content:         announcement,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		recordFailure("announcing project status", err)
+	}
+
+	if status == statusArchived {
+		if err := archiveChannel(s, p, fmt.Sprintf("This project was archived <t:%d:R>.", time.Now().Unix())); err != nil {
+			log.Printf("Error archiving channel: %v", err)
+			return fmt.Sprintf("Moved the project to %s, but couldn't archive the channel: %s", status, describeError(err))
+		}
+	}
+	return fmt.Sprintf("Moved the project to %s.", status)
+}
+
+// Check for projects that have been done long enough to archive.
+func startArchivePrompts(s *discordgo.Session) {
+	go func() {
+		for range time.Tick(archivePromptInterval) {
+			if !paused("archive prompts") {
+				promptArchival(s)
+			}
+		}
+	}()
+}
+
+// Ask staff in the internal channel whether to archive projects that have been done for a while. Each project is only
+// asked about once per time it's done.
+func promptArchival(s *discordgo.Session) {
+	var due []project
+	err := updateStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if p.Status == statusDone && !p.ArchivePrompted && time.Since(p.StatusChangedAt) > archivePromptDelay {
+				p.ArchivePrompted = true
+				due = append(due, copyProject(p))
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving archive prompts: %v", err)
+		return
+	}
+
+	for _, p := range due {
+		_, err := s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{
+			Content: fmt.Sprintf("<#%s> has been done since <t:%d:D>. Archive it?", p.ChannelID, p.StatusChangedAt.Unix()),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "Archive", Style: discordgo.DangerButton, CustomID: "status-archive:" + p.ChannelID},
+				}},
+			},
+		})
+		if err != nil {
+			recordFailure("sending archive prompt", err)
+		}
+	}
+}
+
+// Archive the project from an archive prompt.
+func archiveFromPrompt(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, channelID, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	content := "That project no longer exists."
+	if p, ok := getProject(channelID); ok {
+		content = changeStatus(s, p, statusArchived, i.Member.User.ID)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    fmt.Sprintf("<#%s>: %s", channelID, content),
+			Components: []discordgo.MessageComponent{},
+		},
+	}))
+}
+
+// Archive a project's channel: take away the members' access, move it to ARCHIVE_CATEGORY_ID if it's set, and leave
+// a note for anyone still there.
+func archiveChannel(s *discordgo.Session, p project, note string) error {
+	for userID := range p.Members {
+		if err := s.ChannelPermissionDelete(p.ChannelID, userID); err != nil {
+			return err
+		}
+	}
+	edit := &discordgo.ChannelEdit{Name: truncate("archived-"+p.Name, 100)}
+	if categoryID := os.Getenv("ARCHIVE_CATEGORY_ID"); categoryID != "" {
+		edit.ParentID = categoryID
+	}
+	if _, err := s.ChannelEdit(p.ChannelID, edit); err != nil {
+		return err
+	}
+	if _, err := s.ChannelMessageSend(p.ChannelID, note); err != nil {
+		return err
+	}
+
+	// The internal channel goes with it.
+	if p.InternalChannelID != "" {
+		edit.Name = truncate("archived-"+p.Name+internalChannelSuffix, 100)
+		if _, err := s.ChannelEdit(p.InternalChannelID, edit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// Everything the bot persists between restarts. The whole struct is saved as JSON to DATA_FILE on every update.
+type storeData struct {
+	// The project registry, keyed by channel ID.
+	Projects map[string]*project `json:"projects,omitempty"`
+	// Rooms on other platforms bridged to each Discord channel, keyed by Discord channel ID.
+	Bridges map[string]bridgeConfig `json:"bridges,omitempty"`
+	// Email conversations posted by the email gateway, keyed by thread ID.
+	EmailThreads map[string]*emailThread `json:"emailThreads,omitempty"`
+	// Clients receiving the weekly digest, keyed by channel ID.
+	DigestRecipients map[string]*digestRecipient `json:"digestRecipients,omitempty"`
+	// REST hook subscriptions, keyed by subscription ID.
+	HookSubscriptions map[string]*hookSubscription `json:"hookSubscriptions,omitempty"`
+	// IDs of the events the bot put in the shared calendar for each project, keyed by channel ID.
+	CalendarEvents map[string][]string `json:"calendarEvents,omitempty"`
+	// Staff members' own scheduling links for /book, keyed by user ID.
+	BookingLinks map[string]string `json:"bookingLinks,omitempty"`
+	// Members' standard hourly rates, keyed by user ID.
+	RateCards map[string]*rateCard `json:"rateCards,omitempty"`
+	// The terms external users have to accept before they're added to a project.
+	Terms string `json:"terms,omitempty"`
+	// Users' acceptances of the terms, keyed by user ID.
+	TermsAcceptances map[string]*termsAcceptance `json:"termsAcceptances,omitempty"`
+	// The self-service role menu.
+	RoleMenu roleMenu `json:"roleMenu"`
+	// Messages members react to for roles, keyed by message ID.
+	ReactionRoles map[string]*reactionRoleMessage `json:"reactionRoles,omitempty"`
+	// Recently emitted events, oldest first.
+	EventLog []loggedEvent `json:"eventLog,omitempty"`
+	// Recently failed background operations, oldest first.
+	Failures []failure `json:"failures,omitempty"`
+	// When the team is working. Nil means always.
+	BusinessHours *businessHours `json:"businessHours,omitempty"`
+	// Channels and categories commands are allowed or denied in, keyed by command name.
+	CommandChannels map[string]*channelRules `json:"commandChannels,omitempty"`
+	// What quarantined users had before they were quarantined, keyed by user ID.
+	Quarantines map[string]*quarantine `json:"quarantines,omitempty"`
+	// Open huddles, keyed by voice channel ID.
+	Huddles map[string]*huddle `json:"huddles,omitempty"`
+	// The scheduled or running town hall, if any.
+	Townhall *townhall `json:"townhall,omitempty"`
+	// Templates new projects can be set up from, keyed by lowercase name.
+	ProjectTemplates map[string]*projectTemplate `json:"projectTemplates,omitempty"`
+	// The client directory, keyed by lowercase name.
+	Clients map[string]*client `json:"clients,omitempty"`
+	// Canned responses. Shared snippets are keyed by lowercase name, and personal ones by owner ID and name.
+	Snippets map[string]*snippet `json:"snippets,omitempty"`
+	// Messages people saved for later, keyed by user ID, oldest first.
+	Bookmarks map[string][]bookmark `json:"bookmarks,omitempty"`
+	// Messages people asked to be reminded about, until they're sent.
+	Reminders []reminder `json:"reminders,omitempty"`
+	// Incidents, in the order they were opened.
+	Incidents []*incident `json:"incidents,omitempty"`
+	// Client portal accounts linked to Discord accounts, keyed by Discord user ID.
+	AccountLinks map[string]accountLink `json:"accountLinks,omitempty"`
+	// Tokens for the API, keyed by ID.
+	APITokens map[string]*apiToken `json:"apiTokens,omitempty"`
+	// Audited actions, oldest first.
+	AuditLog []auditEntry `json:"auditLog,omitempty"`
+	// When channels that look like projects but aren't in the registry were reported to staff, keyed by channel ID.
+	OrphansReported map[string]time.Time `json:"orphansReported,omitempty"`
+	// Leads from /work-with-us, in the order they came in.
+	Leads []*lead `json:"leads,omitempty"`
+	// Maintenance mode, if it's been turned on with /maintenance.
+	Maintenance *maintenance `json:"maintenance,omitempty"`
+	// Background jobs that failed, oldest first, and the last ID given to a job.
+	Jobs      []*job `json:"jobs,omitempty"`
+	LastJobID int    `json:"lastJobId,omitempty"`
+	// How the bot's embeds look, keyed by guild ID.
+	Themes map[string]*embedTheme `json:"themes,omitempty"`
+	// Message templates changed with /templates, keyed by name.
+	MessageTemplates map[string]string `json:"messageTemplates,omitempty"`
+}
+
+var (
+	storeMu sync.Mutex
+	store   storeData
+)
+
+// The path of the data file, defaulting to data.json in the working directory.
+func storePath() string {
+	if p := os.Getenv("DATA_FILE"); p != "" {
+		return p
+	}
+	return "data.json"
+}
+
+// Load the data file into memory. A missing file is treated as an empty store.
+func loadStore() error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	b, err := os.ReadFile(storePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &store)
+}
+
+// Read from the store while holding its lock. Handlers and background jobs all share the store, so nothing read from
+// it may be kept past f: copy values out, like getProject does, instead of keeping pointers, maps or slices that a
+// later update could change underneath.
+func readStore(f func(d *storeData)) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	f(&store)
+}
+
+// Modify the store while holding its lock, then save it. Read-modify-write has to happen inside f, not around it, or
+// a concurrent update in between is lost. The file is written to a temporary path and renamed so a
+// crash mid-write can't corrupt it.
+func updateStore(f func(d *storeData)) error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	f(&store)
+
+	b, err := json.MarshalIndent(&store, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := storePath() + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, storePath())
+}
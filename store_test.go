@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How many of each thing the concurrency test does at once.
+const concurrentWorkers = 20
+
+// Handlers and background jobs share the store, so run them all at once against the same project and check nothing
+// was lost along the way. Run it with -race to check the locking too.
+func TestConcurrentStateAccess(t *testing.T) {
+	f, s := newTestBot(t)
+	// Every escalation is past its response time, so the SLA check changes the store as handlers do.
+	t.Setenv("ESCALATION_RESPONSE_TIME", "1ns")
+	channelID := addProject(t, f, "acme")
+
+	var staff, clients []*discordgo.Member
+	for n := range concurrentWorkers {
+		staff = append(staff, f.addMember(fmt.Sprint(2000000000000000000+n), fmt.Sprintf("staff-%d", n), JuiceworksRoleId))
+		client := f.addMember(fmt.Sprint(3000000000000000000+n), fmt.Sprintf("client-%d", n))
+		clients = append(clients, client)
+		err := updateProject(channelID, func(p *project) {
+			if p.Members == nil {
+				p.Members = make(map[string]string)
+			}
+			p.Members[client.User.ID] = client.User.Username
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for n := range concurrentWorkers {
+		wg.Add(6)
+		go func() {
+			defer wg.Done()
+			handleInteraction(s, commandInteraction(channelID, clients[n], "escalate", stringOption("reason", "The site is down")))
+		}()
+		go func() {
+			defer wg.Done()
+			handleInteraction(s, commandInteraction(channelID, staff[n], "milestone",
+				subcommand("add", stringOption("name", fmt.Sprintf("Milestone %d", n)), numberOption("amount", 100))))
+		}()
+		go func() {
+			defer wg.Done()
+			handleInteraction(s, commandInteraction(channelID, staff[n], "project-info"))
+		}()
+		go func() {
+			defer wg.Done()
+			err := updateProject(channelID, func(p *project) {
+				p.Pins = append(p.Pins, pinnedMessage{MessageID: fmt.Sprint(4000000000000000000 + n)})
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			checkEscalationSLAs(s)
+		}()
+		go func() {
+			defer wg.Done()
+			purgeExpired(false)
+		}()
+	}
+	wg.Wait()
+	checkEscalationSLAs(s)
+
+	p, _ := getProject(channelID)
+	if len(p.Milestones) != concurrentWorkers {
+		t.Errorf("the project has %d milestones, want %d", len(p.Milestones), concurrentWorkers)
+	}
+	if len(p.Pins) != concurrentWorkers {
+		t.Errorf("the project has %d pins, want %d", len(p.Pins), concurrentWorkers)
+	}
+	if len(p.Members) != concurrentWorkers {
+		t.Errorf("the project has %d members, want %d", len(p.Members), concurrentWorkers)
+	}
+	var incidents []incident
+	readStore(func(d *storeData) {
+		for _, inc := range d.Incidents {
+			incidents = append(incidents, *inc)
+		}
+	})
+	if len(incidents) != 1 {
+		t.Fatalf("%d escalations were opened, want 1", len(incidents))
+	}
+	if !incidents[0].SLABreached {
+		t.Error("the escalation wasn't marked as past its response time")
+	}
+	_, responses := f.history()
+	answered := 0
+	for _, r := range responses {
+		if r.Kind == "response" {
+			answered++
+		}
+	}
+	if answered != 3*concurrentWorkers {
+		t.Errorf("%d interactions were answered, want %d", answered, 3*concurrentWorkers)
+	}
+
+	// The data file has to match memory, or a restart would lose changes.
+	b, err := os.ReadFile(storePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved storeData
+	if err := json.Unmarshal(b, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.Projects[channelID]; got == nil || len(got.Milestones) != concurrentWorkers || len(got.Pins) != concurrentWorkers {
+		t.Error("the data file is missing changes that are in memory")
+	}
+	if len(saved.Incidents) != 1 || !saved.Incidents[0].SLABreached {
+		t.Errorf("the data file has %d escalations, want 1 past its response time", len(saved.Incidents))
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+// How many events can queue for a stream client before it's considered too slow and disconnected, and how often
+// idle connections are pinged to keep proxies from closing them.
+const (
+	streamBuffer       = 64
+	streamPingInterval = 30 * time.Second
+)
+
+// An event as sent to stream clients.
+type streamEvent struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+	At    time.Time       `json:"at"`
+}
+
+// A connected stream client, and the events it asked for. No events means all of them.
+type streamClient struct {
+	events []string
+	send   chan streamEvent
+}
+
+// The connected stream clients.
+var (
+	streamMu      sync.Mutex
+	streamClients = make(map[*streamClient]bool)
+)
+
+var streamUpgrader = websocket.Upgrader{
+	// Clients authenticate with the API key rather than cookies, so cross-origin connections are safe.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Stream events to a WebSocket client as they're emitted. Clients can limit the stream to some events with a
+// comma-separated events query parameter, like ?events=project.created,member.added.
+func eventStream(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	var events []string
+	for _, event := range strings.Split(r.URL.Query().Get("events"), ",") {
+		if event = strings.TrimSpace(event); event == "" {
+			continue
+		}
+		if _, ok := hookEvents[event]; !ok {
+			http.Error(w, "unknown event "+event, http.StatusBadRequest)
+			return
+		}
+		events = append(events, event)
+	}
+
+	conn, err := streamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Error opening event stream: %v", err)
+		return
+	}
+	client := &streamClient{events: events, send: make(chan streamEvent, streamBuffer)}
+	streamMu.Lock()
+	streamClients[client] = true
+	streamMu.Unlock()
+	log.Printf("Event stream opened from %s.", r.RemoteAddr)
+
+	// Read in the background so closes and pongs are handled. Clients aren't expected to send anything.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer func() {
+		ping.Stop()
+		streamMu.Lock()
+		delete(streamClients, client)
+		streamMu.Unlock()
+		conn.Close()
+		log.Printf("Event stream from %s closed.", r.RemoteAddr)
+	}()
+	for {
+		select {
+		case e, ok := <-client.send:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too slow"), time.Now().Add(time.Second))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// Send an event to every stream client that wants it. Clients that have fallen too far behind are disconnected
+// rather than holding up the bot.
+func streamEmit(event string, body []byte) {
+	e := streamEvent{Event: event, Data: body, At: time.Now()}
+	streamMu.Lock()
+	defer streamMu.Unlock()
+	for client := range streamClients {
+		if len(client.events) > 0 && !slices.Contains(client.events, event) {
+			continue
+		}
+		select {
+		case client.send <- e:
+		default:
+			close(client.send)
+			delete(streamClients, client)
+		}
+	}
+}
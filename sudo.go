@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long /sudo lets someone run the command they asked for.
+const sudoLifetime = 15 * time.Minute
+
+// How long the confirmation button on a /sudo request keeps working.
+const sudoConfirmLifetime = 5 * time.Minute
+
+// Someone's permission to run one command their roles normally don't let them, with why they need it.
+type elevation struct {
+	userID    string
+	command   string
+	reason    string
+	createdAt time.Time
+	expiresAt time.Time
+}
+
+// Elevations waiting to be confirmed, keyed by the ID of the /sudo interaction, and elevations in effect, keyed by
+// user ID. These only live in memory, so a restart ends every elevation, which errs on the safe side.
+var (
+	sudoMu      sync.Mutex
+	pendingSudo = make(map[string]elevation)
+	activeSudo  = make(map[string]elevation)
+)
+
+// Whether a user has an elevation in effect for a command.
+func elevated(userID, command string) bool {
+	sudoMu.Lock()
+	defer sudoMu.Unlock()
+	e, ok := activeSudo[userID]
+	return ok && e.command == command && time.Now().Before(e.expiresAt)
+}
+
+// The command an interaction belongs to, for elevations: the command itself, or for a component, the command whose
+// response it's on, so an elevated command's confirmation buttons work too.
+func elevatedCommand(i *discordgo.InteractionCreate) string {
+	if i.Type == discordgo.InteractionApplicationCommand {
+		return resolveCommand(baseCommandName(i.ApplicationCommandData().Name))
+	}
+	if i.Message == nil || i.Message.Interaction == nil {
+		return ""
+	}
+	name, _, _ := strings.Cut(i.Message.Interaction.Name, " ")
+	return resolveCommand(baseCommandName(name))
+}
+
+// Let a member of SUDO_ROLE_ID run a command their roles normally don't let them, for a short while, once they've
+// given a reason and confirmed it. It's recorded in the audit log, and so is every use of it.
+func sudoCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range i.ApplicationCommandData().Options {
+		options[o.Name] = o
+	}
+	command := strings.TrimPrefix(strings.TrimSpace(options["command"].StringValue()), "/")
+	reason := strings.TrimSpace(options["reason"].StringValue())
+
+	p, ok := commandPolicies[command]
+	roleID := os.Getenv("SUDO_ROLE_ID")
+	var problem string
+	switch {
+	case roleID == "":
+		problem = "Sudo isn't set up. Set SUDO_ROLE_ID to the role allowed to use it."
+	case !slices.Contains(i.Member.Roles, roleID):
+		problem = fmt.Sprintf("Only members of <@&%s> can use sudo.", roleID)
+	case !ok:
+		problem = fmt.Sprintf("There's no /%s command.", command)
+	case command == "sudo":
+		problem = "Sudo can't elevate itself."
+	case roleDenial(i, p) == "":
+		problem = fmt.Sprintf("You can already use /%s.", command)
+	case reason == "":
+		problem = "Give a reason for needing sudo."
+	}
+	if problem != "" {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: problem,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	sudoMu.Lock()
+	for id, e := range pendingSudo {
+		if time.Since(e.createdAt) > sudoConfirmLifetime {
+			delete(pendingSudo, id)
+		}
+	}
+	pendingSudo[i.ID] = elevation{userID: i.Member.User.ID, command: command, reason: reason, createdAt: time.Now()}
+	sudoMu.Unlock()
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("This lets you run /%s for %s, past the roles it normally needs. Confirming it, and every "+
+				"use of it, is recorded in the audit log with your reason:\n> %s", command, formatWait(sudoLifetime), reason),
+			Flags: discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "Confirm sudo", Style: discordgo.DangerButton, CustomID: "sudo-confirm:" + i.ID},
+				}},
+			},
+		},
+	}))
+}
+
+// Put an elevation into effect once its requester confirms it.
+func confirmSudo(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, id, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	sudoMu.Lock()
+	e, ok := pendingSudo[id]
+	if ok && e.userID == i.Member.User.ID && time.Since(e.createdAt) <= sudoConfirmLifetime {
+		delete(pendingSudo, id)
+		e.expiresAt = time.Now().Add(sudoLifetime)
+		activeSudo[e.userID] = e
+	} else {
+		ok = false
+	}
+	sudoMu.Unlock()
+	if !ok {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "This sudo request has expired. Run /sudo again.",
+				Components: []discordgo.MessageComponent{},
+			},
+		}))
+		return
+	}
+
+	log.Printf("%s used sudo for %s: %s", i.Member.User, e.command, e.reason)
+	postAudit(s, auditEntry{
+		Action:    auditSudo,
+		ActorID:   e.userID,
+		ChannelID: i.ChannelID,
+		Summary: fmt.Sprintf("🔓 **Sudo:** <@%s> can run /%s until <t:%d:t>. Reason: %s", e.userID, e.command,
+			e.expiresAt.Unix(), e.reason),
+	})
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("You can run /%s until <t:%d:t>. If you can't see it, an administrator has to let <@&%s> use "+
+				"it in the server's Integrations settings.", e.command, e.expiresAt.Unix(), os.Getenv("SUDO_ROLE_ID")),
+			Components: []discordgo.MessageComponent{},
+		},
+	}))
+}
+
+// Record a command run with sudo in the audit log.
+func auditSudoUse(s *discordgo.Session, i *discordgo.InteractionCreate, name string) {
+	postAudit(s, auditEntry{
+		Action:    auditSudo,
+		ActorID:   i.Member.User.ID,
+		ChannelID: i.ChannelID,
+		Summary:   fmt.Sprintf("🔓 **Sudo:** %s ran `%s` in <#%s>.", i.Member.User.Mention(), name, i.ChannelID),
+	})
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How a new project channel is set up when /make-channel is given a template.
+type projectTemplate struct {
+	// The category the channel is created in, if any.
+	CategoryID string `json:"categoryId,omitempty"`
+	// The channel topic. {name} is replaced with the channel's name.
+	Topic string `json:"topic,omitempty"`
+	// Roles that can see the channel, besides the Juiceworks role.
+	RoleIDs []string `json:"roleIds,omitempty"`
+	// Messages posted and pinned in the channel, in order.
+	Pins []string `json:"pins,omitempty"`
+	// Whether the huddle button is posted.
+	HuddleButton bool      `json:"huddleButton"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	UpdatedBy    string    `json:"updatedBy"`
+}
+
+// Look up a project template by name.
+func getTemplate(name string) (projectTemplate, bool) {
+	var t projectTemplate
+	var ok bool
+	readStore(func(d *storeData) {
+		var p *projectTemplate
+		if p, ok = d.ProjectTemplates[strings.ToLower(name)]; ok {
+			t = *p
+			t.RoleIDs = slices.Clone(p.RoleIDs)
+			t.Pins = slices.Clone(p.Pins)
+		}
+	})
+	return t, ok
+}
+
+// Post and pin a template's messages in a new project channel. Failures are recorded rather than reported, since the
+// channel has already been created.
+func postTemplatePins(s *discordgo.Session, channelID string, t projectTemplate) {
+	for _, content := range t.Pins {
+		m, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content:         content,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err == nil {
+			err = s.ChannelMessagePin(channelID, m.ID)
+		}
+		if err != nil {
+			recordFailure("posting template pin", err)
+		}
+	}
+}
+
+// Describe a template for /project-template list.
+func describeTemplate(name string, t *projectTemplate) string {
+	var parts []string
+	if t.CategoryID != "" {
+		parts = append(parts, "in <#"+t.CategoryID+">")
+	}
+	if len(t.RoleIDs) > 0 {
+		roles := make([]string, len(t.RoleIDs))
+		for n, id := range t.RoleIDs {
+			roles[n] = "<@&" + id + ">"
+		}
+		parts = append(parts, "shared with "+strings.Join(roles, ", "))
+	}
+	if len(t.Pins) > 0 {
+		parts = append(parts, fmt.Sprintf("%d pinned messages", len(t.Pins)))
+	}
+	if !t.HuddleButton {
+		parts = append(parts, "no huddle button")
+	}
+	if t.Topic != "" {
+		parts = append(parts, fmt.Sprintf("topic %q", truncate(t.Topic, 100)))
+	}
+	if len(parts) == 0 {
+		return "- **" + name + "**"
+	}
+	return "- **" + name + "**: " + strings.Join(parts, ", ")
+}
+
+// Manage the templates /make-channel can set projects up from.
+func projectTemplateCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+	var name string
+	if o, ok := options["name"]; ok {
+		name = strings.ToLower(strings.TrimSpace(o.StringValue()))
+	}
+
+	// Change an existing template, reporting whether it exists.
+	var found bool
+	change := func(f func(t *projectTemplate)) error {
+		return updateStore(func(d *storeData) {
+			var t *projectTemplate
+			if t, found = d.ProjectTemplates[name]; found {
+				f(t)
+				t.UpdatedAt = time.Now()
+				t.UpdatedBy = i.Member.User.ID
+			}
+		})
+	}
+
+	var content string
+	switch sub.Name {
+	case "set":
+		err := updateStore(func(d *storeData) {
+			if d.ProjectTemplates == nil {
+				d.ProjectTemplates = make(map[string]*projectTemplate)
+			}
+			t, ok := d.ProjectTemplates[name]
+			if !ok {
+				t = &projectTemplate{HuddleButton: true}
+				d.ProjectTemplates[name] = t
+			}
+			if o, ok := options["category"]; ok {
+				t.CategoryID = o.ChannelValue(s).ID
+			}
+			if o, ok := options["topic"]; ok {
+				t.Topic = strings.TrimSpace(o.StringValue())
+			}
+			if o, ok := options["huddle-button"]; ok {
+				t.HuddleButton = o.BoolValue()
+			}
+			t.UpdatedAt = time.Now()
+			t.UpdatedBy = i.Member.User.ID
+		})
+		if err != nil {
+			log.Printf("Error saving project template: %v", err)
+			content = "Error saving project template: " + describeError(err)
+			break
+		}
+		log.Printf("Saved project template %q.", name)
+		content = fmt.Sprintf("Saved the **%s** template. Use it with `/make-channel template:%s`.", name, name)
+
+	case "role":
+		role := options["role"].RoleValue(s, i.GuildID)
+		var added bool
+		err := change(func(t *projectTemplate) {
+			if n := slices.Index(t.RoleIDs, role.ID); n >= 0 {
+				t.RoleIDs = slices.Delete(t.RoleIDs, n, n+1)
+			} else {
+				t.RoleIDs = append(t.RoleIDs, role.ID)
+				added = true
+			}
+		})
+		switch {
+		case err != nil:
+			log.Printf("Error saving project template: %v", err)
+			content = "Error saving project template: " + describeError(err)
+		case !found:
+			content = fmt.Sprintf("There's no template named %s.", name)
+		case added:
+			content = fmt.Sprintf("Channels made from **%s** will be shared with %s.", name, role.Mention())
+		default:
+			content = fmt.Sprintf("Channels made from **%s** will no longer be shared with %s.", name, role.Mention())
+		}
+
+	case "pin":
+		message := options["message"].StringValue()
+		err := change(func(t *projectTemplate) {
+			t.Pins = append(t.Pins, message)
+		})
+		switch {
+		case err != nil:
+			log.Printf("Error saving project template: %v", err)
+			content = "Error saving project template: " + describeError(err)
+		case !found:
+			content = fmt.Sprintf("There's no template named %s.", name)
+		default:
+			content = fmt.Sprintf("Added a pinned message to **%s**.", name)
+		}
+
+	case "clear-pins":
+		err := change(func(t *projectTemplate) {
+			t.Pins = nil
+		})
+		switch {
+		case err != nil:
+			log.Printf("Error saving project template: %v", err)
+			content = "Error saving project template: " + describeError(err)
+		case !found:
+			content = fmt.Sprintf("There's no template named %s.", name)
+		default:
+			content = fmt.Sprintf("Removed the pinned messages from **%s**.", name)
+		}
+
+	case "delete":
+		err := updateStore(func(d *storeData) {
+			if _, found = d.ProjectTemplates[name]; found {
+				delete(d.ProjectTemplates, name)
+			}
+		})
+		switch {
+		case err != nil:
+			log.Printf("Error deleting project template: %v", err)
+			content = "Error deleting project template: " + describeError(err)
+		case !found:
+			content = fmt.Sprintf("There's no template named %s.", name)
+		default:
+			log.Printf("Deleted project template %q.", name)
+			content = fmt.Sprintf("Deleted the **%s** template.", name)
+		}
+
+	case "list":
+		var lines []string
+		readStore(func(d *storeData) {
+			for name, t := range d.ProjectTemplates {
+				lines = append(lines, describeTemplate(name, t))
+			}
+		})
+		slices.Sort(lines)
+		content = "No project templates have been set up."
+		if len(lines) > 0 {
+			content = truncate(strings.Join(lines, "\n"), 2000)
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         content,
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}))
+}
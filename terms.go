@@ -0,0 +1,228 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A user's acceptance of the terms. The hash identifies which version of the terms they agreed to, so changing the
+// terms asks everyone to agree again.
+type termsAcceptance struct {
+	AcceptedAt time.Time `json:"acceptedAt"`
+	TermsHash  string    `json:"termsHash"`
+}
+
+// A short hash identifying a version of the terms.
+func termsHash(terms string) string {
+	sum := sha256.Sum256([]byte(terms))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Check whether a member has to accept the terms before being added to a project. Juiceworks members never do, and
+// nobody does while no terms are set.
+func requiresTerms(member *discordgo.Member) bool {
+	if slices.Contains(member.Roles, JuiceworksRoleId) {
+		return false
+	}
+	required := false
+	readStore(func(d *storeData) {
+		if d.Terms == "" {
+			return
+		}
+		a, ok := d.TermsAcceptances[member.User.ID]
+		required = !ok || a.TermsHash != termsHash(d.Terms)
+	})
+	return required
+}
+
+// DM the terms to a user with an "I agree" button. They're added to the channel when they click it.
+func sendTerms(s *discordgo.Session, i *discordgo.InteractionCreate, channelID string, user *discordgo.User) {
+	if err := deliverTerms(s, channelID, user); err != nil {
+		log.Printf("Error sending terms: %v", err)
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Error sending terms: " + describeError(err),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Sent the terms to %s. They'll be added to the channel once they agree.", user.Mention()),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// DM a user the terms with a button to accept them, which adds them to the channel.
+func deliverTerms(s *discordgo.Session, channelID string, user *discordgo.User) error {
+	var terms string
+	readStore(func(d *storeData) {
+		terms = d.Terms
+	})
+
+	dm, err := s.UserChannelCreate(user.ID)
+	if err != nil {
+		return err
+	}
+	_, err = s.ChannelMessageSendComplex(dm.ID, &discordgo.MessageSend{
+		Embed: themed(JuiceworksGuildId, &discordgo.MessageEmbed{
+			Title:       "Terms of access",
+			Description: truncate(terms, 4096),
+			Footer:      &discordgo.MessageEmbedFooter{Text: "You'll be added to the project once you agree."},
+		}),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "I agree",
+					Style:    discordgo.SuccessButton,
+					CustomID: "terms-accept:" + channelID + ":" + user.ID + ":" + termsHash(terms),
+				},
+			}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Sent terms to %s for channel %s.", user, channelID)
+	return nil
+}
+
+// Handle the "I agree" button on the terms: record the acceptance and add the user to the channel they were invited
+// to.
+func acceptTerms(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	parts := strings.Split(i.MessageComponentData().CustomID, ":")
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	if len(parts) != 4 || user == nil || user.ID != parts[2] {
+		return
+	}
+	channelID, hash := parts[1], parts[3]
+
+	// The terms may have changed since they were sent, in which case the user hasn't seen the current version.
+	current := false
+	err := updateStore(func(d *storeData) {
+		if d.Terms == "" || termsHash(d.Terms) != hash {
+			return
+		}
+		current = true
+		if d.TermsAcceptances == nil {
+			d.TermsAcceptances = make(map[string]*termsAcceptance)
+		}
+		d.TermsAcceptances[user.ID] = &termsAcceptance{AcceptedAt: time.Now(), TermsHash: hash}
+	})
+	if err != nil {
+		log.Printf("Error recording terms acceptance: %v", err)
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Error recording terms acceptance: " + describeError(err),
+			},
+		}))
+		return
+	}
+	if !current {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "These terms have changed since they were sent. Please ask to be added again.",
+			},
+		}))
+		return
+	}
+	postAudit(s, auditEntry{
+		Action:    auditTermsAccepted,
+		ActorID:   user.ID,
+		ChannelID: channelID,
+		Summary:   fmt.Sprintf("<@%s> accepted version %s of the terms.", user.ID, hash),
+	})
+
+	member, err := s.GuildMember(JuiceworksGuildId, user.ID)
+	if err != nil {
+		log.Printf("Error reading member roles: %v", err)
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Error reading member roles: " + describeError(err),
+			},
+		}))
+		return
+	}
+	if err := grantChannelAccess(s, i, channelID, user, member); err != nil {
+		return
+	}
+
+	// Replace the button so it can't be clicked again, and let the project know.
+	log.Printf("%s accepted the terms and was added to channel %s.", user, channelID)
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    fmt.Sprintf("You agreed to these terms <t:%d:f> and were added to <#%s>.", time.Now().Unix(), channelID),
+			Components: []discordgo.MessageComponent{},
+		},
+	}))
+	pings, allowed := projectPings(channelID, user.ID)
+	msg := &discordgo.MessageSend{
+		Content:         strings.TrimSpace(fmt.Sprintf("%s accepted the terms and was added to the channel. %s", user.Mention(), pings)),
+		AllowedMentions: allowed,
+	}
+	if _, err := s.ChannelMessageSendComplex(channelID, msg); err != nil {
+		log.Printf("Error announcing new member: %v", err)
+	}
+}
+
+// Manage the terms external users have to accept before they're added to a project. Only administrators can change
+// them.
+func termsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	var content string
+	switch sub.Name {
+	case "set", "clear":
+		var terms string
+		if sub.Name == "set" {
+			terms = strings.TrimSpace(sub.Options[0].StringValue())
+		}
+		err := updateStore(func(d *storeData) {
+			d.Terms = terms
+		})
+		if err != nil {
+			log.Printf("Error saving terms: %v", err)
+			content = "Error saving terms: " + describeError(err)
+			break
+		}
+		log.Printf("Set terms to version %q.", termsHash(terms))
+		content = "Updated the terms. External users will be asked to agree to them before they're added to a project."
+		if terms == "" {
+			content = "Cleared the terms. External users will be added to projects without agreeing to anything."
+		}
+
+	case "show":
+		readStore(func(d *storeData) {
+			content = "No terms are set."
+			if d.Terms != "" {
+				content = truncate(d.Terms, 2000)
+			}
+		})
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How the bot's embeds look in a guild, set with /theme.
+type embedTheme struct {
+	Color int `json:"color"`
+	// Footer text for embeds that don't have their own. Empty means no footer.
+	Footer string `json:"footer,omitempty"`
+	// Shown as the thumbnail of embeds that don't have their own, and next to the footer.
+	LogoURL string `json:"logoUrl,omitempty"`
+	// Whether embeds show when they were sent. Discord shows it in each reader's own locale and timezone.
+	Timestamps bool `json:"timestamps,omitempty"`
+}
+
+// The theme for guilds that haven't set their own.
+var defaultTheme = embedTheme{Color: brandColor, Footer: "Juiceworks"}
+
+// The theme for a guild. Embeds sent outside a guild, like DMs, use the Juiceworks guild's.
+func themeFor(guildID string) embedTheme {
+	if guildID == "" {
+		guildID = JuiceworksGuildId
+	}
+	t := defaultTheme
+	readStore(func(d *storeData) {
+		if custom, ok := d.Themes[guildID]; ok {
+			t = *custom
+		}
+	})
+	return t
+}
+
+// Apply a guild's theme to an embed, filling in whatever the embed doesn't set itself, and return it. Every embed the
+// bot sends goes through this, so they all look the same.
+func themed(guildID string, e *discordgo.MessageEmbed) *discordgo.MessageEmbed {
+	t := themeFor(guildID)
+	if e.Color == 0 {
+		e.Color = t.Color
+	}
+	if e.Footer == nil && t.Footer != "" {
+		e.Footer = &discordgo.MessageEmbedFooter{Text: t.Footer, IconURL: t.LogoURL}
+	}
+	if e.Thumbnail == nil && t.LogoURL != "" {
+		e.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: t.LogoURL}
+	}
+	if e.Timestamp == "" && t.Timestamps {
+		e.Timestamp = time.Now().Format(time.RFC3339)
+	}
+	return e
+}
+
+// Show, change or reset how the bot's embeds look in the guild.
+func themeCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	// Successful changes show a preview of the theme with the message.
+	var content string
+	var preview bool
+	switch sub.Name {
+	case "set":
+		t := themeFor(i.GuildID)
+		if o, ok := options["color"]; ok {
+			c := strings.TrimPrefix(strings.TrimSpace(o.StringValue()), "#")
+			color, err := strconv.ParseUint(c, 16, 32)
+			if err != nil || len(c) != 6 {
+				content = "The color has to be a hex code like #f7931e."
+				break
+			}
+			t.Color = int(color)
+		}
+		// "none" turns the footer or logo off.
+		if o, ok := options["footer"]; ok {
+			t.Footer = strings.TrimSpace(o.StringValue())
+			if strings.EqualFold(t.Footer, "none") {
+				t.Footer = ""
+			}
+		}
+		if o, ok := options["logo"]; ok {
+			t.LogoURL = strings.TrimSpace(o.StringValue())
+			if strings.EqualFold(t.LogoURL, "none") {
+				t.LogoURL = ""
+			}
+			if u, err := url.Parse(t.LogoURL); t.LogoURL != "" && (err != nil || u.Scheme != "https" || u.Host == "") {
+				content = "The logo has to be an https:// address."
+				break
+			}
+		}
+		if o, ok := options["timestamps"]; ok {
+			t.Timestamps = o.BoolValue()
+		}
+		err := updateStore(func(d *storeData) {
+			if d.Themes == nil {
+				d.Themes = make(map[string]*embedTheme)
+			}
+			d.Themes[i.GuildID] = &t
+		})
+		if err != nil {
+			log.Printf("Error saving theme: %v", err)
+			content = "Error saving theme: " + describeError(err)
+			break
+		}
+		log.Printf("%s changed the embed theme.", i.Member.User)
+		content, preview = "Saved the theme. This is how embeds look now.", true
+
+	case "reset":
+		err := updateStore(func(d *storeData) {
+			delete(d.Themes, i.GuildID)
+		})
+		if err != nil {
+			log.Printf("Error saving theme: %v", err)
+			content = "Error saving theme: " + describeError(err)
+			break
+		}
+		log.Printf("%s reset the embed theme.", i.Member.User)
+		content, preview = "Reset the theme to the default.", true
+
+	case "show":
+		content, preview = "This is how embeds look.", true
+	}
+
+	data := &discordgo.InteractionResponseData{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	}
+	if preview {
+		t := themeFor(i.GuildID)
+		footer, logo := t.Footer, t.LogoURL
+		if footer == "" {
+			footer = "None"
+		}
+		if logo == "" {
+			logo = "None"
+		}
+		data.Embeds = []*discordgo.MessageEmbed{themed(i.GuildID, &discordgo.MessageEmbed{
+			Title:       "Theme preview",
+			Description: fmt.Sprintf("Color: #%06x\nFooter: %s\nLogo: %s\nTimestamps: %t", t.Color, footer, logo, t.Timestamps),
+		})}
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	}))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/joho/godotenv"
+)
+
+// How often a rejected bot token can send the bot back to its secret source.
+const tokenReloadCooldown = time.Minute
+
+var (
+	tokenReloadMu   sync.Mutex
+	tokenReloadedAt time.Time
+)
+
+// Watches the bot's requests to Discord for a rejected token.
+type authWatcher struct {
+	base      http.RoundTripper
+	onFailure func()
+}
+
+func (w authWatcher) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := w.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && strings.HasPrefix(req.Header.Get("Authorization"), "Bot ") {
+		go w.onFailure()
+	}
+	return resp, err
+}
+
+// Notice when Discord rejects the bot token, so a rotated token is picked up without a restart.
+func watchForAuthFailures(s *discordgo.Session) {
+	base := s.Client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	s.Client.Transport = authWatcher{base: base, onFailure: func() { reloadDiscordToken(s) }}
+}
+
+// A rejected token also shows up on the gateway, as a disconnect, so check whether the token still works. The
+// request is watched like any other.
+func checkDiscordToken(s *discordgo.Session, d *discordgo.Disconnect) {
+	go s.User("@me")
+}
+
+// Read the bot token from its source again after Discord rejected it, and switch to it if it changed: the secret
+// manager, or the .env file without one. Only one reload runs at a time, and not more than once a minute.
+func reloadDiscordToken(s *discordgo.Session) {
+	tokenReloadMu.Lock()
+	defer tokenReloadMu.Unlock()
+	if time.Since(tokenReloadedAt) < tokenReloadCooldown {
+		return
+	}
+	tokenReloadedAt = time.Now()
+	log.Printf("Discord rejected the bot token. Reading it again from %s.", secretsProvider())
+
+	if secretsProvider() == secretsEnv {
+		env, err := godotenv.Read(".env")
+		if err != nil {
+			recordFailure("reloading the bot token", err)
+			return
+		}
+		if t := env["DISCORD_TOKEN"]; t != "" {
+			os.Setenv("DISCORD_TOKEN", t)
+		}
+	} else if _, err := loadSecrets(); err != nil {
+		recordFailure("reloading the bot token", err)
+		return
+	}
+
+	s.RLock()
+	current := strings.TrimPrefix(s.Token, "Bot ")
+	s.RUnlock()
+	token := os.Getenv("DISCORD_TOKEN")
+	if token == "" || token == current {
+		recordFailure("reloading the bot token", errors.New("Discord rejected the bot token and its source has no new one"))
+		return
+	}
+	rotateDiscordToken(s, token, "Discord rejected the old one")
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// API token scopes, from least to most access. Each scope includes the ones before it.
+const (
+	scopeRead         = "read"
+	scopeProjectAdmin = "project-admin"
+	scopeAdmin        = "admin"
+)
+
+var apiScopes = []string{scopeRead, scopeProjectAdmin, scopeAdmin}
+
+// How many API requests each token can make in a window, unless API_RATE_LIMIT says otherwise.
+const (
+	defaultAPIRateLimit  = 120
+	defaultAPIRateWindow = time.Minute
+)
+
+// A token for the API. Only a hash of the secret is kept, so tokens can't be read back out of the data file.
+type apiToken struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scope     string    `json:"scope"`
+	Hash      string    `json:"hash,omitempty"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// When each token recently made requests, keyed by token ID.
+var (
+	apiRequestsMu sync.Mutex
+	apiRequests   = make(map[string][]time.Time)
+)
+
+// Hash a token secret for storage.
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Find who's calling the API from the token in the X-API-Key header or a bearer Authorization header. API_KEY is an
+// admin token that isn't stored, for setting things up. Tokens look like jw_<id>_<secret>.
+func authenticateAPI(r *http.Request) (name, id, scope string, ok bool) {
+	presented := r.Header.Get("X-API-Key")
+	if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		presented = bearer
+	}
+	return authenticateToken(presented)
+}
+
+// Find the API token a caller presented.
+func authenticateToken(presented string) (name, id, scope string, ok bool) {
+	if presented == "" {
+		return "", "", "", false
+	}
+	if key := os.Getenv("API_KEY"); key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(presented)) == 1 {
+		return "API_KEY", "API_KEY", scopeAdmin, true
+	}
+
+	parts := strings.SplitN(presented, "_", 3)
+	if len(parts) != 3 || parts[0] != "jw" {
+		return "", "", "", false
+	}
+	var token apiToken
+	readStore(func(d *storeData) {
+		if t, found := d.APITokens[parts[1]]; found {
+			token, ok = *t, true
+		}
+	})
+	if !ok || subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hashToken(presented))) != 1 {
+		return "", "", "", false
+	}
+	return token.Name, token.ID, token.Scope, true
+}
+
+// Whether a token's scope includes the given scope.
+func hasScope(tokenScope, scope string) bool {
+	return slices.Index(apiScopes, tokenScope) >= slices.Index(apiScopes, scope)
+}
+
+// How many API requests each token can make in what window, from API_RATE_LIMIT, like 120/1m.
+func apiRateLimit() (int, time.Duration) {
+	if v := os.Getenv("API_RATE_LIMIT"); v != "" {
+		count, window, _ := strings.Cut(v, "/")
+		n, err := strconv.Atoi(count)
+		d, err2 := time.ParseDuration(window)
+		if err == nil && err2 == nil && n > 0 && d > 0 {
+			return n, d
+		}
+		log.Printf("Ignoring malformed API_RATE_LIMIT %q", v)
+	}
+	return defaultAPIRateLimit, defaultAPIRateWindow
+}
+
+// Count an API request against a token's rate limit, returning how long to wait if it's over. Tokens that haven't made
+// a request in the last window are forgotten, so idle and revoked tokens don't pile up.
+func apiCooldown(id string, now time.Time) time.Duration {
+	limit, window := apiRateLimit()
+	apiRequestsMu.Lock()
+	defer apiRequestsMu.Unlock()
+	for other, requests := range apiRequests {
+		if now.Sub(requests[len(requests)-1]) >= window {
+			delete(apiRequests, other)
+		}
+	}
+	requests := apiRequests[id]
+	for len(requests) > 0 && now.Sub(requests[0]) >= window {
+		requests = requests[1:]
+	}
+	if len(requests) >= limit {
+		apiRequests[id] = requests
+		return window - now.Sub(requests[0])
+	}
+	apiRequests[id] = append(requests, now)
+	return 0
+}
+
+// Records the status of a response for the request log. It can still be hijacked, for WebSockets.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.status = http.StatusSwitchingProtocols
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
+
+// Only allow requests with a token that has at least the given scope, within the token's rate limit. Every request
+// is logged with the token that made it.
+func requireAPIKey(scope string, h func(s *discordgo.Session, w http.ResponseWriter, r *http.Request)) func(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	return func(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+		name, id, tokenScope, ok := authenticateAPI(r)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			log.Printf("API %s %s by %s: %d", r.Method, r.URL.Path, cmp.Or(name, "unknown token"), rec.status)
+		}()
+
+		switch {
+		case !ok:
+			http.Error(rec, "invalid API key", http.StatusUnauthorized)
+		case !hasScope(tokenScope, scope):
+			http.Error(rec, "this token needs the "+scope+" scope", http.StatusForbidden)
+		default:
+			if wait := apiCooldown(id, time.Now()); wait > 0 {
+				rec.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+				http.Error(rec, "rate limited", http.StatusTooManyRequests)
+				return
+			}
+			h(s, rec, r)
+		}
+	}
+}
+
+// Create, revoke and list API tokens. Only administrators can manage tokens.
+func apiTokenCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	var content string
+	switch sub.Name {
+	case "create":
+		token, presented, err := createAPIToken(options["name"].StringValue(), options["scope"].StringValue(), i.Member.User.ID)
+		if err != nil {
+			log.Printf("Error creating token: %v", err)
+			content = "Error creating token: " + describeError(err)
+			break
+		}
+		log.Printf("%s created %s API token %s (%s).", i.Member.User, token.Scope, token.ID, token.Name)
+		postAudit(s, auditEntry{
+			Action:   auditTokenCreated,
+			ActorID:  i.Member.User.ID,
+			TargetID: token.ID,
+			Summary:  fmt.Sprintf("<@%s> created the %s API token %s (%s).", i.Member.User.ID, token.Scope, token.ID, token.Name),
+		})
+		content = fmt.Sprintf("Created the %s token **%s**. Copy it now, since it can't be shown again:\n```\n%s\n```",
+			token.Scope, token.Name, presented)
+
+	case "revoke":
+		id := options["id"].StringValue()
+		found, err := revokeAPIToken(id)
+		switch {
+		case err != nil:
+			log.Printf("Error revoking token: %v", err)
+			content = "Error revoking token: " + describeError(err)
+		case !found:
+			content = fmt.Sprintf("There's no token with the ID %s.", id)
+		default:
+			log.Printf("%s revoked API token %s.", i.Member.User, id)
+			postAudit(s, auditEntry{
+				Action:   auditTokenRevoked,
+				ActorID:  i.Member.User.ID,
+				TargetID: id,
+				Summary:  fmt.Sprintf("<@%s> revoked API token %s.", i.Member.User.ID, id),
+			})
+			content = fmt.Sprintf("Revoked token %s.", id)
+		}
+
+	case "list":
+		var lines []string
+		for _, t := range listAPITokens() {
+			// Tokens created over the API record the token that created them instead of a user.
+			createdBy := "<@" + t.CreatedBy + ">"
+			if token, ok := strings.CutPrefix(t.CreatedBy, "api:"); ok {
+				createdBy = token + " over the API"
+			}
+			lines = append(lines, fmt.Sprintf("- `%s` **%s** (%s), created by %s <t:%d:D>",
+				t.ID, t.Name, t.Scope, createdBy, t.CreatedAt.Unix()))
+		}
+		content = "No API tokens have been created."
+		if len(lines) > 0 {
+			content = strings.Join(lines, "\n")
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         truncate(content, 2000),
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}))
+}
+
+// Create an API token with one of apiScopes, returning it along with the secret to present, which can't be read back
+// later.
+func createAPIToken(name, scope, createdBy string) (apiToken, string, error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 24)
+	if _, err := rand.Read(id); err != nil {
+		return apiToken{}, "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return apiToken{}, "", err
+	}
+	token := apiToken{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Scope:     scope,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	presented := "jw_" + token.ID + "_" + hex.EncodeToString(secret)
+	token.Hash = hashToken(presented)
+	err := updateStore(func(d *storeData) {
+		if d.APITokens == nil {
+			d.APITokens = make(map[string]*apiToken)
+		}
+		d.APITokens[token.ID] = &token
+	})
+	return token, presented, err
+}
+
+// Revoke an API token, reporting whether there was one with the ID.
+func revokeAPIToken(id string) (bool, error) {
+	var found bool
+	err := updateStore(func(d *storeData) {
+		if _, found = d.APITokens[id]; found {
+			delete(d.APITokens, id)
+		}
+	})
+	if found && err == nil {
+		apiRequestsMu.Lock()
+		delete(apiRequests, id)
+		apiRequestsMu.Unlock()
+	}
+	return found, err
+}
+
+// The API tokens, ordered by name, without their hashes.
+func listAPITokens() []apiToken {
+	var tokens []apiToken
+	readStore(func(d *storeData) {
+		for _, t := range d.APITokens {
+			listed := *t
+			listed.Hash = ""
+			tokens = append(tokens, listed)
+		}
+	})
+	slices.SortFunc(tokens, func(a, b apiToken) int { return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.ID, b.ID)) })
+	return tokens
+}
+
+// List the API tokens.
+func apiListTokens(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, listAPITokens())
+}
+
+// Create an API token, like /api-token create. The secret is only in this response.
+func apiCreateToken(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  string `json:"name"`
+		Scope string `json:"scope"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(apiScopes, req.Scope) {
+		http.Error(w, "scope must be one of "+strings.Join(apiScopes, ", "), http.StatusBadRequest)
+		return
+	}
+	name, _, _, _ := authenticateAPI(r)
+	token, presented, err := createAPIToken(req.Name, req.Scope, "api:"+name)
+	if err != nil {
+		log.Printf("Error creating token: %v", err)
+		http.Error(w, "could not create token", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("%s created %s API token %s (%s) over the API.", name, token.Scope, token.ID, token.Name)
+	postAudit(s, auditEntry{
+		Action:   auditTokenCreated,
+		TargetID: token.ID,
+		Summary:  fmt.Sprintf("%s created the %s API token %s (%s) over the API.", name, token.Scope, token.ID, token.Name),
+	})
+	token.Hash = ""
+	writeJSON(w, http.StatusCreated, map[string]any{"token": token, "secret": presented})
+}
+
+// Revoke an API token, like /api-token revoke.
+func apiRevokeToken(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	found, err := revokeAPIToken(id)
+	switch {
+	case err != nil:
+		log.Printf("Error revoking token: %v", err)
+		http.Error(w, "could not revoke token", http.StatusInternalServerError)
+	case !found:
+		http.Error(w, "unknown token", http.StatusNotFound)
+	default:
+		log.Printf("Revoked API token %s over the API.", id)
+		postAudit(s, auditEntry{
+			Action:   auditTokenRevoked,
+			TargetID: id,
+			Summary:  fmt.Sprintf("API token %s was revoked over the API.", id),
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A town hall held on a stage channel, with the queue of members waiting to speak.
+type townhall struct {
+	EventID        string    `json:"eventId"`
+	StageChannelID string    `json:"stageChannelId"`
+	Topic          string    `json:"topic"`
+	StartsAt       time.Time `json:"startsAt"`
+	StartedAt      time.Time `json:"startedAt,omitempty"`
+	// Members who asked to speak and haven't been brought up yet, in the order they asked.
+	Queue []string `json:"queue,omitempty"`
+	// Members who have been brought up to speak.
+	Speakers []string `json:"speakers,omitempty"`
+	// Everyone who joined the stage while the town hall was running.
+	Attendees []string `json:"attendees,omitempty"`
+}
+
+// Schedule and run town halls for Juiceworks members. Only one town hall can be scheduled at a time.
+func townhallCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, o := range sub.Options {
+		options[o.Name] = o
+	}
+
+	var content string
+	switch sub.Name {
+	case "schedule":
+		content = scheduleTownhall(s, options)
+	case "start":
+		content = startTownhall(s)
+	case "queue":
+		content = townhallQueue()
+	case "next":
+		content = nextTownhallSpeaker(s)
+	case "end":
+		var notes string
+		if o, ok := options["notes"]; ok {
+			notes = o.StringValue()
+		}
+		content = endTownhall(s, notes)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Read the current town hall, if there is one.
+func currentTownhall() (townhall, bool) {
+	var t townhall
+	var ok bool
+	readStore(func(d *storeData) {
+		if d.Townhall != nil {
+			t, ok = *d.Townhall, true
+			t.Queue = slices.Clone(t.Queue)
+			t.Speakers = slices.Clone(t.Speakers)
+			t.Attendees = slices.Clone(t.Attendees)
+		}
+	})
+	return t, ok
+}
+
+// Create the scheduled event for a town hall and announce it with a button to ask to speak, returning the response to
+// show the caller.
+func scheduleTownhall(s *discordgo.Session, options map[string]*discordgo.ApplicationCommandInteractionDataOption) string {
+	if t, ok := currentTownhall(); ok {
+		return fmt.Sprintf("There's already a town hall scheduled: %s. End it before scheduling another.", t.Topic)
+	}
+	topic := strings.TrimSpace(options["topic"].StringValue())
+	stage := options["stage"].ChannelValue(s)
+	start, err := time.ParseInLocation("2006-01-02 15:04", strings.TrimSpace(options["start"].StringValue()), time.Local)
+	if err != nil {
+		return "The start time has to look like 2024-07-01 16:00."
+	}
+	if !start.After(time.Now()) {
+		return "The start time has to be in the future."
+	}
+	description := "A town hall for all Juiceworks members. Use the button in the announcement to ask to speak."
+	if o, ok := options["description"]; ok {
+		description = o.StringValue()
+	}
+
+	event, err := s.GuildScheduledEventCreate(JuiceworksGuildId, &discordgo.GuildScheduledEventParams{
+		ChannelID:          stage.ID,
+		Name:               truncate(topic, 100),
+		Description:        truncate(description, 1000),
+		ScheduledStartTime: &start,
+		PrivacyLevel:       discordgo.GuildScheduledEventPrivacyLevelGuildOnly,
+		EntityType:         discordgo.GuildScheduledEventEntityTypeStageInstance,
+	})
+	if err != nil {
+		log.Printf("Error creating town hall event: %v", err)
+		return "Error creating town hall event: " + describeError(err)
+	}
+
+	err = updateStore(func(d *storeData) {
+		d.Townhall = &townhall{EventID: event.ID, StageChannelID: stage.ID, Topic: topic, StartsAt: start}
+	})
+	if err != nil {
+		log.Printf("Error saving town hall: %v", err)
+		return "Error saving town hall: " + describeError(err)
+	}
+
+	_, err = s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{
+		Content: fmt.Sprintf("**Town hall: %s**\n<t:%d:F> in <#%s>\n%s\nhttps://discord.com/events/%s/%s",
+			topic, start.Unix(), stage.ID, description, JuiceworksGuildId, event.ID),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Request to speak",
+					Style:    discordgo.SecondaryButton,
+					CustomID: "townhall-speak",
+					Emoji:    &discordgo.ComponentEmoji{Name: "✋"},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		log.Printf("Error announcing town hall: %v", err)
+	}
+	log.Printf("Scheduled town hall %q for %s.", topic, start)
+	return fmt.Sprintf("Scheduled the town hall for <t:%d:F>.", start.Unix())
+}
+
+// Start the town hall's event so members can join the stage, returning the response to show the caller.
+func startTownhall(s *discordgo.Session) string {
+	t, ok := currentTownhall()
+	if !ok {
+		return "No town hall is scheduled."
+	}
+	if !t.StartedAt.IsZero() {
+		return "The town hall has already started."
+	}
+	_, err := s.GuildScheduledEventEdit(JuiceworksGuildId, t.EventID, &discordgo.GuildScheduledEventParams{
+		Status: discordgo.GuildScheduledEventStatusActive,
+	})
+	if err != nil {
+		log.Printf("Error starting town hall event: %v", err)
+		return "Error starting town hall event: " + describeError(err)
+	}
+	err = updateStore(func(d *storeData) {
+		if d.Townhall != nil {
+			d.Townhall.StartedAt = time.Now()
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving town hall: %v", err)
+	}
+	log.Printf("Started town hall %q.", t.Topic)
+	return fmt.Sprintf("Started the town hall in <#%s>. Use /townhall next to bring up whoever's next in the queue.", t.StageChannelID)
+}
+
+// Add the member who pressed the button to the queue to speak.
+func requestToSpeak(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	userID := i.Member.User.ID
+	position := 0
+	err := updateStore(func(d *storeData) {
+		t := d.Townhall
+		if t == nil {
+			return
+		}
+		if !slices.Contains(t.Queue, userID) {
+			t.Queue = append(t.Queue, userID)
+		}
+		position = slices.Index(t.Queue, userID) + 1
+	})
+
+	var content string
+	switch {
+	case err != nil:
+		log.Printf("Error saving town hall queue: %v", err)
+		content = "Error joining the queue: " + describeError(err)
+	case position == 0:
+		content = "That town hall is over."
+	default:
+		log.Printf("%s asked to speak at the town hall.", i.Member.User)
+		content = fmt.Sprintf("You're number %d in the queue to speak. You'll be brought up on stage when it's your turn, as long as you've joined it.", position)
+	}
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// List the members waiting to speak.
+func townhallQueue() string {
+	t, ok := currentTownhall()
+	if !ok {
+		return "No town hall is scheduled."
+	}
+	if len(t.Queue) == 0 {
+		return "Nobody is waiting to speak."
+	}
+	lines := make([]string, len(t.Queue))
+	for n, id := range t.Queue {
+		lines[n] = fmt.Sprintf("%d. <@%s>", n+1, id)
+	}
+	return truncate(strings.Join(lines, "\n"), 2000)
+}
+
+// Bring the next member in the queue up to speak on the stage, returning the response to show the caller. Members who
+// haven't joined the stage are skipped and stay at the front of the queue.
+func nextTownhallSpeaker(s *discordgo.Session) string {
+	t, ok := currentTownhall()
+	switch {
+	case !ok:
+		return "No town hall is scheduled."
+	case t.StartedAt.IsZero():
+		return "The town hall hasn't started. Use /townhall start first."
+	case len(t.Queue) == 0:
+		return "Nobody is waiting to speak."
+	}
+
+	for _, userID := range t.Queue {
+		// Letting someone speak on a stage means un-suppressing their voice state.
+		endpoint := discordgo.EndpointGuild(JuiceworksGuildId) + "/voice-states/" + userID
+		_, err := s.RequestWithBucketID("PATCH", endpoint, map[string]any{
+			"channel_id": t.StageChannelID,
+			"suppress":   false,
+		}, discordgo.EndpointGuild(JuiceworksGuildId)+"/voice-states/")
+		if err != nil {
+			log.Printf("Error bringing %s up to speak: %v", userID, err)
+			continue
+		}
+
+		err = updateStore(func(d *storeData) {
+			if d.Townhall != nil {
+				d.Townhall.Queue = slices.DeleteFunc(d.Townhall.Queue, func(id string) bool { return id == userID })
+				if !slices.Contains(d.Townhall.Speakers, userID) {
+					d.Townhall.Speakers = append(d.Townhall.Speakers, userID)
+				}
+			}
+		})
+		if err != nil {
+			log.Printf("Error saving town hall queue: %v", err)
+		}
+		log.Printf("Brought %s up to speak at the town hall.", userID)
+		return fmt.Sprintf("<@%s> can speak now.", userID)
+	}
+	return "Nobody in the queue is on the stage yet."
+}
+
+// Note who joins the stage while a town hall is running.
+func trackTownhallAttendance(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+	if v.GuildID != JuiceworksGuildId || v.ChannelID == "" {
+		return
+	}
+	t, ok := currentTownhall()
+	if !ok || t.StartedAt.IsZero() || v.ChannelID != t.StageChannelID || slices.Contains(t.Attendees, v.UserID) {
+		return
+	}
+	err := updateStore(func(d *storeData) {
+		if d.Townhall != nil && !slices.Contains(d.Townhall.Attendees, v.UserID) {
+			d.Townhall.Attendees = append(d.Townhall.Attendees, v.UserID)
+		}
+	})
+	if err != nil {
+		recordFailure("recording town hall attendance", err)
+	}
+}
+
+// End the town hall's event and post a summary in the internal channel, returning the response to show the caller.
+// Notes, such as a link to a recording, are included in the summary.
+func endTownhall(s *discordgo.Session, notes string) string {
+	t, ok := currentTownhall()
+	if !ok {
+		return "No town hall is scheduled."
+	}
+
+	// Scheduled events that never started are cancelled rather than completed.
+	status := discordgo.GuildScheduledEventStatusCompleted
+	if t.StartedAt.IsZero() {
+		status = discordgo.GuildScheduledEventStatusCanceled
+	}
+	_, err := s.GuildScheduledEventEdit(JuiceworksGuildId, t.EventID, &discordgo.GuildScheduledEventParams{Status: status})
+	if err != nil {
+		log.Printf("Error ending town hall event: %v", err)
+	}
+	if err := updateStore(func(d *storeData) { d.Townhall = nil }); err != nil {
+		log.Printf("Error removing town hall: %v", err)
+		return "Error removing town hall: " + describeError(err)
+	}
+	if t.StartedAt.IsZero() {
+		log.Printf("Cancelled town hall %q.", t.Topic)
+		return "Cancelled the town hall."
+	}
+
+	mentions := func(ids []string) string {
+		if len(ids) == 0 {
+			return "nobody"
+		}
+		return "<@" + strings.Join(ids, ">, <@") + ">"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Town hall summary: %s**\n", t.Topic)
+	fmt.Fprintf(&b, "Ran for %s with %d attendees.\n", formatCallDuration(time.Since(t.StartedAt)), len(t.Attendees))
+	fmt.Fprintf(&b, "Speakers: %s\n", mentions(t.Speakers))
+	if len(t.Queue) > 0 {
+		fmt.Fprintf(&b, "Still waiting to speak: %s\n", mentions(t.Queue))
+	}
+	if notes != "" {
+		fmt.Fprintf(&b, "\n%s\n", notes)
+	}
+	_, err = s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{
+		Content:         truncate(b.String(), 2000),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		log.Printf("Error posting town hall summary: %v", err)
+		return "Ended the town hall, but posting the summary failed: " + describeError(err)
+	}
+	log.Printf("Ended town hall %q.", t.Topic)
+	return "Ended the town hall and posted the summary."
+}
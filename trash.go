@@ -0,0 +1,315 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How many days deleted projects stay in the trash by default.
+const defaultTrashDays = 30
+
+// How often the trash is checked for projects to delete for good.
+const trashPurgeInterval = time.Hour
+
+// The most messages exported from each channel of a deleted project.
+const maxExportMessages = 10000
+
+// What's needed to restore a project from the trash.
+type trashedProject struct {
+	DeletedAt time.Time `json:"deletedAt"`
+	DeletedBy string    `json:"deletedBy"`
+	// When the project's channels are deleted for good.
+	PurgeAt time.Time `json:"purgeAt"`
+	// The project's status and the channel's category before it was deleted.
+	Status   string `json:"status,omitempty"`
+	ParentID string `json:"parentId,omitempty"`
+}
+
+// How long deleted projects stay in the trash, from TRASH_DAYS.
+func trashGracePeriod() time.Duration {
+	days := defaultTrashDays
+	if v := os.Getenv("TRASH_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Printf("Ignoring malformed TRASH_DAYS %q", v)
+		} else {
+			days = n
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Write out a channel's messages as Markdown, oldest first.
+func exportChannelMessages(s *discordgo.Session, channelID string) (string, error) {
+	var messages []*discordgo.Message
+	before := ""
+	for len(messages) < maxExportMessages {
+		page, err := s.ChannelMessages(channelID, 100, before, "", "")
+		if err != nil {
+			return "", err
+		}
+		messages = append(messages, page...)
+		if len(page) < 100 {
+			break
+		}
+		before = page[len(page)-1].ID
+	}
+	slices.Reverse(messages)
+
+	var b strings.Builder
+	for _, m := range messages {
+		fmt.Fprintf(&b, "- **%s** %s: %s\n", m.Timestamp.UTC().Format("2006-01-02 15:04 MST"), m.Author.Username,
+			strings.ReplaceAll(textWithAttachments(m), "\n", "\n  "))
+	}
+	return b.String(), nil
+}
+
+// Move the project the command was called from to the trash: export its messages to the internal channel, take
+// away its members' access, archive it, and schedule its channels to be deleted after the grace period.
+func deleteProjectCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	p, ok := getProject(workspaceProjectID(i.ChannelID))
+	var problem string
+	switch {
+	case !ok:
+		problem = "This channel is not a registered project."
+	case p.Trash != nil:
+		problem = fmt.Sprintf("This project is already in the trash. It'll be deleted for good <t:%d:R>.", p.Trash.PurgeAt.Unix())
+	}
+	if problem != "" {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: problem,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	// Exporting the messages can take a while.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}))
+	content := deleteProject(s, p, i.Member.User.ID)
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+	}
+}
+
+// Move a project to the trash, returning the response to show the caller. Nothing is changed unless the export
+// reaches the internal channel.
+func deleteProject(s *discordgo.Session, p project, userID string) string {
+	channel, err := s.Channel(p.ChannelID)
+	if err != nil {
+		log.Printf("Error reading channel: %v", err)
+		return "Error reading channel: " + describeError(err)
+	}
+	now := time.Now()
+	trash := &trashedProject{
+		DeletedAt: now,
+		DeletedBy: userID,
+		PurgeAt:   now.Add(trashGracePeriod()),
+		Status:    p.Status,
+		ParentID:  channel.ParentID,
+	}
+
+	var files []*discordgo.File
+	channels := workspaceChannels(p.ChannelID)
+	for _, id := range channels {
+		export, err := exportChannelMessages(s, id)
+		if err != nil {
+			log.Printf("Error exporting messages: %v", err)
+			return "Error exporting messages: " + describeError(err)
+		}
+		files = append(files, &discordgo.File{Name: id + ".md", ContentType: "text/markdown", Reader: strings.NewReader(export)})
+	}
+	_, err = s.ChannelMessageSendComplex(InternalChannelId, &discordgo.MessageSend{
+		Content: fmt.Sprintf("<@%s> deleted #%s. Its channels will be deleted for good <t:%d:R>; until then, /restore-project "+
+			"in <#%s> brings it back. Here are its messages.", userID, p.Name, trash.PurgeAt.Unix(), p.ChannelID),
+		Files:           files,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		log.Printf("Error posting message export: %v", err)
+		return "Error posting message export: " + describeError(err)
+	}
+
+	// The project channel loses its members when it's archived, and the rest of the workspace loses them here.
+	var changes []func() error
+	for _, id := range channels[1:] {
+		for memberID := range p.Members {
+			changes = append(changes, func() error {
+				return s.ChannelPermissionDelete(id, memberID)
+			})
+		}
+	}
+	if err := firstOverwriteErr(runOverwrites(changes)); err != nil {
+		log.Printf("Error removing member access: %v", err)
+		return "Error removing member access: " + describeError(err)
+	}
+	note := fmt.Sprintf("This project was deleted. The channel will be deleted for good <t:%d:R>.", trash.PurgeAt.Unix())
+	if err := archiveChannel(s, p, note); err != nil {
+		log.Printf("Error archiving channel: %v", err)
+		return "Error archiving channel: " + describeError(err)
+	}
+
+	err = updateProject(p.ChannelID, func(p *project) {
+		p.Trash = trash
+		p.Status = statusArchived
+		p.StatusChangedAt = now
+	})
+	if err != nil {
+		log.Printf("Error recording deleted project: %v", err)
+		return "Error recording deleted project: " + describeError(err)
+	}
+
+	log.Printf("%s moved project %s to the trash.", userID, p.ChannelID)
+	postAudit(s, auditEntry{
+		Action:    auditProjectDeleted,
+		ActorID:   userID,
+		ChannelID: p.ChannelID,
+		Summary:   fmt.Sprintf("<@%s> deleted #%s. It'll be deleted for good <t:%d:f>.", userID, p.Name, trash.PurgeAt.Unix()),
+	})
+	return fmt.Sprintf("Moved #%s to the trash and posted its messages in <#%s>. Its channels will be deleted for good "+
+		"<t:%d:R>. /restore-project undoes it until then.", p.Name, InternalChannelId, trash.PurgeAt.Unix())
+}
+
+// Bring the project the command was called from back out of the trash.
+func restoreProjectCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	p, ok := getProject(workspaceProjectID(i.ChannelID))
+	var content string
+	switch {
+	case !ok:
+		content = "This channel is not a registered project."
+	case p.Trash == nil:
+		content = "This project isn't in the trash."
+	default:
+		content = restoreProject(s, p, i.Member.User.ID)
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Undo deleteProject, returning the response to show the caller: move the channels back and unarchive them, give
+// the members their access back, and put the project back in the status it had.
+func restoreProject(s *discordgo.Session, p project, userID string) string {
+	edit := &discordgo.ChannelEdit{Name: p.Name, ParentID: p.Trash.ParentID}
+	if _, err := s.ChannelEdit(p.ChannelID, edit); err != nil {
+		log.Printf("Error restoring channel: %v", err)
+		return "Error restoring channel: " + describeError(err)
+	}
+	if p.InternalChannelID != "" {
+		edit.Name = truncate(p.Name, 100-len(internalChannelSuffix)) + internalChannelSuffix
+		if _, err := s.ChannelEdit(p.InternalChannelID, edit); err != nil {
+			log.Printf("Error restoring internal channel: %v", err)
+			return "Error restoring internal channel: " + describeError(err)
+		}
+	}
+	for memberID := range p.Members {
+		if err := joinWorkspace(s, p.ChannelID, memberID); err != nil {
+			log.Printf("Error restoring member access: %v", err)
+			return "Error restoring member access: " + describeError(err)
+		}
+	}
+
+	status := p.Trash.Status
+	err := updateProject(p.ChannelID, func(p *project) {
+		p.Trash = nil
+		p.Status = status
+		p.StatusChangedAt = time.Now()
+		p.ArchivePrompted = false
+	})
+	if err != nil {
+		log.Printf("Error recording restored project: %v", err)
+		return "Error recording restored project: " + describeError(err)
+	}
+	if _, err := s.ChannelMessageSend(p.ChannelID, "This project was restored."); err != nil {
+		recordFailure("announcing restored project", err)
+	}
+
+	log.Printf("%s restored project %s from the trash.", userID, p.ChannelID)
+	postAudit(s, auditEntry{
+		Action:    auditProjectRestored,
+		ActorID:   userID,
+		ChannelID: p.ChannelID,
+		Summary:   fmt.Sprintf("<@%s> restored <#%s> from the trash.", userID, p.ChannelID),
+	})
+	return fmt.Sprintf("Restored <#%s> and gave its %d members their access back.", p.ChannelID, len(p.Members))
+}
+
+// Whether an error is Discord saying a channel doesn't exist, like when someone already deleted it.
+func isUnknownChannel(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownChannel
+}
+
+// Delete projects whose time in the trash is up, now and then.
+func startTrashPurge(s *discordgo.Session) {
+	go func() {
+		for range time.Tick(trashPurgeInterval) {
+			if !paused("purging the trash") {
+				purgeTrash(s)
+			}
+		}
+	}()
+}
+
+// Delete the channels of projects whose time in the trash is up, and then the projects. A project whose channels
+// can't all be deleted stays in the trash to be tried again.
+func purgeTrash(s *discordgo.Session) {
+	var due []project
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if p.Trash != nil && time.Now().After(p.Trash.PurgeAt) {
+				due = append(due, copyProject(p))
+			}
+		}
+	})
+
+	for _, p := range due {
+		channels := append(append(slices.Clone(p.Workspace), p.VoiceChannels...), p.InternalChannelID, p.ChannelID)
+		failed := false
+		for _, id := range channels {
+			if id == "" {
+				continue
+			}
+			if _, err := s.ChannelDelete(id); err != nil && !isUnknownChannel(err) {
+				recordFailure(fmt.Sprintf("deleting channel %s of trashed project %s", id, p.ChannelID), err)
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+
+		err := updateStore(func(d *storeData) {
+			delete(d.Projects, p.ChannelID)
+			delete(d.DigestRecipients, p.ChannelID)
+		})
+		if err != nil {
+			log.Printf("Error removing deleted project: %v", err)
+			continue
+		}
+		log.Printf("Deleted project %s for good.", p.ChannelID)
+		postAudit(s, auditEntry{
+			Action:    auditProjectDeleted,
+			ChannelID: p.ChannelID,
+			Summary:   fmt.Sprintf("#%s was deleted for good, %s after <@%s> deleted it.", p.Name, formatWait(time.Since(p.Trash.DeletedAt)), p.Trash.DeletedBy),
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Projects in the trash don't get digests or rotations, and purging one forgets its digest subscription.
+func TestTrashedProjectsSkipScheduledJobs(t *testing.T) {
+	f, s := newTestBot(t)
+	m := useFakeMailer(t)
+	channelID := addProject(t, f, "acme")
+	err := updateStore(func(d *storeData) {
+		p := d.Projects[channelID]
+		p.Trash = &trashedProject{DeletedAt: time.Now(), PurgeAt: time.Now().Add(time.Hour)}
+		p.Rotation = &rotation{UserIDs: []string{"2000000000000000001", "2000000000000000002"}}
+		d.DigestRecipients = map[string]*digestRecipient{channelID: {Email: "client@example.com"}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendDigests(s)
+	rotate(s)
+	if len(m.sent) != 0 {
+		t.Errorf("%d digests were sent, want none", len(m.sent))
+	}
+	if p, _ := getProject(channelID); p.Rotation.Current != 0 {
+		t.Error("the trashed project's rotation moved on")
+	}
+	if posted := f.posted(channelID); len(posted) != 0 {
+		t.Errorf("the trashed project's channel got %q", posted)
+	}
+
+	if err := updateProject(channelID, func(p *project) { p.Trash.PurgeAt = time.Now() }); err != nil {
+		t.Fatal(err)
+	}
+	purgeTrash(s)
+	readStore(func(d *storeData) {
+		if _, ok := d.Projects[channelID]; ok {
+			t.Error("the project wasn't purged")
+		}
+		if _, ok := d.DigestRecipients[channelID]; ok {
+			t.Error("the purged project's digest subscription was kept")
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long an action can still be undone.
+const undoWindow = 10 * time.Minute
+
+// An action the bot took for someone that can be reverted.
+type undoAction struct {
+	id          int
+	description string
+	at          time.Time
+	revert      func(s *discordgo.Session) error
+}
+
+// Recent reversible actions, keyed by the ID of the user who caused them, newest last. These only live in memory:
+// nothing is undoable for long enough to be worth persisting.
+var (
+	undoMu      sync.Mutex
+	undoStacks  = make(map[string][]undoAction)
+	undoCounter int
+)
+
+// Record an action the caller can revert with /undo.
+func pushUndo(userID, description string, revert func(s *discordgo.Session) error) {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+	undoCounter++
+	stack := slices.DeleteFunc(undoStacks[userID], func(a undoAction) bool { return time.Since(a.at) >= undoWindow })
+	undoStacks[userID] = append(stack, undoAction{id: undoCounter, description: description, at: time.Now(), revert: revert})
+}
+
+// Find the caller's most recent action that can still be undone.
+func lastUndo(userID string) (undoAction, bool) {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+	stack := undoStacks[userID]
+	if len(stack) == 0 || time.Since(stack[len(stack)-1].at) >= undoWindow {
+		return undoAction{}, false
+	}
+	return stack[len(stack)-1], true
+}
+
+// Take an action off the caller's stack so it can be reverted, as long as it's still there and recent enough.
+func popUndo(userID string, id int) (undoAction, bool) {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+	stack := undoStacks[userID]
+	n := slices.IndexFunc(stack, func(a undoAction) bool { return a.id == id })
+	if n < 0 {
+		return undoAction{}, false
+	}
+	a := stack[n]
+	undoStacks[userID] = slices.Delete(stack, n, n+1)
+	return a, time.Since(a.at) < undoWindow
+}
+
+// Work out how to take back adding a member to a project channel, from the state before they're added: their
+// permission overwrites in the channel and the rest of its workspace, whether they had the Project Creator role, and
+// whether the registry listed them. Only what the add changes is reverted.
+func undoAddingMember(s *discordgo.Session, channelID string, user *discordgo.User, member *discordgo.Member) func(s *discordgo.Session) error {
+	channels := workspaceChannels(channelID)
+	previous := make(map[string]*discordgo.PermissionOverwrite)
+	for _, id := range channels {
+		channel, err := s.State.Channel(id)
+		if err != nil {
+			channel, err = s.Channel(id)
+		}
+		if err != nil {
+			continue
+		}
+		for _, o := range channel.PermissionOverwrites {
+			if o.ID == user.ID && o.Type == discordgo.PermissionOverwriteTypeMember {
+				previous[id] = &discordgo.PermissionOverwrite{ID: o.ID, Type: o.Type, Allow: o.Allow, Deny: o.Deny}
+			}
+		}
+	}
+	hadRole := slices.Contains(member.Roles, ProjectCreatorRoleId) || slices.Contains(member.Roles, ServicesRoleId)
+	listed := false
+	if p, ok := getProject(channelID); ok {
+		_, listed = p.Members[user.ID]
+	}
+
+	return func(s *discordgo.Session) error {
+		var changes []func() error
+		for _, id := range channels {
+			changes = append(changes, func() error {
+				if o, ok := previous[id]; ok {
+					return s.ChannelPermissionSet(id, user.ID, o.Type, o.Allow, o.Deny)
+				}
+				return s.ChannelPermissionDelete(id, user.ID)
+			})
+		}
+		errs := runOverwrites(changes)
+		if !hadRole {
+			errs = append(errs, s.GuildMemberRoleRemove(JuiceworksGuildId, user.ID, ProjectCreatorRoleId))
+		}
+		if !listed {
+			errs = append(errs, updateProject(channelID, func(p *project) {
+				delete(p.Members, user.ID)
+			}))
+			revertClientNickname(s, channelID, user.ID)
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// Record how to take back the roles a member toggled from the role menu.
+func pushUndoRoles(userID string, added, removed []string) {
+	var changes []string
+	for _, roleID := range added {
+		changes = append(changes, "added <@&"+roleID+">")
+	}
+	for _, roleID := range removed {
+		changes = append(changes, "removed <@&"+roleID+">")
+	}
+	description := fmt.Sprintf("Your role menu picks (%s)", strings.Join(changes, ", "))
+
+	pushUndo(userID, description, func(s *discordgo.Session) error {
+		var errs []error
+		for _, roleID := range added {
+			errs = append(errs, s.GuildMemberRoleRemove(JuiceworksGuildId, userID, roleID))
+		}
+		for _, roleID := range removed {
+			errs = append(errs, s.GuildMemberRoleAdd(JuiceworksGuildId, userID, roleID))
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// Show the caller's most recent reversible action, with a button to confirm reverting it.
+func undo(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	a, ok := lastUndo(i.Member.User.ID)
+	if !ok {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("You have nothing to undo from the last %d minutes.", int(undoWindow.Minutes())),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("This will revert: %s (<t:%d:R>).", a.description, a.at.Unix()),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Undo",
+						Style:    discordgo.DangerButton,
+						CustomID: "undo:" + strconv.Itoa(a.id),
+					},
+				}},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}))
+}
+
+// Revert the action confirmed with the Undo button.
+func confirmUndo(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, arg, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	id, _ := strconv.Atoi(arg)
+
+	content := "That action can no longer be undone."
+	if a, ok := popUndo(i.Member.User.ID, id); ok {
+		if err := a.revert(s); err != nil {
+			log.Printf("Error undoing action: %v", err)
+			content = "Error undoing action: " + describeError(err)
+		} else {
+			log.Printf("%s undid: %s", i.Member.User, a.description)
+			content = "Reverted: " + a.description + "."
+		}
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	}))
+}
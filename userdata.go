@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// What references to a user are replaced with once their data is deleted.
+const deletedUserID = "deleted"
+
+// A user's membership of a project.
+type userMembership struct {
+	ChannelID  string `json:"channelId"`
+	Project    string `json:"project"`
+	Username   string `json:"username"`
+	Nickname   string `json:"nickname,omitempty"`
+	Role       string `json:"role,omitempty"`
+	HourlyRate int64  `json:"hourlyRate,omitempty"`
+}
+
+// Everything the bot stores about a user, for data subject requests.
+type userData struct {
+	UserID          string           `json:"userId"`
+	Memberships     []userMembership `json:"memberships,omitempty"`
+	CallSessions    []callSession    `json:"callSessions,omitempty"`
+	Files           []archivedFile   `json:"files,omitempty"`
+	RateCard        *rateCard        `json:"rateCard,omitempty"`
+	BookingLink     string           `json:"bookingLink,omitempty"`
+	TermsAcceptance *termsAcceptance `json:"termsAcceptance,omitempty"`
+	Quarantine      *quarantine      `json:"quarantine,omitempty"`
+	Bookmarks       []bookmark       `json:"bookmarks,omitempty"`
+	Reminders       []reminder       `json:"reminders,omitempty"`
+	Snippets        []snippet        `json:"snippets,omitempty"`
+	AccountLink     *accountLink     `json:"accountLink,omitempty"`
+	Leads           []lead           `json:"leads,omitempty"`
+	AuditLog        []auditEntry     `json:"auditLog,omitempty"`
+	Events          []loggedEvent    `json:"events,omitempty"`
+}
+
+// Collect everything the bot stores about a user.
+func collectUserData(userID string) userData {
+	u := userData{UserID: userID}
+	mentioned := regexp.MustCompile(`\b` + userID + `\b`)
+	readStore(func(d *storeData) {
+		for _, p := range d.Projects {
+			if username, ok := p.Members[userID]; ok {
+				m := userMembership{ChannelID: p.ChannelID, Project: p.Name, Username: username, Nickname: p.Nicknames[userID]}
+				if n := slices.IndexFunc(p.Assignments, func(a assignment) bool { return a.UserID == userID }); n >= 0 {
+					m.Role, m.HourlyRate = p.Assignments[n].Role, p.Assignments[n].HourlyRate
+				}
+				u.Memberships = append(u.Memberships, m)
+			}
+			for _, c := range p.Calls {
+				if c.UserID == userID {
+					u.CallSessions = append(u.CallSessions, c)
+				}
+			}
+			for _, f := range p.Files {
+				if f.UploadedBy == userID {
+					u.Files = append(u.Files, f)
+				}
+			}
+		}
+		if r, ok := d.RateCards[userID]; ok {
+			card := *r
+			u.RateCard = &card
+		}
+		u.BookingLink = d.BookingLinks[userID]
+		if t, ok := d.TermsAcceptances[userID]; ok {
+			acceptance := *t
+			u.TermsAcceptance = &acceptance
+		}
+		if q, ok := d.Quarantines[userID]; ok {
+			snapshot := *q
+			u.Quarantine = &snapshot
+		}
+		u.Bookmarks = slices.Clone(d.Bookmarks[userID])
+		for _, r := range d.Reminders {
+			if r.UserID == userID {
+				u.Reminders = append(u.Reminders, r)
+			}
+		}
+		for _, sn := range d.Snippets {
+			if sn.OwnerID == userID {
+				u.Snippets = append(u.Snippets, *sn)
+			}
+		}
+		if l, ok := d.AccountLinks[userID]; ok {
+			u.AccountLink = &l
+		}
+		for _, l := range d.Leads {
+			if l.UserID == userID {
+				u.Leads = append(u.Leads, *l)
+			}
+		}
+		for _, e := range d.AuditLog {
+			if e.ActorID == userID || e.TargetID == userID || mentioned.MatchString(e.Summary) {
+				u.AuditLog = append(u.AuditLog, e)
+			}
+		}
+		for _, e := range d.EventLog {
+			if mentioned.Match(e.Data) {
+				u.Events = append(u.Events, e)
+			}
+		}
+	})
+	return u
+}
+
+// Delete a user's personal records and replace every other reference to them, in logs and in who did what, with a
+// placeholder. The content of their messages saved in others' bookmarks and reminders is dropped too.
+func deleteUserData(userID string) error {
+	mentioned := regexp.MustCompile(`\b` + userID + `\b`)
+	var anonymizeErr error
+	err := updateStore(func(d *storeData) {
+		delete(d.RateCards, userID)
+		delete(d.BookingLinks, userID)
+		delete(d.TermsAcceptances, userID)
+		delete(d.Bookmarks, userID)
+		delete(d.AccountLinks, userID)
+		d.Reminders = slices.DeleteFunc(d.Reminders, func(r reminder) bool { return r.UserID == userID })
+		d.Jobs = slices.DeleteFunc(d.Jobs, func(j *job) bool {
+			return (j.Kind == jobReminder || j.Kind == jobBridgeRelay) && mentioned.Match(j.Payload)
+		})
+		for key, sn := range d.Snippets {
+			if sn.OwnerID == userID {
+				delete(d.Snippets, key)
+			}
+		}
+		// Leads are numbered by their position, so they're emptied rather than removed.
+		for _, l := range d.Leads {
+			if l.UserID == userID {
+				l.Name, l.Company, l.Email, l.Details, l.Timeline = "", "", "", "", ""
+			}
+		}
+		for _, p := range d.Projects {
+			delete(p.Nicknames, userID)
+			p.Assignments = slices.DeleteFunc(p.Assignments, func(a assignment) bool { return a.UserID == userID })
+			p.Calls = slices.DeleteFunc(p.Calls, func(c callSession) bool { return c.UserID == userID })
+		}
+		for owner, bookmarks := range d.Bookmarks {
+			for n := range bookmarks {
+				if bookmarks[n].AuthorID == userID {
+					d.Bookmarks[owner][n].Preview = ""
+				}
+			}
+		}
+		for n := range d.Reminders {
+			if d.Reminders[n].AuthorID == userID {
+				d.Reminders[n].Content = ""
+			}
+		}
+
+		// Whatever's left only refers to the user, so rewrite the references wherever they are.
+		body, err := json.Marshal(d)
+		if err != nil {
+			anonymizeErr = err
+			return
+		}
+		var anonymized storeData
+		if anonymizeErr = json.Unmarshal(mentioned.ReplaceAll(body, []byte(deletedUserID)), &anonymized); anonymizeErr == nil {
+			*d = anonymized
+		}
+	})
+	return errors.Join(err, anonymizeErr)
+}
+
+// Export or delete everything the bot stores about a user. Deleting asks for confirmation first, and is refused
+// while the user is still in a project or quarantined, since the bot needs those records to manage their access.
+func userDataCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	sub := i.ApplicationCommandData().Options[0]
+	user := sub.Options[0].UserValue(s)
+	u := collectUserData(user.ID)
+
+	data := &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+	switch sub.Name {
+	case "export":
+		body, err := json.MarshalIndent(u, "", "  ")
+		if err != nil {
+			log.Printf("Error exporting user data: %v", err)
+			data.Content = "Error exporting user data: " + describeError(err)
+			break
+		}
+		data.Content = fmt.Sprintf("Everything stored about %s.", user.Mention())
+		data.Files = []*discordgo.File{{Name: user.ID + ".json", ContentType: "application/json", Reader: bytes.NewReader(body)}}
+		log.Printf("%s exported the data stored about %s.", i.Member.User, user)
+
+	case "delete":
+		if len(u.Memberships) > 0 {
+			var channels []string
+			for _, m := range u.Memberships {
+				channels = append(channels, "<#"+m.ChannelID+">")
+			}
+			data.Content = fmt.Sprintf("%s is still in %s. Remove them with /remove-member first.", user.Mention(), strings.Join(channels, ", "))
+			break
+		}
+		if u.Quarantine != nil {
+			data.Content = fmt.Sprintf("%s is quarantined. Lift the quarantine first.", user.Mention())
+			break
+		}
+		data.Content = fmt.Sprintf("Delete everything stored about %s and anonymize them in the logs? This can't be undone.", user.Mention())
+		data.Components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Delete", Style: discordgo.DangerButton, CustomID: "user-data-delete:" + user.ID},
+			}},
+		}
+	}
+	data.AllowedMentions = &discordgo.MessageAllowedMentions{}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	}))
+}
+
+// Delete a user's data once the deletion is confirmed.
+func confirmUserDataDelete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, userID, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+
+	content := "Deleted the stored data and anonymized the logs."
+	if err := deleteUserData(userID); err != nil {
+		log.Printf("Error deleting user data: %v", err)
+		content = "Error deleting user data: " + describeError(err)
+	} else {
+		log.Printf("%s deleted the data stored about a user.", i.Member.User)
+		postAudit(s, auditEntry{
+			Action:  auditUserDataDeleted,
+			ActorID: i.Member.User.ID,
+			Summary: fmt.Sprintf("<@%s> deleted the data stored about a user.", i.Member.User.ID),
+		})
+	}
+
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{Content: content, Components: []discordgo.MessageComponent{}},
+	}))
+}
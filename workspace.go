@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The channels a workspace gets when /make-workspace isn't told which to create.
+var defaultWorkspaceChannels = []string{"general", "dev", "design", "billing"}
+
+// The channel a project is registered under. Channels in a project's workspace resolve to the project's channel;
+// any other channel resolves to itself.
+func workspaceProjectID(channelID string) string {
+	projectID := channelID
+	readStore(func(d *storeData) {
+		if _, ok := d.Projects[channelID]; ok {
+			return
+		}
+		for id, p := range d.Projects {
+			if slices.Contains(p.Workspace, channelID) {
+				projectID = id
+				return
+			}
+		}
+	})
+	return projectID
+}
+
+// Every channel in the workspace of the project a channel belongs to, starting with the project's channel. A project
+// without a workspace has just its own channel.
+func workspaceChannels(channelID string) []string {
+	projectID := workspaceProjectID(channelID)
+	p, _ := getProject(projectID)
+	return append([]string{projectID}, p.Workspace...)
+}
+
+// Make a category of private channels for a new project, sharing membership. The first channel is the one the
+// project is registered under; members added from any of them are added to all of them.
+func makeWorkspace(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var name string
+	suffixes := defaultWorkspaceChannels
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "name":
+			name = sanitizeChannelName(o.StringValue())
+		case "channels":
+			suffixes = nil
+			for _, suffix := range strings.Split(o.StringValue(), ",") {
+				suffix = sanitizeChannelName(suffix)
+				if suffix != "" && !slices.Contains(suffixes, suffix) {
+					suffixes = append(suffixes, suffix)
+				}
+			}
+		}
+	}
+	var problem string
+	switch {
+	case utf8.RuneCountInString(name) < 2 || utf8.RuneCountInString(name) > 80:
+		problem = "Workspace name must be between 2 and 80 characters."
+	case len(suffixes) == 0 || len(suffixes) > 10:
+		problem = "A workspace needs between 1 and 10 channels."
+	}
+	if problem != "" {
+		logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: problem,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}))
+		return
+	}
+
+	// Creating several channels can take longer than Discord waits for a response.
+	logResponseErr(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}))
+	content := createWorkspace(s, name, suffixes, i.Member.User.ID)
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+	}
+}
+
+// Create a workspace's category and channels, register it, and return the response for /make-workspace.
+func createWorkspace(s *discordgo.Session, name string, suffixes []string, createdBy string) string {
+	// Channels in the category inherit its permissions: private, except to the Juiceworks role.
+	overwrites := []*discordgo.PermissionOverwrite{
+		{ID: JuiceworksRoleId, Type: discordgo.PermissionOverwriteTypeRole, Allow: discordgo.PermissionViewChannel | discordgo.PermissionSendMessages},
+		{ID: JuiceworksGuildId, Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionViewChannel},
+	}
+	category, err := s.GuildChannelCreateComplex(JuiceworksGuildId, discordgo.GuildChannelCreateData{
+		Name:                 name,
+		Type:                 discordgo.ChannelTypeGuildCategory,
+		PermissionOverwrites: overwrites,
+	})
+	if err != nil {
+		return channelErrorContent(s, "creating category", err, "Manage Channels and Manage Roles", channelLocation(""))
+	}
+
+	var channels []*discordgo.Channel
+	var createErr error
+	for _, suffix := range suffixes {
+		var channel *discordgo.Channel
+		channel, createErr = s.GuildChannelCreateComplex(JuiceworksGuildId, discordgo.GuildChannelCreateData{
+			Name:                 name + "-" + suffix,
+			Type:                 discordgo.ChannelTypeGuildText,
+			ParentID:             category.ID,
+			PermissionOverwrites: overwrites,
+		})
+		if createErr != nil {
+			if len(channels) == 0 {
+				return channelErrorContent(s, "creating channel", createErr, "Manage Channels and Manage Roles", channelLocation(category.ID))
+			}
+			break
+		}
+		channels = append(channels, channel)
+	}
+
+	workspace := make([]string, len(channels)-1)
+	for n, channel := range channels[1:] {
+		workspace[n] = channel.ID
+	}
+	err = updateProject(channels[0].ID, func(p *project) {
+		p.Name = channels[0].Name
+		p.CreatedAt = time.Now()
+		p.CreatedBy = createdBy
+		p.CategoryID = category.ID
+		p.Workspace = workspace
+	})
+	if err != nil {
+		log.Printf("Error recording project: %v", err)
+	}
+	emitEvent("project.created", projectCreatedEvent{ChannelID: channels[0].ID, Name: channels[0].Name, CreatedBy: createdBy})
+	auditProjectCreation(s, createdBy, channels[0].ID)
+	if err := postHuddleButton(s, channels[0].ID); err != nil {
+		log.Printf("Error posting huddle button: %v", err)
+	}
+
+	mentions := make([]string, len(channels))
+	for n, channel := range channels {
+		mentions[n] = "<#" + channel.ID + ">"
+	}
+	log.Printf("Created workspace %s with %d channels.", name, len(channels))
+	content := fmt.Sprintf("Created the %s workspace: %s", name, strings.Join(mentions, ", "))
+	if createErr != nil {
+		content += fmt.Sprintf("\nOnly %d of the %d channels could be created. %s", len(channels), len(suffixes),
+			channelErrorContent(s, "creating channel", createErr, "Manage Channels and Manage Roles", channelLocation(category.ID)))
+	}
+	return content
+}